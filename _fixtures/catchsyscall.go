@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+func main() {
+	f, err := os.Open("/dev/null")
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+	f.Close()
+	// system call number that does not exist, it returns ENOSYS
+	syscall.Syscall(1000, 0, 0, 0)
+}
@@ -0,0 +1,14 @@
+package main
+
+import "fmt"
+
+var g1, g2, g3, g4, g5 int
+
+func main() {
+	g1 = 1
+	g2 = 2
+	g3 = 3
+	g4 = 4
+	g5 = 5
+	fmt.Println(g1, g2, g3, g4, g5)
+}
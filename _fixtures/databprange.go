@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+type Foo struct {
+	a   int32
+	bar int32
+	c   int64
+}
+
+var gfoo Foo
+var gbuf = make([]byte, 64)
+
+func main() {
+	gfoo.a = 1
+	gfoo.bar = 2
+	gbuf[5] = 'x'
+	gbuf[40] = 'y'
+	fmt.Println(gfoo, gbuf)
+}
@@ -0,0 +1,17 @@
+package main
+
+import "fmt"
+
+var gval int64
+
+func get() int64 {
+	return gval
+}
+
+func main() {
+	gval = 1
+	gval = 2
+	x := get()
+	gval = 3
+	fmt.Println(x, gval)
+}
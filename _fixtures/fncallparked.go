@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+type state struct {
+	n int
+}
+
+func (s *state) String() string {
+	return fmt.Sprintf("state %d", s.n)
+}
+
+func double(n int) int {
+	return 2 * n
+}
+
+func worker(s *state, ch chan int) {
+	s.n = <-ch
+}
+
+func main() {
+	s := &state{n: 1}
+	ch := make(chan int)
+	go worker(s, ch)
+	for i := 0; i < 10; i++ {
+		runtime.Gosched()
+	}
+	runtime.Breakpoint()
+	ch <- 2
+	fmt.Println(s, double)
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+func child() {
+	fmt.Println("child", os.Getpid())
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		child()
+		return
+	}
+	cmd := exec.Command(os.Args[0], "child")
+	cmd.Stdout = os.Stdout
+	if err := cmd.Run(); err != nil {
+		fmt.Println(err)
+	}
+}
@@ -0,0 +1,20 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+)
+
+func handle(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("recovered:", r)
+		}
+	}()
+	f()
+}
+
+func main() {
+	handle(func() { panic("a string") })
+	handle(func() { panic(errors.New("an error")) })
+}
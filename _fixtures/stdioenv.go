@@ -0,0 +1,13 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+)
+
+func main() {
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	fmt.Printf("%s%s %d\n", line, os.Getenv("DLV_TEST_ENV"), len(os.Environ()))
+	fmt.Fprintln(os.Stderr, "to stderr")
+}
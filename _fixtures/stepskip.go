@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+func skipme(n int) int {
+	return helper(n) + 1
+}
+
+func helper(n int) int {
+	return n * 2
+}
+
+func main() {
+	x := skipme(1)
+	fmt.Println(x)
+	x = skipme(x)
+	fmt.Println(x)
+	y := helper(2)
+	fmt.Println(y)
+}
@@ -0,0 +1,13 @@
+package main
+
+import "os"
+
+var status = 3
+
+func exitWithStatus() {
+	os.Exit(status)
+}
+
+func main() {
+	exitWithStatus()
+}
@@ -9,6 +9,24 @@ type astruct struct {
 	x, y int
 }
 
+type Set[T comparable] struct {
+	m map[T]struct{}
+}
+
+func (s *Set[T]) Add(v T) {
+	s.m[v] = struct{}{}
+}
+
+func (s *Set[T]) Has(v T) bool {
+	_, ok := s.m[v]
+	return ok
+}
+
+func (s *Set[T]) Len() int {
+	runtime.Breakpoint()
+	return len(s.m)
+}
+
 func testfn[T any, K comparable](arg1 T, arg2 K) {
 	m := make(map[K]T)
 	m[arg2] = arg1
@@ -16,7 +34,16 @@ func testfn[T any, K comparable](arg1 T, arg2 K) {
 	fmt.Println(arg1, arg2, m)
 }
 
+func sum[T int | float64](a, b T) T {
+	return a + b
+}
+
 func main() {
 	testfn[int, float32](3, 2.1)
 	testfn(&astruct{0, 1}, astruct{2, 3})
+	s := &Set[string]{m: map[string]struct{}{}}
+	s.Add("a")
+	s.Len()
+	runtime.Breakpoint()
+	fmt.Println(s.Has("a"), sum(1, 2), sum(1.5, 2))
 }
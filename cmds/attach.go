@@ -4,13 +4,18 @@ import (
 	"errors"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc"
 	"github.com/hitzhangjie/dlv/service/debugger"
 )
 
+// waitFor describes the process attach waits for, see --waitfor.
+var waitFor proc.WaitFor
+
 // 'attach' subcommand.
 var attachCommand = &cobra.Command{
 	Use:   "attach <pid>",
@@ -20,14 +25,28 @@ var attachCommand = &cobra.Command{
 This command will cause Delve to take control of an already running process, 
 and begin a new debug session. When exiting the debug session you will have 
 the option to let the process continue or kill it.
+
+With --waitfor Delve waits for a process to be started, instead of
+attaching to a PID, and attaches to it as soon as it appears:
+
+	dlv attach --waitfor worker
+	dlv attach --waitfor 'worker -queue=(high|low)' --waitfor-regexp --waitfor-duration 10m
+
+Without --waitfor-regexp the name must be equal to the name of the process
+or to the base name of its executable, or be a prefix of its command line.
+Processes that are already running are ignored. The process is left
+stopped, usually before main.main runs.
 `,
 	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 0 {
-			return errors.New("you must provide a PID")
+		if len(args) == 0 && waitFor.Name == "" {
+			return errors.New("you must provide a PID or --waitfor")
 		}
 		return nil
 	},
 	Run: func(cmd *cobra.Command, args []string) {
+		if waitFor.Name != "" {
+			os.Exit(execute(0, args, "", debugger.ExecutingOther, args))
+		}
 		pid, err := strconv.Atoi(args[0])
 		if err != nil {
 			log.Error("Invalid pid: %s", args[0])
@@ -39,5 +58,9 @@ the option to let the process continue or kill it.
 
 func init() {
 	attachCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start.")
+	attachCommand.Flags().StringVar(&waitFor.Name, "waitfor", "", "Wait for a process with the given name, executable or command line prefix to be started and attach to it.")
+	attachCommand.Flags().BoolVar(&waitFor.Regexp, "waitfor-regexp", false, "The --waitfor name is a regular expression matched against the name and command line of the processes.")
+	attachCommand.Flags().DurationVar(&waitFor.Interval, "waitfor-interval", time.Millisecond, "Interval between two scans of the process list for --waitfor.")
+	attachCommand.Flags().DurationVar(&waitFor.Duration, "waitfor-duration", 0, "Maximum time to wait for the --waitfor process, zero waits forever.")
 	rootCommand.AddCommand(attachCommand)
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/hitzhangjie/dlv/pkg/config"
 	"github.com/hitzhangjie/dlv/pkg/gobuild"
 	"github.com/hitzhangjie/dlv/pkg/log"
 	"github.com/hitzhangjie/dlv/service/debugger"
@@ -27,6 +28,7 @@ session.`,
 func init() {
 	debugCommand.Flags().String("output", "./__debug_bin", "Output path for the binary.")
 	debugCommand.Flags().BoolVar(&continueOnStart, "continue", false, "Continue the debugged process on start.")
+	debugCommand.Flags().BoolVar(&watch, "watch", false, "Rebuild and restart the program, keeping its breakpoints, when the Go source files of the main module change.")
 	rootCommand.AddCommand(debugCommand)
 }
 
@@ -51,10 +53,17 @@ func buildBinary(cmd *cobra.Command, args []string, isTest bool) (string, bool)
 		return "", false
 	}
 
+	if !cmd.Flag("build-flags").Changed {
+		// the flags of the configuration file are used unless overridden
+		if conf, err := config.LoadConfig(); err == nil {
+			buildFlags = conf.BuildFlags
+		}
+	}
+
 	if isTest {
-		err = gobuild.GoTestBuild(debugname, args)
+		err = gobuild.GoTestBuild(debugname, args, buildFlags)
 	} else {
-		err = gobuild.GoBuild(debugname, args)
+		err = gobuild.GoBuild(debugname, args, buildFlags)
 	}
 	if err != nil {
 		log.Error("%v", err)
@@ -5,6 +5,7 @@ import (
 	"net"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -27,6 +28,20 @@ var (
 	addr        string // the debugging server listen address
 	workingDir  string // the working directory for running the program
 	disableASLR bool   // whether disables ASLR
+	followFork  bool   // whether child processes created with fork are debugged too
+	followExec  bool   // whether programs executed by the target are debugged too
+	nonStop     bool   // whether only the thread that hits a breakpoint is stopped
+	stopOnExit  bool   // whether the target is stopped just before it exits
+
+	// build settings
+	buildFlags string // flags passed to the go command by debug, test and trace
+	watch      bool   // whether debug and test rebuild and restart the target when the sources change
+
+	// target process settings
+	redirects []string // redirect rules for the standard file descriptors, see 'dlv help redirect'
+	tty       string   // terminal the target is attached to
+	envs      []string // environment variables added to the environment of the target
+	clearEnv  bool     // whether the target does not inherit the environment of the debugger
 
 	// checkGoVersion is true if the debugger should check the version of Go
 	// used to compile the executable and refuse to work on incompatible
@@ -39,6 +54,12 @@ var (
 	traceTestBinary bool
 	traceStackDepth int
 	traceUseEBPF    bool
+	traceCaptures   []string
+	traceStringLen  int
+	traceArrayLen   int
+	traceCond       string
+	traceLatency    bool
+	traceFollow     int
 
 	// logging level
 	verbose bool
@@ -76,7 +97,74 @@ func init() {
 	rootCommand.PersistentFlags().StringVar(&workingDir, "wd", "", "Working directory for running the program.")
 	rootCommand.PersistentFlags().BoolVarP(&checkGoVersion, "check-go-version", "", true, "Exits if the version of Go in use is not compatible (too old or too new) with the version of Delve.")
 	rootCommand.PersistentFlags().BoolVar(&disableASLR, "disable-aslr", false, "Disables address space randomization")
+	rootCommand.PersistentFlags().BoolVar(&followFork, "follow-fork", false, "Debugs child processes created with fork as additional targets.")
+	rootCommand.PersistentFlags().BoolVar(&followExec, "follow-exec", false, "Debugs programs executed by the target (exec, os/exec) as additional targets.")
+	rootCommand.PersistentFlags().BoolVar(&nonStop, "non-stop", false, "Only stops the thread that hits a breakpoint, the other threads keep running.")
+	rootCommand.PersistentFlags().BoolVar(&stopOnExit, "stop-on-exit", false, "Stops the target just before it exits, while goroutines and variables can still be inspected.")
+	rootCommand.PersistentFlags().StringVar(&buildFlags, "build-flags", "", "Build flags to pass to the go command, for example --build-flags=\"-tags=integration -ldflags='-X main.v=1'\". Values of -gcflags are merged with the flags disabling optimizations.")
+	rootCommand.PersistentFlags().StringArrayVarP(&redirects, "redirect", "r", []string{}, "Specifies redirect rules for target process (see 'dlv help redirect')")
+	rootCommand.PersistentFlags().StringVar(&tty, "tty", "", "TTY to use for the target program")
+	rootCommand.PersistentFlags().StringArrayVar(&envs, "env", []string{}, "Adds an environment variable, in the KEY=VALUE form, to the environment of the target program (see 'dlv help redirect')")
+	rootCommand.PersistentFlags().BoolVar(&clearEnv, "clear-env", false, "The target program does not inherit the environment of the debugger, it only gets the variables set with --env")
 	rootCommand.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show verbose logging message")
+
+	rootCommand.AddCommand(&cobra.Command{
+		Use:   "redirect",
+		Short: "Help about file redirection.",
+		Long: `The standard file descriptors of the target process can be controlled using the '-r' and '--tty' arguments. 
+
+The --tty argument allows redirecting all standard descriptors to a terminal, specified as an argument to --tty.
+
+The syntax for '-r' argument is:
+
+		-r [source:]destination
+
+Where source is one of 'stdin', 'stdout' or 'stderr' and destination is the path to a file. If the source is omitted stdin is used implicitly.
+Redirects take precedence over --tty, for example '--tty /dev/pts/3 -r stdin:input.txt' reads stdin from a file and writes stdout and stderr to the terminal.
+
+The environment of the target process can be controlled using the '--env' and '--clear-env' arguments:
+
+		--env KEY=VALUE
+
+adds a variable to the environment of the target, it can be repeated. With --clear-env the target does not inherit the environment of Delve and only gets the variables set with --env.
+
+File redirects, the tty and the environment are reused by the 'restart' command.`,
+	})
+}
+
+// parseRedirects parses the redirect rules in the [source:]destination form
+// into the paths stdin, stdout and stderr are redirected to.
+func parseRedirects(rules []string) ([3]string, error) {
+	var r [3]string
+	for _, rule := range rules {
+		source, dest := "stdin", rule
+		if i := strings.Index(rule, ":"); i >= 0 {
+			switch rule[:i] {
+			case "stdin", "stdout", "stderr":
+				source, dest = rule[:i], rule[i+1:]
+			}
+		}
+		if dest == "" {
+			return r, fmt.Errorf("redirect %q has no destination", rule)
+		}
+		i := map[string]int{"stdin": 0, "stdout": 1, "stderr": 2}[source]
+		if r[i] != "" {
+			return r, fmt.Errorf("%s redirected more than once", source)
+		}
+		r[i] = dest
+	}
+	return r, nil
+}
+
+// checkEnv returns an error if one of the environment variables in env is
+// not in the KEY=VALUE form.
+func checkEnv(env []string) error {
+	for _, kv := range env {
+		if i := strings.Index(kv, "="); i <= 0 {
+			return fmt.Errorf("environment variable %q must be in the KEY=VALUE form", kv)
+		}
+	}
+	return nil
 }
 
 func execute(attachPid int, processArgs []string, coreFile string, kind debugger.ExecuteKind, dlvArgs []string) int {
@@ -104,6 +192,16 @@ func execute(attachPid int, processArgs []string, coreFile string, kind debugger
 		workingDir = "."
 	}
 
+	redirectPaths, err := parseRedirects(redirects)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := checkEnv(envs); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+
 	var listener net.Listener
 	var clientConn net.Conn
 
@@ -130,13 +228,24 @@ func execute(attachPid int, processArgs []string, coreFile string, kind debugger
 		DisconnectChan: disconnectChan,
 		DebuggerConfig: debugger.Config{
 			AttachPid:      attachPid,
+			AttachWaitFor:  &waitFor,
 			WorkingDir:     workingDir,
 			CoreFile:       coreFile,
 			Foreground:     headless,
 			Packages:       dlvArgs,
+			BuildFlags:     buildFlags,
+			Watch:          watch,
 			ExecuteKind:    kind,
 			CheckGoVersion: checkGoVersion,
 			DisableASLR:    disableASLR,
+			FollowFork:     followFork,
+			FollowExec:     followExec,
+			NonStop:        nonStop,
+			StopOnExit:     stopOnExit,
+			TTY:            tty,
+			Redirects:      redirectPaths,
+			Env:            envs,
+			ClearEnv:       clearEnv,
 		},
 	})
 
@@ -30,6 +30,7 @@ See also: 'go help testflag'.`,
 
 func init() {
 	testCommand.Flags().String("output", "debug.test", "Output path for the binary.")
+	testCommand.Flags().BoolVar(&watch, "watch", false, "Rebuild and restart the test binary, keeping its breakpoints, when the Go source files of the main module change.")
 	rootCommand.AddCommand(testCommand)
 }
 
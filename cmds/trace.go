@@ -2,9 +2,10 @@ package cmds
 
 import (
 	"fmt"
+	"io"
 	"os"
-	"reflect"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
@@ -38,6 +39,12 @@ func init() {
 	traceCommand.Flags().BoolVarP(&traceTestBinary, "test", "t", false, "Trace a test binary.")
 	traceCommand.Flags().BoolVarP(&traceUseEBPF, "ebpf", "", false, "Trace using eBPF (experimental).")
 	traceCommand.Flags().IntVarP(&traceStackDepth, "stack", "s", 0, "Show stack trace with given depth. (Ignored with -ebpf)")
+	traceCommand.Flags().StringSliceVarP(&traceCaptures, "capture", "", nil, "Expressions, such as req.Header.Host, read by following pointers from the arguments of traced functions. (Only with -ebpf)")
+	traceCommand.Flags().IntVarP(&traceStringLen, "string-len", "", 64, "Maximum number of bytes read from string arguments. (Only with -ebpf)")
+	traceCommand.Flags().IntVarP(&traceArrayLen, "array-len", "", 64, "Maximum number of elements read from slice arguments. (Only with -ebpf)")
+	traceCommand.Flags().StringVarP(&traceCond, "cond", "", "", "Only report calls for which the condition, for example 'req.ID > 100 && len(name) == 0', is true. It is evaluated in the kernel. (Only with -ebpf)")
+	traceCommand.Flags().BoolVarP(&traceLatency, "latency", "", false, "Print a histogram of the duration of the calls to the traced functions instead of reporting each call. (Only with -ebpf)")
+	traceCommand.Flags().IntVarP(&traceFollow, "follow-calls", "", 0, "Trace the functions called by the matched functions, down to the given depth, and print a call tree. (Ignored with -ebpf)")
 	traceCommand.Flags().String("output", "debug", "Output path for the binary.")
 	rootCommand.AddCommand(traceCommand)
 }
@@ -115,7 +122,7 @@ func status(cmd *cobra.Command, args []string) int {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
-	if err := createTracepoints(client, funcs); err != nil {
+	if err := createTracepoints(client, funcs, regexp); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		return 1
 	}
@@ -123,6 +130,16 @@ func status(cmd *cobra.Command, args []string) int {
 	cmds := terminal.DebugCommands(client)
 	t := terminal.New(client, nil)
 	defer t.Close()
+	if traceUseEBPF && traceLatency {
+		done := make(chan struct{})
+		go printLatencyHistograms(client, done)
+		cmds.Call("continue", t)
+		close(done)
+		if hists, err := client.GetLatencyHistograms(); err == nil {
+			writeLatencyHistograms(os.Stderr, hists)
+		}
+		return 0
+	}
 	if traceUseEBPF {
 		done := make(chan struct{})
 		defer close(done)
@@ -132,11 +149,18 @@ func status(cmd *cobra.Command, args []string) int {
 	return 0
 }
 
-func createTracepoints(client *service.RPCClient, funcs []string) error {
+// createTracepoints sets a tracepoint on every function in funcs, which are
+// the functions matching regexp.
+func createTracepoints(client *service.RPCClient, funcs []string, regexp string) error {
+	var rootFuncName string
+	if traceFollow > 0 {
+		rootFuncName = regexp
+	}
 	for i := range funcs {
 		// use EBPF based tracing
 		if traceUseEBPF {
-			if err := client.CreateEBPFTracepoint(funcs[i]); err != nil {
+			loadArgs := &api.LoadConfig{MaxStringLen: traceStringLen, MaxArrayValues: traceArrayLen}
+			if err := client.CreateEBPFTracepoint(funcs[i], loadArgs, traceCaptures, traceCond, traceLatency); err != nil {
 				return err
 			}
 			continue
@@ -144,11 +168,13 @@ func createTracepoints(client *service.RPCClient, funcs []string) error {
 
 		// fall back to breakpoint based tracing if we get an error.
 		_, err := client.CreateBreakpoint(&api.Breakpoint{
-			FunctionName: funcs[i],
-			Tracepoint:   true,
-			Line:         -1,
-			Stacktrace:   traceStackDepth,
-			LoadArgs:     &terminal.ShortLoadConfig,
+			FunctionName:     funcs[i],
+			Tracepoint:       true,
+			Line:             -1,
+			Stacktrace:       traceStackDepth,
+			LoadArgs:         &terminal.ShortLoadConfig,
+			RootFuncName:     rootFuncName,
+			TraceFollowCalls: traceFollow,
 		})
 		if err != nil && !isBreakpointExistsErr(err) {
 			return err
@@ -159,11 +185,13 @@ func createTracepoints(client *service.RPCClient, funcs []string) error {
 		}
 		for i := range addrs {
 			_, err = client.CreateBreakpoint(&api.Breakpoint{
-				Addr:        addrs[i],
-				TraceReturn: true,
-				Stacktrace:  traceStackDepth,
-				Line:        -1,
-				LoadArgs:    &terminal.ShortLoadConfig,
+				Addr:             addrs[i],
+				TraceReturn:      true,
+				Stacktrace:       traceStackDepth,
+				Line:             -1,
+				LoadArgs:         &terminal.ShortLoadConfig,
+				RootFuncName:     rootFuncName,
+				TraceFollowCalls: traceFollow,
 			})
 			if err != nil && !isBreakpointExistsErr(err) {
 				return err
@@ -198,22 +226,90 @@ func printTracepointHits(client *service.RPCClient, done chan struct{}) {
 				if params.Len() > 0 {
 					params.WriteString(", ")
 				}
-				if p.Kind == reflect.String {
-					params.WriteString(fmt.Sprintf("%q", p.Value))
-				} else {
-					params.WriteString(p.Value)
-				}
+				params.WriteString(p.SinglelineString())
+			}
+			var captures strings.Builder
+			for _, p := range t.Captures {
+				fmt.Fprintf(&captures, " %s=%s", p.Name, p.SinglelineString())
+			}
+			if t.Inlined {
+				// Inlined calls have no return event.
+				log.Error("> (%d) %s(%s)%s [inlined]", t.GoroutineID, t.FunctionName, params.String(), captures.String())
+				continue
 			}
 			_, seen := gFnEntrySeen[t.GoroutineID]
 			if seen {
 				for _, p := range t.ReturnParams {
 					log.Error("=> %#v", p.Value)
 				}
+				if captures.Len() > 0 {
+					log.Error("=>%s", captures.String())
+				}
 				delete(gFnEntrySeen, t.GoroutineID)
 			} else {
 				gFnEntrySeen[t.GoroutineID] = struct{}{}
-				log.Error("> (%d) %s(%s)", t.GoroutineID, t.FunctionName, params.String())
+				log.Error("> (%d) %s(%s)%s", t.GoroutineID, t.FunctionName, params.String(), captures.String())
+			}
+		}
+	}
+}
+
+// latencyInterval is how often latency histograms are printed while the
+// target is running.
+const latencyInterval = 5 * time.Second
+
+func printLatencyHistograms(client *service.RPCClient, done chan struct{}) {
+	ticker := time.NewTicker(latencyInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		hists, err := client.GetLatencyHistograms()
+		if err != nil {
+			log.Error("could not read latency histograms: %v", err)
+			return
+		}
+		writeLatencyHistograms(os.Stderr, hists)
+	}
+}
+
+// writeLatencyHistograms prints hists in the same format used by the
+// funclatency tool of bcc, only rows between the first and the last
+// non-empty bucket are printed.
+func writeLatencyHistograms(w io.Writer, hists []api.LatencyHistogram) {
+	const barWidth = 40
+	for _, h := range hists {
+		first, last := -1, -1
+		var max uint64
+		for i, n := range h.Counts {
+			if n == 0 {
+				continue
+			}
+			if first < 0 {
+				first = i
+			}
+			last = i
+			if n > max {
+				max = n
+			}
+		}
+		if first < 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\nFunction = %s\n", h.FunctionName)
+		fmt.Fprintf(w, "%20s : %-10s %s\n", "nsecs", "count", "distribution")
+		for i := first; i <= last; i++ {
+			var lo uint64
+			if i > 0 {
+				lo = 1 << uint(i)
 			}
+			hi := uint64(1)<<uint(i+1) - 1
+			bar := int(h.Counts[i] * barWidth / max)
+			fmt.Fprintf(w, "%10d -> %-10d : %-10d |%-*s|\n", lo, hi, h.Counts[i], barWidth, strings.Repeat("*", bar))
 		}
 	}
 }
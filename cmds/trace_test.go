@@ -0,0 +1,32 @@
+package cmds
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/hitzhangjie/dlv/service/api"
+)
+
+func TestWriteLatencyHistograms(t *testing.T) {
+	counts := make([]uint64, 64)
+	counts[1] = 2
+	counts[3] = 4
+	var buf bytes.Buffer
+	writeLatencyHistograms(&buf, []api.LatencyHistogram{
+		{FunctionName: "main.empty", Counts: make([]uint64, 64)},
+		{FunctionName: "main.f", Counts: counts},
+	})
+
+	// empty histograms are skipped, only the rows between the first and
+	// the last non-empty bucket are printed.
+	const expected = `
+Function = main.f
+               nsecs : count      distribution
+         2 -> 3          : 2          |********************                    |
+         4 -> 7          : 0          |                                        |
+         8 -> 15         : 4          |****************************************|
+`
+	if out := buf.String(); out != expected {
+		t.Fatalf("wrong output:\n%s\nexpected:\n%s", out, expected)
+	}
+}
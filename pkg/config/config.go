@@ -22,12 +22,47 @@ type SubstitutePathRule struct {
 // SubstitutePathRules is a slice of source code path substitution rules.
 type SubstitutePathRules []SubstitutePathRule
 
+// StepSkipRule describes functions that the step command never stops in,
+// the call is stepped over instead.
+type StepSkipRule struct {
+	// Kind is the part of the function matched by Pattern, one of "func",
+	// "package" or "file".
+	Kind string
+	// Pattern is a glob pattern, a "**" path element matches any number of
+	// path elements. File patterns without a path separator are matched
+	// against the base name of the file.
+	Pattern string
+}
+
+// StepSkipRules is a slice of step skip rules.
+type StepSkipRules []StepSkipRule
+
+// SignalPolicy describes how a signal received by the target is handled,
+// see the handle command.
+type SignalPolicy struct {
+	// Signal is the name of the signal, for example SIGUSR1.
+	Signal string
+	// Stop the target when it receives the signal.
+	Stop bool
+	// Print a message when the target receives the signal.
+	Print bool
+	// Pass the signal to the target.
+	Pass bool
+}
+
 // Config defines all configuration options available to be set through the config file.
 type Config struct {
 	// Commands aliases.
 	Aliases map[string][]string `yaml:"aliases"`
 	// Source code path substitution rules.
 	SubstitutePath SubstitutePathRules `yaml:"substitute-path"`
+	// Functions, packages and files that step never stops in.
+	StepSkip StepSkipRules `yaml:"step-skip"`
+	// How signals received by the target are handled.
+	Signals []SignalPolicy `yaml:"signals"`
+	// Flags passed to the go command by debug, test and rebuild, when
+	// --build-flags is not used.
+	BuildFlags string `yaml:"build-flags,omitempty"`
 
 	// MaxStringLen is the maximum string length that the commands print,
 	// locals, args and vars should read (in verbose mode).
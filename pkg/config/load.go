@@ -9,14 +9,19 @@ import (
 	"gopkg.in/yaml.v2"
 )
 
-var loadConfigOnce sync.Once
+var (
+	loadConfigOnce sync.Once
+	loadedConfig   *Config
+	loadConfigErr  error
+)
 
 // LoadConfig attempts to populate a Config object from the config.yml file.
-func LoadConfig() (cfg *Config, err error) {
+// The file is only read the first time, later calls return the same result.
+func LoadConfig() (*Config, error) {
 	loadConfigOnce.Do(func() {
-		cfg, err = loadConfig()
+		loadedConfig, loadConfigErr = loadConfig()
 	})
-	return cfg, err
+	return loadedConfig, loadConfigErr
 }
 
 func loadConfig() (*Config, error) {
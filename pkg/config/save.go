@@ -85,6 +85,24 @@ aliases:
 # commands.
 substitute-path:
   # - {from: path, to: path}
+
+# Functions, packages and source files the step command never stops in,
+# calls to them are stepped over. Patterns are globs, a ** path element matches
+# any number of path elements, kind is one of func, package or file.
+step-skip:
+  # - {kind: package, pattern: github.com/sirupsen/logrus}
+  # - {kind: package, pattern: "google.golang.org/grpc/**"}
+  # - {kind: func, pattern: "*.String"}
+  # - {kind: file, pattern: "*.pb.go"}
+
+# How signals received by the target are handled, see the handle command.
+# Signals that are not listed are passed to the target silently.
+signals:
+  # - {signal: SIGUSR1, stop: false, print: true, pass: true}
+
+# Flags passed to the go command by debug, test and rebuild, unless --build-flags is used.
+# Values of -gcflags are merged with the flags disabling optimizations.
+# build-flags: "-tags=integration -ldflags='-X main.version=dev'"
   
 # Maximum number of elements loaded from an array.
 # max-array-values: 64
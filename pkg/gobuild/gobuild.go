@@ -5,7 +5,9 @@ package gobuild
 import (
 	"os"
 	"os/exec"
+	"strings"
 
+	"github.com/hitzhangjie/dlv/pkg/config"
 	"github.com/hitzhangjie/dlv/pkg/goversion"
 	"github.com/hitzhangjie/dlv/pkg/log"
 )
@@ -19,7 +21,9 @@ func Remove(path string) {
 }
 
 // optflags generates default build flags to turn off optimization and inlining.
-func optflags(args []string) []string {
+// The returned string is the value of -gcflags disabling them, without the
+// package pattern.
+func optflags(args []string) ([]string, string) {
 	// after go1.9 building with -gcflags='-N -l' and -a simultaneously works.
 	// after go1.10 specifying -a is unnecessary because of the new caching strategy,
 	// but we should pass -gcflags=all=-N -l to have it applied to all packages
@@ -34,31 +38,63 @@ func optflags(args []string) []string {
 	default:
 		args = append(args, "-gcflags", "-N -l")
 	}
-	return args
+	return args, "-N -l"
 }
 
 // GoBuild builds non-test files in 'pkgs' and writes the output at 'debugname'.
-func GoBuild(debugname string, pkgs []string) error {
-	args := goBuildArgs(debugname, pkgs, false)
+// The flags in 'buildflags' are passed to go build, see goBuildArgs.
+func GoBuild(debugname string, pkgs []string, buildflags string) error {
+	args := goBuildArgs(debugname, pkgs, buildflags, false)
 	return goCommandRun("build", args...)
 }
 
 // GoTestBuild builds test files 'pkgs' and writes the output at 'debugname'.
-func GoTestBuild(debugname string, pkgs []string) error {
-	args := goBuildArgs(debugname, pkgs, true)
+// The flags in 'buildflags' are passed to go test, see goBuildArgs.
+func GoTestBuild(debugname string, pkgs []string, buildflags string) error {
+	args := goBuildArgs(debugname, pkgs, buildflags, true)
 	return goCommandRun("test", args...)
 }
 
-func goBuildArgs(debugname string, pkgs []string, isTest bool) []string {
+// goBuildArgs returns the arguments of go build, or go test if isTest is
+// set. The flags in buildflags are split on spaces, single quotes can be
+// used for flags containing spaces, for example -ldflags='-X main.v=1'.
+// Since the go command only honors the last -gcflags matching a package,
+// the values of -gcflags in buildflags are merged with the flags disabling
+// optimizations.
+func goBuildArgs(debugname string, pkgs []string, buildflags string, isTest bool) []string {
 	args := []string{"-o", debugname}
 	if isTest {
 		args = append([]string{"-c"}, args...)
 	}
-	args = optflags(args)
+	args, noopt := optflags(args)
+	userflags := config.SplitQuotedFields(buildflags, '\'')
+	for i := 0; i < len(userflags); i++ {
+		flag := userflags[i]
+		name := strings.TrimLeft(flag, "-")
+		switch {
+		case name == "gcflags" && i+1 < len(userflags):
+			i++
+			args = append(args, "-gcflags", mergeGcflags(userflags[i], noopt))
+		case strings.HasPrefix(name, "gcflags="):
+			args = append(args, "-gcflags", mergeGcflags(name[len("gcflags="):], noopt))
+		default:
+			args = append(args, flag)
+		}
+	}
 	args = append(args, pkgs...)
 	return args
 }
 
+// mergeGcflags adds the flags disabling optimizations to value, a value of
+// -gcflags optionally starting with a package pattern.
+func mergeGcflags(value, noopt string) string {
+	pattern, flags := "", value
+	if i := strings.Index(value, "="); i >= 0 && !strings.HasPrefix(value, "-") {
+		pattern, flags = value[:i+1], value[i+1:]
+	}
+	return pattern + strings.TrimSpace(noopt+" "+flags)
+}
+
 func goCommandRun(command string, args ...string) error {
 	cmdArgs := []string{command}
 	cmdArgs = append(cmdArgs, args...)
@@ -0,0 +1,31 @@
+package gobuild
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGoBuildArgs(t *testing.T) {
+	base, noopt := optflags([]string{"-o", "out"})
+	if noopt != "-N -l" {
+		t.Fatalf("unexpected optimization flags %q", noopt)
+	}
+	tests := []struct {
+		buildflags string
+		tail       []string
+	}{
+		{"", []string{"pkg"}},
+		{"-tags=foo -race", []string{"-tags=foo", "-race", "pkg"}},
+		{"-ldflags='-X main.v=1' -mod vendor", []string{"-ldflags=-X main.v=1", "-mod", "vendor", "pkg"}},
+		{"-gcflags=-m", []string{"-gcflags", "-N -l -m", "pkg"}},
+		{"-gcflags 'all=-d=checkptr'", []string{"-gcflags", "all=-N -l -d=checkptr", "pkg"}},
+		{"--gcflags=example.com/pkg=-m -tags foo", []string{"-gcflags", "example.com/pkg=-N -l -m", "-tags", "foo", "pkg"}},
+	}
+	for _, tc := range tests {
+		got := goBuildArgs("out", []string{"pkg"}, tc.buildflags, false)
+		want := append(append([]string{}, base...), tc.tail...)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("goBuildArgs(%q): got %q, want %q", tc.buildflags, got, want)
+		}
+	}
+}
@@ -7,6 +7,7 @@
 //  * <filename> can be the full path of a file or just a suffix
 //  * <function> ::= <package>.<receiver type>.<name> | <package>.(*<receiver type>).<name> | <receiver type>.<name> | <package>.<name> | (*<receiver type>).<name> | <name>
 //    <function> must be unambiguous
+//    generic functions and receiver types can be followed by type arguments, <name>[<type args>], to select a single instantiation
 //  * /<regex>/ will return a location for each function matched by regex
 //  * +<offset> returns a location for the line that is <offset> lines after the current line
 //  * -<offset> returns a location for the line that is <offset> lines before the current line
@@ -53,4 +54,7 @@ type FuncLocationSpec struct {
 	ReceiverName          string
 	PackageOrReceiverName string
 	BaseName              string
+	// TypeArgs are the type arguments of an instantiation of a generic
+	// function, for example "int, string" for main.fn[int, string].
+	TypeArgs string
 }
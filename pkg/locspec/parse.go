@@ -138,6 +138,16 @@ func readRegex(in string) (rx string, rest string) {
 }
 
 func parseFuncLocationSpec(in string) *FuncLocationSpec {
+	// remove type arguments of instantiations of generic functions, they
+	// can contain dots
+	var typeArgs string
+	if i := strings.Index(in, "["); i >= 0 {
+		if j := strings.LastIndex(in, "]"); j > i {
+			typeArgs = in[i+1 : j]
+			in = in[:i] + in[j+1:]
+		}
+	}
+
 	var v []string
 	pathend := strings.LastIndex(in, "/")
 	if pathend < 0 {
@@ -183,6 +193,8 @@ func parseFuncLocationSpec(in string) *FuncLocationSpec {
 		return nil
 	}
 
+	spec.TypeArgs = typeArgs
+
 	return &spec
 }
 
@@ -380,7 +392,14 @@ func (loc *NormalLocationSpec) Find(t *proc.Target, processArgs []string, scope
 			}
 		}
 	} else { // len(candidateFuncs) == 1
-		addrs, err = proc.FindFunctionLocation(t, candidateFuncs[0], loc.LineOffset)
+		fname := candidateFuncs[0]
+		if loc.FuncBase.TypeArgs != "" {
+			fname, err = scope.InstantiationName(fname, loc.FuncBase.TypeArgs)
+			if err != nil {
+				return nil, err
+			}
+		}
+		addrs, err = proc.FindFunctionLocation(t, fname, loc.LineOffset)
 	}
 
 	if err != nil {
@@ -405,7 +424,7 @@ func (loc *NormalLocationSpec) findFuncCandidates(scope *proc.EvalScope, limit i
 		candidateFuncs[fname] = struct{}{}
 	}
 	for _, f := range scope.BinInfo.LookupFunc {
-		if len(candidateFuncs) >= limit {
+		if len(candidateFuncs) >= limit || loc.FuncBase.TypeArgs != "" {
 			break
 		}
 		if !loc.FuncBase.Match(f, scope.BinInfo.PackageMap) {
@@ -65,4 +65,8 @@ func TestFunctionLocationParsing(t *testing.T) {
 	assertNormalLocationSpec(t, "github.com/hitzhangjie/dlv/pkg/proc.(*Process).Continue:10", NormalLocationSpec{"github.com/hitzhangjie/dlv/pkg/proc.(*Process).Continue", &FuncLocationSpec{PackageName: "github.com/hitzhangjie/dlv/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
 	assertNormalLocationSpec(t, "github.com/hitzhangjie/dlv/pkg/proc.Process.Continue:10", NormalLocationSpec{"github.com/hitzhangjie/dlv/pkg/proc.Process.Continue", &FuncLocationSpec{PackageName: "github.com/hitzhangjie/dlv/pkg/proc", ReceiverName: "Process", BaseName: "Continue"}, 10})
 	assertNormalLocationSpec(t, "github.com/hitzhangjie/dlv/pkg/proc.Continue:10", NormalLocationSpec{"github.com/hitzhangjie/dlv/pkg/proc.Continue", &FuncLocationSpec{PackageName: "github.com/hitzhangjie/dlv/pkg/proc", BaseName: "Continue"}, 10})
+
+	// Instantiations of generic functions
+	assertNormalLocationSpec(t, "main.testfn[int, main.astruct]", NormalLocationSpec{"main.testfn[int, main.astruct]", &FuncLocationSpec{PackageOrReceiverName: "main", BaseName: "testfn", TypeArgs: "int, main.astruct"}, -1})
+	assertNormalLocationSpec(t, "main.(*Set[string]).Add:2", NormalLocationSpec{"main.(*Set[string]).Add", &FuncLocationSpec{PackageName: "main", ReceiverName: "Set", BaseName: "Add", TypeArgs: "string"}, 2})
 }
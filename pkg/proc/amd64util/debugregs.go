@@ -1,7 +1,6 @@
 package amd64util
 
 import (
-	"errors"
 	"fmt"
 )
 
@@ -62,6 +61,11 @@ func (drs *DebugRegisters) SetBreakpoint(idx uint8, addr uint64, read, write boo
 	if int(idx) >= len(drs.pAddrs) {
 		return fmt.Errorf("hardware breakpoints exhausted")
 	}
+	if read && !write {
+		// There is no break on read only, break on reads and writes and let the
+		// caller ignore the writes.
+		write = true
+	}
 	curaddr, curread, curwrite, cursz := drs.breakpoint(idx)
 	if curaddr != 0 {
 		if (curaddr != addr) || (curread != read) || (curwrite != write) || (cursz != sz) {
@@ -71,10 +75,6 @@ func (drs *DebugRegisters) SetBreakpoint(idx uint8, addr uint64, read, write boo
 		return nil
 	}
 
-	if read && !write {
-		return errors.New("break on read only not supported")
-	}
-
 	*(drs.pAddrs[idx]) = addr
 	var lenrw uint64
 	if write {
@@ -115,7 +115,7 @@ func (drs *DebugRegisters) ClearBreakpoint(idx uint8) {
 // GetActiveBreakpoint returns the active hardware breakpoint and resets the
 // condition flags.
 func (drs *DebugRegisters) GetActiveBreakpoint() (ok bool, idx uint8) {
-	for idx := uint8(0); idx < 3; idx++ {
+	for idx := uint8(0); idx < uint8(len(drs.pAddrs)); idx++ {
 		enable := *(drs.pDR7) & (1 << enableBitOffset(idx))
 		if enable == 0 {
 			continue
@@ -24,6 +24,7 @@ type Arch struct {
 	SPRegNum                 uint64
 	BPRegNum                 uint64
 	ContextRegNum            uint64 // register used to pass a closure context when calling a function pointer
+	hwWatchpoints            int    // number of debug registers available for watchpoints
 
 	// asmDecode decodes the assembly instruction starting at mem[0:] into asmInst.
 	// It assumes that the Loc and AtPC fields of asmInst have already been filled.
@@ -58,6 +58,9 @@ type BinaryInfo struct {
 
 	// SymNames maps addr to a description *elf.Symbol of this addr.
 	SymNames map[uint64]*elf.Symbol
+	// dictionaries maps the names of the dictionaries of instantiations of
+	// generic functions and types to their addresses.
+	dictionaries map[string]uint64
 
 	// Images is a list of loaded shared libraries (also known as
 	// shared objects on linux or DLLs on windows).
@@ -284,6 +287,10 @@ func (bi *BinaryInfo) FindFunction(funcName string) ([]*Function, error) {
 	}
 	fns := bi.LookupGenericFunc()[funcName]
 	if len(fns) == 0 {
+		if _, _, ok := splitInstName(funcName); ok {
+			// instantiation of a generic function
+			return bi.findInstantiation(funcName)
+		}
 		return nil, &ErrFunctionNotFound{funcName}
 	}
 	return fns, nil
@@ -670,8 +677,10 @@ type Image struct {
 
 	compileUnits []*compileUnit // compileUnits is sorted by increasing DWARF offset
 
-	dwarfTreeCache      *simplelru.LRU
-	runtimeMallocgcTree *godwarf.Tree // patched version of runtime.mallocgc's DIE
+	dwarfTreeCache *simplelru.LRU
+	// runtimePatchedTrees are the patched DIEs of the runtime functions
+	// called by the debugger, see regabiRuntimeWorkarounds.
+	runtimePatchedTrees map[dwarf.Offset]*godwarf.Tree
 
 	// runtimeTypeToDIE maps between the offset of a runtime._type in
 	// runtime.moduledata.types and the offset of the DIE in debug_info. This
@@ -831,8 +840,8 @@ func (img *Image) LoadError() error {
 }
 
 func (img *Image) getDwarfTree(off dwarf.Offset) (*godwarf.Tree, error) {
-	if img.runtimeMallocgcTree != nil && off == img.runtimeMallocgcTree.Offset {
-		return img.runtimeMallocgcTree, nil
+	if tree := img.runtimePatchedTrees[off]; tree != nil {
+		return tree, nil
 	}
 	if r, ok := img.dwarfTreeCache.Get(off); ok {
 		return r.(*godwarf.Tree), nil
@@ -1171,11 +1180,16 @@ func (bi *BinaryInfo) loadSymbolName(image *Image, file *elf.File, wg *sync.Wait
 	if bi.SymNames == nil {
 		bi.SymNames = make(map[uint64]*elf.Symbol)
 	}
+	if bi.dictionaries == nil {
+		bi.dictionaries = make(map[string]uint64)
+	}
 	symSecs, _ := file.Symbols()
 	for _, symSec := range symSecs {
 		if symSec.Info == _STT_FUNC { // TODO(chainhelen), need to parse others types.
 			s := symSec
 			bi.SymNames[symSec.Value+image.StaticBase] = &s
+		} else if elf.ST_TYPE(symSec.Info) == elf.STT_OBJECT && strings.Contains(symSec.Name, goDictionarySymbolInfix) {
+			bi.dictionaries[symSec.Name] = symSec.Value + image.StaticBase
 		}
 	}
 }
@@ -1483,16 +1497,22 @@ func (bi *BinaryInfo) loadDebugInfoMaps(image *Image, debugInfoBytes, debugLineB
 	bi.Sources = uniq(bi.Sources)
 
 	if bi.regabi {
-		// prepare patch for runtime.mallocgc's DIE
-		fn := bi.LookupFunc["runtime.mallocgc"]
-		if fn != nil && fn.cu.image == image {
+		// prepare patches for the DIEs of the runtime functions we call
+		for name, workaround := range regabiRuntimeWorkarounds {
+			fn := bi.LookupFunc[name]
+			if fn == nil || fn.cu.image != image {
+				continue
+			}
 			tree, err := image.getDwarfTree(fn.offset)
 			if err == nil {
-				tree.Children, err = regabiMallocgcWorkaround(bi)
+				tree.Children, err = workaround(bi)
 				if err != nil {
-					log.Error("could not patch runtime.mallogc: %v", err)
+					log.Error("could not patch %s: %v", name, err)
 				} else {
-					image.runtimeMallocgcTree = tree
+					if image.runtimePatchedTrees == nil {
+						image.runtimePatchedTrees = make(map[dwarf.Offset]*godwarf.Tree)
+					}
+					image.runtimePatchedTrees[fn.offset] = tree
 				}
 			}
 		}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"bytes"
 	"debug/dwarf"
 	"errors"
 	"fmt"
@@ -9,12 +10,17 @@ import (
 	"go/parser"
 	"go/token"
 	"reflect"
+	"regexp"
+	"sort"
 
+	"github.com/hitzhangjie/dlv/pkg/dwarf/frame"
 	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
 	"github.com/hitzhangjie/dlv/pkg/dwarf/op"
 	"github.com/hitzhangjie/dlv/pkg/dwarf/reader"
 	"github.com/hitzhangjie/dlv/pkg/goversion"
+	"github.com/hitzhangjie/dlv/pkg/log"
 	"github.com/hitzhangjie/dlv/pkg/proc/internal/ebpf"
+	"golang.org/x/arch/x86/x86asm"
 )
 
 const (
@@ -25,8 +31,13 @@ const (
 	// process dies because of a fatal runtime error.
 	FatalThrow = "runtime-fatal-throw"
 
+	// PanicCatch is the name given to the breakpoint set on runtime.gopanic
+	// by SetPanicCatchpoint to stop on recovered panics.
+	PanicCatch = "panic-catch"
+
 	unrecoveredPanicID = -1
 	fatalThrowID       = -2
+	panicCatchID       = -3
 
 	NoLogicalID = -1000 // Logical breakpoint ID for breakpoints internal breakpoints.
 )
@@ -47,8 +58,13 @@ type Breakpoint struct {
 	WatchExpr     string
 	WatchType     WatchType
 	HWBreakIndex  uint8 // hardware breakpoint index
+	SoftwareWatch bool  // watchpoint implemented by the backend without a debug register, see SetWatchpoint
 	watchStackOff int64 // for watchpoints of stack variables, offset of the address from top of the stack
 
+	// watch is the memory watched by the logical watchpoint this breakpoint
+	// is part of, see watchrange.go.
+	watch *watchedMemory
+
 	// Breaklets is the list of overlapping breakpoints on this physical breakpoint.
 	// There can be at most one UserBreakpoint in this list but multiple internal breakpoints are allowed.
 	Breaklets []*Breaklet
@@ -63,6 +79,15 @@ type Breakpoint struct {
 	LoadLocals  *LoadConfig
 	UserData    interface{} // Any additional information about the breakpoint
 
+	// RootFuncName and TraceFollowCalls are set for the tracepoints of
+	// trace --follow-calls. RootFuncName is a regular expression matching
+	// the functions whose calls are followed, the tracepoint is only hit
+	// when it is reached less than TraceFollowCalls calls below one of them,
+	// see FollowCallsDepth.
+	RootFuncName     string
+	TraceFollowCalls int
+	rootFuncRegexp   *regexp.Regexp
+
 	// ReturnInfo describes how to collect return variables when this
 	// breakpoint is hit as a return breakpoint.
 	returnInfo *returnBreakpointInfo
@@ -197,7 +222,7 @@ func (bp *Breakpoint) VerboseDescr() []string {
 	r = append(r, fmt.Sprintf("OriginalData=%#x", bp.Orig))
 
 	if bp.WatchType != 0 {
-		r = append(r, fmt.Sprintf("HWBreakIndex=%#x watchStackOff=%#x", bp.HWBreakIndex, bp.watchStackOff))
+		r = append(r, fmt.Sprintf("HWBreakIndex=%#x watchStackOff=%#x SoftwareWatch=%v", bp.HWBreakIndex, bp.watchStackOff, bp.SoftwareWatch))
 	}
 
 	for _, breaklet := range bp.Breaklets {
@@ -252,12 +277,73 @@ type returnBreakpointInfo struct {
 
 // CheckCondition evaluates bp's condition on thread.
 func (bp *Breakpoint) checkCondition(tgt *Target, thread Thread, bpstate *BreakpointState) {
-	*bpstate = BreakpointState{Breakpoint: bp, Active: false, Stepping: false, SteppingInto: false, CondError: nil}
+	*bpstate = BreakpointState{Breakpoint: bp, Active: false, Stepping: false, SteppingInto: false, CondError: nil, WatchAddr: bpstate.WatchAddr}
+	if bp.WatchType.Read() && !bp.WatchType.Write() && readWatchAccessWasWrite(tgt, thread, bp) {
+		return
+	}
 	for _, breaklet := range bp.Breaklets {
 		bpstate.checkCond(tgt, breaklet, thread)
 	}
 }
 
+// readWatchAccessWasWrite returns true if the read watchpoint bp was
+// triggered by an instruction that only wrote to the watched memory.
+// Debug registers can not trigger on reads only, read watchpoints use
+// read/write debug registers and writes are filtered out here by decoding
+// the instruction that was just executed. If it can not be decoded, or its
+// memory operand isn't the watched memory, the watched memory is compared
+// with its previous value instead.
+func readWatchAccessWasWrite(tgt *Target, thread Thread, bp *Breakpoint) bool {
+	changed := false
+	if wm := bp.watch; wm != nil {
+		cur := make([]byte, len(wm.old))
+		if _, err := tgt.Memory().ReadMemory(cur, wm.addr); err == nil {
+			changed = !bytes.Equal(cur, wm.old)
+			wm.old = cur
+		}
+	}
+
+	inst := watchAccessInstruction(tgt, thread, bp)
+	if inst == nil {
+		return changed
+	}
+	return x86OnlyWritesMemory(inst)
+}
+
+// watchAccessInstruction returns the instruction that ends at the current
+// PC of thread and accesses the memory watched by bp, which is the one that
+// triggered it, or nil if it can not be determined.
+func watchAccessInstruction(tgt *Target, thread Thread, bp *Breakpoint) *x86asm.Inst {
+	bi := tgt.BinInfo()
+	if bi.Arch.Name != "amd64" {
+		return nil
+	}
+	regs, err := thread.Registers()
+	if err != nil {
+		return nil
+	}
+	pc := regs.PC()
+	buf := make([]byte, x86MaxInstLen)
+	if _, err := tgt.Memory().ReadMemory(buf, pc-uint64(len(buf))); err != nil {
+		return nil
+	}
+	dregs := bi.Arch.RegistersToDwarfRegisters(0, regs)
+	// x86 instructions can not be decoded backwards, try every length and
+	// only accept an instruction whose memory operand overlaps the watched
+	// memory.
+	for n := 1; n <= len(buf); n++ {
+		inst, err := x86asm.Decode(buf[len(buf)-n:], 64)
+		if err != nil || inst.Len != n {
+			continue
+		}
+		addr, ok := x86MemArgAddr(&inst, pc, dregs, bi)
+		if ok && addr < bp.Addr+uint64(bp.WatchSize()) && bp.Addr < addr+uint64(inst.MemBytes) {
+			return &inst
+		}
+	}
+	return nil
+}
+
 func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, thread Thread) {
 	var condErr error
 	active := true
@@ -274,6 +360,11 @@ func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, threa
 
 	switch breaklet.Kind {
 	case UserBreakpoint:
+		if bp := bpstate.Breakpoint; bp.TraceFollowCalls > 0 {
+			if _, ok := FollowCallsDepth(thread, bp); !ok {
+				return
+			}
+		}
 		if g, err := GetG(thread); err == nil {
 			breaklet.HitCount[g.ID]++
 		}
@@ -324,6 +415,33 @@ func (bpstate *BreakpointState) checkCond(tgt *Target, breaklet *Breaklet, threa
 	}
 }
 
+// FollowCallsDepth returns the depth of the call that reached bp, a
+// tracepoint of trace --follow-calls, relative to the closest call to a
+// function matching bp.RootFuncName. Returns false if no such call is found
+// within bp.TraceFollowCalls frames.
+func FollowCallsDepth(thread Thread, bp *Breakpoint) (int, bool) {
+	if bp.rootFuncRegexp == nil || bp.rootFuncRegexp.String() != bp.RootFuncName {
+		re, err := regexp.Compile(bp.RootFuncName)
+		if err != nil {
+			return 0, false
+		}
+		bp.rootFuncRegexp = re
+	}
+	frames, err := ThreadStacktrace(thread, bp.TraceFollowCalls)
+	if err != nil {
+		return 0, false
+	}
+	for depth, frame := range frames {
+		if depth > bp.TraceFollowCalls {
+			break
+		}
+		if frame.Call.Fn != nil && bp.rootFuncRegexp.MatchString(frame.Call.Fn.Name) {
+			return depth, true
+		}
+	}
+	return 0, false
+}
+
 // checkHitCond evaluates bp's hit condition on thread.
 func checkHitCond(breaklet *Breaklet) bool {
 	if breaklet.HitCond == nil {
@@ -434,6 +552,11 @@ func evalBreakpointCondition(tgt *Target, thread Thread, cond ast.Expr) (bool, e
 			return true, err
 		}
 	}
+	return scope.evalCondition(cond)
+}
+
+// evalCondition evaluates the boolean expression cond in scope.
+func (scope *EvalScope) evalCondition(cond ast.Expr) (bool, error) {
 	v, err := scope.evalAST(cond)
 	if err != nil {
 		return true, fmt.Errorf("error evaluating expression: %v", err)
@@ -479,14 +602,24 @@ func NewBreakpointMap() BreakpointMap {
 // SetBreakpoint sets a breakpoint at addr, and stores it in the process wide
 // break point table.
 func (t *Target) SetBreakpoint(addr uint64, kind BreakpointKind, cond ast.Expr) (*Breakpoint, error) {
-	return t.setBreakpointInternal(addr, kind, 0, cond)
+	return t.setBreakpointInternal(addr, kind, 0, nil, cond, 0)
 }
 
 // SetEBPFTracepoint will attach a uprobe to the function
 // specified by 'fnName'.
+// Strings and slices passed to the function are read up to the limits
+// specified by cfg, if cfg is nil the limits of loadFullValue are used.
+// Each expression in captures, for example "req.Header.Host", describes a
+// value reached by following pointers from one of the arguments of the
+// function, expressions that do not refer to an argument of fnName are
+// ignored.
+// If cond is not nil calls for which it is false are discarded by the eBPF
+// program, see ebpfFilters for the conditions that are supported.
+// If latency is set calls are not reported, their duration is added to the
+// latency histogram of the function instead, see GetLatencyHistograms.
 //
 // Note: Not all Linux versions supported.
-func (t *Target) SetEBPFTracepoint(fnName string) error {
+func (t *Target) SetEBPFTracepoint(fnName string, cfg *LoadConfig, captures []string, cond ast.Expr, latency bool) error {
 	// Not every OS/arch that we support has support for eBPF,
 	// so check early and return an error if this is called on an
 	// unsupported system.
@@ -518,8 +651,12 @@ func (t *Target) SetEBPFTracepoint(fnName string) error {
 		}
 	}
 
+	if cfg == nil {
+		cfg = &loadFullValue
+	}
+
 	for _, fn := range fns {
-		err := t.setEBPFTracepointOnFunc(fn, goidOffset)
+		err := t.setEBPFTracepointOnFunc(fn, goidOffset, cfg, captures, cond, latency)
 		if err != nil {
 			return err
 		}
@@ -527,58 +664,515 @@ func (t *Target) SetEBPFTracepoint(fnName string) error {
 	return nil
 }
 
-func (t *Target) setEBPFTracepointOnFunc(fn *Function, goidOffset int64) error {
-	// Start putting together the argument map. This will tell the eBPF program
-	// all of the arguments we want to trace and how to find them.
+func (t *Target) setEBPFTracepointOnFunc(fn *Function, goidOffset int64, cfg *LoadConfig, captures []string, cond ast.Expr, latency bool) error {
+	// Functions that are always inlined have no out-of-line copy.
+	if fn.Entry != 0 {
+		// Start putting together the argument map. This will tell the eBPF program
+		// all of the arguments we want to trace and how to find them.
 
-	// Start looping through each argument / return parameter for the function we
-	// are setting the uprobe on. Parse location information so that we can pass it
-	// along to the eBPF program.
-	dwarfTree, err := fn.cu.image.getDwarfTree(fn.offset)
-	if err != nil {
-		return err
+		// Start looping through each argument / return parameter for the function we
+		// are setting the uprobe on. Parse location information so that we can pass it
+		// along to the eBPF program.
+		dwarfTree, err := fn.cu.image.getDwarfTree(fn.offset)
+		if err != nil {
+			return err
+		}
+		variablesFlags := reader.VariablesOnlyVisible
+		if t.BinInfo().Producer() != "" && goversion.ProducerAfterOrEqual(t.BinInfo().Producer(), 1, 15) {
+			variablesFlags |= reader.VariablesTrustDeclLine
+		}
+		_, l, _ := t.BinInfo().PCToLine(fn.Entry)
+
+		var args []ebpf.UProbeArgMap
+		varEntries := reader.Variables(dwarfTree, fn.Entry, l, variablesFlags)
+		for _, entry := range varEntries {
+			// At the entry point the CFA is right above the return address.
+			arg, err := t.ebpfArgMap(entry.Tree, fn.cu.image, fn.Entry, int64(t.BinInfo().Arch.PtrSize()))
+			if err != nil {
+				return err
+			}
+			args = append(args, arg)
+		}
+
+		ucfg, err := ebpfLoadConfig(args, cfg, captures, cond, t.BinInfo().Arch.PtrSize())
+		if err != nil {
+			return fmt.Errorf("%s: %v", fn.Name, err)
+		}
+		ucfg.Latency = latency
+
+		// Finally, set the uprobe on the function.
+		if err := t.proc.SetUProbe(fn.Name, goidOffset, args, ucfg); err != nil {
+			return err
+		}
 	}
-	variablesFlags := reader.VariablesOnlyVisible
-	if t.BinInfo().Producer() != "" && goversion.ProducerAfterOrEqual(t.BinInfo().Producer(), 1, 15) {
-		variablesFlags |= reader.VariablesTrustDeclLine
+
+	if len(fn.InlinedCalls) == 0 {
+		return nil
+	}
+	if latency {
+		// Inlined calls have no return probe, their duration can not be
+		// measured.
+		if fn.Entry == 0 {
+			return fmt.Errorf("%s is always inlined, its latency can not be measured", fn.Name)
+		}
+		return nil
 	}
-	_, l, _ := t.BinInfo().PCToLine(fn.Entry)
+	return t.setEBPFTracepointOnInlinedCalls(fn, goidOffset, cfg, captures, cond)
+}
+
+// setEBPFTracepointOnInlinedCalls sets a uprobe at the start of every
+// inlined call to fn. The location of the arguments of an inlined call is
+// decided by its caller, so it is described separately for every call
+// site. Arguments that are not available at the start of the call are not
+// reported, if one of them is needed to evaluate cond the call site is
+// skipped.
+func (t *Target) setEBPFTracepointOnInlinedCalls(fn *Function, goidOffset int64, cfg *LoadConfig, captures []string, cond ast.Expr) error {
+	bi := t.BinInfo()
+	seen := make(map[*Function]bool)
+	for _, call := range fn.InlinedCalls {
+		callerFn := bi.PCToFunc(call.LowPC)
+		if callerFn == nil || seen[callerFn] {
+			continue
+		}
+		seen[callerFn] = true
 
-	var args []ebpf.UProbeArgMap
-	varEntries := reader.Variables(dwarfTree, fn.Entry, l, variablesFlags)
-	for _, entry := range varEntries {
-		_, dt, err := readVarEntry(entry.Tree, fn.cu.image)
+		dwarfTree, err := callerFn.cu.image.getDwarfTree(callerFn.offset)
 		if err != nil {
 			return err
 		}
+		for _, site := range ebpfInlinedCallSites(dwarfTree, fn.offset) {
+			cfaOffset, err := ebpfCFAOffset(bi, site.pc)
+			if err != nil {
+				log.Warn("skipping inlined call to %s at %#x: %v", fn.Name, site.pc, err)
+				continue
+			}
+
+			var args []ebpf.UProbeArgMap
+			for _, entry := range reader.Variables(site.tree, site.pc, 0, reader.VariablesOnlyVisible|reader.VariablesNoDeclLineCheck) {
+				if entry.Depth != 1 || entry.Tag != dwarf.TagFormalParameter {
+					continue
+				}
+				if isret, _ := entry.Val(dwarf.AttrVarParam).(bool); isret {
+					continue
+				}
+				arg, err := t.ebpfArgMap(entry.Tree, callerFn.cu.image, site.pc, cfaOffset)
+				if err != nil || !ebpfRegPiecesOnly(arg.DwarfPieces) {
+					// optimized out, or partially spilled to the stack
+					continue
+				}
+				args = append(args, arg)
+			}
+
+			ucfg, err := ebpfLoadConfig(args, cfg, captures, cond, bi.Arch.PtrSize())
+			if err != nil {
+				log.Warn("skipping inlined call to %s at %#x: %v", fn.Name, site.pc, err)
+				continue
+			}
+			if err := t.proc.SetInlinedUProbe(fn.Name, site.pc, goidOffset, args, ucfg); err != nil {
+				return err
+			}
+			if t.ebpfInlinedCalls == nil {
+				t.ebpfInlinedCalls = make(map[uint64]*Function)
+			}
+			t.ebpfInlinedCalls[site.pc] = fn
+		}
+	}
+	return nil
+}
+
+// ebpfInlinedCall is the start of an inlined call.
+type ebpfInlinedCall struct {
+	pc   uint64
+	tree *godwarf.Tree // DW_TAG_inlined_subroutine entry of the call.
+}
+
+// ebpfInlinedCallSites returns the inlined calls, contained in tree, to the
+// function whose abstract origin is at offset origin.
+func ebpfInlinedCallSites(tree *godwarf.Tree, origin dwarf.Offset) []ebpfInlinedCall {
+	// A single inlined call can have multiple ranges, the call starts at the
+	// lowest address.
+	start := make(map[dwarf.Offset]uint64)
+	for _, inlrng := range allInlineCallRanges(tree) {
+		if pc, ok := start[inlrng.off]; !ok || inlrng.rng[0] < pc {
+			start[inlrng.off] = inlrng.rng[0]
+		}
+	}
+
+	var r []ebpfInlinedCall
+	var visit func(*godwarf.Tree)
+	visit = func(n *godwarf.Tree) {
+		if n.Tag == dwarf.TagInlinedSubroutine {
+			if ao, _ := n.Val(dwarf.AttrAbstractOrigin).(dwarf.Offset); ao == origin {
+				if pc, ok := start[n.Offset]; ok {
+					r = append(r, ebpfInlinedCall{pc: pc, tree: n})
+				}
+			}
+		}
+		for _, child := range n.Children {
+			visit(child)
+		}
+	}
+	visit(tree)
+	sort.Slice(r, func(i, j int) bool { return r[i].pc < r[j].pc })
+	return r
+}
 
-		offset, pieces, _, err := t.BinInfo().Location(entry, dwarf.AttrLocation, fn.Entry, op.DwarfRegisters{}, nil)
+// ebpfCFAOffset returns the distance between the stack pointer and the CFA
+// at pc.
+func ebpfCFAOffset(bi *BinaryInfo, pc uint64) (int64, error) {
+	fde, err := bi.frameEntries.FDEForPC(pc)
+	if err != nil {
+		return 0, err
+	}
+	framectx := bi.Arch.fixFrameUnwindContext(fde.EstablishFrame(pc), pc, bi)
+	if framectx.CFA.Rule != frame.RuleCFA || framectx.CFA.Reg != bi.Arch.SPRegNum {
+		return 0, fmt.Errorf("CFA at %#x is not relative to the stack pointer", pc)
+	}
+	return framectx.CFA.Offset, nil
+}
+
+// ebpfArgMap describes where the eBPF program finds the variable described
+// by entry when the uprobe at pc is hit, cfaOffset is the distance between
+// the stack pointer and the CFA at pc.
+func (t *Target) ebpfArgMap(entry *godwarf.Tree, image *Image, pc uint64, cfaOffset int64) (ebpf.UProbeArgMap, error) {
+	_, dt, err := readVarEntry(entry, image)
+	if err != nil {
+		return ebpf.UProbeArgMap{}, err
+	}
+
+	offset, pieces, _, err := t.BinInfo().Location(entry, dwarf.AttrLocation, pc, op.DwarfRegisters{}, nil)
+	if err != nil {
+		return ebpf.UProbeArgMap{}, err
+	}
+	paramPieces := make([]int, 0, len(pieces))
+	for _, piece := range pieces {
+		if piece.Kind == op.RegPiece {
+			paramPieces = append(paramPieces, int(piece.Val))
+		}
+	}
+	isret, _ := entry.Val(dwarf.AttrVarParam).(bool)
+	name, _ := entry.Val(dwarf.AttrName).(string)
+	// The frame base of Go functions is the CFA.
+	offset += cfaOffset
+	return ebpf.UProbeArgMap{
+		Offset:      offset,
+		Size:        dt.Size(),
+		Kind:        dt.Common().ReflectKind,
+		Pieces:      paramPieces,
+		InReg:       len(pieces) > 0,
+		Ret:         isret,
+		Name:        name,
+		Type:        resolveTypedef(dt),
+		DwarfPieces: pieces,
+	}, nil
+}
+
+// ebpfRegPiecesOnly returns true if a variable described by pieces is
+// either entirely in registers or entirely in memory.
+func ebpfRegPiecesOnly(pieces []op.Piece) bool {
+	for _, piece := range pieces {
+		if piece.Kind != op.RegPiece && piece.Kind != op.ImmPiece {
+			return false
+		}
+	}
+	return true
+}
+
+// ebpfLoadConfig converts cfg, captures and cond to the configuration of a
+// uprobe for a function with arguments args.
+func ebpfLoadConfig(args []ebpf.UProbeArgMap, cfg *LoadConfig, captures []string, cond ast.Expr, ptrSize int) (ebpf.UProbeLoadConfig, error) {
+	ucfg := ebpf.UProbeLoadConfig{MaxStringLen: cfg.MaxStringLen, MaxArrayValues: cfg.MaxArrayValues}
+	for _, expr := range captures {
+		capture, err := ebpfCapture(args, expr, ptrSize)
 		if err != nil {
-			return err
+			return ucfg, err
 		}
-		paramPieces := make([]int, 0, len(pieces))
-		for _, piece := range pieces {
-			if piece.Kind == op.RegPiece {
-				paramPieces = append(paramPieces, int(piece.Val))
+		if capture != nil {
+			ucfg.Captures = append(ucfg.Captures, *capture)
+		}
+	}
+	if cond != nil {
+		var err error
+		ucfg.Filters, err = ebpfFilters(args, cond, ptrSize)
+		if err != nil {
+			return ucfg, err
+		}
+	}
+	return ucfg, nil
+}
+
+// ebpfCapture describes the chain of pointer dereferences the eBPF program
+// has to follow to read expr, which must be a selector expression rooted at
+// one of the arguments, such as req.Header.Host. Pointers are dereferenced
+// implicitly, as they are by the Go language.
+// Returns nil if expr does not refer to any of the arguments.
+func ebpfCapture(args []ebpf.UProbeArgMap, expr string, ptrSize int) (*ebpf.UProbeCapture, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return ebpfCaptureExpr(args, node, expr, ptrSize)
+}
+
+func ebpfCaptureExpr(args []ebpf.UProbeArgMap, node ast.Expr, expr string, ptrSize int) (*ebpf.UProbeCapture, error) {
+	var fields []string
+	for {
+		if sel, ok := node.(*ast.SelectorExpr); ok {
+			fields = append([]string{sel.Sel.Name}, fields...)
+			node = sel.X
+			continue
+		}
+		break
+	}
+	ident, ok := node.(*ast.Ident)
+	if !ok {
+		return nil, fmt.Errorf("can not capture %q: only field selectors are supported", expr)
+	}
+	argIdx := -1
+	for i := range args {
+		if args[i].Name == ident.Name {
+			argIdx = i
+			break
+		}
+	}
+	if argIdx < 0 {
+		return nil, nil
+	}
+	arg := &args[argIdx]
+
+	typ := arg.Type
+	var derefs []int64
+	var off int64
+	for _, name := range fields {
+		if ptyp, isptr := typ.(*godwarf.PtrType); isptr {
+			derefs = append(derefs, off)
+			off = 0
+			typ = resolveTypedef(ptyp.Type)
+		}
+		styp, isstruct := typ.(*godwarf.StructType)
+		if !isstruct {
+			return nil, fmt.Errorf("can not capture %q: %s is not a struct", expr, typ.String())
+		}
+		var field *godwarf.StructField
+		for _, f := range styp.Field {
+			if f.Name == name {
+				field = f
+				break
 			}
 		}
-		isret, _ := entry.Val(dwarf.AttrVarParam).(bool)
-		offset += int64(t.BinInfo().Arch.PtrSize())
-		args = append(args, ebpf.UProbeArgMap{
-			Offset: offset,
-			Size:   dt.Size(),
-			Kind:   dt.Common().ReflectKind,
-			Pieces: paramPieces,
-			InReg:  len(pieces) > 0,
-			Ret:    isret,
-		})
+		if field == nil {
+			return nil, fmt.Errorf("can not capture %q: %s has no field %s", expr, typ.String(), name)
+		}
+		off += field.ByteOffset
+		typ = resolveTypedef(field.Type)
+	}
+
+	capture := &ebpf.UProbeCapture{
+		Name: expr,
+		Arg:  argIdx,
+		Size: typ.Size(),
+		Kind: typ.Common().ReflectKind,
+		Type: typ,
+	}
+
+	// The eBPF program stores each register a parameter is passed in into
+	// its own 8 byte slot, the first read from a parameter passed in
+	// registers must be translated to that layout.
+	first, size := off, typ.Size()
+	if len(derefs) > 0 {
+		first, size = derefs[0], int64(ptrSize)
+	}
+	if arg.InReg {
+		start, ok1 := regValOffset(arg.DwarfPieces, first)
+		end, ok2 := regValOffset(arg.DwarfPieces, first+size-1)
+		if !ok1 || !ok2 || end-start != size-1 {
+			return nil, fmt.Errorf("can not capture %q: value is not contained in registers", expr)
+		}
+		first = start
 	}
+	if len(derefs) > 0 {
+		derefs[0] = first
+	} else {
+		off = first
+	}
+	capture.Derefs = derefs
+	capture.Offset = off
+	return capture, nil
+}
 
-	// TODO(aarzilli): inlined calls?
+// ebpfFilters compiles cond into filters the eBPF program evaluates at
+// function entry. Only conjunctions of comparisons between an integer,
+// boolean or pointer argument (or field reached from an argument, or the
+// length of a string or slice) and a constant are supported, for example:
+//
+//	req.ID > 100 && len(name) == 0
+func ebpfFilters(args []ebpf.UProbeArgMap, cond ast.Expr, ptrSize int) ([]ebpf.UProbeFilter, error) {
+	switch node := cond.(type) {
+	case *ast.ParenExpr:
+		return ebpfFilters(args, node.X, ptrSize)
+	case *ast.BinaryExpr:
+		switch node.Op {
+		case token.LAND:
+			lhs, err := ebpfFilters(args, node.X, ptrSize)
+			if err != nil {
+				return nil, err
+			}
+			rhs, err := ebpfFilters(args, node.Y, ptrSize)
+			if err != nil {
+				return nil, err
+			}
+			return append(lhs, rhs...), nil
+		case token.EQL, token.NEQ, token.LSS, token.LEQ, token.GTR, token.GEQ:
+			filter, err := ebpfFilter(args, node, ptrSize)
+			if err != nil {
+				return nil, err
+			}
+			return []ebpf.UProbeFilter{*filter}, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported condition %s: only comparisons with constants joined by && are supported", exprToString(cond))
+}
 
-	// Finally, set the uprobe on the function.
-	t.proc.SetUProbe(fn.Name, goidOffset, args)
-	return nil
+func ebpfFilter(args []ebpf.UProbeArgMap, node *ast.BinaryExpr, ptrSize int) (*ebpf.UProbeFilter, error) {
+	operand, op := node.X, node.Op
+	value, isconst := ebpfFilterConst(node.Y)
+	if !isconst {
+		// Constant on the left hand side, mirror the comparison.
+		operand = node.Y
+		value, isconst = ebpfFilterConst(node.X)
+		switch op {
+		case token.LSS:
+			op = token.GTR
+		case token.LEQ:
+			op = token.GEQ
+		case token.GTR:
+			op = token.LSS
+		case token.GEQ:
+			op = token.LEQ
+		}
+	}
+	if !isconst {
+		return nil, fmt.Errorf("unsupported condition %s: one side of the comparison must be a constant", exprToString(node))
+	}
+
+	expr := exprToString(operand)
+	var islen bool
+	if call, ok := operand.(*ast.CallExpr); ok {
+		if fnname, _ := call.Fun.(*ast.Ident); fnname == nil || fnname.Name != "len" || len(call.Args) != 1 {
+			return nil, fmt.Errorf("unsupported condition %s: only len can be called", exprToString(node))
+		}
+		operand = call.Args[0]
+		islen = true
+	}
+
+	capture, err := ebpfCaptureExpr(args, operand, expr, ptrSize)
+	if err != nil {
+		return nil, err
+	}
+	if capture == nil {
+		return nil, fmt.Errorf("unsupported condition %s: %s does not refer to an argument", exprToString(node), expr)
+	}
+
+	filter := &ebpf.UProbeFilter{Op: op}
+	switch value.Kind() {
+	case constant.Int:
+		n, exact := constant.Int64Val(value)
+		if !exact {
+			u, _ := constant.Uint64Val(value)
+			n = int64(u)
+		}
+		filter.Value = n
+	case constant.Bool:
+		if constant.BoolVal(value) {
+			filter.Value = 1
+		}
+	case constant.Unknown:
+		// nil
+	}
+
+	switch {
+	case islen:
+		if capture.Kind != reflect.String && capture.Kind != reflect.Slice {
+			return nil, fmt.Errorf("unsupported condition %s: %s is not a string or a slice", exprToString(node), exprToString(operand))
+		}
+		// The length follows the data pointer in both strings and slices.
+		capture.Offset += int64(ptrSize)
+		capture.Size = int64(ptrSize)
+		filter.Signed = true
+	case value.Kind() == constant.Int:
+		switch capture.Kind {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			filter.Signed = true
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return nil, fmt.Errorf("unsupported condition %s: %s is not an integer", exprToString(node), expr)
+		}
+	case value.Kind() == constant.Bool:
+		if capture.Kind != reflect.Bool || (op != token.EQL && op != token.NEQ) {
+			return nil, fmt.Errorf("unsupported condition %s: %s can not be compared with a boolean", exprToString(node), expr)
+		}
+	default:
+		if (capture.Kind != reflect.Ptr && capture.Kind != reflect.UnsafePointer) || (op != token.EQL && op != token.NEQ) {
+			return nil, fmt.Errorf("unsupported condition %s: %s can not be compared with nil", exprToString(node), expr)
+		}
+	}
+	filter.Capture = *capture
+	return filter, nil
+}
+
+// ebpfFilterConst returns the value of node if it is an integer or boolean
+// constant, or nil (returned as an unknown constant).
+func ebpfFilterConst(node ast.Expr) (constant.Value, bool) {
+	switch node := node.(type) {
+	case *ast.ParenExpr:
+		return ebpfFilterConst(node.X)
+	case *ast.BasicLit:
+		if node.Kind != token.INT {
+			return nil, false
+		}
+		v := constant.MakeFromLiteral(node.Value, node.Kind, 0)
+		return v, v.Kind() == constant.Int
+	case *ast.UnaryExpr:
+		if node.Op != token.SUB {
+			return nil, false
+		}
+		v, ok := ebpfFilterConst(node.X)
+		if !ok || v.Kind() != constant.Int {
+			return nil, false
+		}
+		return constant.UnaryOp(token.SUB, v, 0), true
+	case *ast.Ident:
+		switch node.Name {
+		case "true":
+			return constant.MakeBool(true), true
+		case "false":
+			return constant.MakeBool(false), true
+		case "nil":
+			return constant.MakeUnknown(), true
+		}
+	}
+	return nil, false
+}
+
+// regValOffset translates off, an offset into a variable described by
+// pieces, into an offset into the registers it is stored in, laid out 8
+// bytes apiece.
+func regValOffset(pieces []op.Piece, off int64) (int64, bool) {
+	var memOff int64
+	reg := 0
+	for _, piece := range pieces {
+		if off >= memOff && off < memOff+int64(piece.Size) {
+			if piece.Kind != op.RegPiece {
+				return 0, false
+			}
+			return int64(reg*8) + off - memOff, true
+		}
+		memOff += int64(piece.Size)
+		if piece.Kind == op.RegPiece {
+			reg++
+		}
+	}
+	return 0, false
 }
 
 // SetWatchpoint sets a data breakpoint at addr and stores it in the
@@ -605,15 +1199,12 @@ func (t *Target) SetWatchpoint(scope *EvalScope, expr string, wtype WatchType, c
 	if xv.Kind == reflect.UnsafePointer || xv.Kind == reflect.Invalid {
 		return nil, fmt.Errorf("can not watch variable of type %s", xv.Kind.String())
 	}
-	sz := xv.DwarfType.Size()
-	if sz <= 0 || sz > int64(t.BinInfo().Arch.PtrSize()) {
-		// TODO(aarzilli): it is reasonable to expect to be able to watch string
-		// and interface variables and we could support it by watching certain
-		// member fields here.
-		return nil, fmt.Errorf("can not watch variable of type %s", xv.DwarfType.String())
+	wm, err := newWatchedMemory(t, expr, xv)
+	if err != nil {
+		return nil, err
 	}
 
-	stackWatch := scope.g != nil && !scope.g.SystemStack && xv.Addr >= scope.g.stack.lo && xv.Addr < scope.g.stack.hi
+	stackWatch := scope.g != nil && !scope.g.SystemStack && wm.addr >= scope.g.stack.lo && wm.addr < scope.g.stack.hi
 
 	if stackWatch && wtype&WatchRead != 0 {
 		// In theory this would work except for the fact that the runtime will
@@ -622,14 +1213,50 @@ func (t *Target) SetWatchpoint(scope *EvalScope, expr string, wtype WatchType, c
 		return nil, errors.New("can not watch stack allocated variable for reads")
 	}
 
-	bp, err := t.setBreakpointInternal(xv.Addr, UserBreakpoint, wtype.withSize(uint8(sz)), cond)
-	if err != nil {
-		return bp, err
+	chunks := wm.split(int64(t.BinInfo().Arch.PtrSize()))
+	if free := len(t.freeHWBreakIndexes()); len(chunks) > free {
+		if stackWatch {
+			// Software watchpoints trap on every access to the pages containing the
+			// watched memory, on a goroutine stack that is every function call.
+			return nil, fmt.Errorf("can not watch stack allocated variable: it needs %d hardware watchpoints but only %d are free", len(chunks), free)
+		}
+		wm.software = true
+		chunks = []watchChunk{{wm.addr, wm.size}}
+	}
+
+	// all chunks belong to the same logical breakpoint
+	t.Breakpoints().breakpointIDCounter++
+	logicalID := t.Breakpoints().breakpointIDCounter
+
+	var bp *Breakpoint
+	for _, chunk := range chunks {
+		sz := uint8(chunk.size)
+		if wm.software {
+			sz = 0 // the size is given by Breakpoint.WatchSize
+		}
+		chunkbp, err := t.setBreakpointInternal(chunk.addr, UserBreakpoint, wtype.withSize(sz), wm, cond, logicalID)
+		if err != nil {
+			if bp != nil {
+				t.clearWatchpoint(bp)
+				return nil, err
+			}
+			return chunkbp, err
+		}
+		if bp == nil {
+			bp = chunkbp
+		}
+		chunkbp.WatchExpr = expr
+		wm.chunks = append(wm.chunks, chunkbp)
+	}
+
+	if bp.SoftwareWatch {
+		log.Warn("not enough free hardware watchpoints, watchpoint on %s uses page protection: execution will be much slower while it is set", expr)
 	}
-	bp.WatchExpr = expr
 
 	if stackWatch {
-		bp.watchStackOff = int64(bp.Addr) - int64(scope.g.stack.hi)
+		for _, chunkbp := range wm.chunks {
+			chunkbp.watchStackOff = int64(chunkbp.Addr) - int64(scope.g.stack.hi)
+		}
 		err := t.setStackWatchBreakpoints(scope, bp)
 		if err != nil {
 			return bp, err
@@ -639,21 +1266,26 @@ func (t *Target) SetWatchpoint(scope *EvalScope, expr string, wtype WatchType, c
 	return bp, nil
 }
 
-func (t *Target) setBreakpointInternal(addr uint64, kind BreakpointKind, wtype WatchType, cond ast.Expr) (*Breakpoint, error) {
+// setBreakpointInternal sets a breakpoint at addr. If kind is
+// UserBreakpoint and logicalID is zero a new logical ID is allocated for it.
+func (t *Target) setBreakpointInternal(addr uint64, kind BreakpointKind, wtype WatchType, watch *watchedMemory, cond ast.Expr, logicalID int) (*Breakpoint, error) {
 	if valid, err := t.Valid(); !valid {
 		return nil, err
 	}
 	bpmap := t.Breakpoints()
+	if bp, ok := bpmap.M[addr]; ok && !bp.canOverlap(kind) {
+		return bp, BreakpointExistsError{bp.File, bp.Line, bp.Addr}
+	}
 	newBreaklet := &Breaklet{Kind: kind, Cond: cond}
 	if kind == UserBreakpoint {
 		newBreaklet.HitCount = map[int]uint64{}
-		bpmap.breakpointIDCounter++
-		newBreaklet.LogicalID = bpmap.breakpointIDCounter
+		if logicalID == 0 {
+			bpmap.breakpointIDCounter++
+			logicalID = bpmap.breakpointIDCounter
+		}
+		newBreaklet.LogicalID = logicalID
 	}
 	if bp, ok := bpmap.M[addr]; ok {
-		if !bp.canOverlap(kind) {
-			return bp, BreakpointExistsError{bp.File, bp.Line, bp.Addr}
-		}
 		if kind == UserBreakpoint {
 			bp.Tracepoint = false
 			bp.TraceReturn = false
@@ -675,27 +1307,28 @@ func (t *Target) setBreakpointInternal(addr uint64, kind BreakpointKind, wtype W
 	}
 
 	hwidx := uint8(0)
+	swwatch := false
 	if wtype != 0 {
-		m := make(map[uint8]bool)
-		for _, bp := range bpmap.M {
-			if bp.WatchType != 0 {
-				m[bp.HWBreakIndex] = true
-			}
-		}
-		for hwidx = 0; true; hwidx++ {
-			if !m[hwidx] {
-				break
-			}
+		free := t.freeHWBreakIndexes()
+		switch {
+		case watch != nil && watch.software:
+			swwatch = true
+		case len(free) == 0:
+			return nil, errors.New("hardware watchpoints exhausted")
+		default:
+			hwidx = free[0]
 		}
 	}
 
 	newBreakpoint := &Breakpoint{
-		Function:     fnName,
-		WatchType:    wtype,
-		HWBreakIndex: hwidx,
-		File:         f,
-		Line:         l,
-		Addr:         addr,
+		Function:      fnName,
+		WatchType:     wtype,
+		HWBreakIndex:  hwidx,
+		SoftwareWatch: swwatch,
+		watch:         watch,
+		File:          f,
+		Line:          l,
+		Addr:          addr,
 	}
 
 	err := t.proc.WriteBreakpoint(newBreakpoint)
@@ -710,20 +1343,37 @@ func (t *Target) setBreakpointInternal(addr uint64, kind BreakpointKind, wtype W
 	return newBreakpoint, nil
 }
 
-// SetBreakpointWithID creates a breakpoint at addr, with the specified logical ID.
-func (t *Target) SetBreakpointWithID(id int, addr uint64) (*Breakpoint, error) {
-	bpmap := t.Breakpoints()
-	bp, err := t.SetBreakpoint(addr, UserBreakpoint, nil)
-	if err == nil {
-		for _, breaklet := range bp.Breaklets {
-			if breaklet.Kind == UserBreakpoint {
-				breaklet.LogicalID = id
-				bpmap.breakpointIDCounter--
-				break
-			}
+// freeHWBreakIndexes returns the debug register indexes not used by other
+// watchpoints.
+func (t *Target) freeHWBreakIndexes() []uint8 {
+	m := make(map[uint8]bool)
+	for _, bp := range t.Breakpoints().M {
+		if bp.WatchType != 0 && !bp.SoftwareWatch {
+			m[bp.HWBreakIndex] = true
 		}
 	}
-	return bp, err
+	var r []uint8
+	for idx := uint8(0); int(idx) < t.BinInfo().Arch.hwWatchpoints; idx++ {
+		if !m[idx] {
+			r = append(r, idx)
+		}
+	}
+	return r
+}
+
+// WatchSize returns the number of bytes watched by bp, for software
+// watchpoints it can be larger than the maximum size supported by debug
+// registers.
+func (bp *Breakpoint) WatchSize() int64 {
+	if bp.SoftwareWatch && bp.watch != nil {
+		return bp.watch.size
+	}
+	return int64(bp.WatchType.Size())
+}
+
+// SetBreakpointWithID creates a breakpoint at addr, with the specified logical ID.
+func (t *Target) SetBreakpointWithID(id int, addr uint64) (*Breakpoint, error) {
+	return t.setBreakpointInternal(addr, UserBreakpoint, 0, nil, nil, id)
 }
 
 // canOverlap returns true if a breakpoint of kind can be overlapped to the
@@ -852,6 +1502,12 @@ type BreakpointState struct {
 	// CondError contains any error encountered while evaluating the
 	// breakpoint's condition.
 	CondError error
+	// WatchAddr is the address accessed when a software watchpoint was hit,
+	// zero if it is not known.
+	WatchAddr uint64
+	// WatchHit describes the part of the watched value that was accessed
+	// when a watchpoint was hit, for example "field Foo.bar written".
+	WatchHit string
 }
 
 // Clear zeros the struct.
@@ -861,6 +1517,8 @@ func (bpstate *BreakpointState) Clear() {
 	bpstate.Stepping = false
 	bpstate.SteppingInto = false
 	bpstate.CondError = nil
+	bpstate.WatchAddr = 0
+	bpstate.WatchHit = ""
 }
 
 func (bpstate *BreakpointState) String() string {
@@ -0,0 +1,59 @@
+package proc
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrSyscallCatchNotSupported is returned by SetSyscallCatchpoints when the
+// backend can not stop the target on system calls.
+var ErrSyscallCatchNotSupported = errors.New("backend does not support syscall catchpoints")
+
+// SyscallInfo describes the system call a thread stopped at because of a
+// syscall catchpoint.
+type SyscallInfo struct {
+	Nr   int       // system call number
+	Exit bool      // the thread is returning from the system call, otherwise it is entering it
+	Args [6]uint64 // arguments of the system call
+	Ret  int64     // return value of the system call, only set if Exit is true
+}
+
+// syscallCatchpoints are the system calls that stop the target, see
+// SetSyscallCatchpoints.
+type syscallCatchpoints struct {
+	all bool
+	nrs []int
+}
+
+// SyscallCatchpoints returns the system calls that stop the target, all is
+// true if every system call does.
+func (t *Target) SyscallCatchpoints() (all bool, nrs []int) {
+	return t.group.syscalls.all, append([]int(nil), t.group.syscalls.nrs...)
+}
+
+// SetSyscallCatchpoints makes all the targets of the group stop when
+// entering and returning from the system calls nrs, or every system call
+// if all is true. Calling it with all false and no system calls removes
+// the syscall catchpoints.
+func (t *Target) SetSyscallCatchpoints(all bool, nrs []int) error {
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	sc, ok := t.proc.(SyscallCatcher)
+	if !ok {
+		return ErrSyscallCatchNotSupported
+	}
+	if recorded, _ := t.Recorded(); recorded {
+		return errors.New("can not catch system calls while recording")
+	}
+	if all {
+		nrs = nil
+	}
+	nrs = append([]int(nil), nrs...)
+	sort.Ints(nrs)
+	if err := sc.SetSyscallCatchpoints(all, nrs); err != nil {
+		return err
+	}
+	t.group.syscalls = syscallCatchpoints{all: all, nrs: nrs}
+	return nil
+}
@@ -0,0 +1,179 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotRecorded is returned when an action is requested that is
+	// only possible on recorded programs or on backends supporting checkpoints.
+	ErrNotRecorded = errors.New("not a recording")
+
+	// ErrNoCheckpoint is returned by Rewind when there is no checkpoint
+	// before the current position.
+	ErrNoCheckpoint = errors.New("no checkpoint before the current position")
+)
+
+// dummyRecordingManipulation is used for backends that do not implement
+// RecordingManipulationInternal.
+type dummyRecordingManipulation struct{}
+
+// Recorded always returns false.
+func (*dummyRecordingManipulation) Recorded() (bool, string) { return false, "" }
+
+// ChangeDirection will always return an error when the direction is not
+// Forward.
+func (*dummyRecordingManipulation) ChangeDirection(dir Direction) error {
+	if dir != Forward {
+		return ErrNotRecorded
+	}
+	return nil
+}
+
+// GetDirection will always return Forward.
+func (*dummyRecordingManipulation) GetDirection() Direction { return Forward }
+
+// When will always return an empty string and nil.
+func (*dummyRecordingManipulation) When() (string, error) { return "", nil }
+
+// Checkpoint will always return an error.
+func (*dummyRecordingManipulation) Checkpoint(string) (int, error) { return -1, ErrNotRecorded }
+
+// Checkpoints will always return an error.
+func (*dummyRecordingManipulation) Checkpoints() ([]Checkpoint, error) { return nil, ErrNotRecorded }
+
+// ClearCheckpoint will always return an error.
+func (*dummyRecordingManipulation) ClearCheckpoint(int) error { return ErrNotRecorded }
+
+// Restart will always return an error.
+func (*dummyRecordingManipulation) Restart(string) (Thread, error) { return nil, ErrNotRecorded }
+
+// stopHistory keeps track of the positions the target stopped at, it is
+// used by Rewind to decide which checkpoint should be restored and where
+// execution should be resumed to.
+//
+// Positions are expressed as the number of times ContinueOnce returned
+// since the target was started.
+type stopHistory struct {
+	event       int         // current position
+	checkpoints map[int]int // maps checkpoint IDs to the position they were created at
+	hits        []breakpointHit
+}
+
+// breakpointHit is a stop on a user breakpoint.
+type breakpointHit struct {
+	event int
+	addr  uint64
+}
+
+func (h *stopHistory) init() {
+	h.event = 0
+	h.checkpoints = make(map[int]int)
+	h.hits = nil
+}
+
+// truncate forgets every breakpoint hit that happened after event.
+func (h *stopHistory) truncate(event int) {
+	i := len(h.hits)
+	for i > 0 && h.hits[i-1].event > event {
+		i--
+	}
+	h.hits = h.hits[:i]
+	h.event = event
+}
+
+// Checkpoint sets a checkpoint at the current position.
+func (t *Target) Checkpoint(where string) (int, error) {
+	if _, err := t.Valid(); err != nil {
+		return -1, err
+	}
+	id, err := t.recman.Checkpoint(where)
+	if err != nil {
+		return -1, err
+	}
+	t.history.checkpoints[id] = t.history.event
+	return id, nil
+}
+
+// ClearCheckpoint removes a checkpoint.
+func (t *Target) ClearCheckpoint(id int) error {
+	if err := t.recman.ClearCheckpoint(id); err != nil {
+		return err
+	}
+	delete(t.history.checkpoints, id)
+	return nil
+}
+
+// Rewind restores the most recent checkpoint created before the current
+// position and then runs forward to the last breakpoint hit that happened
+// between that checkpoint and the current position.
+// If no breakpoint was hit in that interval the target is left stopped at
+// the checkpoint.
+// If the target is a recording Rewind instead runs the target backwards
+// until a breakpoint is hit or the beginning of the recording is reached.
+func (t *Target) Rewind() error {
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	if len(t.fncallForG) > 0 {
+		return errors.New("can not rewind while a function call is in progress")
+	}
+
+	if recorded, _ := t.recman.Recorded(); recorded {
+		if err := t.recman.ChangeDirection(Backward); err != nil {
+			return err
+		}
+		return t.Continue()
+	}
+
+	ckpt, ckptEvent := -1, -1
+	for id, event := range t.history.checkpoints {
+		if event >= t.history.event {
+			continue
+		}
+		if event > ckptEvent || (event == ckptEvent && id > ckpt) {
+			ckpt, ckptEvent = id, event
+		}
+	}
+	if ckpt < 0 {
+		return ErrNoCheckpoint
+	}
+
+	// find the last breakpoint hit after the checkpoint and count how many
+	// times that breakpoint was hit since the checkpoint.
+	var last *breakpointHit
+	for i := len(t.history.hits) - 1; i >= 0; i-- {
+		if hit := &t.history.hits[i]; hit.event > ckptEvent && hit.event < t.history.event {
+			last = hit
+			break
+		}
+	}
+	count := 0
+	if last != nil {
+		for _, hit := range t.history.hits {
+			if hit.event > ckptEvent && hit.event <= last.event && hit.addr == last.addr {
+				count++
+			}
+		}
+	}
+	var addr uint64
+	if last != nil {
+		addr = last.addr
+	}
+
+	if err := t.Restart(fmt.Sprintf("c%d", ckpt)); err != nil {
+		return err
+	}
+	t.history.truncate(ckptEvent)
+
+	for count > 0 {
+		if err := t.Continue(); err != nil {
+			return err
+		}
+		if bp := t.CurrentThread().Breakpoint(); bp.Breakpoint != nil && bp.Active && bp.Addr == addr {
+			count--
+		}
+	}
+	return nil
+}
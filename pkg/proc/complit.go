@@ -0,0 +1,546 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/parser"
+	"reflect"
+	"strings"
+
+	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
+)
+
+// Composite literals and the new builtin need memory to be allocated for
+// the values they produce. When function calls are allowed (i.e. the
+// expression is being evaluated by 'call') the memory is allocated in the
+// target by calling runtime.mallocgc, so that the values can be passed to
+// the called functions. Otherwise they are allocated in a fake heap that
+// only exists in the debugger, these values can be printed and copied into
+// the target as long as they do not contain pointers.
+//
+// Note that values allocated in the target are not reachable by the
+// garbage collector until they are assigned to a variable of the target or
+// passed to a function call. Building a composite literal, or the
+// arguments of a function call, can take several allocations: the garbage
+// collector of the target is disabled until the literal is complete, or
+// until the arguments have been copied into the argument frame of the
+// call, so that the values allocated first are not freed while the others
+// are being allocated.
+
+const (
+	fakeHeapBase = 0xbeee000000000000
+	fakeHeapSize = 1 << 32
+)
+
+var (
+	errFakeHeapPointers = errors.New("values containing pointers can not be allocated because function calls are not allowed without using 'call'")
+	errMapLitNotAllowed = errors.New("map literals can not be evaluated because function calls are not allowed without using 'call'")
+)
+
+// fakeHeap is a MemoryReadWriter that allocates memory outside of the
+// target, accesses to addresses outside of it are forwarded to mem.
+type fakeHeap struct {
+	data []byte
+	mem  MemoryReadWriter
+}
+
+func isFakeHeapAddr(addr uint64) bool {
+	return addr >= fakeHeapBase && addr < fakeHeapBase+fakeHeapSize
+}
+
+func (h *fakeHeap) ReadMemory(buf []byte, addr uint64) (int, error) {
+	if !isFakeHeapAddr(addr) {
+		return h.mem.ReadMemory(buf, addr)
+	}
+	off := addr - fakeHeapBase
+	if off+uint64(len(buf)) > uint64(len(h.data)) {
+		return 0, fmt.Errorf("invalid address %#x", addr)
+	}
+	return copy(buf, h.data[off:]), nil
+}
+
+func (h *fakeHeap) WriteMemory(addr uint64, data []byte) (int, error) {
+	if !isFakeHeapAddr(addr) {
+		return h.mem.WriteMemory(addr, data)
+	}
+	off := addr - fakeHeapBase
+	if off+uint64(len(data)) > uint64(len(h.data)) {
+		return 0, fmt.Errorf("invalid address %#x", addr)
+	}
+	return copy(h.data[off:], data), nil
+}
+
+func (h *fakeHeap) alloc(size, align int64) (uint64, error) {
+	off := alignAddr(int64(len(h.data)), align)
+	if off+size > fakeHeapSize {
+		return 0, errors.New("fake heap exhausted")
+	}
+	h.data = append(h.data, make([]byte, off+size-int64(len(h.data)))...)
+	return fakeHeapBase + uint64(off), nil
+}
+
+// inFakeHeap returns true if v, or the memory it points to, was allocated
+// in the fake heap.
+func inFakeHeap(v *Variable) bool {
+	if isFakeHeapAddr(v.Addr) || isFakeHeapAddr(v.Base) {
+		return true
+	}
+	if v.Kind == reflect.Ptr && len(v.Children) > 0 {
+		return isFakeHeapAddr(v.Children[0].Addr)
+	}
+	return false
+}
+
+// hasPointers returns true if values of type typ contain pointers.
+func hasPointers(typ godwarf.Type) bool {
+	switch t := resolveTypedef(typ).(type) {
+	case *godwarf.PtrType, *godwarf.StringType, *godwarf.SliceType, *godwarf.MapType, *godwarf.ChanType, *godwarf.FuncType, *godwarf.InterfaceType:
+		return true
+	case *godwarf.StructType:
+		for _, field := range t.Field {
+			if hasPointers(field.Type) {
+				return true
+			}
+		}
+	case *godwarf.ArrayType:
+		return t.Count > 0 && hasPointers(t.Type)
+	}
+	return false
+}
+
+// alloc allocates memory for n consecutive values of type typ and returns
+// its address and the memory containing it.
+func (scope *EvalScope) alloc(typ godwarf.Type, n int64) (uint64, MemoryReadWriter, error) {
+	// Array types made up by the debugger do not have a runtime type,
+	// allocate their elements instead.
+	for {
+		at, isarr := resolveTypedef(typ).(*godwarf.ArrayType)
+		if !isarr || typ.Common().Offset != 0 {
+			break
+		}
+		typ, n = at.Type, n*at.Count
+	}
+	size := typ.Size() * n
+
+	if scope.callCtx == nil {
+		if scope.fakeHeap == nil {
+			scope.fakeHeap = &fakeHeap{mem: scope.Mem}
+		}
+		addr, err := scope.fakeHeap.alloc(size, typ.Align())
+		return addr, scope.fakeHeap, err
+	}
+
+	rtype := "nil"
+	if hasPointers(typ) {
+		if _, isptr := resolveTypedef(typ).(*godwarf.PtrType); isptr && typ.Common().Offset == 0 {
+			// pointer types made up by the debugger do not have a runtime type
+			// either, but all pointers look the same to the garbage collector.
+			ptyp, err := scope.BinInfo.findType("unsafe.Pointer")
+			if err != nil {
+				return 0, nil, err
+			}
+			typ = ptyp
+		}
+		typeAddr, _, found, err := dwarfToRuntimeType(scope.BinInfo, scope.Mem, typ)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !found {
+			return 0, nil, fmt.Errorf("could not find runtime type of %s", typ.Common().Name)
+		}
+		rtype = fmt.Sprintf("(*runtime._type)(%#x)", typeAddr)
+	}
+	addr, err := scope.callRuntime(fmt.Sprintf("runtime.mallocgc(%d, %s, true)", size, rtype))
+	return addr, scope.Mem, err
+}
+
+// callRuntime calls the runtime function in expr, which must return a
+// pointer, and returns the value of the pointer.
+func (scope *EvalScope) callRuntime(expr string) (uint64, error) {
+	node, err := parser.ParseExpr(expr)
+	if err != nil {
+		return 0, err
+	}
+	savedLoadCfg := scope.callCtx.retLoadCfg
+	scope.callCtx.retLoadCfg = loadFullValue
+	defer func() {
+		scope.callCtx.retLoadCfg = savedLoadCfg
+	}()
+	v, err := evalFunctionCall(scope, node.(*ast.CallExpr))
+	if err != nil {
+		return 0, err
+	}
+	if v.Unreadable != nil {
+		return 0, v.Unreadable
+	}
+	if v.Kind != reflect.Ptr || len(v.Children) != 1 {
+		return 0, fmt.Errorf("internal error, could not interpret return value of %s", expr)
+	}
+	return v.Children[0].Addr, nil
+}
+
+// findTypeExpr is like BinaryInfo.findTypeExpr but also looks up unqualified
+// type names in the package of the current function, type parameters of the
+// current function and instantiations of generic types.
+func (scope *EvalScope) findTypeExpr(expr ast.Expr) (godwarf.Type, error) {
+	typ, err := scope.BinInfo.findTypeExpr(expr)
+	if err == nil || scope.Fn == nil {
+		return typ, err
+	}
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		if v := scope.typeParam(expr.Name); v != nil {
+			return v.DwarfType, nil
+		}
+		if typ, err := scope.BinInfo.findType(scope.Fn.PackageName() + "." + expr.Name); err == nil {
+			return typ, nil
+		}
+	case *ast.StarExpr:
+		if typ, err := scope.findTypeExpr(expr.X); err == nil {
+			return pointerTo(typ, scope.BinInfo.Arch), nil
+		}
+	case *ast.ArrayType:
+		if expr.Len == nil {
+			if typ, err := scope.findTypeExpr(expr.Elt); err == nil {
+				return fakeSliceType(typ), nil
+			}
+		}
+	default:
+		if gname, targs, ok := splitInstName(exprToString(expr)); ok {
+			if names, err := scope.typeArgNames(targs); err == nil {
+				if typ, err := scope.BinInfo.findType(scope.qualifiedName(gname) + "[" + strings.Join(names, ",") + "]"); err == nil {
+					return typ, nil
+				}
+			}
+		}
+	}
+	return nil, err
+}
+
+// newBuiltin evaluates new(T).
+func (scope *EvalScope) newBuiltin(node *ast.CallExpr) (*Variable, error) {
+	if len(node.Args) != 1 {
+		return nil, fmt.Errorf("wrong number of arguments to new: %d", len(node.Args))
+	}
+	typ, err := scope.findTypeExpr(node.Args[0])
+	if err != nil {
+		return nil, err
+	}
+	addr, mem, err := scope.alloc(typ, 1)
+	if err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, mem).pointerToVariable(), nil
+}
+
+// evalCompositeLit evaluates a composite literal, typ is the type of the
+// literal when it is elided because the literal is an element of another
+// composite literal.
+func (scope *EvalScope) evalCompositeLit(node *ast.CompositeLit, typ godwarf.Type) (retv *Variable, reterr error) {
+	if node.Type != nil {
+		var err error
+		typ, err = scope.compositeLitType(node)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if typ == nil {
+		return nil, errors.New("missing type in composite literal")
+	}
+
+	if scope.callCtx != nil {
+		restore, err := scope.disableGC()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			if err := restore(); err != nil && reterr == nil {
+				retv, reterr = nil, err
+			}
+		}()
+	}
+
+	switch rtyp := resolveTypedef(typ).(type) {
+	case *godwarf.StructType:
+		return scope.evalStructLit(node, typ, rtyp)
+	case *godwarf.ArrayType:
+		return scope.evalArrayLit(node, typ, rtyp)
+	case *godwarf.SliceType:
+		return scope.evalSliceLit(node, typ, rtyp)
+	case *godwarf.MapType:
+		return scope.evalMapLit(node, typ, rtyp)
+	default:
+		return nil, fmt.Errorf("invalid composite literal type %s", typ.Common().Name)
+	}
+}
+
+// disableGC stops the garbage collector of the target from starting new
+// cycles by clearing runtime.memstats.enablegc and returns a function that
+// restores it. If the garbage collector is already disabled, for example
+// because this is a literal nested in another one or an argument of a
+// function call, the returned function does nothing.
+func (scope *EvalScope) disableGC() (func() error, error) {
+	// +rtype -var memstats anytype
+	memstatsv, err := scope.findGlobal("runtime", "memstats")
+	if err != nil {
+		return nil, err
+	}
+	enablegcv, err := memstatsv.structMember("enablegc") // +rtype bool
+	if err != nil {
+		return nil, err
+	}
+	enablegcv.loadValue(loadFullValue)
+	if enablegcv.Unreadable != nil {
+		return nil, enablegcv.Unreadable
+	}
+	if !constant.BoolVal(enablegcv.Value) {
+		return func() error { return nil }, nil
+	}
+	if err := scope.setValue(enablegcv, newConstant(constant.MakeBool(false), scope.Mem), ""); err != nil {
+		return nil, fmt.Errorf("could not disable the garbage collector: %v", err)
+	}
+	return func() error {
+		if err := scope.setValue(enablegcv, newConstant(constant.MakeBool(true), scope.Mem), ""); err != nil {
+			return fmt.Errorf("could not enable the garbage collector: %v", err)
+		}
+		return nil
+	}, nil
+}
+
+func (scope *EvalScope) compositeLitType(node *ast.CompositeLit) (godwarf.Type, error) {
+	if at, ok := node.Type.(*ast.ArrayType); ok && at.Len != nil {
+		if _, ok := at.Len.(*ast.Ellipsis); ok {
+			_, n, err := scope.compositeLitIndexes(node)
+			if err != nil {
+				return nil, err
+			}
+			etyp, err := scope.findTypeExpr(at.Elt)
+			if err != nil {
+				return nil, err
+			}
+			return fakeArrayType(uint64(n), etyp), nil
+		}
+	}
+	return scope.findTypeExpr(node.Type)
+}
+
+// compositeLitIndexes returns the index of each element of an array or
+// slice literal and the length of the literal.
+func (scope *EvalScope) compositeLitIndexes(node *ast.CompositeLit) ([]int64, int64, error) {
+	idxs := make([]int64, len(node.Elts))
+	seen := make(map[int64]bool)
+	idx, n := int64(0), int64(0)
+	for i, elt := range node.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			keyv, err := scope.evalAST(kv.Key)
+			if err != nil {
+				return nil, 0, err
+			}
+			if keyv.Value == nil || keyv.Value.Kind() != constant.Int {
+				return nil, 0, fmt.Errorf("index %s must be an integer constant", exprToString(kv.Key))
+			}
+			var exact bool
+			idx, exact = constant.Int64Val(keyv.Value)
+			if !exact || idx < 0 {
+				return nil, 0, fmt.Errorf("invalid index %s", keyv.Value)
+			}
+		}
+		if seen[idx] {
+			return nil, 0, fmt.Errorf("duplicate index %d in array or slice literal", idx)
+		}
+		seen[idx] = true
+		idxs[i] = idx
+		idx++
+		if idx > n {
+			n = idx
+		}
+	}
+	return idxs, n, nil
+}
+
+func (scope *EvalScope) evalStructLit(node *ast.CompositeLit, typ godwarf.Type, rtyp *godwarf.StructType) (*Variable, error) {
+	keyed := len(node.Elts) > 0
+	if keyed {
+		_, keyed = node.Elts[0].(*ast.KeyValueExpr)
+	}
+	if !keyed && len(node.Elts) > 0 && len(node.Elts) != len(rtyp.Field) {
+		return nil, fmt.Errorf("wrong number of values in struct literal of type %s", typ.Common().Name)
+	}
+
+	addr, mem, err := scope.alloc(typ, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, elt := range node.Elts {
+		var field *godwarf.StructField
+		val := elt
+		if kv, ok := elt.(*ast.KeyValueExpr); ok != keyed {
+			return nil, errors.New("mixture of field:value and value elements in struct literal")
+		} else if keyed {
+			ident, ok := kv.Key.(*ast.Ident)
+			if !ok {
+				return nil, fmt.Errorf("invalid field name %s in struct literal", exprToString(kv.Key))
+			}
+			for _, f := range rtyp.Field {
+				if f.Name == ident.Name {
+					field = f
+					break
+				}
+			}
+			if field == nil {
+				return nil, fmt.Errorf("unknown field %s in struct literal of type %s", ident.Name, typ.Common().Name)
+			}
+			val = kv.Value
+		} else {
+			field = rtyp.Field[i]
+		}
+		fieldv := newVariable(field.Name, addr+uint64(field.ByteOffset), field.Type, scope.BinInfo, mem)
+		if err := scope.setCompositeLitElem(fieldv, val); err != nil {
+			return nil, err
+		}
+	}
+
+	return newVariable("", addr, typ, scope.BinInfo, mem), nil
+}
+
+func (scope *EvalScope) evalArrayLit(node *ast.CompositeLit, typ godwarf.Type, rtyp *godwarf.ArrayType) (*Variable, error) {
+	idxs, n, err := scope.compositeLitIndexes(node)
+	if err != nil {
+		return nil, err
+	}
+	if n > rtyp.Count {
+		return nil, fmt.Errorf("index %d out of bounds in array literal of type %s", n-1, typ.Common().Name)
+	}
+	addr, mem, err := scope.alloc(typ, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.setCompositeLitElems(node, idxs, addr, rtyp.Type, mem); err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, mem), nil
+}
+
+func (scope *EvalScope) evalSliceLit(node *ast.CompositeLit, typ godwarf.Type, rtyp *godwarf.SliceType) (*Variable, error) {
+	idxs, n, err := scope.compositeLitIndexes(node)
+	if err != nil {
+		return nil, err
+	}
+	var base uint64
+	if n > 0 {
+		var mem MemoryReadWriter
+		base, mem, err = scope.alloc(rtyp.ElemType, n)
+		if err != nil {
+			return nil, err
+		}
+		if err := scope.setCompositeLitElems(node, idxs, base, rtyp.ElemType, mem); err != nil {
+			return nil, err
+		}
+	}
+	addr, mem, err := scope.alloc(typ, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := newVariable("", addr, typ, scope.BinInfo, mem).writeSlice(n, n, base); err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, mem), nil
+}
+
+func (scope *EvalScope) setCompositeLitElems(node *ast.CompositeLit, idxs []int64, addr uint64, elemType godwarf.Type, mem MemoryReadWriter) error {
+	stride := uint64(alignAddr(elemType.Size(), elemType.Align()))
+	for i, elt := range node.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		elemv := newVariable("", addr+uint64(idxs[i])*stride, elemType, scope.BinInfo, mem)
+		if err := scope.setCompositeLitElem(elemv, elt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (scope *EvalScope) evalMapLit(node *ast.CompositeLit, typ godwarf.Type, rtyp *godwarf.MapType) (*Variable, error) {
+	if scope.callCtx == nil {
+		return nil, errMapLitNotAllowed
+	}
+	maptype, _, found, err := dwarfToRuntimeType(scope.BinInfo, scope.Mem, typ)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, fmt.Errorf("could not find runtime type of %s", typ.Common().Name)
+	}
+
+	hmap, err := scope.callRuntime("runtime.makemap_small()")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, elt := range node.Elts {
+		kv, ok := elt.(*ast.KeyValueExpr)
+		if !ok {
+			return nil, fmt.Errorf("missing key in map literal: %s", exprToString(elt))
+		}
+		keyaddr, keymem, err := scope.alloc(rtyp.KeyType, 1)
+		if err != nil {
+			return nil, err
+		}
+		if err := scope.setCompositeLitElem(newVariable("", keyaddr, rtyp.KeyType, scope.BinInfo, keymem), kv.Key); err != nil {
+			return nil, err
+		}
+		slot, err := scope.callRuntime(fmt.Sprintf("runtime.mapassign((*runtime.maptype)(%#x), (*runtime.hmap)(%#x), unsafe.Pointer(%#x))", maptype, hmap, keyaddr))
+		if err != nil {
+			return nil, err
+		}
+		if err := scope.setCompositeLitElem(newVariable("", slot, rtyp.ElemType, scope.BinInfo, scope.Mem), kv.Value); err != nil {
+			return nil, err
+		}
+	}
+
+	addr, mem, err := scope.alloc(typ, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err := writePointer(scope.BinInfo, mem, addr, hmap); err != nil {
+		return nil, err
+	}
+	return newVariable("", addr, typ, scope.BinInfo, mem), nil
+}
+
+// setCompositeLitElem evaluates expr, an element of a composite literal, and
+// writes it to dstv.
+func (scope *EvalScope) setCompositeLitElem(dstv *Variable, expr ast.Expr) error {
+	var srcv *Variable
+	var err error
+	if lit, ok := expr.(*ast.CompositeLit); ok && lit.Type == nil {
+		// The type of the literal is elided, for pointer elements &T is.
+		if ptyp, isptr := resolveTypedef(dstv.DwarfType).(*godwarf.PtrType); isptr {
+			srcv, err = scope.evalCompositeLit(lit, ptyp.Type)
+			if err == nil {
+				srcv = srcv.pointerToVariable()
+			}
+		} else {
+			srcv, err = scope.evalCompositeLit(lit, dstv.DwarfType)
+		}
+	} else {
+		srcv, err = scope.evalAST(expr)
+	}
+	if err != nil {
+		return err
+	}
+	return scope.setValue(dstv, srcv, exprToString(expr))
+}
+
+// checkFakeHeapAssign returns an error if srcv was allocated in the fake
+// heap, contains pointers and is being assigned to memory of the target.
+func checkFakeHeapAssign(dstv, srcv *Variable) error {
+	if inFakeHeap(srcv) && !isFakeHeapAddr(dstv.Addr) && hasPointers(srcv.DwarfType) {
+		return errFakeHeapPointers
+	}
+	return nil
+}
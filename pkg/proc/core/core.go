@@ -271,7 +271,11 @@ func (p *process) SupportsBPF() bool {
 	return false
 }
 
-func (dbp *process) SetUProbe(fnName string, goidOffset int64, args []ebpf.UProbeArgMap) error {
+func (dbp *process) SetUProbe(fnName string, goidOffset int64, args []ebpf.UProbeArgMap, cfg ebpf.UProbeLoadConfig) error {
+	panic("not implemented")
+}
+
+func (dbp *process) SetInlinedUProbe(fnName string, addr uint64, goidOffset int64, args []ebpf.UProbeArgMap, cfg ebpf.UProbeLoadConfig) error {
 	panic("not implemented")
 }
 
@@ -487,3 +491,7 @@ func (p *process) DumpProcessNotes(notes []elfwriter.Note, threadDone func()) (t
 func (dbp *process) GetBufferedTracepoints() []ebpf.RawUProbeParams {
 	return nil
 }
+
+func (dbp *process) GetLatencyHistograms() map[uint64][]uint64 {
+	return nil
+}
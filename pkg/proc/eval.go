@@ -54,6 +54,10 @@ type EvalScope struct {
 	callCtx *callContext
 
 	dictAddr uint64 // dictionary address for instantiated generic functions
+
+	fakeHeap *fakeHeap // memory for values allocated when function calls are not allowed
+
+	boundVars map[string]*Variable // identifiers bound to values, they shadow every other variable
 }
 
 type localsFlags uint8
@@ -366,6 +370,10 @@ func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 		return fmt.Errorf("Expression \"%s\" is unreadable: %v", srcExpr, srcv.Unreadable)
 	}
 
+	if err := checkFakeHeapAssign(dstv, srcv); err != nil {
+		return err
+	}
+
 	// Numerical types
 	switch dstv.Kind {
 	case reflect.Float32, reflect.Float64:
@@ -398,7 +406,7 @@ func (scope *EvalScope) setValue(dstv, srcv *Variable, srcExpr string) error {
 	}
 
 	if srcv.Kind == reflect.String {
-		if err := allocString(scope, srcv); err != nil {
+		if err := allocString(scope, srcv, isFakeHeapAddr(dstv.Addr)); err != nil {
 			return err
 		}
 		return dstv.writeString(uint64(srcv.Len), uint64(srcv.Base))
@@ -483,7 +491,7 @@ func (scope *EvalScope) FunctionArguments(cfg LoadConfig) ([]*Variable, error) {
 	})
 	cfg.MaxMapBuckets = maxMapBucketsFactor * cfg.MaxArrayValues
 	loadValues(vars, cfg)
-	return vars, nil
+	return append(scope.typeParams(), vars...), nil
 }
 
 func filterVariables(vars []*Variable, pred func(v *Variable) bool) []*Variable {
@@ -745,7 +753,7 @@ func (scope *EvalScope) evalToplevelTypeCast(t ast.Expr, cfg LoadConfig) (*Varia
 func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 	switch node := t.(type) {
 	case *ast.CallExpr:
-		if len(node.Args) == 1 {
+		if len(node.Args) == 1 && !isNewBuiltin(node) {
 			v, err := scope.evalTypeCast(node)
 			if err == nil || err != reader.ErrTypeNotFound {
 				return v, err
@@ -756,6 +764,9 @@ func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 	case *ast.Ident:
 		return scope.evalIdent(node)
 
+	case *ast.CompositeLit:
+		return scope.evalCompositeLit(node, nil)
+
 	case *ast.ParenExpr:
 		// otherwise just eval recursively
 		return scope.evalAST(node.X)
@@ -799,6 +810,9 @@ func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 		return scope.evalTypeAssert(node)
 
 	case *ast.IndexExpr:
+		if v, err := scope.evalInstantiation(node); v != nil || err != nil {
+			return v, err
+		}
 		return scope.evalIndex(node)
 
 	case *ast.SliceExpr:
@@ -828,6 +842,10 @@ func (scope *EvalScope) evalAST(t ast.Expr) (*Variable, error) {
 		return newConstant(constant.MakeFromLiteral(node.Value, node.Kind, 0), scope.Mem), nil
 
 	default:
+		// instantiations of generic functions with more than one type argument
+		if v, err := scope.evalInstantiation(node); v != nil || err != nil {
+			return v, err
+		}
 		return nil, fmt.Errorf("expression %T not implemented", t)
 
 	}
@@ -866,7 +884,7 @@ func (scope *EvalScope) evalTypeCast(node *ast.CallExpr) (*Variable, error) {
 	// remove all enclosing parenthesis from the type name
 	fnnode = removeParen(fnnode)
 
-	styp, err := scope.BinInfo.findTypeExpr(fnnode)
+	styp, err := scope.findTypeExpr(fnnode)
 	if err != nil {
 		return nil, err
 	}
@@ -1001,11 +1019,18 @@ func (scope *EvalScope) evalBuiltinCall(node *ast.CallExpr) (*Variable, error) {
 		return callBuiltinWithArgs(imagBuiltin)
 	case "real":
 		return callBuiltinWithArgs(realBuiltin)
+	case "new":
+		return scope.newBuiltin(node)
 	}
 
 	return nil, nil
 }
 
+func isNewBuiltin(node *ast.CallExpr) bool {
+	fnnode, ok := node.Fun.(*ast.Ident)
+	return ok && fnnode.Name == "new"
+}
+
 func capBuiltin(args []*Variable, nodeargs []ast.Expr) (*Variable, error) {
 	if len(args) != 1 {
 		return nil, fmt.Errorf("wrong number of arguments to cap: %d", len(args))
@@ -1177,6 +1202,10 @@ func (scope *EvalScope) evalIdent(node *ast.Ident) (*Variable, error) {
 		return nilVariable, nil
 	}
 
+	if v, ok := scope.boundVars[node.Name]; ok {
+		return v.clone(), nil
+	}
+
 	vars, err := scope.Locals(0)
 	if err != nil {
 		return nil, err
@@ -1187,6 +1216,11 @@ func (scope *EvalScope) evalIdent(node *ast.Ident) (*Variable, error) {
 		}
 	}
 
+	// or a type parameter of a generic function
+	if v := scope.typeParam(node.Name); v != nil {
+		return v, nil
+	}
+
 	// if it's not a local variable then it could be a package variable w/o explicit package name
 	if scope.Fn != nil {
 		if v, err := scope.findGlobal(scope.Fn.PackageName(), node.Name); err == nil {
@@ -1280,7 +1314,7 @@ func (scope *EvalScope) evalTypeAssert(node *ast.TypeAssertExpr) (*Variable, err
 	// can access the data field of an interface without actually having to
 	// type the concrete type.
 	if idtyp, isident := node.Type.(*ast.Ident); !isident || idtyp.Name != "data" {
-		typ, err := scope.BinInfo.findTypeExpr(node.Type)
+		typ, err := scope.findTypeExpr(node.Type)
 		if err != nil {
 			return nil, err
 		}
@@ -2104,6 +2138,11 @@ func (v *Variable) findMethod(mname string) (*Variable, error) {
 		}
 
 		typePath := typ.Common().Name
+		var targs []string
+		if i := strings.Index(typePath, "["); i > 0 && strings.Contains(typePath[:i], ".") && !complexType(typePath[:i]) {
+			// instantiation of a generic type
+			typePath, targs, _ = splitInstName(typePath)
+		}
 		dot := strings.LastIndex(typePath, ".")
 		if dot < 0 {
 			// probably just a C type
@@ -2113,7 +2152,33 @@ func (v *Variable) findMethod(mname string) (*Variable, error) {
 		pkg := typePath[:dot]
 		receiver := typePath[dot+1:]
 
-		//TODO(aarzilli): support generic functions?
+		if targs != nil {
+			// methods of instantiations of generic types
+			for _, ptrRecv := range []bool{false, true} {
+				gname := fmt.Sprintf("%s.%s.%s", pkg, receiver, mname)
+				name := fmt.Sprintf("%s.%s[%s].%s", pkg, receiver, strings.Join(targs, ","), mname)
+				if ptrRecv {
+					gname = fmt.Sprintf("%s.(*%s).%s", pkg, receiver, mname)
+					name = fmt.Sprintf("%s.(*%s[%s]).%s", pkg, receiver, strings.Join(targs, ","), mname)
+				}
+				if len(v.bin.LookupGenericFunc()[gname]) == 0 {
+					continue
+				}
+				r, err := genericFunctionToVariable(name, v.bin, v.mem)
+				if err != nil {
+					return nil, err
+				}
+				switch {
+				case ptrRecv == isptr:
+					r.Children = append(r.Children, *v)
+				case isptr:
+					r.Children = append(r.Children, *(v.maybeDereference()))
+				default:
+					r.Children = append(r.Children, *(v.pointerToVariable()))
+				}
+				return r, nil
+			}
+		}
 
 		if fn, ok := v.bin.LookupFunc[fmt.Sprintf("%s.%s.%s", pkg, receiver, mname)]; ok {
 			r, err := functionToVariable(fn, v.bin, v.mem)
@@ -2244,6 +2309,9 @@ func (fn *Function) fakeType(bi *BinaryInfo, removeReceiver bool) (*godwarf.Func
 	rets := make([]string, 0, len(formalArgs))
 
 	for _, formalArg := range formalArgs {
+		if formalArg.name == goDictionaryName {
+			continue
+		}
 		var s string
 		if strings.HasPrefix(formalArg.name, "~") {
 			s = formalArg.typ.String()
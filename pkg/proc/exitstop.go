@@ -0,0 +1,44 @@
+package proc
+
+import "errors"
+
+// ErrStopOnExitNotSupported is returned by SetStopOnExit when the backend
+// can not stop the target before it exits.
+var ErrStopOnExitNotSupported = errors.New("backend does not support stopping before the process exits")
+
+// ExitInfo describes the exit of the process a thread stopped at, see
+// SetStopOnExit.
+type ExitInfo struct {
+	// Status is the exit status of the process, or minus the number of the
+	// signal that killed it.
+	Status int
+}
+
+// StopOnExit returns true if the targets stop just before they exit.
+func (t *Target) StopOnExit() bool {
+	return t.group.stopOnExit
+}
+
+// SetStopOnExit makes all the targets of the group stop just before they
+// exit, because they called os.Exit, returned from main or were killed by a
+// signal. At that point the address space of the process still exists:
+// goroutines, variables and stacks can be inspected and a core dump can be
+// created. The target stops with StopExiting and the thread that called
+// exit, if any, as the current thread; resuming it lets the process exit.
+func (t *Target) SetStopOnExit(enabled bool) error {
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	es, ok := t.proc.(ExitStopper)
+	if !ok {
+		if !enabled {
+			return nil
+		}
+		return ErrStopOnExitNotSupported
+	}
+	if err := es.SetStopOnExit(enabled); err != nil {
+		return err
+	}
+	t.group.stopOnExit = enabled
+	return nil
+}
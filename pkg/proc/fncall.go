@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"go/ast"
 	"go/constant"
-	"go/token"
 	"reflect"
 	"sort"
 	"strconv"
@@ -56,6 +55,7 @@ var (
 	errFuncCallInProgress         = errors.New("cannot call function while another function call is already in progress")
 	errNoGoroutine                = errors.New("no goroutine selected")
 	errGoroutineNotRunning        = errors.New("selected goroutine not running")
+	errNoInjectionThread          = errors.New("can not call function on a parked goroutine: no goroutine is running user code")
 	errNotEnoughStack             = errors.New("not enough stack space")
 	errTooManyArguments           = errors.New("too many arguments")
 	errNotEnoughArguments         = errors.New("not enough arguments")
@@ -77,6 +77,9 @@ type functionCallState struct {
 	receiver *Variable
 	// closureAddr is the address of the closure being called
 	closureAddr uint64
+	// dictAddr is the dictionary of the instantiation being called, if fn is
+	// a generic function
+	dictAddr uint64
 	// formalArgs are the formal arguments of fn
 	formalArgs []funcCallArg
 	// argFrameSize contains the size of the arguments
@@ -156,13 +159,9 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		return errFuncCallUnsupportedBackend
 	}
 
-	// check that the target goroutine is running
 	if g == nil {
 		return errNoGoroutine
 	}
-	if g.Status != Grunning || g.Thread == nil {
-		return errGoroutineNotRunning
-	}
 
 	if callinj := t.fncallForG[g.ID]; callinj != nil && callinj.continueCompleted != nil {
 		return errFuncCallInProgress
@@ -173,7 +172,26 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		return errFuncCallUnsupported
 	}
 
-	scope, err := GoroutineScope(t, g.Thread)
+	var scope *EvalScope
+	var injectionThread Thread
+	var injectionG *G
+	var err error
+	switch {
+	case g.Status == Grunning && g.Thread != nil:
+		injectionThread = g.Thread
+		scope, err = GoroutineScope(t, g.Thread)
+	case g.Thread == nil && (g.Status == Gwaiting || g.Status == Grunnable):
+		// The goroutine is parked, the call is injected on a goroutine that is
+		// running and evaluation happens in the scope of the parked goroutine,
+		// like it does for nested calls in Go 1.15 and later.
+		injectionThread, injectionG, err = parkedCallInjectionThread(t)
+		if err != nil {
+			return err
+		}
+		scope, err = parkedGoroutineScope(t, g)
+	default:
+		return errGoroutineNotRunning
+	}
 	if err != nil {
 		return err
 	}
@@ -188,6 +206,9 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		continueRequest:   continueRequest,
 		continueCompleted: continueCompleted,
 	}
+	if g.Thread == nil {
+		scope.callCtx.injectionThread = injectionThread
+	}
 
 	endCallInjection, err := t.proc.StartCallInjection()
 	if err != nil {
@@ -200,6 +221,13 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		startThreadID:     0,
 		endCallInjection:  endCallInjection,
 	}
+	if injectionG != nil {
+		// The goroutine running on the injection thread executes the
+		// debugCall frames before and after the call, including the final
+		// register restore, when the call's startThreadID has already been
+		// reset.
+		t.fncallForG[injectionG.ID] = t.fncallForG[g.ID]
+	}
 
 	go scope.EvalExpression(expr, retLoadCfg)
 
@@ -208,32 +236,34 @@ func EvalExpressionWithCalls(t *Target, g *G, expr string, retLoadCfg LoadConfig
 		return t.Continue()
 	}
 
-	return finishEvalExpressionWithCalls(t, g, contReq, ok)
+	return finishEvalExpressionWithCalls(t, g, injectionThread, contReq, ok)
 }
 
-func finishEvalExpressionWithCalls(t *Target, g *G, contReq continueRequest, ok bool) error {
-	fncallLog("stashing return values for %d in thread=%d", g.ID, g.Thread.ThreadID())
-	g.Thread.Common().CallReturn = true
+// finishEvalExpressionWithCalls stashes the return values of the call
+// injection for goroutine g in thread.
+func finishEvalExpressionWithCalls(t *Target, g *G, thread Thread, contReq continueRequest, ok bool) error {
+	fncallLog("stashing return values for %d in thread=%d", g.ID, thread.ThreadID())
+	thread.Common().CallReturn = true
 	var err error
 	if !ok {
 		err = errors.New("internal error EvalExpressionWithCalls didn't return anything")
 	} else if contReq.err != nil {
 		if fpe, ispanic := contReq.err.(fncallPanicErr); ispanic {
-			g.Thread.Common().returnValues = []*Variable{fpe.panicVar}
+			thread.Common().returnValues = []*Variable{fpe.panicVar}
 		} else {
 			err = contReq.err
 		}
 	} else if contReq.ret == nil {
-		g.Thread.Common().returnValues = nil
+		thread.Common().returnValues = nil
 	} else if contReq.ret.Addr == 0 && contReq.ret.DwarfType == nil && contReq.ret.Kind == reflect.Invalid {
 		// this is a variable returned by a function call with multiple return values
 		r := make([]*Variable, len(contReq.ret.Children))
 		for i := range contReq.ret.Children {
 			r[i] = &contReq.ret.Children[i]
 		}
-		g.Thread.Common().returnValues = r
+		thread.Common().returnValues = r
 	} else {
-		g.Thread.Common().returnValues = []*Variable{contReq.ret}
+		thread.Common().returnValues = []*Variable{contReq.ret}
 	}
 
 	close(t.fncallForG[g.ID].continueCompleted)
@@ -247,6 +277,43 @@ func finishEvalExpressionWithCalls(t *Target, g *G, contReq continueRequest, ok
 	return err
 }
 
+// parkedCallInjectionThread returns a thread that can be used to inject a
+// call on behalf of a parked goroutine. The thread must be running a user
+// goroutine, that isn't executing the runtime or another call injection,
+// the current thread is preferred. The goroutine running on the returned
+// thread is also returned.
+func parkedCallInjectionThread(t *Target) (Thread, *G, error) {
+	threads := append([]Thread{t.CurrentThread()}, t.ThreadList()...)
+	for _, thread := range threads {
+		g, err := GetG(thread)
+		if err != nil || g == nil || g.System(t) || t.fncallForG[g.ID] != nil {
+			continue
+		}
+		loc, err := thread.Location()
+		if err != nil || loc.Fn == nil || strings.HasPrefix(loc.Fn.Name, "runtime.") {
+			continue
+		}
+		return thread, g, nil
+	}
+	return nil, nil, errNoInjectionThread
+}
+
+// parkedGoroutineScope returns the scope of the topmost frame of g, that
+// isn't executing the runtime.
+func parkedGoroutineScope(t *Target, g *G) (*EvalScope, error) {
+	const maxdepth = 50
+	frames, err := g.Stacktrace(maxdepth, 0)
+	if err != nil {
+		return nil, err
+	}
+	for i := range frames {
+		if frames[i].Call.Fn != nil && !strings.HasPrefix(frames[i].Call.Fn.Name, "runtime.") {
+			return FrameToScope(t, t.Memory(), g, frames[i:]...), nil
+		}
+	}
+	return nil, errors.New("could not find a frame of the goroutine outside of the runtime")
+}
+
 // evalFunctionCall evaluates a function call.
 // If this is a built-in function it's evaluated directly.
 // Otherwise this will start the function call injection protocol and
@@ -349,11 +416,13 @@ func evalFunctionCall(scope *EvalScope, node *ast.CallExpr) (*Variable, error) {
 		if g.ID == scope.g.ID {
 			scope.g = g
 		} else {
-			// We are in Go 1.15 and we switched to a new goroutine, the original
-			// goroutine is now parked and therefore does not have a thread
-			// associated.
-			scope.g.Thread = nil
-			scope.g.Status = Gwaiting
+			// We are in Go 1.15 and we switched to a new goroutine, or the call
+			// was made for a parked goroutine, the original goroutine is now
+			// parked and therefore does not have a thread associated.
+			if scope.g.Thread != nil {
+				scope.g.Thread = nil
+				scope.g.Status = Gwaiting
+			}
 			scope.callCtx.injectionThread = g.Thread
 		}
 
@@ -489,6 +558,7 @@ func funcCallEvalFuncExpr(scope *EvalScope, fncall *functionCallState, allowCall
 		return errNotAGoFunction
 	}
 	fncall.closureAddr = fnvar.closureAddr
+	fncall.dictAddr = fnvar.dictAddr
 
 	fncall.argFrameSize, fncall.formalArgs, err = funcCallArgs(fncall.fn, bi, false)
 	if err != nil {
@@ -496,22 +566,33 @@ func funcCallEvalFuncExpr(scope *EvalScope, fncall *functionCallState, allowCall
 	}
 
 	argnum := len(fncall.expr.Args)
+	formalArgnum := len(fncall.formalArgs)
+
+	// The dictionary of generic functions is passed by us, not by the user.
+	for _, formalArg := range fncall.formalArgs {
+		if formalArg.name == goDictionaryName {
+			if fncall.dictAddr == 0 {
+				return fmt.Errorf("can not call generic function %s without type arguments", fncall.fn.Name)
+			}
+			formalArgnum--
+		}
+	}
 
 	// If the function variable has a child then that child is the method
 	// receiver. However, if the method receiver is not being used (e.g.
 	// func (_ X) Foo()) then it will not actually be listed as a formal
 	// argument. Ensure that we are really off by 1 to add the receiver to
 	// the function call.
-	if len(fnvar.Children) > 0 && argnum == (len(fncall.formalArgs)-1) {
+	if len(fnvar.Children) > 0 && argnum == (formalArgnum-1) {
 		argnum++
 		fncall.receiver = &fnvar.Children[0]
 		fncall.receiver.Name = exprToString(fncall.expr.Fun)
 	}
 
-	if argnum > len(fncall.formalArgs) {
+	if argnum > formalArgnum {
 		return errTooManyArguments
 	}
-	if argnum < len(fncall.formalArgs) {
+	if argnum < formalArgnum {
 		return errNotEnoughArguments
 	}
 
@@ -542,14 +623,27 @@ func funcCallEvalArgs(scope *EvalScope, fncall *functionCallState, formalScope *
 		fncall.formalArgs = fncall.formalArgs[1:]
 	}
 
-	for i := range fncall.formalArgs {
-		formalArg := &fncall.formalArgs[i]
+	i := 0
+	for j := range fncall.formalArgs {
+		formalArg := &fncall.formalArgs[j]
+
+		if formalArg.name == goDictionaryName {
+			dictVar, err := extractVarInfoFromEntry(scope.target, formalScope.BinInfo, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, 0)
+			if err != nil {
+				return err
+			}
+			if err := dictVar.writeUint(fncall.dictAddr, int64(scope.BinInfo.Arch.PtrSize())); err != nil {
+				return err
+			}
+			continue
+		}
 
 		actualArg, err := scope.evalAST(fncall.expr.Args[i])
 		if err != nil {
 			return fmt.Errorf("error evaluating %q as argument %s in function %s: %v", exprToString(fncall.expr.Args[i]), formalArg.name, fncall.fn.Name, err)
 		}
 		actualArg.Name = exprToString(fncall.expr.Args[i])
+		i++
 
 		err = funcCallCopyOneArg(scope, fncall, actualArg, formalArg, formalScope)
 		if err != nil {
@@ -579,7 +673,7 @@ func funcCallCopyOneArg(scope *EvalScope, fncall *functionCallState, actualArg *
 	var formalArgVar *Variable
 	if formalArg.dwarfEntry != nil {
 		var err error
-		formalArgVar, err = extractVarInfoFromEntry(scope.target, formalScope.BinInfo, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, 0)
+		formalArgVar, err = extractVarInfoFromEntry(scope.target, formalScope.BinInfo, formalScope.image(), formalScope.Regs, formalScope.Mem, formalArg.dwarfEntry, fncall.dictAddr)
 		if err != nil {
 			return err
 		}
@@ -602,14 +696,15 @@ func funcCallArgs(fn *Function, bi *BinaryInfo, includeRet bool) (argFrameSize i
 	producer := bi.Producer()
 	trustArgOrder := producer != "" && goversion.ProducerAfterOrEqual(bi.Producer(), 1, 12)
 
-	if bi.regabi && fn.cu.optimized && fn.Name != "runtime.mallocgc" {
+	if _, patched := regabiRuntimeWorkarounds[fn.Name]; bi.regabi && fn.cu.optimized && !patched {
 		// Debug info for function arguments on optimized functions is currently
 		// too incomplete to attempt injecting calls to arbitrary optimized
 		// functions.
 		// Prior to regabi we could do this because the ABI was simple enough to
 		// manually encode it in Delve.
-		// Runtime.mallocgc is an exception, we specifically patch it's DIE to be
-		// correct for call injection purposes.
+		// The runtime functions in regabiRuntimeWorkarounds are an exception,
+		// we specifically patch their DIEs to be correct for call injection
+		// purposes.
 		return 0, nil, fmt.Errorf("can not call optimized function %s when regabi is in use", fn.Name)
 	}
 
@@ -814,54 +909,20 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 
 	case debugCallRegCompleteCall:
 		p.fncallForG[callScope.g.ID].startThreadID = 0
-		// evaluate arguments of the target function, copy them into its argument frame and call the function
-		if fncall.fn == nil || fncall.receiver != nil || fncall.closureAddr != 0 {
-			// if we couldn't figure out which function we are calling before
-			// (because the function we are calling is the return value of a call to
-			// another function) now we have to figure it out by recursively
-			// evaluating the function calls.
-			// This also needs to be done if the function call has a receiver
-			// argument or a closure address (because those addresses could be on the stack
-			// and have changed position between the start of the call and now).
-
-			err := funcCallEvalFuncExpr(callScope, fncall, true)
-			if err != nil {
-				fncall.err = err
-				fncall.lateCallFailure = true
-				break
-			}
-			//TODO: double check that function call size isn't too big
-		}
-
-		// instead of evaluating the arguments we start first by pushing the call
-		// on the stack, this is the opposite of what would happen normally but
-		// it's necessary because otherwise the GC wouldn't be able to deal with
-		// the argument frame.
-		if fncall.closureAddr != 0 {
-			// When calling a function pointer we must set the DX register to the
-			// address of the function pointer itself.
-			setClosureReg(thread, fncall.closureAddr)
-		}
-		cfa := regs.SP()
-		oldpc := regs.PC()
-		callOP(bi, thread, regs, fncall.fn.Entry)
-		formalScope, err := GoroutineScope(callScope.target, thread)
-		if formalScope != nil && formalScope.Regs.CFA != int64(cfa) {
-			// This should never happen, checking just to avoid hard to figure out disasters.
-			err = fmt.Errorf("mismatch in CFA %#x (calculated) %#x (expected)", formalScope.Regs.CFA, int64(cfa))
-		}
-		if err == nil {
-			err = funcCallEvalArgs(callScope, fncall, formalScope)
-		}
-
+		// Values allocated in the target while the function and its
+		// arguments are evaluated are only referenced by the debugger until
+		// they are copied into the argument frame, the garbage collector is
+		// disabled until then.
+		restoreGC, err := callScope.disableGC()
 		if err != nil {
-			// rolling back the call, note: this works because we called regs.Copy() above
-			setSP(thread, cfa)
-			setPC(thread, oldpc)
 			fncall.err = err
 			fncall.lateCallFailure = true
 			break
 		}
+		funcCallCompleteCall(callScope, fncall, thread, regs)
+		if err := restoreGC(); err != nil && fncall.err == nil {
+			fncall.err = err
+		}
 
 	case debugCallRegRestoreRegisters:
 		// runtime requests that we restore the registers (all except pc and sp),
@@ -894,6 +955,8 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 
 		// pretend we are still inside the function we called
 		fakeFunctionEntryScope(retScope, fncall.fn, int64(regs.SP()), regs.SP()-uint64(bi.Arch.PtrSize()))
+		// the .dict argument is no longer available, use the one we passed
+		retScope.dictAddr = fncall.dictAddr
 		var flags localsFlags
 		flags |= localsNoDeclLineCheck // if the function we are calling is an autogenerated stub then declaration lines have no meaning
 		if !bi.regabi {
@@ -939,6 +1002,59 @@ func funcCallStep(callScope *EvalScope, fncall *functionCallState, thread Thread
 	return false
 }
 
+// funcCallCompleteCall evaluates the function and the arguments of fncall,
+// copies them into its argument frame and sets up thread to call it.
+func funcCallCompleteCall(callScope *EvalScope, fncall *functionCallState, thread Thread, regs Registers) {
+	bi := callScope.BinInfo
+	// evaluate arguments of the target function, copy them into its argument frame and call the function
+	if fncall.fn == nil || fncall.receiver != nil || fncall.closureAddr != 0 {
+		// if we couldn't figure out which function we are calling before
+		// (because the function we are calling is the return value of a call to
+		// another function) now we have to figure it out by recursively
+		// evaluating the function calls.
+		// This also needs to be done if the function call has a receiver
+		// argument or a closure address (because those addresses could be on the stack
+		// and have changed position between the start of the call and now).
+
+		err := funcCallEvalFuncExpr(callScope, fncall, true)
+		if err != nil {
+			fncall.err = err
+			fncall.lateCallFailure = true
+			return
+		}
+		//TODO: double check that function call size isn't too big
+	}
+
+	// instead of evaluating the arguments we start first by pushing the call
+	// on the stack, this is the opposite of what would happen normally but
+	// it's necessary because otherwise the GC wouldn't be able to deal with
+	// the argument frame.
+	if fncall.closureAddr != 0 {
+		// When calling a function pointer we must set the DX register to the
+		// address of the function pointer itself.
+		setClosureReg(thread, fncall.closureAddr)
+	}
+	cfa := regs.SP()
+	oldpc := regs.PC()
+	callOP(bi, thread, regs, fncall.fn.Entry)
+	formalScope, err := GoroutineScope(callScope.target, thread)
+	if formalScope != nil && formalScope.Regs.CFA != int64(cfa) {
+		// This should never happen, checking just to avoid hard to figure out disasters.
+		err = fmt.Errorf("mismatch in CFA %#x (calculated) %#x (expected)", formalScope.Regs.CFA, int64(cfa))
+	}
+	if err == nil {
+		err = funcCallEvalArgs(callScope, fncall, formalScope)
+	}
+
+	if err != nil {
+		// rolling back the call, note: this works because we called regs.Copy() above
+		setSP(thread, cfa)
+		setPC(thread, oldpc)
+		fncall.err = err
+		fncall.lateCallFailure = true
+	}
+}
+
 func readTopstackVariable(t *Target, thread Thread, regs Registers, typename string, loadCfg LoadConfig) (*Variable, error) {
 	bi := thread.BinInfo()
 	scope, err := ThreadScope(t, thread)
@@ -981,46 +1097,25 @@ func fakeFunctionEntryScope(scope *EvalScope, fn *Function, cfa int64, sp uint64
 	return nil
 }
 
-// allocString allocates spaces for the contents of v if it needs to be allocated
-func allocString(scope *EvalScope, v *Variable) error {
+// allocString allocates spaces for the contents of v if it needs to be allocated.
+// If function calls are not allowed the contents are allocated in the fake
+// heap, but only if fake is set.
+func allocString(scope *EvalScope, v *Variable, fake bool) error {
 	if v.Base != 0 || v.Len == 0 {
 		// already allocated
 		return nil
 	}
 
-	if scope.callCtx == nil {
+	if scope.callCtx == nil && !fake {
 		return errFuncCallNotAllowedStrAlloc
 	}
-	savedLoadCfg := scope.callCtx.retLoadCfg
-	scope.callCtx.retLoadCfg = loadFullValue
-	defer func() {
-		scope.callCtx.retLoadCfg = savedLoadCfg
-	}()
-	mallocv, err := evalFunctionCall(scope, &ast.CallExpr{
-		Fun: &ast.SelectorExpr{
-			X:   &ast.Ident{Name: "runtime"},
-			Sel: &ast.Ident{Name: "mallocgc"},
-		},
-		Args: []ast.Expr{
-			&ast.BasicLit{Kind: token.INT, Value: strconv.Itoa(int(v.Len))},
-			&ast.Ident{Name: "nil"},
-			&ast.Ident{Name: "false"},
-		},
-	})
+	byteType := &godwarf.UintType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{ByteSize: 1, Name: "byte"}, BitSize: 8, BitOffset: 0}}
+	base, mem, err := scope.alloc(byteType, v.Len)
 	if err != nil {
 		return err
 	}
-	if mallocv.Unreadable != nil {
-		return mallocv.Unreadable
-	}
-	if mallocv.DwarfType.String() != "*void" {
-		return fmt.Errorf("unexpected return type for mallocgc call: %v", mallocv.DwarfType.String())
-	}
-	if len(mallocv.Children) != 1 {
-		return errors.New("internal error, could not interpret return value of mallocgc call")
-	}
-	v.Base = mallocv.Children[0].Addr
-	_, err = scope.Mem.WriteMemory(v.Base, []byte(constant.StringVal(v.Value)))
+	v.Base = base
+	_, err = mem.WriteMemory(v.Base, []byte(constant.StringVal(v.Value)))
 	return err
 }
 
@@ -1068,7 +1163,7 @@ func callInjectionProtocol(t *Target, threads []Thread) (done bool, err error) {
 		callinj.continueCompleted <- g
 		contReq, ok := <-callinj.continueRequest
 		if !contReq.cont {
-			err := finishEvalExpressionWithCalls(t, g, contReq, ok)
+			err := finishEvalExpressionWithCalls(t, g, thread, contReq, ok)
 			if err != nil {
 				return done, err
 			}
@@ -1149,7 +1244,48 @@ func (e fakeEntry) Val(attr dwarf.Attr) interface{} {
 	return e[attr]
 }
 
+// regabiRuntimeWorkarounds maps the runtime functions called by the
+// debugger to functions returning the correct DIEs of their arguments.
+var regabiRuntimeWorkarounds = map[string]func(*BinaryInfo) ([]*godwarf.Tree, error){
+	"runtime.mallocgc":      regabiMallocgcWorkaround,
+	"runtime.makemap_small": regabiMakemapSmallWorkaround,
+	"runtime.mapassign":     regabiMapassignWorkaround,
+}
+
 func regabiMallocgcWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
+	return regabiFakeArgs(bi, []fakeArg{
+		{"size", "uintptr", regnum.AMD64_Rax, false},
+		{"typ", "*runtime._type", regnum.AMD64_Rbx, false},
+		{"needzero", "bool", regnum.AMD64_Rcx, false},
+		{"~r1", "unsafe.Pointer", regnum.AMD64_Rax, true},
+	})
+}
+
+func regabiMakemapSmallWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
+	return regabiFakeArgs(bi, []fakeArg{
+		{"~r0", "*runtime.hmap", regnum.AMD64_Rax, true},
+	})
+}
+
+func regabiMapassignWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
+	return regabiFakeArgs(bi, []fakeArg{
+		{"t", "*runtime.maptype", regnum.AMD64_Rax, false},
+		{"h", "*runtime.hmap", regnum.AMD64_Rbx, false},
+		{"key", "unsafe.Pointer", regnum.AMD64_Rcx, false},
+		{"~r3", "unsafe.Pointer", regnum.AMD64_Rax, true},
+	})
+}
+
+// fakeArg describes an argument of a runtime function passed in a register.
+type fakeArg struct {
+	name  string
+	typ   string
+	reg   int
+	isret bool
+}
+
+// regabiFakeArgs returns DIEs describing args.
+func regabiFakeArgs(bi *BinaryInfo, args []fakeArg) ([]*godwarf.Tree, error) {
 	var err1 error
 
 	t := func(name string) godwarf.Type {
@@ -1181,11 +1317,9 @@ func regabiMallocgcWorkaround(bi *BinaryInfo) ([]*godwarf.Tree, error) {
 		}
 	}
 
-	r := []*godwarf.Tree{
-		m("size", t("uintptr"), regnum.AMD64_Rax, false),
-		m("typ", t("*runtime._type"), regnum.AMD64_Rbx, false),
-		m("needzero", t("bool"), regnum.AMD64_Rcx, false),
-		m("~r1", t("unsafe.Pointer"), regnum.AMD64_Rax, true),
+	r := make([]*godwarf.Tree, 0, len(args))
+	for _, arg := range args {
+		r = append(r, m(arg.name, t(arg.typ), arg.reg, arg.isret))
 	}
 
 	return r, err1
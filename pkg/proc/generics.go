@@ -0,0 +1,408 @@
+package proc
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
+	"github.com/hitzhangjie/dlv/pkg/log"
+)
+
+// Generic functions are compiled once for each "shape" of their type
+// arguments: all type arguments with the same underlying type share the
+// same shape and all pointer types share a single shape. The
+// instantiation that is actually executing is described by a dictionary,
+// passed to the shape function as an extra argument named ".dict", whose
+// first entries are the runtime._type of each type argument.
+//
+// The names of the type parameters are not recorded in the debug
+// information, we read them from the declaration of the function in its
+// source file.
+//
+// Names of instantiations have the type arguments, separated by commas,
+// between square brackets after the name of the function or after the name
+// of the receiver type, for example:
+//
+//	main.testfn[int,float32]
+//	main.(*Set[string]).Add
+//
+// The same names, with the type arguments replaced by their shapes, are
+// used for the shape functions. The dictionary for an instantiation is
+// stored in the symbol table under the name of the function, or receiver
+// type, with "..dict." after the package name:
+//
+//	main..dict.testfn[int,float32]
+//	main..dict.Set[string]
+
+const goDictionarySymbolInfix = "..dict."
+
+// splitInstName splits the name of an instantiation of a generic function
+// into the name of the generic function and its type arguments.
+func splitInstName(name string) (gname string, targs []string, ok bool) {
+	start := strings.Index(name, "[")
+	if start < 0 {
+		return name, nil, false
+	}
+	depth := 0
+	for i := start; i < len(name); i++ {
+		switch name[i] {
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				return name[:start] + name[i+1:], splitTypeArgs(name[start+1 : i]), true
+			}
+		}
+	}
+	return name, nil, false
+}
+
+// splitTypeArgs splits a comma separated list of type arguments.
+func splitTypeArgs(s string) []string {
+	var r []string
+	depth, start := 0, 0
+	for i, ch := range s {
+		switch ch {
+		case '[', '(', '{':
+			depth++
+		case ']', ')', '}':
+			depth--
+		case ',':
+			if depth == 0 {
+				r = append(r, strings.TrimSpace(s[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(r, strings.TrimSpace(s[start:]))
+}
+
+// dictionaryName returns the name of the symbol of the dictionary used by
+// the instantiation of gname with type arguments targs.
+func dictionaryName(gname string, targs []string) string {
+	pkg := packageName(gname)
+	name := strings.TrimPrefix(gname[len(pkg)+1:], "(*")
+	if i := strings.IndexAny(name, ")."); i >= 0 {
+		// method of a generic type, the dictionary belongs to the receiver type
+		name = name[:i]
+	}
+	return pkg + goDictionarySymbolInfix + name + "[" + strings.Join(targs, ",") + "]"
+}
+
+// findInstantiation returns the shape functions that can execute the
+// instantiation of a generic function called name. Type arguments must be
+// specified using the names of their DWARF types.
+func (bi *BinaryInfo) findInstantiation(name string) ([]*Function, error) {
+	gname, targs, _ := splitInstName(name)
+	if _, ok := bi.dictionaries[dictionaryName(gname, targs)]; !ok {
+		// not instantiated by the program
+		return nil, &ErrFunctionNotFound{name}
+	}
+	types := make([]godwarf.Type, len(targs))
+	for i := range targs {
+		typ, err := bi.findTypeArg(targs[i])
+		if err != nil {
+			return nil, &ErrFunctionNotFound{name}
+		}
+		types[i] = typ
+	}
+
+	var r []*Function
+	for _, fn := range bi.LookupGenericFunc()[gname] {
+		_, shapes, _ := splitInstName(fn.Name)
+		if len(shapes) != len(types) {
+			continue
+		}
+		match := true
+		for i := range shapes {
+			shape, err := bi.findType(shapes[i])
+			if err != nil || !shapeMatches(shape, types[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			r = append(r, fn)
+		}
+	}
+	if len(r) == 0 {
+		return nil, &ErrFunctionNotFound{name}
+	}
+	return r, nil
+}
+
+// findTypeArg is like findType but also accepts pointers to types that
+// are never used through a pointer in the target program.
+func (bi *BinaryInfo) findTypeArg(name string) (godwarf.Type, error) {
+	typ, err := bi.findType(name)
+	if err != nil && strings.HasPrefix(name, "*") {
+		typ, err = bi.findTypeArg(name[1:])
+		if err == nil {
+			typ = pointerTo(typ, bi.Arch)
+		}
+	}
+	return typ, err
+}
+
+// shapeMatches returns true if typ could have the shape described by shape.
+func shapeMatches(shape, typ godwarf.Type) bool {
+	shape, typ = resolveTypedef(shape), resolveTypedef(typ)
+	switch s := shape.(type) {
+	case *godwarf.PtrType:
+		_, isptr := typ.(*godwarf.PtrType)
+		return isptr
+	case *godwarf.InterfaceType:
+		_, isiface := typ.(*godwarf.InterfaceType)
+		return isiface
+	case *godwarf.StructType:
+		t, isstruct := typ.(*godwarf.StructType)
+		if !isstruct || s.Kind != t.Kind || len(s.Field) != len(t.Field) {
+			return false
+		}
+		for i := range s.Field {
+			// field names in shape types are qualified by their package
+			sname := s.Field[i].Name[strings.LastIndex(s.Field[i].Name, ".")+1:]
+			if sname != t.Field[i].Name || s.Field[i].Type.String() != t.Field[i].Type.String() {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.TypeOf(shape) == reflect.TypeOf(typ) && strings.TrimPrefix(shape.Common().Name, "go.shape.") == typ.String()
+}
+
+// InstantiationName returns the name of the instantiation of the generic
+// function gname with the type arguments targs, a comma separated list of
+// type expressions evaluated in scope. The name returned can be passed to
+// FindFunction.
+func (scope *EvalScope) InstantiationName(gname, targs string) (string, error) {
+	fns := scope.BinInfo.LookupGenericFunc()[gname]
+	if len(fns) == 0 {
+		return "", &ErrFunctionNotFound{gname}
+	}
+	names, err := scope.typeArgNames(splitTypeArgs(targs))
+	if err != nil {
+		return "", err
+	}
+	inst := fns[0].instRange()
+	return fns[0].Name[:inst[0]] + "[" + strings.Join(names, ",") + "]" + fns[0].Name[inst[1]+1:], nil
+}
+
+// typeArgNames resolves a list of type expressions to the names of their
+// DWARF types.
+func (scope *EvalScope) typeArgNames(targs []string) ([]string, error) {
+	r := make([]string, len(targs))
+	for i := range targs {
+		expr, err := parser.ParseExpr(targs[i])
+		if err != nil {
+			return nil, fmt.Errorf("could not parse type argument %q: %v", targs[i], err)
+		}
+		typ, err := scope.findTypeExpr(expr)
+		if err != nil {
+			return nil, fmt.Errorf("could not find type argument %q: %v", targs[i], err)
+		}
+		r[i] = typ.Common().Name
+		if r[i] == "" {
+			r[i] = typ.String()
+		}
+	}
+	return r, nil
+}
+
+// qualifiedName returns the fully qualified name of the function or type
+// name, which can be either unqualified, in which case it is looked up in
+// the package of the current function, or qualified by a package name.
+func (scope *EvalScope) qualifiedName(name string) string {
+	dot := strings.Index(name, ".")
+	if dot < 0 {
+		if scope.Fn == nil {
+			return name
+		}
+		return scope.Fn.PackageName() + "." + name
+	}
+	if pkgs := scope.BinInfo.PackageMap[name[:dot]]; len(pkgs) > 0 {
+		return pkgs[0] + name[dot:]
+	}
+	return name
+}
+
+// evalInstantiation evaluates node as the instantiation of a generic
+// function (for example testfn[int, float32]). Returns nil, nil if node
+// does not name a generic function.
+func (scope *EvalScope) evalInstantiation(node ast.Expr) (*Variable, error) {
+	gname, targs, ok := splitInstName(exprToString(node))
+	if !ok {
+		return nil, nil
+	}
+	gname = scope.qualifiedName(gname)
+	if len(scope.BinInfo.LookupGenericFunc()[gname]) == 0 {
+		return nil, nil
+	}
+	name, err := scope.InstantiationName(gname, strings.Join(targs, ","))
+	if err != nil {
+		return nil, err
+	}
+	return genericFunctionToVariable(name, scope.BinInfo, scope.Mem)
+}
+
+// genericFunctionToVariable returns a function variable for the
+// instantiation of a generic function called name, that can be used to call it.
+func genericFunctionToVariable(name string, bi *BinaryInfo, mem MemoryReadWriter) (*Variable, error) {
+	fns, err := bi.findInstantiation(name)
+	if err != nil {
+		return nil, err
+	}
+	gname, targs, _ := splitInstName(name)
+	v, err := functionToVariable(fns[0], bi, mem)
+	if err != nil {
+		return nil, err
+	}
+	v.dictAddr = bi.dictionaries[dictionaryName(gname, targs)]
+	return v, nil
+}
+
+// typeParams returns the type parameters of the current function as
+// variables with the VariableTypeParameter flag set, whose type is the
+// type argument of the instantiation being executed.
+func (scope *EvalScope) typeParams() []*Variable {
+	if scope.Fn == nil {
+		return nil
+	}
+	_, shapes, ok := splitInstName(scope.Fn.Name)
+	if !ok {
+		return nil
+	}
+	names := typeParamNames(scope.Fn)
+	if len(names) != len(shapes) {
+		return nil
+	}
+	if scope.dictAddr == 0 {
+		// the dictionary is loaded by Locals
+		if _, err := scope.Locals(0); err != nil || scope.dictAddr == 0 {
+			return nil
+		}
+	}
+	vars := make([]*Variable, 0, len(names))
+	for i, name := range names {
+		typ, err := dictionaryType(scope.BinInfo, scope.Mem, scope.dictAddr, int64(i))
+		if err != nil {
+			log.Error("could not resolve type parameter %s: %v", name, err)
+			return nil
+		}
+		v := newVariable(name, 0, typ, scope.BinInfo, scope.Mem)
+		v.Flags |= VariableTypeParameter
+		v.loaded = true
+		vars = append(vars, v)
+	}
+	return vars
+}
+
+// typeParam returns the type parameter of the current function called name.
+func (scope *EvalScope) typeParam(name string) *Variable {
+	for _, v := range scope.typeParams() {
+		if v.Name == name {
+			return v
+		}
+	}
+	return nil
+}
+
+// typeParamNames returns the names of the type parameters of fn, reading
+// them from its declaration in the source file.
+func typeParamNames(fn *Function) []string {
+	if fn.cu == nil || fn.cu.lineInfo == nil {
+		return nil
+	}
+	filename, lineno := fn.cu.lineInfo.PCToLine(fn.Entry, fn.Entry)
+	src, err := os.ReadFile(filename)
+	if err != nil {
+		return nil
+	}
+	for i := 1; i < lineno; i++ {
+		nl := bytes.IndexByte(src, '\n')
+		if nl < 0 {
+			return nil
+		}
+		src = src[nl+1:]
+	}
+	return scanTypeParamNames(src)
+}
+
+// scanTypeParamNames returns the names of the type parameters of the
+// function or method declared at the start of src.
+func scanTypeParamNames(src []byte) []string {
+	var s scanner.Scanner
+	s.Init(token.NewFileSet().AddFile("", -1, len(src)), src, nil, 0)
+	next := func() (token.Token, string) {
+		_, tok, lit := s.Scan()
+		return tok, lit
+	}
+
+	if tok, _ := next(); tok != token.FUNC {
+		return nil
+	}
+
+	var names []string
+	tok, _ := next()
+	switch tok {
+	case token.LPAREN:
+		// method of a generic type: the type parameters are the type arguments
+		// of the receiver, func (s *Set[T]) Add(v T)
+		for depth := 1; depth > 0; {
+			tok, lit := next()
+			switch tok {
+			case token.LPAREN, token.LBRACK:
+				depth++
+			case token.RPAREN, token.RBRACK:
+				depth--
+			case token.IDENT:
+				if depth == 2 {
+					names = append(names, lit)
+				}
+			case token.EOF:
+				return nil
+			}
+		}
+		if tok, _ := next(); tok != token.IDENT {
+			return nil
+		}
+		if tok, _ := next(); tok != token.LPAREN {
+			return nil
+		}
+
+	case token.IDENT:
+		// generic function: func testfn[T any, K comparable](arg1 T, arg2 K)
+		if tok, _ := next(); tok != token.LBRACK {
+			return nil
+		}
+		prev := token.LBRACK
+		for depth := 1; depth > 0; {
+			tok, lit := next()
+			switch tok {
+			case token.LPAREN, token.LBRACK, token.LBRACE:
+				depth++
+			case token.RPAREN, token.RBRACK, token.RBRACE:
+				depth--
+			case token.IDENT:
+				if depth == 1 && (prev == token.LBRACK || prev == token.COMMA) {
+					names = append(names, lit)
+				}
+			case token.EOF:
+				return nil
+			}
+			prev = tok
+		}
+
+	default:
+		return nil
+	}
+	return names
+}
@@ -51,8 +51,10 @@ type ProcessInternal interface {
 	EraseBreakpoint(*Breakpoint) error
 
 	SupportsBPF() bool
-	SetUProbe(string, int64, []ebpf.UProbeArgMap) error
+	SetUProbe(string, int64, []ebpf.UProbeArgMap, ebpf.UProbeLoadConfig) error
+	SetInlinedUProbe(string, uint64, int64, []ebpf.UProbeArgMap, ebpf.UProbeLoadConfig) error
 	GetBufferedTracepoints() []ebpf.RawUProbeParams
+	GetLatencyHistograms() map[uint64][]uint64
 
 	// DumpProcessNotes returns ELF core notes describing the process and its threads.
 	// Implementing this method is optional.
@@ -64,6 +66,89 @@ type ProcessInternal interface {
 	StartCallInjection() (func(), error)
 }
 
+// RecordingManipulation is an interface for manipulating process recordings
+// and checkpoints.
+type RecordingManipulation interface {
+	// Recorded returns true if the current process is a recording and the path
+	// to the trace directory.
+	Recorded() (recorded bool, tracedir string)
+	// ChangeDirection changes execution direction.
+	ChangeDirection(Direction) error
+	// GetDirection returns the current direction of execution.
+	GetDirection() Direction
+	// When returns current recording position.
+	When() (string, error)
+	// Checkpoint sets a checkpoint at the current position.
+	Checkpoint(where string) (id int, err error)
+	// Checkpoints returns the list of currently set checkpoint.
+	Checkpoints() ([]Checkpoint, error)
+	// ClearCheckpoint removes a checkpoint.
+	ClearCheckpoint(id int) error
+}
+
+// RecordingManipulationInternal is an interface that a Delve backend can
+// implement if it is able to go back to a previous position of the target,
+// either because it is a recording or because it supports checkpoints.
+type RecordingManipulationInternal interface {
+	RecordingManipulation
+	// Restart restarts the target from the specified position.
+	// If pos starts with 'c' it's a checkpoint ID, otherwise it's a position
+	// in the format returned by When.
+	// Returns the new current thread after the restart has completed.
+	Restart(pos string) (Thread, error)
+}
+
+// Follower is implemented by backends that can follow the processes
+// created by the target, see TargetGroup.
+type Follower interface {
+	SetFollowMode(FollowMode) error
+}
+
+// NonStopper is implemented by backends that can stop only the thread that
+// caused a stop, leaving the other threads running, see Target.SetNonStop.
+type NonStopper interface {
+	SetNonStop(enabled bool) error
+	// SetContinueMode changes which threads the following calls to
+	// ContinueOnce resume.
+	SetContinueMode(ContinueMode)
+	// StopThreads stops the threads that are running.
+	StopThreads() error
+}
+
+// SignalHandler is implemented by backends that can intercept the signals
+// received by the target, see Target.SetSignalPolicy. When a signal whose
+// policy has Stop set is received ContinueOnce returns StopSignal and the
+// signal is described by the Signal field of the thread.
+type SignalHandler interface {
+	SetSignalPolicy(sig int, policy SignalPolicy)
+}
+
+// SyscallCatcher is implemented by backends that can stop the target when
+// it enters or returns from a system call, see Target.SetSyscallCatchpoints.
+// When that happens ContinueOnce returns StopSyscall and the system call is
+// described by the Syscall field of the thread.
+type SyscallCatcher interface {
+	SetSyscallCatchpoints(all bool, nrs []int) error
+}
+
+// ExitStopper is implemented by backends that can stop the target just
+// before it exits, see Target.SetStopOnExit. When that happens ContinueOnce
+// returns StopExiting and the exit is described by the Exit field of the
+// thread.
+type ExitStopper interface {
+	SetStopOnExit(enabled bool) error
+}
+
+// Recorder is an interface that a Delve backend can implement if it is
+// able to record the execution of a thread on demand. While a thread is
+// being recorded the target is a recording and can be executed backwards.
+type Recorder interface {
+	// StartRecording starts recording the instructions executed by thread tid.
+	StartRecording(tid int) error
+	// StopRecording stops recording.
+	StopRecording() error
+}
+
 // Direction is the direction of execution for the target process.
 type Direction int8
 
@@ -1,30 +1,105 @@
 #include <stdbool.h>
 
+// Maximum number of bytes read when dereferencing a string or a slice.
+#define MAX_DEREF_SIZE 0x200
+// Maximum number of pointers followed by a single capture.
+#define MAX_DEREF_CHAIN 4
+// Maximum number of captures for a single function.
+#define MAX_CAPTURES 8
+// Maximum number of filters for a single function.
+#define MAX_FILTERS 4
+// Number of log2 buckets of a latency histogram.
+#define MAX_LATENCY_SLOTS 64
+// Maximum recursion depth, per goroutine, at which latency is measured.
+#define MAX_LATENCY_DEPTH 16
+
+// Comparison operators used by filters.
+#define FILTER_EQ 0
+#define FILTER_NE 1
+#define FILTER_LT 2
+#define FILTER_LE 3
+#define FILTER_GT 4
+#define FILTER_GE 5
+
 // function_parameter stores information about a single parameter to a function.
 typedef struct function_parameter {
       // Type of the parameter as defined by the reflect.Kind enum.
-      unsigned int kind; 
+      unsigned int kind;
       // Size of the variable in bytes.
-      unsigned int size; 
+      unsigned int size;
 
       // Offset from stack pointer. This should only be set from the Go side.
       int offset;
 
       // If true, the parameter is passed in a register.
-      bool in_reg;      
+      bool in_reg;
       // The number of register pieces the parameter is passed in.
       int n_pieces;
       // If in_reg is true, this represents the registers that the parameter is passed in.
       // This is an array because the number of registers may vary and the parameter may be
       // passed in multiple registers.
-      int reg_nums[6]; 
+      int reg_nums[6];
+
+      // Maximum number of bytes (for strings) or elements (for slices) to
+      // read when dereferencing the parameter.
+      unsigned int max_len;
+      // Size of a single element, only used for slices.
+      unsigned int elem_size;
 
       // The following are filled in by the eBPF program.
       size_t daddr;   // Data address.
-      char val[0x30];       // Value of the parameter.
-      char deref_val[0x30]; // Dereference value of the parameter.
+      unsigned int deref_len;         // Number of bytes read into deref_val.
+      char val[0x30];                 // Value of the parameter.
+      char deref_val[MAX_DEREF_SIZE]; // Dereference value of the parameter.
 } function_parameter_t;
 
+// function_chain describes how to reach a value from one of the parameters
+// through a chain of pointer dereferences, for example req.Header.Host.
+typedef struct function_chain {
+      // Index of the parameter the chain starts from, in params or, if
+      // ret is set, in ret_params.
+      unsigned int param_idx;
+      bool ret;
+
+      // Number of pointers to follow.
+      int n_derefs;
+      // derefs[i] is the offset of the i-th pointer from the address
+      // reached so far, derefs[0] is relative to the parameter itself.
+      long long derefs[MAX_DEREF_CHAIN];
+      // Offset of the value from the last pointer followed.
+      long long offset;
+} function_chain_t;
+
+// function_capture describes a value, reached through a chain of pointers,
+// that is read in addition to the parameters.
+typedef struct function_capture {
+      function_chain_t chain;
+
+      // The following have the same meaning they have in function_parameter.
+      unsigned int kind;
+      unsigned int size;
+      unsigned int max_len;
+      unsigned int elem_size;
+
+      // The following are filled in by the eBPF program.
+      size_t daddr;
+      unsigned int deref_len;
+      bool ok;                        // True if the whole chain could be followed.
+      char val[0x30];
+      char deref_val[MAX_DEREF_SIZE];
+} function_capture_t;
+
+// function_filter describes a comparison between an integer, reached through
+// a chain of pointers, and a constant. Function calls for which any filter
+// is false are not reported.
+typedef struct function_filter {
+      function_chain_t chain;
+      unsigned int size; // Size of the integer, at most 8 bytes.
+      bool is_signed;
+      unsigned int op;   // One of the FILTER_ operators.
+      long long value;   // Constant the integer is compared to.
+} function_filter_t;
+
 // function_parameter_list holds info about the function parameters and
 // stores information on up to 6 parameters.
 typedef struct function_parameter_list {
@@ -32,12 +107,21 @@ typedef struct function_parameter_list {
       long long g_addr_offset;  // Offset of the Goroutine struct from the TLS segment.
       int goroutine_id;
 
-      unsigned int fn_addr;
+      unsigned long long fn_addr;
+      unsigned long long fn_entry; // Entry point of the function fn_addr belongs to.
       bool is_ret;
+      bool latency; // If true calls are measured instead of being reported.
+      bool inlined; // If true fn_addr is the start of an inlined call, which has no return probe.
 
       unsigned int n_parameters;          // number of parameters.
       function_parameter_t params[6];     // list of parameters.
 
       unsigned int n_ret_parameters;      // number of return parameters.
       function_parameter_t ret_params[6]; // list of return parameters.
+
+      unsigned int n_captures;                  // number of captures.
+      function_capture_t captures[MAX_CAPTURES]; // list of captures.
+
+      unsigned int n_filters;                // number of filters.
+      function_filter_t filters[MAX_FILTERS]; // list of filters, evaluated at function entry.
 } function_parameter_list_t;
@@ -23,3 +23,56 @@ struct {
     __type(key, u64);
     __type(value, function_parameter_list_t);
 } arg_map SEC(".maps");
+
+// Identifies the calls of a function made by a goroutine.
+struct call_key {
+    u64 goroutine_id;
+    u64 fn_entry;
+};
+
+// Value of filter_map. Bit i of filtered is set if the call at depth i
+// was discarded by a filter, so that its return is discarded too.
+struct filter_state {
+    u64 filtered;
+    u32 depth;
+};
+
+// Map which remembers, for each goroutine and function, which of the
+// active calls were discarded by a filter.
+struct {
+    __uint(max_entries, 4096);
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, struct call_key);
+    __type(value, struct filter_state);
+} filter_map SEC(".maps");
+
+// Value of latency_start, start times of the active calls of a function
+// made by a goroutine, indexed by recursion depth.
+struct latency_start {
+    u64 ts[MAX_LATENCY_DEPTH];
+    u32 depth;
+};
+
+// Map which remembers when the active calls of each function started.
+struct {
+    __uint(max_entries, 4096);
+    __uint(type, BPF_MAP_TYPE_LRU_HASH);
+    __type(key, struct call_key);
+    __type(value, struct latency_start);
+} latency_start SEC(".maps");
+
+// Key of latency_hist, identifies a slot of the histogram of a function.
+// The slot counts the calls that took between 2^slot and 2^(slot+1)-1
+// nanoseconds.
+struct latency_key {
+    u64 fn_entry;
+    u64 slot;
+};
+
+// Map which holds the latency histograms of all functions.
+struct {
+    __uint(max_entries, 42 * MAX_LATENCY_SLOTS);
+    __uint(type, BPF_MAP_TYPE_HASH);
+    __type(key, struct latency_key);
+    __type(value, u64);
+} latency_hist SEC(".maps");
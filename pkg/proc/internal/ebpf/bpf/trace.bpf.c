@@ -1,30 +1,41 @@
 #include "include/trace.bpf.h"
 #include <string.h>
 
+#define SLICE_KIND 23
 #define STRING_KIND 24
 
-// parse_string_param will parse a string parameter. The parsed value of the string
-// will be put into param->deref_val. This function expects the string struct
-// which contains a pointer to the string and the length of the string to have
-// already been read from memory and passed in as param->val.
+// parse_deref_val will read the contents of a string or a slice into
+// deref_val. This function expects the string or slice header, which starts
+// with a pointer to the data followed by its length, to have already been
+// read from memory into val. At most max_len bytes of a string and max_len
+// elements of a slice are read, and never more than MAX_DEREF_SIZE bytes.
 __always_inline
-int parse_string_param(struct pt_regs *ctx, function_parameter_t *param) {
-    u64 str_len;
-    size_t str_addr;
+int parse_deref_val(char *val, unsigned int kind, unsigned int max_len, unsigned int elem_size,
+                    size_t *daddr, unsigned int *deref_len, char *deref_val) {
+    u64 len;
+    size_t addr;
 
-    memcpy(&str_addr, param->val, sizeof(str_addr));
-    memcpy(&str_len, param->val + sizeof(str_addr), sizeof(str_len));
-    param->daddr = str_addr;
+    memcpy(&addr, val, sizeof(addr));
+    memcpy(&len, val + sizeof(addr), sizeof(len));
+    *daddr = addr;
 
-    if (str_addr != 0) {
-        if (str_len > 0x30) {
-            str_len = 0x30;
-        }
-        int ret = bpf_probe_read_user(&param->deref_val, str_len, (void *)(str_addr));
-        if (ret < 0) {
-            return 1;
-        }
+    if (addr == 0) {
+        return 0;
+    }
+    if (len > max_len) {
+        len = max_len;
     }
+    if (kind == SLICE_KIND) {
+        len *= elem_size;
+    }
+    if (len > MAX_DEREF_SIZE) {
+        len = MAX_DEREF_SIZE;
+    }
+    long ret = bpf_probe_read_user(deref_val, len, (void *)(addr));
+    if (ret < 0) {
+        return 1;
+    }
+    *deref_len = len;
     return 0;
 }
 
@@ -39,58 +50,68 @@ int parse_param_stack(struct pt_regs *ctx, function_parameter_t *param) {
     return 0;
 }
 
+// load_ctx_field reads a field of ctx. The load is written in assembly so
+// that the compiler can not merge the loads of different fields into a
+// single load at a computed offset, which the verifier rejects.
+#define load_ctx_field(dst, ctx, field) \
+    asm volatile("%0 = *(u64 *)(%1 + %2)" : "=r"(dst) : "r"(ctx), "i"(__builtin_offsetof(struct pt_regs, field)))
+
 __always_inline
 void get_value_from_register(struct pt_regs *ctx, void *dest, int reg_num) {
+    u64 val;
     switch (reg_num) {
         case 0: // RAX
-            memcpy(dest, &ctx->ax, sizeof(ctx->ax));
+            load_ctx_field(val, ctx, ax);
             break;
         case 1: // RDX
-            memcpy(dest, &ctx->dx, sizeof(ctx->dx));
+            load_ctx_field(val, ctx, dx);
             break;
         case 2: // RCX
-            memcpy(dest, &ctx->cx, sizeof(ctx->cx));
+            load_ctx_field(val, ctx, cx);
             break;
         case 3: // RBX
-            memcpy(dest, &ctx->bx, sizeof(ctx->bx));
+            load_ctx_field(val, ctx, bx);
             break;
         case 4: // RSI
-            memcpy(dest, &ctx->si, sizeof(ctx->si));
+            load_ctx_field(val, ctx, si);
             break;
         case 5: // RDI
-            memcpy(dest, &ctx->di, sizeof(ctx->di));
+            load_ctx_field(val, ctx, di);
             break;
         case 6: // RBP
-            memcpy(dest, &ctx->bp, sizeof(ctx->bp));
+            load_ctx_field(val, ctx, bp);
             break;
         case 7: // RSP
-            memcpy(dest, &ctx->sp, sizeof(ctx->sp));
+            load_ctx_field(val, ctx, sp);
             break;
         case 8: // R8
-            memcpy(dest, &ctx->r8, sizeof(ctx->r8));
+            load_ctx_field(val, ctx, r8);
             break;
         case 9: // R9
-            memcpy(dest, &ctx->r9, sizeof(ctx->r9));
+            load_ctx_field(val, ctx, r9);
             break;
         case 10: // R10
-            memcpy(dest, &ctx->r10, sizeof(ctx->r10));
+            load_ctx_field(val, ctx, r10);
             break;
         case 11: // R11
-            memcpy(dest, &ctx->r11, sizeof(ctx->r11));
+            load_ctx_field(val, ctx, r11);
             break;
         case 12: // R12
-            memcpy(dest, &ctx->r12, sizeof(ctx->r12));
+            load_ctx_field(val, ctx, r12);
             break;
         case 13: // R13
-            memcpy(dest, &ctx->r13, sizeof(ctx->r13));
+            load_ctx_field(val, ctx, r13);
             break;
         case 14: // R14
-            memcpy(dest, &ctx->r14, sizeof(ctx->r14));
+            load_ctx_field(val, ctx, r14);
             break;
         case 15: // R15
-            memcpy(dest, &ctx->r15, sizeof(ctx->r15));
+            load_ctx_field(val, ctx, r15);
             break;
+        default:
+            return;
     }
+    memcpy(dest, &val, sizeof(val));
 }
 
 __always_inline
@@ -114,7 +135,9 @@ int parse_param_registers(struct pt_regs *ctx, function_parameter_t *param) {
 
 __always_inline
 int parse_param(struct pt_regs *ctx, function_parameter_t *param) {
-    if (param->size > 0x30) {
+    // Parameters that do not fit into val are not read, their fields can
+    // still be reached through captures.
+    if (param->size > sizeof(param->val)) {
         return 0;
     }
 
@@ -134,12 +157,221 @@ int parse_param(struct pt_regs *ctx, function_parameter_t *param) {
 
     switch (param->kind) {
         case STRING_KIND:
-            return parse_string_param(ctx, param);
+        case SLICE_KIND:
+            return parse_deref_val(param->val, param->kind, param->max_len, param->elem_size,
+                                   &param->daddr, &param->deref_len, param->deref_val);
+    }
+
+    return 0;
+}
+
+// read_param_mem reads size bytes at offset off from the start of a parameter.
+// Parameters passed in registers have already been copied into param->val by
+// parse_param, the Go side translates offsets to match that layout.
+__always_inline
+long read_param_mem(struct pt_regs *ctx, function_parameter_t *param, long long off, void *dest, u64 size) {
+    if (param->in_reg) {
+        // Bound off and size separately, the verifier does not learn
+        // anything about either of them from a check on their sum.
+        u64 uoff = off;
+        if (uoff >= sizeof(param->val) || size > sizeof(param->val) - uoff) {
+            return -1;
+        }
+        return bpf_probe_read_kernel(dest, size, param->val + uoff);
+    }
+    return bpf_probe_read_user(dest, size, (void *)(ctx->sp + param->offset + off));
+}
+
+// read_chain follows the chain of pointers described by chain, starting from
+// one of the parameters, and reads size bytes from the address found at the
+// end of it into dest, which can hold dest_size bytes.
+__always_inline
+int read_chain(struct pt_regs *ctx, function_parameter_list_t *args, function_chain_t *chain, void *dest, u64 size, u64 dest_size) {
+    function_parameter_t *param;
+    unsigned int idx = chain->param_idx;
+    if (idx >= 6) {
+        return 1;
+    }
+    if (chain->ret) {
+        param = &args->ret_params[idx];
+    } else {
+        param = &args->params[idx];
+    }
+
+    long ret;
+    size_t addr = 0;
+    #pragma unroll
+    for (int i = 0; i < MAX_DEREF_CHAIN; i++) {
+        if (i >= chain->n_derefs) {
+            break;
+        }
+        if (i == 0) {
+            ret = read_param_mem(ctx, param, chain->derefs[0], &addr, sizeof(addr));
+        } else {
+            ret = bpf_probe_read_user(&addr, sizeof(addr), (void *)(addr + chain->derefs[i]));
+        }
+        if (ret < 0 || addr == 0) {
+            return 1;
+        }
+    }
+
+    // Check size again right before it is used: the compiler merges
+    // range checks done by the callers in ways the verifier can not
+    // follow, the empty asm statement stops it from dropping this one.
+    asm volatile("" : "+r"(size));
+    if (size > dest_size) {
+        return 1;
+    }
+    if (chain->n_derefs == 0) {
+        ret = read_param_mem(ctx, param, chain->offset, dest, size);
+    } else {
+        ret = bpf_probe_read_user(dest, size, (void *)(addr + chain->offset));
+    }
+    if (ret < 0) {
+        return 1;
+    }
+    return 0;
+}
+
+// parse_capture reads the value at the end of the chain of pointers
+// described by capture.
+__always_inline
+int parse_capture(struct pt_regs *ctx, function_parameter_list_t *args, function_capture_t *capture) {
+    u64 size = capture->size;
+    if (size > sizeof(capture->val)) {
+        return 1;
+    }
+    if (read_chain(ctx, args, &capture->chain, capture->val, size, sizeof(capture->val)) != 0) {
+        return 1;
+    }
+    capture->ok = true;
+
+    switch (capture->kind) {
+        case STRING_KIND:
+        case SLICE_KIND:
+            return parse_deref_val(capture->val, capture->kind, capture->max_len, capture->elem_size,
+                                   &capture->daddr, &capture->deref_len, capture->deref_val);
     }
 
     return 0;
 }
 
+// eval_filter returns true if the integer described by filter satisfies the
+// comparison. Values that can not be read never satisfy it.
+__always_inline
+bool eval_filter(struct pt_regs *ctx, function_parameter_list_t *args, function_filter_t *filter) {
+    u64 val = 0;
+    u64 size = filter->size;
+    if (size == 0 || size > sizeof(val)) {
+        return false;
+    }
+    if (read_chain(ctx, args, &filter->chain, &val, size, sizeof(val)) != 0) {
+        return false;
+    }
+
+    int cmp;
+    if (filter->is_signed) {
+        s64 sval;
+        switch (size) {
+            case 1:
+                sval = (s8)val;
+                break;
+            case 2:
+                sval = (s16)val;
+                break;
+            case 4:
+                sval = (s32)val;
+                break;
+            default:
+                sval = (s64)val;
+        }
+        cmp = sval < filter->value ? -1 : (sval > filter->value ? 1 : 0);
+    } else {
+        u64 uval = (u64)filter->value;
+        cmp = val < uval ? -1 : (val > uval ? 1 : 0);
+    }
+
+    switch (filter->op) {
+        case FILTER_EQ:
+            return cmp == 0;
+        case FILTER_NE:
+            return cmp != 0;
+        case FILTER_LT:
+            return cmp < 0;
+        case FILTER_LE:
+            return cmp <= 0;
+        case FILTER_GT:
+            return cmp > 0;
+        case FILTER_GE:
+            return cmp >= 0;
+    }
+    return false;
+}
+
+__always_inline
+bool eval_filters(struct pt_regs *ctx, function_parameter_list_t *args) {
+    // All filters must be satisfied, like parse_params we take advantage
+    // of the fact that switch cases fall through.
+    function_filter_t *filters = args->filters;
+    switch (args->n_filters) {
+        case 4:
+            if (!eval_filter(ctx, args, &filters[3])) return false;
+        case 3:
+            if (!eval_filter(ctx, args, &filters[2])) return false;
+        case 2:
+            if (!eval_filter(ctx, args, &filters[1])) return false;
+        case 1:
+            if (!eval_filter(ctx, args, &filters[0])) return false;
+    }
+    return true;
+}
+
+// filter_call records whether the call that is being entered was discarded
+// by the filters, and, at the function's return, whether the matching entry
+// was. Returns true if the event should be discarded.
+__always_inline
+bool filter_call(function_parameter_list_t *args, bool discard) {
+    struct call_key key = {};
+    key.goroutine_id = args->goroutine_id;
+    key.fn_entry = args->fn_entry;
+
+    struct filter_state zero = {};
+    struct filter_state *state = bpf_map_lookup_elem(&filter_map, &key);
+    if (!state) {
+        if (args->is_ret) {
+            return false;
+        }
+        bpf_map_update_elem(&filter_map, &key, &zero, BPF_ANY);
+        state = bpf_map_lookup_elem(&filter_map, &key);
+        if (!state) {
+            return discard;
+        }
+    }
+
+    u32 depth = state->depth;
+    if (!args->is_ret) {
+        if (depth < 64) {
+            if (discard) {
+                state->filtered |= (1ULL << depth);
+            } else {
+                state->filtered &= ~(1ULL << depth);
+            }
+        }
+        state->depth = depth + 1;
+        return discard;
+    }
+
+    if (depth == 0) {
+        return false;
+    }
+    depth--;
+    state->depth = depth;
+    if (depth < 64) {
+        return (state->filtered & (1ULL << depth)) != 0;
+    }
+    return false;
+}
+
 __always_inline
 int get_goroutine_id(function_parameter_list_t *parsed_args) {
     // Since eBPF programs have such strict stack requirements
@@ -196,6 +428,105 @@ void parse_params(struct pt_regs *ctx, unsigned int n_params, function_parameter
     }
 }
 
+__always_inline
+void parse_captures(struct pt_regs *ctx, function_parameter_list_t *args) {
+    // Captures are described once for both the entry and the return
+    // uprobes, only parse the ones whose parameter is available here.
+    unsigned int n = args->n_captures;
+    function_capture_t *captures = args->captures;
+    switch (n) {
+        case 8:
+            if (captures[7].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[7]);
+        case 7:
+            if (captures[6].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[6]);
+        case 6:
+            if (captures[5].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[5]);
+        case 5:
+            if (captures[4].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[4]);
+        case 4:
+            if (captures[3].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[3]);
+        case 3:
+            if (captures[2].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[2]);
+        case 2:
+            if (captures[1].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[1]);
+        case 1:
+            if (captures[0].chain.ret == args->is_ret) parse_capture(ctx, args, &captures[0]);
+    }
+}
+
+__always_inline
+unsigned int log2_u64(u64 v) {
+    unsigned int r, shift;
+
+    r = (v > 0xFFFFFFFF) << 5; v >>= r;
+    shift = (v > 0xFFFF) << 4; v >>= shift; r |= shift;
+    shift = (v > 0xFF) << 3; v >>= shift; r |= shift;
+    shift = (v > 0xF) << 2; v >>= shift; r |= shift;
+    shift = (v > 0x3) << 1; v >>= shift; r |= shift;
+    r |= (v >> 1);
+    return r;
+}
+
+// record_latency stores the start time of the call being entered or, at
+// the function's return, adds the duration of the call to the histogram of
+// the function.
+__always_inline
+void record_latency(function_parameter_list_t *args) {
+    u64 now = bpf_ktime_get_ns();
+
+    struct call_key key = {};
+    key.goroutine_id = args->goroutine_id;
+    key.fn_entry = args->fn_entry;
+
+    struct latency_start *start = bpf_map_lookup_elem(&latency_start, &key);
+    if (!start) {
+        if (args->is_ret) {
+            return;
+        }
+        struct latency_start zero = {};
+        bpf_map_update_elem(&latency_start, &key, &zero, BPF_ANY);
+        start = bpf_map_lookup_elem(&latency_start, &key);
+        if (!start) {
+            return;
+        }
+    }
+
+    u32 depth = start->depth;
+    if (!args->is_ret) {
+        if (depth < MAX_LATENCY_DEPTH) {
+            start->ts[depth] = now;
+        }
+        start->depth = depth + 1;
+        return;
+    }
+
+    if (depth == 0) {
+        return;
+    }
+    depth--;
+    start->depth = depth;
+    if (depth >= MAX_LATENCY_DEPTH) {
+        return;
+    }
+
+    struct latency_key hkey = {};
+    hkey.fn_entry = args->fn_entry;
+    hkey.slot = log2_u64(now - start->ts[depth]);
+    if (hkey.slot >= MAX_LATENCY_SLOTS) {
+        hkey.slot = MAX_LATENCY_SLOTS - 1;
+    }
+    u64 *count = bpf_map_lookup_elem(&latency_hist, &hkey);
+    if (!count) {
+        u64 zero = 0;
+        bpf_map_update_elem(&latency_hist, &hkey, &zero, BPF_NOEXIST);
+        count = bpf_map_lookup_elem(&latency_hist, &hkey);
+        if (!count) {
+            return;
+        }
+    }
+    __sync_fetch_and_add(count, 1);
+}
+
 SEC("uprobe/dlv_trace")
 int uprobe__dlv_trace(struct pt_regs *ctx) {
     function_parameter_list_t *args;
@@ -212,15 +543,14 @@ int uprobe__dlv_trace(struct pt_regs *ctx) {
         return 1;
     }
 
-    // Initialize the parsed_args struct.
-    parsed_args->goid_offset = args->goid_offset;
-    parsed_args->g_addr_offset = args->g_addr_offset;
-    parsed_args->goroutine_id = args->goroutine_id;
-    parsed_args->fn_addr = args->fn_addr;
-    parsed_args->n_parameters = args->n_parameters;
-    parsed_args->n_ret_parameters = args->n_ret_parameters;
-    memcpy(parsed_args->params, args->params, sizeof(args->params));
-    memcpy(parsed_args->ret_params, args->ret_params, sizeof(args->ret_params));
+    // Initialize the parsed_args struct. It is too large to be copied with
+    // memcpy, which the compiler must unroll into individual stores, copy it
+    // with a helper instead, both the map value and the ring buffer entry
+    // live in kernel memory.
+    if (bpf_probe_read_kernel(parsed_args, sizeof(function_parameter_list_t), args) < 0) {
+        bpf_ringbuf_discard(parsed_args, 0);
+        return 1;
+    }
 
     if (!get_goroutine_id(parsed_args)) {
         bpf_ringbuf_discard(parsed_args, 0);
@@ -232,13 +562,43 @@ int uprobe__dlv_trace(struct pt_regs *ctx) {
 
         // Parse input parameters.
         parse_params(ctx, args->n_parameters, parsed_args->params);
+
+        // Discard calls that do not satisfy the filters before captures
+        // are read, the return of the call will be discarded too.
+        // Inlined calls have no return to correlate the entry with.
+        if (args->n_filters > 0) {
+            bool discard = !eval_filters(ctx, parsed_args);
+            if (!args->inlined) {
+                discard = filter_call(parsed_args, discard);
+            }
+            if (discard) {
+                bpf_ringbuf_discard(parsed_args, 0);
+                return 0;
+            }
+        }
     } else {
+        if (args->n_filters > 0 && filter_call(parsed_args, false)) {
+            bpf_ringbuf_discard(parsed_args, 0);
+            return 0;
+        }
+
         // We are now stopped at the RET instruction for this function.
 
         // Parse output parameters.
         parse_params(ctx, args->n_ret_parameters, parsed_args->ret_params);
     }
 
+    // When measuring latency calls are only added to the histograms,
+    // userspace never sees them.
+    if (args->latency) {
+        record_latency(parsed_args);
+        bpf_ringbuf_discard(parsed_args, 0);
+        return 0;
+    }
+
+    // Follow pointers from the parameters parsed above.
+    parse_captures(ctx, parsed_args);
+
     bpf_ringbuf_submit(parsed_args, BPF_RB_FORCE_WAKEUP);
 
     return 0;
@@ -1,6 +1,7 @@
 package ebpf
 
 import (
+	"go/token"
 	"reflect"
 
 	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
@@ -14,21 +15,73 @@ type UProbeArgMap struct {
 	Pieces []int        // Pieces of the variables as stored in registers.
 	InReg  bool         // True if this param is contained in a register.
 	Ret    bool         // True if this param is a return value.
+
+	Name        string       // Name of the variable.
+	Type        godwarf.Type // Type of the variable, with typedefs resolved.
+	DwarfPieces []op.Piece   // Location of the variable as described by DWARF, if it is contained in registers.
+}
+
+// UProbeLoadConfig describes how much of the arguments of a function the
+// eBPF program reads. It plays the role proc.LoadConfig plays for
+// breakpoint based tracepoints.
+type UProbeLoadConfig struct {
+	// MaxStringLen is the maximum number of bytes read from a string.
+	MaxStringLen int
+	// MaxArrayValues is the maximum number of elements read from a slice.
+	MaxArrayValues int
+	// Captures lists values reached from the arguments by following pointers.
+	Captures []UProbeCapture
+	// Filters are evaluated at function entry, calls for which any of them
+	// is false are discarded by the eBPF program.
+	Filters []UProbeFilter
+	// Latency, if set, makes the eBPF program add the duration of each call
+	// to the latency histogram of the function instead of reporting it.
+	Latency bool
+	// Inlined is set for the uprobes at the start of inlined calls, which
+	// have no return probe.
+	Inlined bool
+}
+
+// UProbeCapture describes a value reached from an argument through a chain
+// of pointer dereferences, for example req.Header.Host.
+type UProbeCapture struct {
+	Name   string  // Expression describing the captured value.
+	Arg    int     // Index of the argument the chain starts from.
+	Derefs []int64 // Offsets of the pointers to follow, the first one is relative to the argument.
+	Offset int64   // Offset of the value from the last pointer followed.
+
+	Size int64        // Size in bytes.
+	Kind reflect.Kind // Kind of variable.
+	Type godwarf.Type // Type of the variable, with typedefs resolved.
+}
+
+// UProbeFilter compares an integer, reached from an argument like the value
+// of a capture, with a constant.
+type UProbeFilter struct {
+	Capture UProbeCapture // Only Arg, Derefs, Offset and Size are used.
+	Signed  bool          // True if the integer is signed.
+	Op      token.Token   // One of ==, !=, <, <=, >, >=.
+	Value   int64         // Constant the integer is compared with.
 }
 
 type RawUProbeParam struct {
+	Name     string
 	Pieces   []op.Piece
 	RealType godwarf.Type
 	Kind     reflect.Kind
 	Len      int64
+	Loaded   int64 // Number of bytes of a string, or elements of a slice, that were read.
 	Base     uint64
 	Addr     uint64
 	Data     []byte
+	Err      error // Set if the value could not be read.
 }
 
 type RawUProbeParams struct {
 	FnAddr       int
 	GoroutineID  int
+	Inlined      bool // True if FnAddr is the start of an inlined call.
 	InputParams  []*RawUProbeParam
 	ReturnParams []*RawUProbeParam
+	Captures     []*RawUProbeParam
 }
@@ -9,6 +9,8 @@ import (
 	"debug/elf"
 	"encoding/binary"
 	"errors"
+	"fmt"
+	"go/token"
 	"reflect"
 	"runtime"
 	"sync"
@@ -34,12 +36,27 @@ type EBPFContext struct {
 	bpfArgMap  *ebpf.Map
 
 	parsedBpfEvents []RawUProbeParams
+	probes          map[uint64]uprobeSpec
+	latency         map[uint64][]uint64 // latency histograms read before the maps were closed
 	m               sync.Mutex
 }
 
+// uprobeSpec remembers what was requested for a uprobe so that the
+// events it generates can be decoded.
+type uprobeSpec struct {
+	args []UProbeArgMap
+	cfg  UProbeLoadConfig
+}
+
 func (ctx *EBPFContext) Close() {
+	ctx.m.Lock()
+	defer ctx.m.Unlock()
 	if ctx.objs != nil {
+		// Keep the latency histograms around, they are still interesting
+		// after the target process has exited.
+		ctx.latency = ctx.readLatencyHistograms()
 		ctx.objs.Close()
+		ctx.objs = nil
 	}
 }
 
@@ -51,13 +68,22 @@ func (ctx *EBPFContext) AttachUprobe(pid int, name string, offset uint64) error
 	return err
 }
 
-func (ctx *EBPFContext) UpdateArgMap(key uint64, goidOffset int64, args []UProbeArgMap, gAddrOffset uint64, isret bool) error {
+// UpdateArgMap describes to the eBPF program the arguments of the function,
+// starting at fnEntry, instrumented by the uprobe at key and how much of
+// them, as specified by cfg, should be read.
+func (ctx *EBPFContext) UpdateArgMap(key, fnEntry uint64, goidOffset int64, args []UProbeArgMap, cfg UProbeLoadConfig, gAddrOffset uint64, isret bool) error {
 	if ctx.bpfArgMap == nil {
 		return errors.New("eBPF map not loaded")
 	}
-	params := createFunctionParameterList(key, goidOffset, args, isret)
+	params, err := createFunctionParameterList(key, fnEntry, goidOffset, args, cfg, isret)
+	if err != nil {
+		return err
+	}
 	params.g_addr_offset = C.longlong(gAddrOffset)
-	return ctx.bpfArgMap.Update(unsafe.Pointer(&key), unsafe.Pointer(&params), ebpf.UpdateAny)
+	ctx.m.Lock()
+	ctx.probes[key] = uprobeSpec{args: args, cfg: cfg}
+	ctx.m.Unlock()
+	return ctx.bpfArgMap.Update(unsafe.Pointer(&key), unsafe.Pointer(params), ebpf.UpdateAny)
 }
 
 func (ctx *EBPFContext) GetBufferedTracepoints() []RawUProbeParams {
@@ -74,6 +100,40 @@ func (ctx *EBPFContext) GetBufferedTracepoints() []RawUProbeParams {
 	return events
 }
 
+// GetLatencyHistograms returns the latency histograms of the functions
+// traced with UProbeLoadConfig.Latency set, indexed by function entry point.
+// The i-th element of each histogram counts the calls that took between 2^i
+// and 2^(i+1)-1 nanoseconds.
+func (ctx *EBPFContext) GetLatencyHistograms() map[uint64][]uint64 {
+	ctx.m.Lock()
+	defer ctx.m.Unlock()
+	if ctx.objs == nil {
+		return ctx.latency
+	}
+	return ctx.readLatencyHistograms()
+}
+
+// readLatencyHistograms reads the latency histograms from the eBPF map.
+// Must be called with ctx.m held.
+func (ctx *EBPFContext) readLatencyHistograms() map[uint64][]uint64 {
+	var (
+		key   struct{ FnEntry, Slot uint64 }
+		count uint64
+	)
+	r := make(map[uint64][]uint64)
+	iter := ctx.objs.LatencyHist.Iterate()
+	for iter.Next(&key, &count) {
+		if key.Slot >= C.MAX_LATENCY_SLOTS {
+			continue
+		}
+		if r[key.FnEntry] == nil {
+			r[key.FnEntry] = make([]uint64, C.MAX_LATENCY_SLOTS)
+		}
+		r[key.FnEntry][key.Slot] = count
+	}
+	return r
+}
+
 func LoadEBPFTracingProgram(path string) (*EBPFContext, error) {
 	var (
 		ctx  EBPFContext
@@ -97,6 +157,7 @@ func LoadEBPFTracingProgram(path string) (*EBPFContext, error) {
 	}
 
 	ctx.bpfArgMap = objs.ArgMap
+	ctx.probes = make(map[uint64]uprobeSpec)
 
 	// TODO(derekparker): This should eventually be moved to a more generalized place.
 	go func() {
@@ -106,9 +167,8 @@ func LoadEBPFTracingProgram(path string) (*EBPFContext, error) {
 				return
 			}
 
-			parsed := parseFunctionParameterList(e.RawSample)
-
 			ctx.m.Lock()
+			parsed := parseFunctionParameterList(e.RawSample, ctx.probes)
 			ctx.parsedBpfEvents = append(ctx.parsedBpfEvents, parsed)
 			ctx.m.Unlock()
 		}
@@ -117,7 +177,7 @@ func LoadEBPFTracingProgram(path string) (*EBPFContext, error) {
 	return &ctx, nil
 }
 
-func parseFunctionParameterList(rawParamBytes []byte) RawUProbeParams {
+func parseFunctionParameterList(rawParamBytes []byte, probes map[uint64]uprobeSpec) RawUProbeParams {
 	params := (*C.function_parameter_list_t)(unsafe.Pointer(&rawParamBytes[0]))
 
 	defer runtime.KeepAlive(params) // Ensure the param is not garbage collected.
@@ -125,59 +185,197 @@ func parseFunctionParameterList(rawParamBytes []byte) RawUProbeParams {
 	var rawParams RawUProbeParams
 	rawParams.FnAddr = int(params.fn_addr)
 	rawParams.GoroutineID = int(params.goroutine_id)
+	rawParams.Inlined = bool(params.inlined)
 
-	parseParam := func(param C.function_parameter_t) *RawUProbeParam {
-		iparam := &RawUProbeParam{}
-		data := make([]byte, 0x60)
-		ret := param
-		iparam.Kind = reflect.Kind(ret.kind)
+	spec, hasSpec := probes[uint64(params.fn_addr)]
+	var inArgs, retArgs []*UProbeArgMap
+	for i := range spec.args {
+		if spec.args[i].Ret {
+			retArgs = append(retArgs, &spec.args[i])
+		} else {
+			inArgs = append(inArgs, &spec.args[i])
+		}
+	}
 
-		val := C.GoBytes(unsafe.Pointer(&ret.val), C.int(ret.size))
-		rawDerefValue := C.GoBytes(unsafe.Pointer(&ret.deref_val[0]), 0x30)
-		copy(data, val)
-		copy(data[0x30:], rawDerefValue)
-		iparam.Data = data
+	for i := 0; i < int(params.n_parameters); i++ {
+		var arg *UProbeArgMap
+		if i < len(inArgs) {
+			arg = inArgs[i]
+		}
+		rawParams.InputParams = append(rawParams.InputParams, parseParam(&params.params[i], arg))
+	}
+	for i := 0; i < int(params.n_ret_parameters); i++ {
+		var arg *UProbeArgMap
+		if i < len(retArgs) {
+			arg = retArgs[i]
+		}
+		rawParams.ReturnParams = append(rawParams.ReturnParams, parseParam(&params.ret_params[i], arg))
+	}
+	if hasSpec {
+		for i := 0; i < int(params.n_captures) && i < len(spec.cfg.Captures); i++ {
+			capture := &params.captures[i]
+			if bool(capture.chain.ret) != bool(params.is_ret) {
+				continue
+			}
+			rawParams.Captures = append(rawParams.Captures, parseCapture(capture, &spec.cfg.Captures[i]))
+		}
+	}
 
-		pieces := make([]op.Piece, 0, 2)
-		pieces = append(pieces, op.Piece{Size: 0x30, Kind: op.AddrPiece, Val: FakeAddressBase})
-		pieces = append(pieces, op.Piece{Size: 0x30, Kind: op.AddrPiece, Val: FakeAddressBase + 0x30})
-		iparam.Pieces = pieces
+	return rawParams
+}
 
-		iparam.Addr = FakeAddressBase
+// parseParam converts a parameter read by the eBPF program. If arg is not
+// nil it is the description of the parameter passed to UpdateArgMap.
+func parseParam(param *C.function_parameter_t, arg *UProbeArgMap) *RawUProbeParam {
+	iparam := &RawUProbeParam{}
+	iparam.Kind = reflect.Kind(param.kind)
 
-		switch iparam.Kind {
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
-			iparam.RealType = &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{ByteSize: 8}}}
-		case reflect.String:
-			strLen := binary.LittleEndian.Uint64(val[8:])
-			iparam.Base = FakeAddressBase + 0x30
-			iparam.Len = int64(strLen)
+	size := int(param.size)
+	if size > len(param.val) {
+		iparam.Err = fmt.Errorf("parameter of %d bytes is too large to be read", size)
+		size = len(param.val)
+	}
+	val := C.GoBytes(unsafe.Pointer(&param.val[0]), C.int(size))
+	derefVal := C.GoBytes(unsafe.Pointer(&param.deref_val[0]), C.int(param.deref_len))
+	iparam.Data = flatData(val, derefVal, len(param.val))
+	iparam.Pieces = flatPieces(len(param.val))
+	iparam.Addr = FakeAddressBase
+
+	if arg != nil {
+		iparam.Name = arg.Name
+		iparam.RealType = arg.Type
+		if pieces := registerPieces(arg.DwarfPieces, len(param.val)); pieces != nil {
+			iparam.Pieces = pieces
 		}
+	}
+	parseDerefVal(iparam, val, int64(param.deref_len), len(param.val))
+	return iparam
+}
+
+// parseCapture converts a value read by following the chain of pointers
+// described by capture.
+func parseCapture(raw *C.function_capture_t, capture *UProbeCapture) *RawUProbeParam {
+	iparam := &RawUProbeParam{Name: capture.Name, Kind: capture.Kind, RealType: capture.Type, Addr: FakeAddressBase}
+	if !raw.ok {
+		iparam.Err = errors.New("could not follow pointers")
 		return iparam
 	}
+	val := C.GoBytes(unsafe.Pointer(&raw.val[0]), C.int(raw.size))
+	derefVal := C.GoBytes(unsafe.Pointer(&raw.deref_val[0]), C.int(raw.deref_len))
+	iparam.Data = flatData(val, derefVal, len(raw.val))
+	iparam.Pieces = flatPieces(len(raw.val))
+	parseDerefVal(iparam, val, int64(raw.deref_len), len(raw.val))
+	return iparam
+}
 
-	for i := 0; i < int(params.n_parameters); i++ {
-		rawParams.InputParams = append(rawParams.InputParams, parseParam(params.params[i]))
+// flatData lays out the value of a parameter followed by the data it
+// points to, the latter starting at valSize.
+func flatData(val, derefVal []byte, valSize int) []byte {
+	data := make([]byte, valSize+C.MAX_DEREF_SIZE)
+	copy(data, val)
+	copy(data[valSize:], derefVal)
+	return data
+}
+
+func flatPieces(valSize int) []op.Piece {
+	pieces := make([]op.Piece, 0, 2)
+	pieces = append(pieces, op.Piece{Size: valSize, Kind: op.AddrPiece, Val: FakeAddressBase})
+	pieces = append(pieces, op.Piece{Size: C.MAX_DEREF_SIZE, Kind: op.AddrPiece, Val: FakeAddressBase + uint64(valSize)})
+	return pieces
+}
+
+// registerPieces returns the pieces needed to rebuild the memory layout of
+// a parameter passed in registers. The eBPF program stores each register in
+// its own 8 byte slot of val, while the parameter itself may use only part
+// of each register.
+func registerPieces(dwarfPieces []op.Piece, valSize int) []op.Piece {
+	if len(dwarfPieces) == 0 {
+		return nil
 	}
-	for i := 0; i < int(params.n_ret_parameters); i++ {
-		rawParams.ReturnParams = append(rawParams.ReturnParams, parseParam(params.ret_params[i]))
+	pieces := make([]op.Piece, 0, len(dwarfPieces)+2)
+	size, reg := 0, 0
+	for _, piece := range dwarfPieces {
+		switch piece.Kind {
+		case op.RegPiece:
+			pieces = append(pieces, op.Piece{Size: piece.Size, Kind: op.AddrPiece, Val: FakeAddressBase + uint64(reg*8)})
+			reg++
+		case op.ImmPiece:
+			pieces = append(pieces, piece)
+		default:
+			return nil
+		}
+		size += piece.Size
+	}
+	if size > valSize {
+		return nil
+	}
+	if size < valSize {
+		// Pad the parameter so that the data it points to is found right
+		// after val, like for parameters passed on the stack.
+		pieces = append(pieces, op.Piece{Size: valSize - size, Kind: op.ImmPiece, Bytes: make([]byte, valSize-size)})
 	}
+	pieces = append(pieces, op.Piece{Size: C.MAX_DEREF_SIZE, Kind: op.AddrPiece, Val: FakeAddressBase + uint64(valSize)})
+	return pieces
+}
 
-	return rawParams
+// parseDerefVal fills in the fields of iparam describing the contents of a
+// string or a slice, which were read by the eBPF program.
+func parseDerefVal(iparam *RawUProbeParam, val []byte, derefLen int64, valSize int) {
+	switch iparam.Kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if iparam.RealType == nil {
+			iparam.RealType = &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{ByteSize: 8}}}
+		}
+	case reflect.String:
+		if len(val) < 16 {
+			return
+		}
+		strLen := binary.LittleEndian.Uint64(val[8:])
+		iparam.Base = FakeAddressBase + uint64(valSize)
+		iparam.Len = int64(strLen)
+		iparam.Loaded = derefLen
+	case reflect.Slice:
+		if len(val) < 16 {
+			return
+		}
+		iparam.Base = FakeAddressBase + uint64(valSize)
+		iparam.Len = int64(binary.LittleEndian.Uint64(val[8:]))
+		if elemSize := sliceElemSize(iparam.RealType); elemSize > 0 {
+			iparam.Loaded = derefLen / elemSize
+		}
+	}
+}
+
+func sliceElemSize(typ godwarf.Type) int64 {
+	if t, ok := typ.(*godwarf.SliceType); ok {
+		return t.ElemType.Size()
+	}
+	return 0
 }
 
-func createFunctionParameterList(entry uint64, goidOffset int64, args []UProbeArgMap, isret bool) C.function_parameter_list_t {
+func createFunctionParameterList(addr, fnEntry uint64, goidOffset int64, args []UProbeArgMap, cfg UProbeLoadConfig, isret bool) (*C.function_parameter_list_t, error) {
 	var params C.function_parameter_list_t
 	params.goid_offset = C.uint(goidOffset)
-	params.fn_addr = C.uint(entry)
+	params.fn_addr = C.ulonglong(addr)
+	params.fn_entry = C.ulonglong(fnEntry)
 	params.is_ret = C.bool(isret)
+	params.latency = C.bool(cfg.Latency)
+	params.inlined = C.bool(cfg.Inlined)
 	params.n_parameters = C.uint(0)
 	params.n_ret_parameters = C.uint(0)
-	for _, arg := range args {
+	idx := make([]int, len(args))
+	for i, arg := range args {
 		var param C.function_parameter_t
 		param.size = C.uint(arg.Size)
 		param.offset = C.int(arg.Offset)
 		param.kind = C.uint(arg.Kind)
+		switch arg.Kind {
+		case reflect.String:
+			param.max_len = C.uint(cfg.MaxStringLen)
+		case reflect.Slice:
+			param.max_len = C.uint(cfg.MaxArrayValues)
+			param.elem_size = C.uint(sliceElemSize(arg.Type))
+		}
 		if arg.InReg {
 			param.in_reg = true
 			param.n_pieces = C.int(len(arg.Pieces))
@@ -189,14 +387,93 @@ func createFunctionParameterList(entry uint64, goidOffset int64, args []UProbeAr
 			}
 		}
 		if !arg.Ret {
+			idx[i] = int(params.n_parameters)
 			params.params[params.n_parameters] = param
 			params.n_parameters++
 		} else {
+			idx[i] = int(params.n_ret_parameters)
 			params.ret_params[params.n_ret_parameters] = param
 			params.n_ret_parameters++
 		}
 	}
-	return params
+	if len(cfg.Captures) > len(params.captures) {
+		return nil, fmt.Errorf("too many captures, max is %d", len(params.captures))
+	}
+	for _, capture := range cfg.Captures {
+		c := &params.captures[params.n_captures]
+		if err := setFunctionChain(&c.chain, &capture, args, idx); err != nil {
+			return nil, err
+		}
+		if capture.Size > int64(len(c.val)) {
+			return nil, fmt.Errorf("capture %s is too large, max is %d bytes", capture.Name, len(c.val))
+		}
+		c.kind = C.uint(capture.Kind)
+		c.size = C.uint(capture.Size)
+		switch capture.Kind {
+		case reflect.String:
+			c.max_len = C.uint(cfg.MaxStringLen)
+		case reflect.Slice:
+			c.max_len = C.uint(cfg.MaxArrayValues)
+			c.elem_size = C.uint(sliceElemSize(capture.Type))
+		}
+		params.n_captures++
+	}
+	if len(cfg.Filters) > len(params.filters) {
+		return nil, fmt.Errorf("too many conditions, max is %d", len(params.filters))
+	}
+	for _, filter := range cfg.Filters {
+		f := &params.filters[params.n_filters]
+		if err := setFunctionChain(&f.chain, &filter.Capture, args, idx); err != nil {
+			return nil, err
+		}
+		if filter.Capture.Size <= 0 || filter.Capture.Size > 8 {
+			return nil, fmt.Errorf("can not compare %s, it is not an integer", filter.Capture.Name)
+		}
+		if args[filter.Capture.Arg].Ret {
+			return nil, fmt.Errorf("can not compare %s, conditions can only refer to input arguments", filter.Capture.Name)
+		}
+		f.size = C.uint(filter.Capture.Size)
+		f.is_signed = C.bool(filter.Signed)
+		switch filter.Op {
+		case token.EQL:
+			f.op = C.FILTER_EQ
+		case token.NEQ:
+			f.op = C.FILTER_NE
+		case token.LSS:
+			f.op = C.FILTER_LT
+		case token.LEQ:
+			f.op = C.FILTER_LE
+		case token.GTR:
+			f.op = C.FILTER_GT
+		case token.GEQ:
+			f.op = C.FILTER_GE
+		default:
+			return nil, fmt.Errorf("unsupported operator %s", filter.Op)
+		}
+		f.value = C.longlong(filter.Value)
+		params.n_filters++
+	}
+	return &params, nil
+}
+
+// setFunctionChain describes to the eBPF program how to reach the value of
+// capture, idx maps the index of each argument to its index in params or
+// ret_params.
+func setFunctionChain(chain *C.function_chain_t, capture *UProbeCapture, args []UProbeArgMap, idx []int) error {
+	if capture.Arg < 0 || capture.Arg >= len(args) {
+		return fmt.Errorf("capture %s refers to an unknown argument", capture.Name)
+	}
+	if len(capture.Derefs) > len(chain.derefs) {
+		return fmt.Errorf("capture %s follows too many pointers, max is %d", capture.Name, len(chain.derefs))
+	}
+	chain.param_idx = C.uint(idx[capture.Arg])
+	chain.ret = C.bool(args[capture.Arg].Ret)
+	chain.n_derefs = C.int(len(capture.Derefs))
+	for i := range capture.Derefs {
+		chain.derefs[i] = C.longlong(capture.Derefs[i])
+	}
+	chain.offset = C.longlong(capture.Offset)
+	return nil
 }
 
 func AddressToOffset(f *elf.File, addr uint64) (uint64, error) {
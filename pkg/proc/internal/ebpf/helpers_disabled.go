@@ -23,7 +23,7 @@ func (ctx *EBPFContext) AttachURetprobe(pid int, name string, offset uint32) err
 	return errors.New("eBPF is disabled")
 }
 
-func (ctx *EBPFContext) UpdateArgMap(key uint64, goidOffset int64, args []UProbeArgMap, gAddrOffset uint64, isret bool) error {
+func (ctx *EBPFContext) UpdateArgMap(key, fnEntry uint64, goidOffset int64, args []UProbeArgMap, cfg UProbeLoadConfig, gAddrOffset uint64, isret bool) error {
 	return errors.New("eBPF is disabled")
 }
 
@@ -31,6 +31,10 @@ func (ctx *EBPFContext) GetBufferedTracepoints() []RawUProbeParams {
 	return nil
 }
 
+func (ctx *EBPFContext) GetLatencyHistograms() map[uint64][]uint64 {
+	return nil
+}
+
 func SymbolToOffset(file, symbol string) (uint32, error) {
 	return 0, errors.New("eBPF disabled")
 }
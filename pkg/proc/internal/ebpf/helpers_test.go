@@ -0,0 +1,115 @@
+//go:build linux && amd64 && cgo && go1.16
+// +build linux,amd64,cgo,go1.16
+
+package ebpf
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+	"unsafe"
+
+	"github.com/cilium/ebpf"
+)
+
+func TestArgMapValueSize(t *testing.T) {
+	// The values written to arg_map by UpdateArgMap must have the size the
+	// compiled program expects, the object has to be regenerated with
+	// go generate every time function_vals.bpf.h changes.
+	spec, err := loadTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := spec.Maps["arg_map"]
+	if !ok {
+		t.Fatal("arg_map not found")
+	}
+	params, err := createFunctionParameterList(0, 0, 0, nil, UProbeLoadConfig{}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := uint32(unsafe.Sizeof(*params)); m.ValueSize != size {
+		t.Fatalf("arg_map value size mismatch: object %d, function_parameter_list_t %d", m.ValueSize, size)
+	}
+}
+
+func TestTraceObjectsMatchSpec(t *testing.T) {
+	// trace_bpfel_x86.go must describe the maps and programs of the
+	// embedded object, otherwise loadTraceObjects fails.
+	spec, err := loadTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var specs traceSpecs
+	if err := spec.Assign(&specs); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTraceProgramVerifier(t *testing.T) {
+	// Load the program into the kernel to make sure the verifier accepts
+	// it. CO-RE relocations are resolved against the types the program was
+	// compiled with rather than the kernel's, which older versions of
+	// cilium/ebpf can not always parse. Their offsets do not matter to the
+	// verifier.
+	var objs traceObjects
+	err := loadTraceObjects(&objs, &ebpf.CollectionOptions{
+		Programs: ebpf.ProgramOptions{
+			TargetBTF: bytes.NewReader(_TraceBytes),
+			LogSize:   64 << 20,
+		},
+	})
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("not allowed to load eBPF programs")
+	}
+	if err != nil {
+		t.Fatalf("%+v", err)
+	}
+	objs.Close()
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	// The histograms are read from latency_hist, one for each function
+	// entry point, and are still available after the context is closed.
+	spec, err := loadTrace()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := ebpf.NewMap(spec.Maps["latency_hist"])
+	if errors.Is(err, os.ErrPermission) {
+		t.Skip("not allowed to create eBPF maps")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	type latencyKey struct{ FnEntry, Slot uint64 }
+	counts := map[latencyKey]uint64{
+		{0x1000, 0}: 1,
+		{0x1000, 3}: 5,
+		{0x2000, 9}: 2,
+	}
+	for k, n := range counts {
+		if err := m.Put(k, n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := &EBPFContext{objs: &traceObjects{traceMaps: traceMaps{LatencyHist: m}}}
+	check := func(hists map[uint64][]uint64) {
+		t.Helper()
+		if len(hists) != 2 {
+			t.Fatalf("wrong number of histograms: %d", len(hists))
+		}
+		for fnEntry, hist := range hists {
+			for slot, n := range hist {
+				if expected := counts[latencyKey{fnEntry, uint64(slot)}]; n != expected {
+					t.Errorf("wrong count for %#x slot %d: %d, expected %d", fnEntry, slot, n, expected)
+				}
+			}
+		}
+	}
+	check(ctx.GetLatencyHistograms())
+	ctx.Close()
+	check(ctx.GetLatencyHistograms())
+}
@@ -29,9 +29,9 @@ func loadTrace() (*ebpf.CollectionSpec, error) {
 //
 // The following types are suitable as obj argument:
 //
-//     *traceObjects
-//     *tracePrograms
-//     *traceMaps
+//	*traceObjects
+//	*tracePrograms
+//	*traceMaps
 //
 // See ebpf.CollectionSpec.LoadAndAssign documentation for details.
 func loadTraceObjects(obj interface{}, opts *ebpf.CollectionOptions) error {
@@ -62,9 +62,12 @@ type traceProgramSpecs struct {
 //
 // It can be passed ebpf.CollectionSpec.Assign.
 type traceMapSpecs struct {
-	ArgMap *ebpf.MapSpec `ebpf:"arg_map"`
-	Events *ebpf.MapSpec `ebpf:"events"`
-	Heap   *ebpf.MapSpec `ebpf:"heap"`
+	ArgMap       *ebpf.MapSpec `ebpf:"arg_map"`
+	Events       *ebpf.MapSpec `ebpf:"events"`
+	FilterMap    *ebpf.MapSpec `ebpf:"filter_map"`
+	Heap         *ebpf.MapSpec `ebpf:"heap"`
+	LatencyHist  *ebpf.MapSpec `ebpf:"latency_hist"`
+	LatencyStart *ebpf.MapSpec `ebpf:"latency_start"`
 }
 
 // traceObjects contains all objects after they have been loaded into the kernel.
@@ -86,16 +89,22 @@ func (o *traceObjects) Close() error {
 //
 // It can be passed to loadTraceObjects or ebpf.CollectionSpec.LoadAndAssign.
 type traceMaps struct {
-	ArgMap *ebpf.Map `ebpf:"arg_map"`
-	Events *ebpf.Map `ebpf:"events"`
-	Heap   *ebpf.Map `ebpf:"heap"`
+	ArgMap       *ebpf.Map `ebpf:"arg_map"`
+	Events       *ebpf.Map `ebpf:"events"`
+	FilterMap    *ebpf.Map `ebpf:"filter_map"`
+	Heap         *ebpf.Map `ebpf:"heap"`
+	LatencyHist  *ebpf.Map `ebpf:"latency_hist"`
+	LatencyStart *ebpf.Map `ebpf:"latency_start"`
 }
 
 func (m *traceMaps) Close() error {
 	return _TraceClose(
 		m.ArgMap,
 		m.Events,
+		m.FilterMap,
 		m.Heap,
+		m.LatencyHist,
+		m.LatencyStart,
 	)
 }
 
@@ -122,5 +131,6 @@ func _TraceClose(closers ...io.Closer) error {
 }
 
 // Do not access this directly.
+//
 //go:embed trace_bpfel_x86.o
 var _TraceBytes []byte
@@ -0,0 +1,86 @@
+package native
+
+import (
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// Syscall catchpoints are implemented by resuming the threads with
+// PTRACE_SYSCALL instead of PTRACE_CONT, which stops them when they enter
+// and when they return from every system call. The stops for system calls
+// that are not caught are filtered out by trapWaitInternal, which resumes
+// the thread immediately.
+// PTRACE_O_TRACESYSGOOD is always set so that syscall stops can be told
+// apart from the delivery of a SIGTRAP.
+
+// syscallTrap is the stop signal of syscall stops.
+const syscallTrap = sys.SIGTRAP | 0x80
+
+// SetSyscallCatchpoints changes the system calls that stop the processes of
+// the group, see proc.SyscallCatcher.
+func (dbp *nativeProcess) SetSyscallCatchpoints(all bool, nrs []int) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	dbp.group.catchAllSyscalls = all
+	dbp.group.catchSyscalls = make(map[int]bool, len(nrs))
+	for _, nr := range nrs {
+		dbp.group.catchSyscalls[nr] = true
+	}
+	return nil
+}
+
+// tracingSyscalls returns true if the threads of the group must be resumed
+// with PTRACE_SYSCALL.
+func (procgrp *processGroup) tracingSyscalls() bool {
+	return procgrp.catchAllSyscalls || len(procgrp.catchSyscalls) > 0
+}
+
+// handleSyscallStop handles th, which was stopped entering or returning
+// from a system call. It returns true if th must stay stopped because the
+// system call is caught, otherwise th is resumed. If halt is true th is
+// being stopped by the debugger and it is always resumed, the SIGSTOP sent
+// to it must be observed first.
+func (dbp *nativeProcess) handleSyscallStop(th *nativeThread, halt bool) (stop bool, err error) {
+	var regs sys.PtraceRegs
+	dbp.execPtraceFunc(func() { err = sys.PtraceGetRegs(th.ID, &regs) })
+	if err != nil {
+		return false, err
+	}
+	exit := th.syscallExitStop()
+	nr := int(regs.Orig_rax)
+	if halt || !(dbp.group.catchAllSyscalls || dbp.group.catchSyscalls[nr]) {
+		return false, th.resumeWithSig(0)
+	}
+
+	info := &proc.SyscallInfo{
+		Nr:   nr,
+		Exit: exit,
+		Args: [6]uint64{regs.Rdi, regs.Rsi, regs.Rdx, regs.R10, regs.R8, regs.R9},
+	}
+	if info.Exit {
+		info.Ret = int64(regs.Rax)
+	}
+	th.os.running = false
+	th.common.Syscall = info
+	return true, nil
+}
+
+// syscallExitStop returns true if th, stopped by a syscall stop, is
+// returning from the system call. It must be called for every syscall stop
+// of th. The kind of stop is read with PTRACE_GET_SYSCALL_INFO, on kernels
+// older than 5.3 it is tracked by alternating between entry and exit, which
+// is wrong for a thread that was already in a system call when it started
+// being traced with PTRACE_SYSCALL.
+func (th *nativeThread) syscallExitStop() bool {
+	var op uint8
+	var err error
+	th.dbp.execPtraceFunc(func() { op, err = ptraceGetSyscallInfoOp(th.ID) })
+	if err == nil && (op == _PTRACE_SYSCALL_INFO_ENTRY || op == _PTRACE_SYSCALL_INFO_EXIT) {
+		th.os.inSyscall = op == _PTRACE_SYSCALL_INFO_ENTRY
+		return op == _PTRACE_SYSCALL_INFO_EXIT
+	}
+	th.os.inSyscall = !th.os.inSyscall
+	return !th.os.inSyscall
+}
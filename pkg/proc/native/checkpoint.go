@@ -0,0 +1,372 @@
+package native
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
+)
+
+// syscallInstruction is the encoding of the amd64 SYSCALL instruction.
+var syscallInstruction = []byte{0x0f, 0x05}
+
+// checkpoint is a copy of the target process, created by injecting a fork
+// system call into the tracee. The copy is traced by us and kept stopped
+// until it is either restored or cleared.
+//
+// Only the thread that executed the fork exists in the copy, all other
+// threads of the target are lost, when a checkpoint is restored the Go
+// runtime will be missing the Ms that were running on those threads.
+type checkpoint struct {
+	id    int
+	pid   int // pid of the parked copy
+	when  string
+	where string
+
+	// regs are the registers of the forking thread before the fork was
+	// injected, they are restored in every copy made from this checkpoint.
+	regs proc.Registers
+
+	// swbps maps the address of every software breakpoint that was written
+	// to memory when the checkpoint was created to its original content.
+	swbps map[uint64][]byte
+
+	// swpages is a copy of the pages protected for software watchpoints
+	// when the checkpoint was created.
+	swpages map[uint64]watchPage
+}
+
+// Recorded always returns false, the native backend is not a recording.
+func (dbp *nativeProcess) Recorded() (bool, string) { return false, "" }
+
+// ChangeDirection will return an error unless dir is proc.Forward, use
+// checkpoints to go back in time.
+func (dbp *nativeProcess) ChangeDirection(dir proc.Direction) error {
+	if dir != proc.Forward {
+		return proc.ErrNotRecorded
+	}
+	return nil
+}
+
+// GetDirection will always return proc.Forward.
+func (dbp *nativeProcess) GetDirection() proc.Direction { return proc.Forward }
+
+// When always returns an empty string, positions are only tracked for
+// checkpoints.
+func (dbp *nativeProcess) When() (string, error) { return "", nil }
+
+// Checkpoint creates a checkpoint at the current position of the memory
+// thread.
+func (dbp *nativeProcess) Checkpoint(where string) (int, error) {
+	if dbp.exited {
+		return -1, proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	th := dbp.memthread
+	regs, err := th.Registers()
+	if err != nil {
+		return -1, err
+	}
+	savedRegs, err := regs.Copy()
+	if err != nil {
+		return -1, err
+	}
+	pid, err := dbp.injectFork(th, savedRegs, true)
+	if err != nil {
+		return -1, fmt.Errorf("could not create checkpoint: %v", err)
+	}
+
+	swbps := make(map[uint64][]byte)
+	for addr, bp := range dbp.breakpoints.M {
+		if bp.WatchType == 0 {
+			swbps[addr] = bp.Orig
+		}
+	}
+
+	swpages := make(map[uint64]watchPage)
+	for page, pg := range dbp.swpages {
+		swpages[page] = *pg
+	}
+
+	dbp.lastCheckpointID++
+	dbp.checkpoints = append(dbp.checkpoints, &checkpoint{
+		id:      dbp.lastCheckpointID,
+		pid:     pid,
+		when:    time.Now().Format("15:04:05.000"),
+		where:   where,
+		regs:    savedRegs,
+		swbps:   swbps,
+		swpages: swpages,
+	})
+	return dbp.lastCheckpointID, nil
+}
+
+// Checkpoints returns the list of checkpoints.
+func (dbp *nativeProcess) Checkpoints() ([]proc.Checkpoint, error) {
+	r := make([]proc.Checkpoint, 0, len(dbp.checkpoints))
+	for _, cp := range dbp.checkpoints {
+		r = append(r, proc.Checkpoint{ID: cp.id, When: cp.when, Where: cp.where})
+	}
+	return r, nil
+}
+
+// ClearCheckpoint kills the copy of the process associated with checkpoint id.
+func (dbp *nativeProcess) ClearCheckpoint(id int) error {
+	for i, cp := range dbp.checkpoints {
+		if cp.id == id {
+			killCheckpoint(cp)
+			dbp.checkpoints = append(dbp.checkpoints[:i], dbp.checkpoints[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("checkpoint not found")
+}
+
+// Restart replaces the target process with a new copy of checkpoint pos,
+// which must be a checkpoint ID prefixed with 'c'. The checkpoint itself is
+// left untouched so that it can be restored again.
+func (dbp *nativeProcess) Restart(pos string) (proc.Thread, error) {
+	if dbp.exited {
+		return nil, proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	if !strings.HasPrefix(pos, "c") {
+		return nil, errors.New("can only restart from a checkpoint")
+	}
+	id, err := strconv.Atoi(pos[1:])
+	if err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %q", pos)
+	}
+	var cp *checkpoint
+	for i := range dbp.checkpoints {
+		if dbp.checkpoints[i].id == id {
+			cp = dbp.checkpoints[i]
+			break
+		}
+	}
+	if cp == nil {
+		return nil, errors.New("checkpoint not found")
+	}
+
+	parked := &nativeThread{ID: cp.pid, dbp: dbp, os: new(osSpecificDetails)}
+	pid, err := dbp.injectFork(parked, cp.regs, false)
+	if err != nil {
+		return nil, fmt.Errorf("could not restore checkpoint: %v", err)
+	}
+
+	// get rid of the current process, without killing the process group
+	// the checkpoints belong to.
+	if err := sys.Kill(dbp.pid, sys.SIGKILL); err != nil {
+		return nil, fmt.Errorf("could not deliver signal: %v", err)
+	}
+	if err := dbp.waitKilled(); err != nil {
+		return nil, err
+	}
+
+	dbp.pid = pid
+	dbp.threads = make(map[int]*nativeThread)
+	dbp.memthread = nil
+	th, err := dbp.addThread(pid, false)
+	if err != nil {
+		return nil, err
+	}
+	dbp.memthread = th
+
+	// The copy contains the software breakpoints that existed when the
+	// checkpoint was created, bring them in sync with the current breakpoint
+	// table.
+	for addr, orig := range cp.swbps {
+		if bp, ok := dbp.breakpoints.M[addr]; ok && bp.WatchType == 0 {
+			continue
+		}
+		if _, err := th.WriteMemory(addr, orig); err != nil {
+			return nil, err
+		}
+	}
+	for addr, bp := range dbp.breakpoints.M {
+		if _, ok := cp.swbps[addr]; ok || bp.WatchType != 0 {
+			continue
+		}
+		if err := dbp.writeSoftwareBreakpoint(th, addr); err != nil {
+			return nil, err
+		}
+	}
+	// Debug registers are not inherited across fork, install the hardware
+	// watchpoints in the restored thread.
+	for _, bp := range dbp.breakpoints.M {
+		if bp.WatchType == 0 || bp.SoftwareWatch {
+			continue
+		}
+		if err := th.writeHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex); err != nil {
+			return nil, fmt.Errorf("could not restore watchpoint at %#x: %v", bp.Addr, err)
+		}
+	}
+	for page, cpg := range cp.swpages {
+		if dbp.swpages[page] == nil {
+			if err := dbp.mprotect(th, page, &cpg, cpg.orig); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for page, pg := range dbp.swpages {
+		pg.cur = pg.orig
+		if cpg, ok := cp.swpages[page]; ok {
+			pg.cur = cpg.cur
+		}
+	}
+	if err := dbp.protectWatchPages(th); err != nil {
+		return nil, err
+	}
+
+	if err := th.SetCurrentBreakpoint(false); err != nil {
+		return nil, err
+	}
+	return th, nil
+}
+
+// injectFork makes th execute a fork system call and returns the pid of the
+// child, which is left in the stopped state. Both th and the child are
+// restored to regs and to the memory contents they had before the
+// injection.
+// If keepSignals is false any signal received by th while the fork is in
+// progress is discarded, otherwise it is delivered the next time th is
+// resumed.
+func (dbp *nativeProcess) injectFork(th *nativeThread, regs proc.Registers, keepSignals bool) (int, error) {
+	pc := regs.PC()
+	orig := make([]byte, len(syscallInstruction))
+	if _, err := th.ReadMemory(orig, pc); err != nil {
+		return 0, err
+	}
+	if _, err := th.WriteMemory(pc, syscallInstruction); err != nil {
+		return 0, err
+	}
+
+	ir, err := registers(th)
+	if err != nil {
+		return 0, err
+	}
+	r := ir.(*linutil.AMD64Registers)
+	r.Regs.Rax = sys.SYS_FORK
+	r.Regs.Orig_rax = ^uint64(0) // prevents the kernel from restarting an interrupted system call
+	r.Regs.Rip = pc
+	dbp.execPtraceFunc(func() { err = sys.PtraceSetRegs(th.ID, (*sys.PtraceRegs)(r.Regs)) })
+	if err != nil {
+		return 0, err
+	}
+	dbp.execPtraceFunc(func() { err = sys.PtraceSetOptions(th.ID, dbp.group.ptraceOptions()|sys.PTRACE_O_TRACEFORK) })
+	if err != nil {
+		return 0, err
+	}
+
+	child, stepErr := dbp.stepFork(th, keepSignals)
+
+	dbp.execPtraceFunc(func() { err = sys.PtraceSetOptions(th.ID, dbp.group.ptraceOptions()) })
+	if _, werr := th.WriteMemory(pc, orig); werr != nil && err == nil {
+		err = werr
+	}
+	if rerr := th.restoreRegisters(regs); rerr != nil && err == nil {
+		err = rerr
+	}
+	if stepErr != nil {
+		return 0, stepErr
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	// wait for the child to reach its initial stop and restore it.
+	if _, _, err := dbp.waitFast(child); err != nil {
+		return 0, err
+	}
+	childth := &nativeThread{ID: child, dbp: dbp, os: new(osSpecificDetails)}
+	if _, err := childth.WriteMemory(pc, orig); err != nil {
+		return 0, err
+	}
+	if err := childth.restoreRegisters(regs); err != nil {
+		return 0, err
+	}
+	return child, nil
+}
+
+// stepFork single steps th over the injected system call, returning the pid
+// of the child reported by the PTRACE_EVENT_FORK stop.
+func (dbp *nativeProcess) stepFork(th *nativeThread, keepSignals bool) (int, error) {
+	child := 0
+	var err error
+	for {
+		dbp.execPtraceFunc(func() { err = ptraceSingleStep(th.ID, 0) })
+		if err != nil {
+			return 0, err
+		}
+		_, status, err := dbp.waitFast(th.ID)
+		if err != nil {
+			return 0, err
+		}
+		if status.Exited() || status.Signaled() {
+			return 0, fmt.Errorf("process %d exited during fork", th.ID)
+		}
+		switch s := status.StopSignal(); {
+		case s == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_FORK:
+			var msg uint
+			dbp.execPtraceFunc(func() { msg, err = sys.PtraceGetEventMsg(th.ID) })
+			if err != nil {
+				return 0, err
+			}
+			child = int(msg)
+		case s == sys.SIGTRAP:
+			if child == 0 {
+				regs, err := registers(th)
+				if err != nil {
+					return 0, err
+				}
+				return 0, fmt.Errorf("fork failed: %v", sys.Errno(-int64(regs.(*linutil.AMD64Registers).Regs.Rax)))
+			}
+			return child, nil
+		case s == sys.SIGSTOP:
+			// delayed SIGSTOP, ignore it
+		default:
+			if keepSignals {
+				th.os.delayedSignal = int(s)
+			}
+		}
+	}
+}
+
+// waitKilled waits for all threads of the target process to terminate
+// after they have been sent a SIGKILL.
+func (dbp *nativeProcess) waitKilled() error {
+	// wait for other threads first or the thread group leader (dbp.pid) will never exit.
+	for threadID := range dbp.threads {
+		if threadID != dbp.pid {
+			dbp.wait(threadID, 0)
+		}
+	}
+	for {
+		wpid, status, err := dbp.wait(dbp.pid, 0)
+		if err != nil {
+			return err
+		}
+		if wpid == dbp.pid && status != nil && ((status.Signaled() && status.Signal() == sys.SIGKILL) || status.Exited()) {
+			return nil
+		}
+	}
+}
+
+// clearCheckpoints kills all the copies of the process created by
+// Checkpoint.
+func (dbp *nativeProcess) clearCheckpoints() {
+	for _, cp := range dbp.checkpoints {
+		killCheckpoint(cp)
+	}
+	dbp.checkpoints = nil
+}
+
+func killCheckpoint(cp *checkpoint) {
+	_ = sys.Kill(cp.pid, sys.SIGKILL)
+	var s sys.WaitStatus
+	_, _ = sys.Wait4(cp.pid, &s, sys.WALL, nil)
+}
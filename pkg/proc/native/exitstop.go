@@ -0,0 +1,76 @@
+package native
+
+import (
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// Stopping before the process exits is implemented with
+// PTRACE_O_TRACEEXIT: every thread stops when it is about to exit, while
+// the address space of the process still exists. When a thread calls
+// exit_group the other threads are killed and they stop too, the one that
+// called exit_group is reported as the thread that stopped.
+
+// SetStopOnExit makes the processes of the group stop before exiting, see
+// proc.ExitStopper.
+func (dbp *nativeProcess) SetStopOnExit(enabled bool) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	dbp.group.stopOnExit = enabled
+	opts := dbp.group.ptraceOptions()
+	for _, th := range dbp.group.threads() {
+		var err error
+		dbp.execPtraceFunc(func() { err = sys.PtraceSetOptions(th.ID, opts) })
+		if err != nil && err != sys.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleExitStop handles th, which stopped because it is about to exit. It
+// returns true if th must stay stopped, otherwise th is resumed and lets
+// it exit. Threads exiting on their own, without the rest of the process,
+// are always resumed.
+func (dbp *nativeProcess) handleExitStop(th *nativeThread) (stop bool, err error) {
+	if !dbp.group.stopOnExit {
+		return false, th.resumeWithSig(0)
+	}
+	var regs sys.PtraceRegs
+	dbp.execPtraceFunc(func() { err = sys.PtraceGetRegs(th.ID, &regs) })
+	if err != nil {
+		return false, err
+	}
+	if regs.Orig_rax == sys.SYS_EXIT {
+		return false, th.resumeWithSig(0)
+	}
+	var msg uint
+	dbp.execPtraceFunc(func() { msg, err = sys.PtraceGetEventMsg(th.ID) })
+	if err != nil {
+		return false, err
+	}
+	ws := sys.WaitStatus(msg)
+	info := &proc.ExitInfo{Status: ws.ExitStatus()}
+	if ws.Signaled() {
+		info.Status = -int(ws.Signal())
+	}
+	th.os.running = false
+	th.os.exitCaller = regs.Orig_rax == sys.SYS_EXIT_GROUP
+	th.common.Exit = info
+	return true, nil
+}
+
+// letExit makes the threads of dbp that stopped before exiting finish
+// exiting and the other threads not stop when they exit, it is called
+// before killing dbp.
+func (dbp *nativeProcess) letExit() {
+	opts := dbp.group.ptraceOptions() &^ sys.PTRACE_O_TRACEEXIT
+	for _, th := range dbp.threads {
+		dbp.execPtraceFunc(func() { _ = sys.PtraceSetOptions(th.ID, opts) })
+		if th.common.Exit != nil {
+			_ = th.resumeWithSig(0)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package native
+
+import (
+	"runtime"
+	"sync"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// processGroup is the set of processes traced together: the process that
+// was launched or attached to and the processes it created that are being
+// followed, see proc.FollowMode.
+//
+// All processes of a group share the same ptrace thread, the kernel only
+// accepts ptrace requests for a child that was attached automatically from
+// the thread that traces its parent, and they are resumed and stopped
+// together.
+type processGroup struct {
+	procs   []*nativeProcess
+	targets *proc.TargetGroup
+	follow  proc.FollowMode
+
+	// nonStop is true if only the thread that stops is halted, cont
+	// describes which threads are resumed, see proc.NonStopper.
+	nonStop bool
+	cont    proc.ContinueMode
+
+	// signals are the signal policies set with SetSignalPolicy.
+	signals map[int]proc.SignalPolicy
+
+	// catchAllSyscalls and catchSyscalls are the system calls that stop
+	// the threads, see catch.go.
+	catchAllSyscalls bool
+	catchSyscalls    map[int]bool
+
+	// stopOnExit makes the processes stop before exiting, see exitstop.go.
+	stopOnExit bool
+
+	stopMu sync.Mutex // protects manualStopRequested
+	// manualStopRequested is set if all the threads in the process were
+	// signalled to stop as a result of a Halt API call. Used to disambiguate
+	// why a thread is found to have stopped.
+	manualStopRequested bool
+}
+
+// newChildProcess returns a nativeProcess for the process pid, created by
+// parent, and adds it to the group of parent.
+func newChildProcess(parent *nativeProcess, pid int) *nativeProcess {
+	dbp := &nativeProcess{
+		pid:            pid,
+		parentPid:      parent.pid,
+		threads:        make(map[int]*nativeThread),
+		breakpoints:    proc.NewBreakpointMap(),
+		os:             new(osProcessDetails),
+		ptraceChan:     parent.ptraceChan,
+		ptraceDoneChan: parent.ptraceDoneChan,
+		bi:             proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH),
+		childProcess:   parent.childProcess,
+		group:          parent.group,
+	}
+	parent.group.procs = append(parent.group.procs, dbp)
+	return dbp
+}
+
+// procForThread returns the process that owns thread tid.
+func (grp *processGroup) procForThread(tid int) *nativeProcess {
+	for _, p := range grp.procs {
+		if _, ok := p.threads[tid]; ok {
+			return p
+		}
+	}
+	return nil
+}
+
+// threads returns the threads of all processes in the group.
+func (grp *processGroup) threads() []*nativeThread {
+	var r []*nativeThread
+	for _, p := range grp.procs {
+		for _, th := range p.threads {
+			r = append(r, th)
+		}
+	}
+	return r
+}
+
+// remove removes dbp from the group, the ptrace thread is stopped when the
+// last process is removed.
+func (grp *processGroup) remove(dbp *nativeProcess) {
+	for i := range grp.procs {
+		if grp.procs[i] == dbp {
+			grp.procs = append(grp.procs[:i], grp.procs[i+1:]...)
+			break
+		}
+	}
+	if len(grp.procs) == 0 {
+		close(dbp.ptraceChan)
+		close(dbp.ptraceDoneChan)
+	}
+}
+
+// processExited is called after the main thread of dbp exited with the
+// specified status. If dbp was the last process of the group it returns
+// proc.ErrProcessExited.
+func (grp *processGroup) processExited(dbp *nativeProcess, status int) error {
+	dbp.postExit()
+	if len(grp.procs) == 0 {
+		return proc.ErrProcessExited{Pid: dbp.pid, Status: status}
+	}
+	if !dbp.waitingExec {
+		log.Info("Process %d has exited with status %d", dbp.pid, status)
+	}
+	return nil
+}
+
+// ptraceOptions returns the ptrace options to set on every thread of the
+// group.
+func (grp *processGroup) ptraceOptions() int {
+	opts := sys.PTRACE_O_TRACECLONE | sys.PTRACE_O_TRACESYSGOOD
+	if grp.follow&proc.FollowFork != 0 {
+		opts |= sys.PTRACE_O_TRACEFORK | sys.PTRACE_O_TRACEEXEC
+	}
+	if grp.follow&proc.FollowExec != 0 {
+		// Programs are usually spawned with vfork followed by exec (this is
+		// what os/exec does), the child is traced until it calls exec.
+		opts |= sys.PTRACE_O_TRACEVFORK | sys.PTRACE_O_TRACEEXEC
+	}
+	if grp.stopOnExit {
+		opts |= sys.PTRACE_O_TRACEEXIT
+	}
+	return opts
+}
+
+// SetFollowMode changes which of the processes created by the target are
+// followed.
+func (dbp *nativeProcess) SetFollowMode(mode proc.FollowMode) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	dbp.group.follow = mode
+	opts := dbp.group.ptraceOptions()
+	for _, th := range dbp.group.threads() {
+		var err error
+		dbp.execPtraceFunc(func() { err = sys.PtraceSetOptions(th.ID, opts) })
+		if err != nil && err != sys.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+// handleFork is called when thread tid of dbp stops because it created a
+// new process, the new process is added to the group and returned, stopped.
+// A child created with vfork shares the memory of its parent until it
+// calls exec, until then it is traced but there is no target for it.
+func (dbp *nativeProcess) handleFork(tid int, vfork bool) (*nativeProcess, error) {
+	var msg uint
+	var err error
+	dbp.execPtraceFunc(func() { msg, err = sys.PtraceGetEventMsg(tid) })
+	if err != nil {
+		return nil, err
+	}
+
+	child := newChildProcess(dbp, int(msg))
+	child.waitingExec = vfork || dbp.waitingExec
+	th, err := child.addThread(child.pid, false)
+	if err != nil {
+		dbp.group.remove(child)
+		return nil, err
+	}
+
+	if !vfork && !dbp.waitingExec {
+		// the child inherited the software breakpoints of its parent
+		for _, bp := range dbp.breakpoints.M {
+			if bp.WatchType != 0 {
+				continue
+			}
+			if _, err := th.WriteMemory(bp.Addr, bp.Orig); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !child.waitingExec {
+		child.followProcess(dbp.pid)
+	}
+	return child, nil
+}
+
+// handleExec is called when dbp calls exec, dbp is replaced by a new
+// process, with a new target, which is returned stopped.
+func (dbp *nativeProcess) handleExec() (*nativeProcess, error) {
+	parentPid := dbp.pid
+	if dbp.waitingExec {
+		parentPid = dbp.parentPid
+	}
+
+	np := newChildProcess(dbp, dbp.pid)
+	np.parentPid = dbp.parentPid
+
+	// All threads except the one that called exec are gone and that thread
+	// now has the pid of the process.
+	dbp.postExit()
+	if _, err := np.addThread(np.pid, false); err != nil {
+		dbp.group.remove(np)
+		return nil, err
+	}
+
+	np.followProcess(parentPid)
+	return np, nil
+}
+
+// followProcess creates a target for dbp and adds it to the target group.
+// The parentPid argument is the pid of the process that created dbp, or
+// that was replaced by it. If dbp can not be debugged we detach from it.
+func (dbp *nativeProcess) followProcess(parentPid int) {
+	path := findExecutable("", dbp.pid)
+	err := initialize(dbp)
+	var tgt *proc.Target
+	if err == nil {
+		tgt, err = dbp.newTarget(path, proc.StopLaunched)
+	}
+	if err != nil {
+		log.Warn("can not debug process %d (%s), detaching: %v", dbp.pid, path, err)
+		dbp.unfollow()
+		return
+	}
+	if err := dbp.group.targets.AddTarget(tgt, parentPid); err != nil {
+		log.Warn("new target %d: %v", dbp.pid, err)
+	}
+}
+
+// unfollow detaches from dbp leaving it running.
+func (dbp *nativeProcess) unfollow() {
+	dbp.execPtraceFunc(func() {
+		for tid := range dbp.threads {
+			_ = ptraceDetach(tid, 0)
+		}
+	})
+	dbp.detached = true
+	dbp.postExit()
+}
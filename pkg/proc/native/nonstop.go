@@ -0,0 +1,217 @@
+package native
+
+import (
+	"bytes"
+	"errors"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
+)
+
+// errWatchRunning is returned when a watchpoint is set while some threads
+// are running, the debug registers of a running thread can not be changed.
+var errWatchRunning = errors.New("can not set watchpoints while threads are running in non-stop mode")
+
+// SetNonStop enables or disables non-stop mode, see proc.Target.SetNonStop.
+// Disabling it stops the threads that are still running.
+func (dbp *nativeProcess) SetNonStop(enabled bool) error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	if !enabled && dbp.group.nonStop {
+		if err := dbp.stopRunning(); err != nil {
+			return err
+		}
+	}
+	dbp.group.nonStop = enabled
+	dbp.group.cont = proc.ContinueMode{}
+	return nil
+}
+
+// SetContinueMode changes which threads are resumed by ContinueOnce in
+// non-stop mode.
+func (dbp *nativeProcess) SetContinueMode(mode proc.ContinueMode) {
+	dbp.group.cont = mode
+}
+
+// StopThreads stops the threads that are running in non-stop mode.
+func (dbp *nativeProcess) StopThreads() error {
+	if dbp.exited {
+		return proc.ErrProcessExited{Pid: dbp.pid}
+	}
+	return dbp.stopRunning()
+}
+
+// continueOnceNonStop is ContinueOnce in non-stop mode: the stopped threads
+// selected by the continue mode are resumed and only the thread that stops
+// is halted.
+func (dbp *nativeProcess) continueOnceNonStop() (proc.Thread, proc.StopReason, error) {
+	mode := dbp.group.cont
+	for {
+		if !mode.NoResume {
+			if err := dbp.resumeStopped(mode.ThreadID); err != nil {
+				return nil, proc.StopUnknown, err
+			}
+		}
+
+		if dbp.resumeChan != nil {
+			close(dbp.resumeChan)
+			dbp.resumeChan = nil
+		}
+
+		var opts trapWaitOptions
+		if mode.NoHang {
+			opts = trapWaitNohang
+		}
+		trapthread, err := dbp.trapWaitInternal(-1, opts)
+		if err != nil {
+			return nil, proc.StopUnknown, err
+		}
+		if trapthread == nil {
+			dbp.group.updateRunning()
+			return nil, proc.StopUnknown, nil
+		}
+		trapthread, err = dbp.stopNonStop(trapthread)
+		if err != nil {
+			return nil, proc.StopUnknown, err
+		}
+		dbp.group.updateRunning()
+		if trapthread != nil {
+			trapthread.dbp.memthread = trapthread
+			return trapthread, trapthread.stopReason(), nil
+		}
+		// the thread was resumed, wait for the next stop
+		mode.NoResume = true
+	}
+}
+
+// resumeStopped resumes the stopped threads of the group, or only thread
+// tid if it isn't zero. Threads stopped over a breakpoint are made to step
+// over it first.
+func (dbp *nativeProcess) resumeStopped(tid int) error {
+	for _, th := range dbp.group.threads() {
+		if th.os.running || (tid != 0 && th.ID != tid) {
+			continue
+		}
+		if th.mustStepOverBreakpoint() {
+			if err := th.StepInstruction(); err != nil {
+				return err
+			}
+		}
+		th.CurrentBreakpoint.Clear()
+		if err := th.resume(); err != nil && err != sys.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopNonStop handles the stop of trapthread in non-stop mode, the other
+// threads are left running unless a manual stop was requested. It returns
+// nil if the stop was caused by a breakpoint that has since been removed,
+// in that case the thread is resumed.
+func (dbp *nativeProcess) stopNonStop(trapthread *nativeThread) (*nativeThread, error) {
+	p := trapthread.dbp
+	if p.exited {
+		return nil, proc.ErrProcessExited{Pid: p.pid}
+	}
+
+	dbp.group.stopMu.Lock()
+	manualStop := dbp.group.manualStopRequested
+	dbp.group.stopMu.Unlock()
+	if manualStop {
+		// a halt stops every thread
+		return dbp.stop(trapthread)
+	}
+
+	// memory can only be accessed through a stopped thread
+	p.memthread = trapthread
+	if !p.waitingExec {
+		if err := linutil.ElfUpdateSharedObjects(p); err != nil {
+			return nil, err
+		}
+	}
+
+	if !trapthread.os.setbp {
+		// stopped by a signal, see trapWaitInternal
+		return trapthread, nil
+	}
+	trapthread.os.setbp = false
+	if err := trapthread.SetCurrentBreakpoint(true); err != nil {
+		return nil, err
+	}
+	if trapthread.CurrentBreakpoint.Breakpoint != nil {
+		return trapthread, nil
+	}
+
+	bi := p.BinInfo()
+	pc, err := trapthread.PC()
+	if err != nil {
+		return nil, err
+	}
+	for _, bpinstr := range [][]byte{bi.Arch.BreakpointInstruction(), bi.Arch.AltBreakpointInstruction()} {
+		if bpinstr == nil {
+			continue
+		}
+		buf := make([]byte, len(bpinstr))
+		_, _ = trapthread.ReadMemory(buf, pc-uint64(len(buf)))
+		if bytes.Equal(buf, bpinstr) {
+			// hardcoded breakpoint
+			return trapthread, nil
+		}
+	}
+
+	// The breakpoint was removed after the thread hit it but before we
+	// received its signal, rewind the thread and let it run.
+	if err := trapthread.setPC(pc - uint64(len(bi.Arch.BreakpointInstruction()))); err != nil {
+		return nil, err
+	}
+	if err := trapthread.resume(); err != nil && err != sys.ESRCH {
+		return nil, err
+	}
+	return nil, nil
+}
+
+// stopRunning stops the threads of the group that are running.
+func (dbp *nativeProcess) stopRunning() error {
+	for _, th := range dbp.group.threads() {
+		if th.os.running {
+			if err := th.stop(); err != nil {
+				return th.dbp.exitGuard(err)
+			}
+		}
+	}
+	for {
+		allstopped := true
+		for _, th := range dbp.group.threads() {
+			if th.os.running {
+				allstopped = false
+				break
+			}
+		}
+		if allstopped {
+			break
+		}
+		if _, err := dbp.trapWaitInternal(-1, trapWaitHalt); err != nil {
+			return err
+		}
+	}
+	dbp.group.updateRunning()
+	return nil
+}
+
+// updateRunning copies the running state of every thread of the group to
+// the state seen by package proc and makes sure that, when possible, the
+// memory of each process is accessed through a stopped thread.
+func (grp *processGroup) updateRunning() {
+	for _, p := range grp.procs {
+		for _, th := range p.threads {
+			th.common.Running = th.os.running
+			if !th.os.running && (p.memthread == nil || p.memthread.os.running) {
+				p.memthread = th
+			}
+		}
+	}
+}
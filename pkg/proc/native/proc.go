@@ -7,7 +7,6 @@ import (
 	"regexp"
 	"runtime"
 	"strings"
-	"sync"
 
 	sys "golang.org/x/sys/unix"
 
@@ -54,16 +53,29 @@ type nativeProcess struct {
 	resumeChan     chan<- struct{}
 	ptraceChan     chan func()
 	ptraceDoneChan chan interface{}
-	childProcess   bool       // this process was launched, not attached to
-	stopMu         sync.Mutex // protects manualStopRequested
-	// manualStopRequested is set if all the threads in the process were
-	// signalled to stop as a result of a Halt API call. Used to disambiguate
-	// why a thread is found to have stopped.
-	manualStopRequested bool
+	childProcess   bool // this process was launched, not attached to
+
+	// group is the group of processes this process is traced with, see
+	// follow.go.
+	group *processGroup
+	// parentPid is the pid of the process that created this process, zero
+	// for the process that was launched or attached to.
+	parentPid int
+	// waitingExec is set for child processes that are only traced to follow
+	// their call to exec, they are not targets.
+	waitingExec bool
 
 	iscgo bool
 
 	exited, detached bool
+
+	// checkpoints created with Checkpoint, see checkpoint.go.
+	checkpoints      []*checkpoint
+	lastCheckpointID int
+
+	// software watchpoints and the pages protected for them, see swwatch.go.
+	swwatch []*softwareWatchpoint
+	swpages map[uint64]*watchPage
 }
 
 // newProcess returns an initialized Process struct. Before returning,
@@ -81,6 +93,7 @@ func newProcess(pid int) *nativeProcess {
 		ptraceDoneChan: make(chan interface{}),
 		bi:             proc.NewBinaryInfo(runtime.GOOS, runtime.GOARCH),
 	}
+	dbp.group = &processGroup{procs: []*nativeProcess{dbp}}
 	go dbp.handlePtraceFuncs()
 	return dbp
 }
@@ -98,7 +111,18 @@ func (dbp *nativeProcess) initialize(path string) (*proc.Target, error) {
 	if !dbp.childProcess {
 		stopReason = proc.StopAttached
 	}
-	return proc.NewTarget(dbp, dbp.pid, dbp.memthread, proc.NewTargetConfig{
+	tgt, err := dbp.newTarget(path, stopReason)
+	if err != nil {
+		return nil, err
+	}
+	dbp.group.targets = tgt.Group()
+	return tgt, nil
+}
+
+// newTarget creates the proc.Target for dbp.
+func (dbp *nativeProcess) newTarget(path string, stopReason proc.StopReason) (*proc.Target, error) {
+	rec := newRecordingProcess(dbp)
+	return proc.NewTarget(rec, dbp.pid, rec.wrap(dbp.memthread), proc.NewTargetConfig{
 		Path:                path,
 		DisableAsyncPreempt: false,
 		StopReason:          stopReason,
@@ -133,6 +157,12 @@ func initialize(dbp *nativeProcess) error {
 	return nil
 }
 
+// Pid returns the pid of the process, it can change when a checkpoint is
+// restored.
+func (dbp *nativeProcess) Pid() int {
+	return dbp.pid
+}
+
 // BinInfo will return the binary info struct associated with this process.
 func (dbp *nativeProcess) BinInfo() *proc.BinaryInfo {
 	return dbp.bi
@@ -221,26 +251,34 @@ func (dbp *nativeProcess) RequestManualStop() error {
 	if dbp.exited {
 		return proc.ErrProcessExited{Pid: dbp.pid}
 	}
-	dbp.stopMu.Lock()
-	defer dbp.stopMu.Unlock()
-	dbp.manualStopRequested = true
+	dbp.group.stopMu.Lock()
+	defer dbp.group.stopMu.Unlock()
+	dbp.group.manualStopRequested = true
 	return dbp.requestManualStop()
 }
 
 // CheckAndClearManualStopRequest checks if a manual stop has
 // been requested, and then clears that state.
 func (dbp *nativeProcess) CheckAndClearManualStopRequest() bool {
-	dbp.stopMu.Lock()
-	defer dbp.stopMu.Unlock()
+	dbp.group.stopMu.Lock()
+	defer dbp.group.stopMu.Unlock()
 
-	msr := dbp.manualStopRequested
-	dbp.manualStopRequested = false
+	msr := dbp.group.manualStopRequested
+	dbp.group.manualStopRequested = false
 
 	return msr
 }
 
 func (dbp *nativeProcess) WriteBreakpoint(bp *proc.Breakpoint) error {
 	if bp.WatchType != 0 {
+		for _, thread := range dbp.threads {
+			if thread.os.running {
+				return errWatchRunning
+			}
+		}
+		if bp.SoftwareWatch {
+			return dbp.writeSoftwareWatchpoint(bp)
+		}
 		for _, thread := range dbp.threads {
 			err := thread.writeHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex)
 			if err != nil {
@@ -260,6 +298,9 @@ func (dbp *nativeProcess) WriteBreakpoint(bp *proc.Breakpoint) error {
 
 func (dbp *nativeProcess) EraseBreakpoint(bp *proc.Breakpoint) error {
 	if bp.WatchType != 0 {
+		if bp.SoftwareWatch {
+			return dbp.eraseSoftwareWatchpoint(bp)
+		}
 		for _, thread := range dbp.threads {
 			err := thread.clearHardwareBreakpoint(bp.Addr, bp.WatchType, bp.HWBreakIndex)
 			if err != nil {
@@ -279,13 +320,21 @@ func (dbp *nativeProcess) ContinueOnce() (proc.Thread, proc.StopReason, error) {
 		return nil, proc.StopExited, proc.ErrProcessExited{Pid: dbp.pid}
 	}
 
+	if dbp.group.nonStop {
+		return dbp.continueOnceNonStop()
+	}
+
 	for {
 
-		if err := dbp.resume(); err != nil {
-			return nil, proc.StopUnknown, err
+		// all processes of the group are resumed together
+		for _, p := range dbp.group.procs {
+			p.refreshSoftwareWatchpoints()
+			if err := p.resume(); err != nil {
+				return nil, proc.StopUnknown, err
+			}
 		}
 
-		for _, th := range dbp.threads {
+		for _, th := range dbp.group.threads() {
 			th.CurrentBreakpoint.Clear()
 		}
 
@@ -303,8 +352,8 @@ func (dbp *nativeProcess) ContinueOnce() (proc.Thread, proc.StopReason, error) {
 			return nil, proc.StopUnknown, err
 		}
 		if trapthread != nil {
-			dbp.memthread = trapthread
-			return trapthread, proc.StopUnknown, nil
+			trapthread.dbp.memthread = trapthread
+			return trapthread, trapthread.stopReason(), nil
 		}
 	}
 }
@@ -342,9 +391,9 @@ func (dbp *nativeProcess) execPtraceFunc(fn func()) {
 }
 
 func (dbp *nativeProcess) postExit() {
+	dbp.clearCheckpoints()
 	dbp.exited = true
-	close(dbp.ptraceChan)
-	close(dbp.ptraceDoneChan)
+	dbp.group.remove(dbp)
 	dbp.bi.Close()
 	dbp.os.Close()
 }
@@ -27,6 +27,13 @@ func (dbp *nativeProcess) GetBufferedTracepoints() []ebpf.RawUProbeParams {
 	return dbp.os.ebpf.GetBufferedTracepoints()
 }
 
+func (dbp *nativeProcess) GetLatencyHistograms() map[uint64][]uint64 {
+	if dbp.os.ebpf == nil {
+		return nil
+	}
+	return dbp.os.ebpf.GetLatencyHistograms()
+}
+
 // kill kills the target process.
 func (dbp *nativeProcess) kill() error {
 	if dbp.exited {
@@ -35,27 +42,29 @@ func (dbp *nativeProcess) kill() error {
 	if !dbp.threads[dbp.pid].Stopped() {
 		return errors.New("process must be stopped in order to kill it")
 	}
-	// send SIGKILL to the every process in the same process group whose pgid is -dbp.pid,
-	// see `man 2 kill`.
-	if err := sys.Kill(-dbp.pid, sys.SIGKILL); err != nil {
-		return errors.New("could not deliver signal " + err.Error())
+	// send SIGKILL to the every process in the same process group whose pgid is -pgid,
+	// see `man 2 kill`. The pgid is not necessarily dbp.pid after a checkpoint
+	// has been restored.
+	// Followed child processes are killed individually.
+	pgid, err := sys.Getpgid(dbp.pid)
+	if err != nil {
+		pgid = dbp.pid
 	}
-	// wait for other threads first or the thread group leader (dbp.pid) will never exit.
-	for threadID := range dbp.threads {
-		if threadID != dbp.pid {
-			dbp.wait(threadID, 0)
-		}
+	killpid := -pgid
+	if dbp.parentPid != 0 {
+		killpid = dbp.pid
 	}
-	for {
-		wpid, status, err := dbp.wait(dbp.pid, 0)
-		if err != nil {
-			return err
-		}
-		if wpid == dbp.pid && status != nil && status.Signaled() && status.Signal() == sys.SIGKILL {
-			dbp.postExit()
-			return nil
-		}
+	if dbp.group.stopOnExit {
+		dbp.letExit()
+	}
+	if err := sys.Kill(killpid, sys.SIGKILL); err != nil {
+		return errors.New("could not deliver signal " + err.Error())
 	}
+	if err := dbp.waitKilled(); err != nil {
+		return err
+	}
+	dbp.postExit()
+	return nil
 }
 
 func (dbp *nativeProcess) requestManualStop() (err error) {
@@ -88,12 +97,13 @@ func (dbp *nativeProcess) addThread(tid int, attach bool) (*nativeThread, error)
 		}
 	}
 
-	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+	opts := dbp.group.ptraceOptions()
+	dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, opts) })
 	if err == syscall.ESRCH {
 		if _, _, err = dbp.waitFast(tid); err != nil {
 			return nil, fmt.Errorf("error while waiting after adding thread: %d %s", tid, err)
 		}
-		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, syscall.PTRACE_O_TRACECLONE) })
+		dbp.execPtraceFunc(func() { err = syscall.PtraceSetOptions(tid, opts) })
 		if err == syscall.ESRCH {
 			return nil, err
 		}
@@ -166,26 +176,35 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 			}
 			continue
 		}
-		th, ok := dbp.threads[wpid]
+		// the thread could belong to any of the processes of the group
+		p := dbp.group.procForThread(wpid)
+		if p == nil {
+			p = dbp
+		}
+		th, ok := p.threads[wpid]
 		if ok {
 			th.Status = (*waitStatus)(status)
 		}
 		if status.Exited() {
-			if wpid == dbp.pid {
-				dbp.postExit()
-				return nil, proc.ErrProcessExited{Pid: wpid, Status: status.ExitStatus()}
+			if wpid == p.pid {
+				if err := dbp.group.processExited(p, status.ExitStatus()); err != nil {
+					return nil, err
+				}
+				continue
 			}
-			delete(dbp.threads, wpid)
+			delete(p.threads, wpid)
 			continue
 		}
 		if status.Signaled() {
 			// Signaled means the thread was terminated due to a signal.
-			if wpid == dbp.pid {
-				dbp.postExit()
-				return nil, proc.ErrProcessExited{Pid: wpid, Status: -int(status.Signal())}
+			if wpid == p.pid {
+				if err := dbp.group.processExited(p, -int(status.Signal())); err != nil {
+					return nil, err
+				}
+				continue
 			}
 			// does this ever happen?
-			delete(dbp.threads, wpid)
+			delete(p.threads, wpid)
 			continue
 		}
 		if status.StopSignal() == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_CLONE {
@@ -200,40 +219,119 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 				}
 				return nil, fmt.Errorf("could not get event message: %s", err)
 			}
-			th, err = dbp.addThread(int(cloned), false)
+			th, err = p.addThread(int(cloned), false)
 			if err != nil {
 				if err == sys.ESRCH {
 					// thread died while we were adding it
-					delete(dbp.threads, int(cloned))
+					delete(p.threads, int(cloned))
 					continue
 				}
 				return nil, err
 			}
 			if halt {
 				th.os.running = false
-				dbp.threads[int(wpid)].os.running = false
+				p.threads[int(wpid)].os.running = false
 				return nil, nil
 			}
 			if err = th.Continue(); err != nil {
 				if err == sys.ESRCH {
 					// thread died while we were adding it
-					delete(dbp.threads, th.ID)
+					delete(p.threads, th.ID)
 					continue
 				}
 				return nil, fmt.Errorf("could not continue new thread %d %s", cloned, err)
 			}
-			if err = dbp.threads[int(wpid)].Continue(); err != nil {
+			if err = p.threads[int(wpid)].Continue(); err != nil {
 				if err != sys.ESRCH {
 					return nil, fmt.Errorf("could not continue existing thread %d %s", wpid, err)
 				}
 			}
 			continue
 		}
+		if status.StopSignal() == sys.SIGTRAP && (status.TrapCause() == sys.PTRACE_EVENT_FORK || status.TrapCause() == sys.PTRACE_EVENT_VFORK) {
+			// A followed process has created a new process.
+			child, err := p.handleFork(wpid, status.TrapCause() == sys.PTRACE_EVENT_VFORK)
+			if err != nil {
+				if err == sys.ESRCH {
+					// the process died while we were adding it
+					continue
+				}
+				return nil, fmt.Errorf("could not follow new process: %v", err)
+			}
+			var childth *nativeThread
+			if !child.exited {
+				childth = child.threads[child.pid]
+			}
+			if halt {
+				if childth != nil {
+					childth.os.running = false
+				}
+				th.os.running = false
+				return nil, nil
+			}
+			if childth != nil {
+				if err := childth.Continue(); err != nil && err != sys.ESRCH {
+					return nil, fmt.Errorf("could not continue new process %d %s", child.pid, err)
+				}
+			}
+			if err := th.Continue(); err != nil && err != sys.ESRCH {
+				return nil, fmt.Errorf("could not continue existing thread %d %s", wpid, err)
+			}
+			continue
+		}
+		if status.StopSignal() == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_EXEC {
+			// A followed process has called exec, it's replaced by a new process
+			// for the new program.
+			np, err := p.handleExec()
+			if err != nil {
+				if err == sys.ESRCH {
+					continue
+				}
+				return nil, fmt.Errorf("could not follow exec: %v", err)
+			}
+			if np.exited {
+				continue
+			}
+			th = np.threads[np.pid]
+			if halt {
+				th.os.running = false
+				return nil, nil
+			}
+			if err := th.Continue(); err != nil && err != sys.ESRCH {
+				return nil, fmt.Errorf("could not continue process %d after exec %s", np.pid, err)
+			}
+			continue
+		}
 		if th == nil {
 			// Sometimes we get an unknown thread, ignore it?
 			continue
 		}
-		if (halt && status.StopSignal() == sys.SIGSTOP) || (status.StopSignal() == sys.SIGTRAP) {
+		if status.StopSignal() == sys.SIGTRAP && status.TrapCause() == sys.PTRACE_EVENT_EXIT {
+			stop, err := p.handleExitStop(th)
+			if stop {
+				return th, nil
+			}
+			if err != nil && err != sys.ESRCH {
+				return nil, err
+			}
+			continue
+		}
+		if status.StopSignal() == syscallTrap {
+			stop, err := p.handleSyscallStop(th, halt)
+			if stop {
+				return th, nil
+			}
+			if err != nil && err != sys.ESRCH {
+				return nil, err
+			}
+			continue
+		}
+		if status.StopSignal() == sys.SIGSEGV && p.isSoftwareWatchFault(th) {
+			// The fault is handled by stop, once all threads are stopped.
+			th.os.running = false
+			return th, nil
+		}
+		if (halt && status.StopSignal() == sys.SIGSTOP) || (status.StopSignal() == sys.SIGTRAP && !p.waitingExec) {
 			th.os.running = false
 			if status.StopSignal() == sys.SIGTRAP {
 				th.os.setbp = true
@@ -241,26 +339,20 @@ func (dbp *nativeProcess) trapWaitInternal(pid int, options trapWaitOptions) (*n
 			return th, nil
 		}
 
-		// TODO(dp) alert user about unexpected signals here.
-		if halt && !th.os.running {
-			// We are trying to stop the process, queue this signal to be delivered
-			// to the thread when we resume.
-			// Do not do this for threads that were running because we sent them a
-			// STOP signal and we need to observe it so we don't mistakenly deliver
-			// it later.
-			th.os.delayedSignal = int(status.StopSignal())
-			th.os.running = false
+		if stop, err := p.handleSignal(th, int(status.StopSignal()), halt); stop {
 			return th, nil
-		} else if err := th.resumeWithSig(int(status.StopSignal())); err != nil {
+		} else if err != nil {
 			if err != sys.ESRCH {
 				return nil, err
 			}
 			// do the same thing we do if a thread quit
-			if wpid == dbp.pid {
-				dbp.postExit()
-				return nil, proc.ErrProcessExited{Pid: wpid, Status: status.ExitStatus()}
+			if wpid == p.pid {
+				if err := dbp.group.processExited(p, status.ExitStatus()); err != nil {
+					return nil, err
+				}
+				continue
 			}
-			delete(dbp.threads, wpid)
+			delete(p.threads, wpid)
 		}
 	}
 }
@@ -341,12 +433,12 @@ func (dbp *nativeProcess) exitGuard(err error) error {
 func (dbp *nativeProcess) resume() error {
 	// all threads stopped over a breakpoint are made to step over it
 	for _, thread := range dbp.threads {
-		if thread.CurrentBreakpoint.Breakpoint != nil {
+		if thread.mustStepOverBreakpoint() {
 			if err := thread.StepInstruction(); err != nil {
 				return err
 			}
-			thread.CurrentBreakpoint.Clear()
 		}
+		thread.CurrentBreakpoint.Clear()
 	}
 	// everything is resumed
 	for _, thread := range dbp.threads {
@@ -359,14 +451,16 @@ func (dbp *nativeProcess) resume() error {
 
 // stop stops all running threads and sets breakpoints
 func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error) {
-	if dbp.exited {
+	if dbp.exited || trapthread.dbp.exited {
 		return nil, proc.ErrProcessExited{Pid: dbp.pid}
 	}
 
-	for _, th := range dbp.threads {
+	for _, th := range dbp.group.threads() {
 		th.os.setbp = false
 	}
-	trapthread.os.setbp = true
+	// a thread stopped by a signal, a system call or an exit did not hit a
+	// breakpoint
+	trapthread.os.setbp = trapthread.common.Signal == nil && trapthread.common.Syscall == nil && trapthread.common.Exit == nil
 
 	// check if any other thread simultaneously received a SIGTRAP
 	for {
@@ -380,10 +474,10 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 	}
 
 	// stop all threads that are still running
-	for _, th := range dbp.threads {
+	for _, th := range dbp.group.threads() {
 		if th.os.running {
 			if err := th.stop(); err != nil {
-				return nil, dbp.exitGuard(err)
+				return nil, th.dbp.exitGuard(err)
 			}
 		}
 	}
@@ -391,7 +485,7 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 	// wait for all threads to stop
 	for {
 		allstopped := true
-		for _, th := range dbp.threads {
+		for _, th := range dbp.group.threads() {
 			if th.os.running {
 				allstopped = false
 				break
@@ -406,20 +500,45 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 		}
 	}
 
-	if err := linutil.ElfUpdateSharedObjects(dbp); err != nil {
-		return nil, err
+	switchTrapthread := false
+
+	// execute the instructions that faulted on software watchpoint pages
+	for _, th := range dbp.group.threads() {
+		if !th.os.swfault {
+			continue
+		}
+		bp, err := th.dbp.stepSoftwareWatch(th)
+		if err != nil {
+			return nil, th.dbp.exitGuard(err)
+		}
+		th.os.swhit = bp
+		th.os.setbp = bp != nil
+		if bp == nil && th.ThreadID() == trapthread.ThreadID() {
+			switchTrapthread = true
+		}
 	}
 
-	switchTrapthread := false
+	for _, p := range dbp.group.procs {
+		if p.waitingExec {
+			continue
+		}
+		if err := linutil.ElfUpdateSharedObjects(p); err != nil {
+			return nil, err
+		}
+	}
 
 	// set breakpoints on SIGTRAP threads
 	var err1 error
-	for _, th := range dbp.threads {
+	for _, th := range dbp.group.threads() {
+		if th.dbp.waitingExec {
+			continue
+		}
+		bi := th.dbp.BinInfo()
 		pc, _ := th.PC()
 
 		if !th.os.setbp && pc != th.os.phantomBreakpointPC {
 			// check if this could be a breakpoint hit anyway that the OS hasn't notified us about, yet.
-			if _, ok := dbp.FindBreakpoint(pc, dbp.BinInfo().Arch.BreakInstrMovesPC()); ok {
+			if _, ok := th.dbp.FindBreakpoint(pc, bi.Arch.BreakInstrMovesPC()); ok {
 				th.os.phantomBreakpointPC = pc
 			}
 		}
@@ -435,12 +554,12 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 			}
 		}
 
-		if th.CurrentBreakpoint.Breakpoint == nil && th.os.setbp && (th.Status != nil) && ((*sys.WaitStatus)(th.Status).StopSignal() == sys.SIGTRAP) && dbp.BinInfo().Arch.BreakInstrMovesPC() {
+		if th.CurrentBreakpoint.Breakpoint == nil && th.os.setbp && (th.Status != nil) && ((*sys.WaitStatus)(th.Status).StopSignal() == sys.SIGTRAP) && bi.Arch.BreakInstrMovesPC() {
 			manualStop := false
 			if th.ThreadID() == trapthread.ThreadID() {
-				dbp.stopMu.Lock()
-				manualStop = dbp.manualStopRequested
-				dbp.stopMu.Unlock()
+				dbp.group.stopMu.Lock()
+				manualStop = dbp.group.manualStopRequested
+				dbp.group.stopMu.Unlock()
 			}
 			if !manualStop && th.os.phantomBreakpointPC == pc {
 				// Thread received a SIGTRAP but we don't have a breakpoint for it and
@@ -451,8 +570,8 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 				isHardcodedBreakpoint := false
 				pc, _ := th.PC()
 				for _, bpinstr := range [][]byte{
-					dbp.BinInfo().Arch.BreakpointInstruction(),
-					dbp.BinInfo().Arch.AltBreakpointInstruction()} {
+					bi.Arch.BreakpointInstruction(),
+					bi.Arch.AltBreakpointInstruction()} {
 					if bpinstr == nil {
 						continue
 					}
@@ -465,7 +584,7 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 				}
 				if !isHardcodedBreakpoint {
 					// phantom breakpoint hit
-					_ = th.setPC(pc - uint64(len(dbp.BinInfo().Arch.BreakpointInstruction())))
+					_ = th.setPC(pc - uint64(len(bi.Arch.BreakpointInstruction())))
 					th.os.setbp = false
 					if trapthread.ThreadID() == th.ThreadID() {
 						// Will switch to a different thread for trapthread because we don't
@@ -481,10 +600,20 @@ func (dbp *nativeProcess) stop(trapthread *nativeThread) (*nativeThread, error)
 		return nil, err1
 	}
 
+	if trapthread.common.Exit != nil && !trapthread.os.exitCaller {
+		// the process is exiting, the thread that called exit is more
+		// interesting than the ones killed by it.
+		for _, th := range dbp.group.threads() {
+			if th.common.Exit != nil && th.os.exitCaller {
+				return th, nil
+			}
+		}
+	}
+
 	if switchTrapthread {
 		trapthreadID := trapthread.ID
 		trapthread = nil
-		for _, th := range dbp.threads {
+		for _, th := range dbp.group.threads() {
 			if th.os.setbp && th.ThreadID() != trapthreadID {
 				return th, nil
 			}
@@ -526,14 +655,20 @@ func (dbp *nativeProcess) EntryPoint() (uint64, error) {
 	return linutil.EntryPointFromAuxv(auxvbuf, dbp.bi.Arch.PtrSize()), nil
 }
 
-func (dbp *nativeProcess) SetUProbe(fnName string, goidOffset int64, args []ebpf.UProbeArgMap) error {
-	// Lazily load and initialize the BPF program upon request to set a uprobe.
-	if dbp.os.ebpf == nil {
-		var err error
-		dbp.os.ebpf, err = ebpf.LoadEBPFTracingProgram(dbp.bi.Images[0].Path)
-		if err != nil {
-			return err
-		}
+// loadEBPF lazily loads and initializes the BPF program upon request to set
+// a uprobe.
+func (dbp *nativeProcess) loadEBPF() error {
+	if dbp.os.ebpf != nil {
+		return nil
+	}
+	var err error
+	dbp.os.ebpf, err = ebpf.LoadEBPFTracingProgram(dbp.bi.Images[0].Path)
+	return err
+}
+
+func (dbp *nativeProcess) SetUProbe(fnName string, goidOffset int64, args []ebpf.UProbeArgMap, cfg ebpf.UProbeLoadConfig) error {
+	if err := dbp.loadEBPF(); err != nil {
+		return err
 	}
 
 	// We only allow up to 12 args for a BPF probe.
@@ -549,7 +684,7 @@ func (dbp *nativeProcess) SetUProbe(fnName string, goidOffset int64, args []ebpf
 	}
 
 	key := fn.Entry
-	err := dbp.os.ebpf.UpdateArgMap(key, goidOffset, args, dbp.BinInfo().GStructOffset(), false)
+	err := dbp.os.ebpf.UpdateArgMap(key, fn.Entry, goidOffset, args, cfg, dbp.BinInfo().GStructOffset(), false)
 	if err != nil {
 		return err
 	}
@@ -584,7 +719,7 @@ func (dbp *nativeProcess) SetUProbe(fnName string, goidOffset int64, args []ebpf
 	}
 	addrs = append(addrs, proc.FindDeferReturnCalls(instructions)...)
 	for _, addr := range addrs {
-		err := dbp.os.ebpf.UpdateArgMap(addr, goidOffset, args, dbp.BinInfo().GStructOffset(), true)
+		err := dbp.os.ebpf.UpdateArgMap(addr, fn.Entry, goidOffset, args, cfg, dbp.BinInfo().GStructOffset(), true)
 		if err != nil {
 			return err
 		}
@@ -605,3 +740,37 @@ func (dbp *nativeProcess) SetUProbe(fnName string, goidOffset int64, args []ebpf
 
 	return dbp.os.ebpf.AttachUprobe(dbp.pid, debugname, off)
 }
+
+// SetInlinedUProbe attaches a uprobe at addr, the first instruction of an
+// inlined call to fnName. Since the caller decides where the arguments of
+// an inlined call are stored args is specific to this call site. There is
+// no return probe for inlined calls.
+func (dbp *nativeProcess) SetInlinedUProbe(fnName string, addr uint64, goidOffset int64, args []ebpf.UProbeArgMap, cfg ebpf.UProbeLoadConfig) error {
+	if err := dbp.loadEBPF(); err != nil {
+		return err
+	}
+
+	if len(args) > 6 {
+		return fmt.Errorf("too many arguments in inlined call to %s, max is 6", fnName)
+	}
+
+	cfg.Inlined = true
+	err := dbp.os.ebpf.UpdateArgMap(addr, addr, goidOffset, args, cfg, dbp.BinInfo().GStructOffset(), false)
+	if err != nil {
+		return err
+	}
+
+	// The call site can be in any image, the offset is computed from the
+	// address in that image's file and the uprobe is attached to it.
+	img := dbp.BinInfo().PCToImage(addr)
+	f, err := elf.Open(img.Path)
+	if err != nil {
+		return fmt.Errorf("could not open elf file to resolve symbol offset: %w", err)
+	}
+	defer f.Close()
+	off, err := ebpf.AddressToOffset(f, addr-img.StaticBase)
+	if err != nil {
+		return err
+	}
+	return dbp.os.ebpf.AttachUprobe(dbp.pid, img.Path, off)
+}
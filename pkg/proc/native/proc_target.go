@@ -13,18 +13,49 @@ import (
 	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
 )
 
+// LaunchOptions control the standard file descriptors and the environment
+// of a process started by Launch.
+type LaunchOptions struct {
+	// TTY is the path of a terminal that becomes the controlling terminal
+	// and the standard file descriptors of the process, instead of the ones
+	// of the debugger.
+	TTY string
+	// Redirects are the paths of the files stdin, stdout and stderr are
+	// redirected to, an empty path leaves the file descriptor unchanged.
+	// Redirects take precedence over TTY.
+	Redirects [3]string
+	// Env are environment variables, in the KEY=VALUE form, added to the
+	// environment of the process.
+	Env []string
+	// ClearEnv makes the process start with only the variables in Env,
+	// instead of inheriting the environment of the debugger.
+	ClearEnv bool
+}
+
 // Launch creates and begins debugging a new process. First entry in
 // `cmd` is the program to run, and then rest are the arguments
 // to be supplied to that process. `wd` is working directory of the program.
 // If the DWARF information cannot be found in the binary, Delve will look
 // for external debug files in the directories passed in.
-func Launch(cmd []string, wd string, flags proc.LaunchFlags) (*proc.Target, error) {
+func Launch(cmd []string, wd string, flags proc.LaunchFlags, opts LaunchOptions) (*proc.Target, error) {
 	var (
 		process *exec.Cmd
 		err     error
 	)
 
-	foreground := flags&proc.LaunchForeground != 0
+	foreground := flags&proc.LaunchForeground != 0 && opts.TTY == ""
+
+	stdio, err := openStdio(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		for _, f := range stdio {
+			if f != nil {
+				f.Close()
+			}
+		}
+	}()
 
 	dbp := newProcess(0)
 	defer func() {
@@ -44,9 +75,6 @@ func Launch(cmd []string, wd string, flags proc.LaunchFlags) (*proc.Target, erro
 
 		process = exec.Command(cmd[0])
 		process.Args = cmd
-		process.Stdin = os.Stdin
-		process.Stdout = os.Stdout
-		process.Stderr = os.Stderr
 		process.SysProcAttr = &syscall.SysProcAttr{
 			Ptrace:     true,
 			Setpgid:    true,
@@ -55,9 +83,14 @@ func Launch(cmd []string, wd string, flags proc.LaunchFlags) (*proc.Target, erro
 		if foreground {
 			signal.Ignore(syscall.SIGTTOU, syscall.SIGTTIN)
 		}
-		if err := attachProcessToTTY(process); err != nil {
+		if err := attachProcessToTTY(process, stdio); err != nil {
 			return
 		}
+		if opts.ClearEnv {
+			process.Env = append([]string{}, opts.Env...)
+		} else if len(opts.Env) > 0 {
+			process.Env = append(os.Environ(), opts.Env...)
+		}
 		if wd != "" {
 			process.Dir = wd
 		}
@@ -79,6 +112,64 @@ func Launch(cmd []string, wd string, flags proc.LaunchFlags) (*proc.Target, erro
 	return tgt, nil
 }
 
+// openStdio opens the files that become stdin, stdout and stderr of a new
+// process, a nil file means the file descriptor of the debugger is used.
+func openStdio(opts LaunchOptions) (stdio [3]*os.File, err error) {
+	defer func() {
+		if err != nil {
+			for _, f := range stdio {
+				if f != nil {
+					f.Close()
+				}
+			}
+		}
+	}()
+	if opts.TTY != "" {
+		tty, err := os.OpenFile(opts.TTY, os.O_RDWR, 0)
+		if err != nil {
+			return stdio, fmt.Errorf("could not open tty: %v", err)
+		}
+		// each file is closed separately
+		for i := range stdio {
+			if stdio[i], err = dupFile(tty); err != nil {
+				tty.Close()
+				return stdio, err
+			}
+		}
+		tty.Close()
+	}
+	for i, path := range opts.Redirects {
+		if path == "" {
+			continue
+		}
+		var f *os.File
+		if i == 0 {
+			f, err = os.Open(path)
+		} else {
+			f, err = os.Create(path)
+		}
+		if err != nil {
+			return stdio, fmt.Errorf("could not redirect %s: %v", stdioNames[i], err)
+		}
+		if stdio[i] != nil {
+			stdio[i].Close()
+		}
+		stdio[i] = f
+	}
+	return stdio, nil
+}
+
+var stdioNames = [3]string{"stdin", "stdout", "stderr"}
+
+func dupFile(f *os.File) (*os.File, error) {
+	fd, err := syscall.Dup(int(f.Fd()))
+	if err != nil {
+		return nil, err
+	}
+	syscall.CloseOnExec(fd)
+	return os.NewFile(uintptr(fd), f.Name()), nil
+}
+
 // Attach to an existing process with the given PID.
 // Usually, go compiler/linker generate DWARF in the binary on Linux.
 // While on Darwin, DWARF may be generated into separate files.
@@ -118,13 +209,30 @@ func findExecutable(path string, pid int) string {
 	return path
 }
 
-func attachProcessToTTY(process *exec.Cmd) error {
+func attachProcessToTTY(process *exec.Cmd, stdio [3]*os.File) error {
 	process.Stdin = os.Stdin
 	process.Stdout = os.Stdout
 	process.Stderr = os.Stderr
+	if stdio[0] != nil {
+		process.Stdin = stdio[0]
+	}
+	if stdio[1] != nil {
+		process.Stdout = stdio[1]
+	}
+	if stdio[2] != nil {
+		process.Stderr = stdio[2]
+	}
 	process.SysProcAttr.Setpgid = false
 	process.SysProcAttr.Setsid = true
-	process.SysProcAttr.Setctty = true
+	// the controlling terminal is stdin of the process, unless it was
+	// redirected to a file
+	ctty, ok := process.Stdin.(*os.File)
+	process.SysProcAttr.Setctty = ok && isTerminal(ctty)
 
 	return nil
 }
+
+func isTerminal(f *os.File) bool {
+	_, err := sys.IoctlGetTermios(int(f.Fd()), sys.TCGETS)
+	return err == nil
+}
@@ -1,6 +1,7 @@
 package native
 
 import (
+	"encoding/binary"
 	"syscall"
 	"unsafe"
 
@@ -23,6 +24,11 @@ func ptraceDetach(tid, sig int) error {
 	return nil
 }
 
+// ptraceSyscall executes ptrace PTRACE_SYSCALL
+func ptraceSyscall(tid, sig int) error {
+	return sys.PtraceSyscall(tid, sig)
+}
+
 // ptraceCont executes ptrace PTRACE_CONT
 func ptraceCont(tid, sig int) error {
 	return sys.PtraceCont(tid, sig)
@@ -37,6 +43,50 @@ func ptraceSingleStep(pid, sig int) error {
 	return nil
 }
 
+// _SEGV_ACCERR is the si_code of a SIGSEGV caused by an access to a mapped
+// page that the access was not permitted on.
+const _SEGV_ACCERR = 2
+
+// siginfo contains the fields of a siginfo_t used by the debugger.
+type siginfo struct {
+	Signo int32
+	Errno int32
+	Code  int32
+	Addr  uint64 // faulting address for SIGSEGV, SIGBUS, SIGILL and SIGFPE
+}
+
+// ptraceGetSiginfo executes ptrace PTRACE_GETSIGINFO
+func ptraceGetSiginfo(tid int) (info siginfo, err error) {
+	var buf [128]byte
+	_, _, e1 := sys.Syscall6(sys.SYS_PTRACE, sys.PTRACE_GETSIGINFO, uintptr(tid), 0, uintptr(unsafe.Pointer(&buf[0])), 0, 0)
+	if e1 != 0 {
+		return info, e1
+	}
+	info.Signo = int32(binary.LittleEndian.Uint32(buf[0:]))
+	info.Errno = int32(binary.LittleEndian.Uint32(buf[4:]))
+	info.Code = int32(binary.LittleEndian.Uint32(buf[8:]))
+	info.Addr = binary.LittleEndian.Uint64(buf[16:])
+	return info, nil
+}
+
+const (
+	_PTRACE_GET_SYSCALL_INFO = 0x420e
+
+	_PTRACE_SYSCALL_INFO_ENTRY = 1
+	_PTRACE_SYSCALL_INFO_EXIT  = 2
+)
+
+// ptraceGetSyscallInfoOp executes ptrace PTRACE_GET_SYSCALL_INFO and returns
+// the op field of the ptrace_syscall_info struct, the kind of stop.
+func ptraceGetSyscallInfoOp(tid int) (uint8, error) {
+	var buf [88]byte
+	_, _, e1 := sys.Syscall6(sys.SYS_PTRACE, _PTRACE_GET_SYSCALL_INFO, uintptr(tid), uintptr(len(buf)), uintptr(unsafe.Pointer(&buf[0])), 0, 0)
+	if e1 != 0 {
+		return 0, e1
+	}
+	return buf[0], nil
+}
+
 // ptraceGetRegset returns floating point registers of the specified thread using PTRACE.
 //
 // See amd64_linux_fetch_inferior_registers in gdb/amd64-linux-nat.c.html
@@ -0,0 +1,784 @@
+package native
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/arch/x86/x86asm"
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/dwarf/op"
+	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc"
+	"github.com/hitzhangjie/dlv/pkg/proc/amd64util"
+	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
+)
+
+const (
+	// recordLogMaxSize is the maximum size, in bytes, of the instruction
+	// log. When it is exceeded the oldest instructions are forgotten.
+	recordLogMaxSize = 256 << 20
+
+	// maxRecordedWrite is the maximum number of bytes a single instruction
+	// can write and still be recorded.
+	maxRecordedWrite = 16 << 20
+)
+
+var errRecordingStart = errors.New("reached the beginning of the recording")
+
+// errUnrecordableSyscall is returned when the recorded thread is about to
+// execute a system call that can not be recorded, either because it could
+// block waiting for one of the other threads, which are stopped while
+// recording, or because it creates or replaces threads.
+type errUnrecordableSyscall struct {
+	nr uint64
+}
+
+func (err errUnrecordableSyscall) Error() string {
+	return fmt.Sprintf("system call %d can not be recorded", err.nr)
+}
+
+// recordingProcess wraps a nativeProcess adding the ability to record the
+// execution of one of its threads.
+//
+// While recording is on the recorded thread is executed one instruction at
+// a time, with all other threads stopped, and its registers along with the
+// memory overwritten by each instruction are saved into a bounded log.
+// The log is then used to run the thread backwards and to replay it
+// forward up to the point where the recording ended, see ContinueOnce.
+//
+// Executing the target without recording discards the log, changing the
+// target's memory or the registers of the recorded thread while replaying
+// discards the part of the log that follows the current position.
+type recordingProcess struct {
+	*nativeProcess
+
+	recording bool
+	tid       int // thread being recorded
+	dir       proc.Direction
+
+	log     []*recordEntry
+	logSize int
+	first   int // number of instructions dropped from the start of the log
+	pos     int // current position in log, len(log) at the live position
+
+	// live holds the registers of the recorded thread at the live position
+	// while the thread is somewhere in the log.
+	live *recordEntry
+
+	threads map[int]*recordingThread
+}
+
+// recordEntry is a recorded instruction.
+type recordEntry struct {
+	regs   linutil.AMD64PtraceRegs // registers before the instruction was executed
+	fpregs *amd64util.AMD64Xstate
+	mem    []memDelta
+	size   int
+}
+
+// memDelta is a memory write executed by a recorded instruction.
+type memDelta struct {
+	addr     uint64
+	old, new []byte
+}
+
+type memRange struct {
+	addr uint64
+	size int
+}
+
+func newRecordingProcess(dbp *nativeProcess) *recordingProcess {
+	return &recordingProcess{nativeProcess: dbp, threads: make(map[int]*recordingThread)}
+}
+
+// recordingThread wraps the threads of a recordingProcess so that
+// StepInstruction follows the execution direction.
+type recordingThread struct {
+	*nativeThread
+	rec *recordingProcess
+}
+
+// recordingMemory is the memory of a recordingProcess, writes made while
+// replaying diverge from the recording.
+type recordingMemory struct {
+	rec *recordingProcess
+}
+
+func (r *recordingProcess) wrap(th *nativeThread) *recordingThread {
+	if rt := r.threads[th.ID]; rt != nil && rt.nativeThread == th {
+		return rt
+	}
+	rt := &recordingThread{nativeThread: th, rec: r}
+	r.threads[th.ID] = rt
+	return rt
+}
+
+func (r *recordingProcess) wrapThread(th proc.Thread) proc.Thread {
+	if nth, ok := th.(*nativeThread); ok && nth != nil {
+		return r.wrap(nth)
+	}
+	return th
+}
+
+// ThreadList returns a list of threads in the process.
+func (r *recordingProcess) ThreadList() []proc.Thread {
+	out := make([]proc.Thread, 0, len(r.nativeProcess.threads))
+	for _, th := range r.nativeProcess.threads {
+		out = append(out, r.wrap(th))
+	}
+	return out
+}
+
+// FindThread attempts to find the thread with the specified ID.
+func (r *recordingProcess) FindThread(threadID int) (proc.Thread, bool) {
+	th, ok := r.nativeProcess.threads[threadID]
+	if !ok {
+		return nil, false
+	}
+	return r.wrap(th), true
+}
+
+// Memory returns the process memory.
+func (r *recordingProcess) Memory() proc.MemoryReadWriter {
+	return recordingMemory{r}
+}
+
+// StartCallInjection notifies the backend that we are about to inject a function call.
+func (r *recordingProcess) StartCallInjection() (func(), error) {
+	if r.pos < len(r.log) {
+		return nil, errors.New("can not call functions while replaying a recording")
+	}
+	return r.nativeProcess.StartCallInjection()
+}
+
+// StartRecording starts recording the instructions executed by thread tid.
+func (r *recordingProcess) StartRecording(tid int) error {
+	if r.exited {
+		return proc.ErrProcessExited{Pid: r.pid}
+	}
+	if _, ok := r.nativeProcess.threads[tid]; !ok {
+		return fmt.Errorf("thread %d does not exist", tid)
+	}
+	if len(r.swwatch) > 0 {
+		return errors.New("can not record while software watchpoints are set")
+	}
+	if r.recorded() && tid != r.tid {
+		if r.recording {
+			return fmt.Errorf("already recording thread %d", r.tid)
+		}
+		r.discard()
+	}
+	r.recording = true
+	r.tid = tid
+	return nil
+}
+
+// WriteBreakpoint writes bp to the target, software watchpoints can not be
+// used while recording because recorded threads are single stepped.
+func (r *recordingProcess) WriteBreakpoint(bp *proc.Breakpoint) error {
+	if bp.SoftwareWatch && r.recorded() {
+		return errors.New("not enough free hardware watchpoints, software watchpoints can not be used while recording")
+	}
+	return r.nativeProcess.WriteBreakpoint(bp)
+}
+
+// StopRecording stops recording, the instructions recorded so far can
+// still be replayed until the target is executed again.
+func (r *recordingProcess) StopRecording() error {
+	r.recording = false
+	return nil
+}
+
+// Recorded returns true while recording is on or there are recorded
+// instructions that can be replayed.
+func (r *recordingProcess) Recorded() (bool, string) { return r.recorded(), "" }
+
+func (r *recordingProcess) recorded() bool {
+	return r.recording || len(r.log) > 0
+}
+
+// ChangeDirection changes execution direction, going backwards is only
+// possible when something was recorded.
+func (r *recordingProcess) ChangeDirection(dir proc.Direction) error {
+	if dir == proc.Backward && !r.recorded() {
+		return proc.ErrNotRecorded
+	}
+	r.dir = dir
+	return nil
+}
+
+// GetDirection returns the current direction of execution.
+func (r *recordingProcess) GetDirection() proc.Direction { return r.dir }
+
+// When returns the position of the recorded thread in the recording.
+func (r *recordingProcess) When() (string, error) {
+	if !r.recorded() {
+		return "", nil
+	}
+	return fmt.Sprintf("instruction %d/%d", r.first+r.pos, r.first+len(r.log)), nil
+}
+
+// Restart moves the recorded thread to the specified position, in the
+// format returned by When, or restores a checkpoint. Restoring a
+// checkpoint discards the recording.
+func (r *recordingProcess) Restart(pos string) (proc.Thread, error) {
+	if strings.HasPrefix(pos, "c") {
+		r.discard()
+		th, err := r.nativeProcess.Restart(pos)
+		return r.wrapThread(th), err
+	}
+	var n int
+	if _, err := fmt.Sscanf(pos, "instruction %d", &n); err != nil {
+		return nil, fmt.Errorf("invalid position %q", pos)
+	}
+	if !r.recorded() || n < r.first || n > r.first+len(r.log) {
+		return nil, fmt.Errorf("position %d is not in the recording", n)
+	}
+	th, err := r.recordedThread()
+	if err != nil {
+		return nil, err
+	}
+	for r.first+r.pos > n {
+		if _, err := r.undo(th); err != nil {
+			return nil, err
+		}
+	}
+	for r.first+r.pos < n {
+		if _, err := r.redo(th); err != nil {
+			return nil, err
+		}
+	}
+	th.CurrentBreakpoint.Clear()
+	return r.wrap(th), nil
+}
+
+// ContinueOnce will continue the target until it stops.
+// While recording, or when the recorded thread is not at the live
+// position, only the recorded thread is executed.
+func (r *recordingProcess) ContinueOnce() (proc.Thread, proc.StopReason, error) {
+	if r.exited {
+		return nil, proc.StopExited, proc.ErrProcessExited{Pid: r.pid}
+	}
+	if r.dir == proc.Backward || r.pos < len(r.log) || r.recording {
+		return r.continueRecorded()
+	}
+	r.discard()
+	trapthread, stopReason, err := r.nativeProcess.ContinueOnce()
+	return r.wrapThread(trapthread), stopReason, err
+}
+
+// continueRecorded moves the recorded thread one instruction at a time, in
+// the current direction, until it reaches a breakpoint or a boundary of the
+// recording or a manual stop is requested.
+func (r *recordingProcess) continueRecorded() (proc.Thread, proc.StopReason, error) {
+	th, err := r.recordedThread()
+	if err != nil {
+		return nil, proc.StopUnknown, err
+	}
+	if r.resumeChan != nil {
+		close(r.resumeChan)
+		r.resumeChan = nil
+	}
+	for {
+		if r.manualStopPending() {
+			return r.wrap(th), proc.StopUnknown, nil
+		}
+		var stop bool
+		switch {
+		case r.dir == proc.Backward:
+			if r.pos == 0 {
+				log.Info("%v", errRecordingStart)
+				return r.stopAtBoundary(th)
+			}
+			stop, err = r.undo(th)
+		case r.pos < len(r.log):
+			stop, err = r.redo(th)
+		case r.recording:
+			stop, err = r.record(th)
+			if uerr, ok := err.(errUnrecordableSyscall); ok {
+				log.Warn("recording stopped: %v", uerr)
+				r.recording = false
+				return r.stopAtBoundary(th)
+			}
+		default:
+			log.Info("reached the end of the recording")
+			return r.stopAtBoundary(th)
+		}
+		if err != nil {
+			if _, exited := err.(proc.ErrProcessExited); exited {
+				return nil, proc.StopExited, err
+			}
+			return nil, proc.StopUnknown, err
+		}
+		if stop {
+			return r.wrap(th), proc.StopUnknown, nil
+		}
+	}
+}
+
+// stopAtBoundary stops the recorded thread at a boundary of the recording.
+// The stop is reported as a manual stop so that any next, step or stepout
+// operation in progress is cancelled.
+func (r *recordingProcess) stopAtBoundary(th *nativeThread) (proc.Thread, proc.StopReason, error) {
+	th.CurrentBreakpoint.Clear()
+	r.group.stopMu.Lock()
+	r.group.manualStopRequested = true
+	r.group.stopMu.Unlock()
+	return r.wrap(th), proc.StopUnknown, nil
+}
+
+func (r *recordingProcess) manualStopPending() bool {
+	r.group.stopMu.Lock()
+	defer r.group.stopMu.Unlock()
+	return r.group.manualStopRequested
+}
+
+func (r *recordingProcess) recordedThread() (*nativeThread, error) {
+	th, ok := r.nativeProcess.threads[r.tid]
+	if !ok {
+		return nil, fmt.Errorf("recorded thread %d does not exist anymore", r.tid)
+	}
+	return th, nil
+}
+
+// discard forgets everything that was recorded.
+func (r *recordingProcess) discard() {
+	r.log = nil
+	r.logSize = 0
+	r.first = 0
+	r.pos = 0
+	r.live = nil
+	r.dir = proc.Forward
+}
+
+// diverge is called before the state of the target is changed by
+// something other than the recorded thread, the part of the recording that
+// follows the current position is discarded.
+func (r *recordingProcess) diverge() {
+	if r.pos >= len(r.log) {
+		return
+	}
+	log.Warn("target modified while replaying, discarding %d recorded instructions", len(r.log)-r.pos)
+	for _, e := range r.log[r.pos:] {
+		r.logSize -= e.size
+	}
+	r.log = r.log[:r.pos]
+	r.live = nil
+}
+
+// record executes one instruction of th and appends it to the log.
+// Returns true if th stopped at a breakpoint.
+func (r *recordingProcess) record(th *nativeThread) (bool, error) {
+	e, err := r.saveRegisters(th)
+	if err != nil {
+		return false, err
+	}
+	inst, err := r.decode(th, e.regs.Rip)
+	if err != nil {
+		return false, err
+	}
+	ranges, err := writtenRanges(&inst, &e.regs)
+	if err != nil {
+		return false, err
+	}
+	for _, rng := range ranges {
+		buf := make([]byte, rng.size)
+		if _, err := th.ReadMemory(buf, rng.addr); err != nil {
+			// the instruction does not actually access this memory (or will fault)
+			continue
+		}
+		e.mem = append(e.mem, memDelta{addr: rng.addr, old: buf})
+	}
+
+	if err := th.StepInstruction(); err != nil {
+		return false, err
+	}
+
+	changed := e.mem[:0]
+	for _, d := range e.mem {
+		buf := make([]byte, len(d.old))
+		if _, err := th.ReadMemory(buf, d.addr); err != nil {
+			// unmapped by the instruction
+			continue
+		}
+		if !bytes.Equal(buf, d.old) {
+			d.new = buf
+			changed = append(changed, d)
+			e.size += 2 * len(buf)
+		}
+	}
+	e.mem = changed
+	r.append(e)
+
+	if err := th.SetCurrentBreakpoint(false); err != nil {
+		return false, err
+	}
+	return th.CurrentBreakpoint.Breakpoint != nil, nil
+}
+
+// undo moves th back by one instruction.
+// Returns true if th stopped at a breakpoint.
+func (r *recordingProcess) undo(th *nativeThread) (bool, error) {
+	if r.pos == len(r.log) {
+		live, err := r.saveRegisters(th)
+		if err != nil {
+			return false, err
+		}
+		r.live = live
+	}
+	e := r.log[r.pos-1]
+	for i := len(e.mem) - 1; i >= 0; i-- {
+		if _, err := th.WriteMemory(e.mem[i].addr, e.mem[i].old); err != nil {
+			return false, err
+		}
+	}
+	if err := r.restoreRegisters(th, e); err != nil {
+		return false, err
+	}
+	r.pos--
+	return r.checkBreakpoint(th, e, e.regs.Rip), nil
+}
+
+// redo moves th forward by one instruction.
+// Returns true if th stopped at a breakpoint.
+func (r *recordingProcess) redo(th *nativeThread) (bool, error) {
+	e := r.log[r.pos]
+	for _, d := range e.mem {
+		if _, err := th.WriteMemory(d.addr, d.new); err != nil {
+			return false, err
+		}
+	}
+	next := r.live
+	if r.pos+1 < len(r.log) {
+		next = r.log[r.pos+1]
+	}
+	if err := r.restoreRegisters(th, next); err != nil {
+		return false, err
+	}
+	r.pos++
+	if r.pos == len(r.log) {
+		r.live = nil
+	}
+	return r.checkBreakpoint(th, e, next.regs.Rip), nil
+}
+
+// checkBreakpoint sets the current breakpoint of th after e was undone or
+// redone and th was moved to pc.
+func (r *recordingProcess) checkBreakpoint(th *nativeThread, e *recordEntry, pc uint64) bool {
+	th.CurrentBreakpoint.Clear()
+	for _, bp := range r.breakpoints.M {
+		if bp.WatchType.Write() && e.writes(bp.Addr, bp.WatchType.Size()) {
+			th.CurrentBreakpoint.Breakpoint = bp
+			return true
+		}
+	}
+	if bp, ok := r.FindBreakpoint(pc, false); ok && bp.WatchType == 0 {
+		th.CurrentBreakpoint.Breakpoint = bp
+		return true
+	}
+	return false
+}
+
+func (e *recordEntry) writes(addr uint64, size int) bool {
+	for _, d := range e.mem {
+		if d.addr < addr+uint64(size) && addr < d.addr+uint64(len(d.new)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *recordingProcess) append(e *recordEntry) {
+	r.log = append(r.log, e)
+	r.logSize += e.size
+	for r.logSize > recordLogMaxSize && len(r.log) > 1 {
+		r.logSize -= r.log[0].size
+		r.log[0] = nil
+		r.log = r.log[1:]
+		r.first++
+	}
+	r.pos = len(r.log)
+}
+
+// saveRegisters returns a new log entry containing the registers of th.
+func (r *recordingProcess) saveRegisters(th *nativeThread) (*recordEntry, error) {
+	e := &recordEntry{size: int(unsafe.Sizeof(recordEntry{}))}
+	var err error
+	r.execPtraceFunc(func() { err = sys.PtraceGetRegs(th.ID, (*sys.PtraceRegs)(&e.regs)) })
+	if err != nil {
+		return nil, err
+	}
+	var fpregs amd64util.AMD64Xstate
+	r.execPtraceFunc(func() { fpregs, err = ptraceGetRegset(th.ID) })
+	if err != nil {
+		return nil, err
+	}
+	// Most instructions do not change the floating point registers, share
+	// them with the previous instruction when possible.
+	if n := len(r.log); n > 0 && sameFpregs(r.log[n-1].fpregs, &fpregs) {
+		e.fpregs = r.log[n-1].fpregs
+	} else {
+		e.fpregs = &fpregs
+		e.size += int(unsafe.Sizeof(fpregs)) + len(fpregs.Xsave)
+	}
+	return e, nil
+}
+
+func sameFpregs(a, b *amd64util.AMD64Xstate) bool {
+	return a.AMD64PtraceFpRegs == b.AMD64PtraceFpRegs && bytes.Equal(a.Xsave, b.Xsave)
+}
+
+func (r *recordingProcess) restoreRegisters(th *nativeThread, e *recordEntry) error {
+	regs := e.regs
+	return th.restoreRegisters(&linutil.AMD64Registers{Regs: &regs, Fpregset: e.fpregs})
+}
+
+// decode decodes the instruction at pc, as it was before any breakpoint
+// was written over it.
+func (r *recordingProcess) decode(th *nativeThread, pc uint64) (x86asm.Inst, error) {
+	buf := make([]byte, r.bi.Arch.MaxInstructionLength())
+	n, err := th.ReadMemory(buf, pc)
+	if n == 0 {
+		return x86asm.Inst{}, err
+	}
+	if bp, ok := r.FindBreakpoint(pc, false); ok && bp.WatchType == 0 {
+		copy(buf, bp.Orig)
+	}
+	inst, err := x86asm.Decode(buf[:n], 64)
+	if err != nil {
+		return inst, fmt.Errorf("could not decode instruction at %#x: %v", pc, err)
+	}
+	return inst, nil
+}
+
+// writtenRanges returns the memory that could be written by inst.
+// Explicit memory operands are returned regardless of whether inst reads
+// or writes them, the caller will discard the ones that did not change.
+func writtenRanges(inst *x86asm.Inst, regs *linutil.AMD64PtraceRegs) ([]memRange, error) {
+	var out []memRange
+
+	switch inst.Op {
+	case x86asm.STOSB, x86asm.STOSW, x86asm.STOSD, x86asm.STOSQ,
+		x86asm.MOVSB, x86asm.MOVSW, x86asm.MOVSD, x86asm.MOVSQ:
+		sz := uint64(1)
+		switch inst.Op {
+		case x86asm.STOSW, x86asm.MOVSW:
+			sz = 2
+		case x86asm.STOSD, x86asm.MOVSD:
+			sz = 4
+		case x86asm.STOSQ, x86asm.MOVSQ:
+			sz = 8
+		}
+		count := uint64(1)
+		for _, p := range inst.Prefix {
+			if p == 0 {
+				break
+			}
+			if p&0xff == x86asm.PrefixREP {
+				count = regs.Rcx
+			}
+		}
+		if count == 0 {
+			return nil, nil
+		}
+		if count > maxRecordedWrite/sz {
+			return nil, fmt.Errorf("instruction at %#x writes too much memory to be recorded", regs.Rip)
+		}
+		start := regs.Rdi
+		if regs.Eflags&(1<<10) != 0 { // direction flag
+			start -= (count - 1) * sz
+		}
+		return []memRange{{start, int(count * sz)}}, nil
+
+	case x86asm.PUSH, x86asm.PUSHF, x86asm.PUSHFQ, x86asm.CALL:
+		out = append(out, memRange{regs.Rsp - 8, 8})
+
+	case x86asm.SYSCALL:
+		return syscallRanges(regs)
+
+	case x86asm.LEA:
+		return nil, nil
+	}
+
+	for _, arg := range inst.Args {
+		if arg == nil {
+			break
+		}
+		mem, ok := arg.(x86asm.Mem)
+		if !ok {
+			continue
+		}
+		size := inst.MemBytes
+		switch inst.Op {
+		case x86asm.FXSAVE, x86asm.FXSAVE64:
+			size = 512
+		case x86asm.XSAVE, x86asm.XSAVE64, x86asm.XSAVEOPT, x86asm.XSAVEOPT64:
+			size = amd64util.AMD64XstateMaxSize()
+		}
+		if size <= 0 {
+			size = 8
+		}
+		out = append(out, memRange{memAddr(mem, inst, regs), size})
+	}
+	return out, nil
+}
+
+// syscallRanges returns the memory written by the kernel during the system
+// call the thread is about to execute. Only system calls that read data
+// into user buffers are tracked, the kernel does not write the memory of the
+// target during any other system call executed by the Go runtime.
+func syscallRanges(regs *linutil.AMD64PtraceRegs) ([]memRange, error) {
+	const (
+		futexCmdMask       = ^uint64(128 | 256) // FUTEX_PRIVATE_FLAG | FUTEX_CLOCK_REALTIME
+		futexWait          = 0
+		futexLockPI        = 6
+		futexWaitBitset    = 9
+		futexWaitRequeuePI = 11
+	)
+	var size uint64
+	var addr uint64
+	switch regs.Rax {
+	case sys.SYS_READ, sys.SYS_PREAD64:
+		addr, size = regs.Rsi, regs.Rdx
+	case sys.SYS_GETRANDOM:
+		addr, size = regs.Rdi, regs.Rsi
+	case sys.SYS_FUTEX:
+		switch regs.Rsi & futexCmdMask {
+		case futexWait, futexLockPI, futexWaitBitset, futexWaitRequeuePI:
+			return nil, errUnrecordableSyscall{regs.Rax}
+		}
+		return nil, nil
+	case sys.SYS_NANOSLEEP, sys.SYS_CLOCK_NANOSLEEP, sys.SYS_PAUSE,
+		sys.SYS_EPOLL_WAIT, sys.SYS_EPOLL_PWAIT, sys.SYS_POLL, sys.SYS_PPOLL,
+		sys.SYS_SELECT, sys.SYS_PSELECT6, sys.SYS_RT_SIGSUSPEND, sys.SYS_RT_SIGTIMEDWAIT,
+		sys.SYS_WAIT4, sys.SYS_WAITID,
+		sys.SYS_CLONE, sys.SYS_CLONE3, sys.SYS_FORK, sys.SYS_VFORK,
+		sys.SYS_EXECVE, sys.SYS_EXECVEAT, sys.SYS_EXIT:
+		return nil, errUnrecordableSyscall{regs.Rax}
+	default:
+		return nil, nil
+	}
+	if size == 0 {
+		return nil, nil
+	}
+	if size > maxRecordedWrite {
+		return nil, errUnrecordableSyscall{regs.Rax}
+	}
+	return []memRange{{addr, int(size)}}, nil
+}
+
+func memAddr(mem x86asm.Mem, inst *x86asm.Inst, regs *linutil.AMD64PtraceRegs) uint64 {
+	addr := uint64(mem.Disp)
+	switch mem.Base {
+	case 0:
+	case x86asm.RIP:
+		addr += regs.Rip + uint64(inst.Len)
+	default:
+		addr += regValue(mem.Base, regs)
+	}
+	if mem.Index != 0 {
+		addr += regValue(mem.Index, regs) * uint64(mem.Scale)
+	}
+	switch mem.Segment {
+	case x86asm.FS:
+		addr += regs.Fs_base
+	case x86asm.GS:
+		addr += regs.Gs_base
+	}
+	if inst.AddrSize == 32 {
+		addr = uint64(uint32(addr))
+	}
+	return addr
+}
+
+func regValue(reg x86asm.Reg, regs *linutil.AMD64PtraceRegs) uint64 {
+	// in the same order as x86asm.RAX...x86asm.R15
+	gprs := [...]uint64{
+		regs.Rax, regs.Rcx, regs.Rdx, regs.Rbx, regs.Rsp, regs.Rbp, regs.Rsi, regs.Rdi,
+		regs.R8, regs.R9, regs.R10, regs.R11, regs.R12, regs.R13, regs.R14, regs.R15,
+	}
+	switch {
+	case reg >= x86asm.RAX && reg <= x86asm.R15:
+		return gprs[reg-x86asm.RAX]
+	case reg >= x86asm.EAX && reg <= x86asm.R15L:
+		return uint64(uint32(gprs[reg-x86asm.EAX]))
+	}
+	return 0
+}
+
+// StepInstruction steps a single instruction in the current direction of
+// execution.
+func (t *recordingThread) StepInstruction() error {
+	r := t.rec
+	if !r.recorded() || t.ID != r.tid {
+		if r.dir == proc.Backward {
+			return proc.ErrNotRecorded
+		}
+		if r.pos < len(r.log) {
+			return fmt.Errorf("can not step thread %d while replaying the recording of thread %d", t.ID, r.tid)
+		}
+		r.discard()
+		return t.nativeThread.StepInstruction()
+	}
+	var err error
+	switch {
+	case r.dir == proc.Backward:
+		if r.pos == 0 {
+			return errRecordingStart
+		}
+		_, err = r.undo(t.nativeThread)
+	case r.pos < len(r.log):
+		_, err = r.redo(t.nativeThread)
+	case r.recording:
+		_, err = r.record(t.nativeThread)
+		if _, ok := err.(errUnrecordableSyscall); ok {
+			r.recording = false
+		}
+	default:
+		r.discard()
+		err = t.nativeThread.StepInstruction()
+	}
+	return err
+}
+
+// SetCurrentBreakpoint sets the current breakpoint that this thread is
+// stopped at. Recorded instructions never execute a breakpoint so PC is
+// never adjusted while something is recorded.
+func (t *recordingThread) SetCurrentBreakpoint(adjustPC bool) error {
+	return t.nativeThread.SetCurrentBreakpoint(adjustPC && !t.rec.recorded())
+}
+
+// RestoreRegisters will set the value of the CPU registers to those
+// passed in via 'savedRegs'.
+func (t *recordingThread) RestoreRegisters(savedRegs proc.Registers) error {
+	if t.ID == t.rec.tid {
+		t.rec.diverge()
+	}
+	return t.nativeThread.RestoreRegisters(savedRegs)
+}
+
+// SetReg changes the value of the specified register.
+func (t *recordingThread) SetReg(regNum uint64, reg *op.DwarfRegister) error {
+	if t.ID == t.rec.tid {
+		t.rec.diverge()
+	}
+	return t.nativeThread.SetReg(regNum, reg)
+}
+
+// ProcessMemory returns the process memory.
+func (t *recordingThread) ProcessMemory() proc.MemoryReadWriter {
+	return recordingMemory{t.rec}
+}
+
+func (mem recordingMemory) ReadMemory(data []byte, addr uint64) (int, error) {
+	return mem.rec.memthread.ReadMemory(data, addr)
+}
+
+func (mem recordingMemory) WriteMemory(addr uint64, data []byte) (int, error) {
+	mem.rec.diverge()
+	return mem.rec.memthread.WriteMemory(addr, data)
+}
@@ -0,0 +1,100 @@
+package native
+
+import (
+	"syscall"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// SetSignalPolicy changes how signal sig is handled by all processes of
+// the group, see proc.SignalHandler.
+func (dbp *nativeProcess) SetSignalPolicy(sig int, policy proc.SignalPolicy) {
+	if dbp.group.signals == nil {
+		dbp.group.signals = make(map[int]proc.SignalPolicy)
+	}
+	dbp.group.signals[sig] = policy
+}
+
+func (procgrp *processGroup) signalPolicy(sig int) proc.SignalPolicy {
+	if policy, ok := procgrp.signals[sig]; ok {
+		return policy
+	}
+	return proc.DefaultSignalPolicy
+}
+
+// stopReason returns the stop reason reported by ContinueOnce when th is
+// the thread that stopped.
+func (t *nativeThread) stopReason() proc.StopReason {
+	if t.common.Signal != nil {
+		return proc.StopSignal
+	}
+	if t.common.Syscall != nil {
+		return proc.StopSyscall
+	}
+	if t.common.Exit != nil {
+		return proc.StopExiting
+	}
+	return proc.StopUnknown
+}
+
+// handleSignal applies the signal policy to th, which was stopped by the
+// delivery of signal sig. It returns true if th must stay stopped, in which
+// case the signal is delivered, if its policy says so, when th is resumed.
+// If halt is true th is being stopped by the debugger and it is never kept
+// stopped here, the SIGSTOP sent to it must be observed first.
+func (dbp *nativeProcess) handleSignal(th *nativeThread, sig int, halt bool) (stop bool, err error) {
+	policy := dbp.group.signalPolicy(sig)
+	if policy.Print && !policy.Stop {
+		log.Info("Thread %d received signal %s", th.ID, sys.SignalName(syscall.Signal(sig)))
+	}
+
+	if halt && !th.os.running {
+		// We are trying to stop the process, queue this signal to be delivered
+		// to the thread when we resume.
+		// Do not do this for threads that were running because we sent them a
+		// STOP signal and we need to observe it so we don't mistakenly deliver
+		// it later.
+		if policy.Pass {
+			th.os.delayedSignal = sig
+		}
+		th.os.running = false
+		return true, nil
+	}
+
+	if !policy.Stop {
+		if !policy.Pass {
+			sig = 0
+		}
+		return false, th.resumeWithSig(sig)
+	}
+
+	info := &proc.SignalInfo{Signo: sig}
+	var si siginfo
+	var sierr error
+	dbp.execPtraceFunc(func() { si, sierr = ptraceGetSiginfo(th.ID) })
+	if sierr == nil {
+		info.Code = int(si.Code)
+		switch syscall.Signal(sig) {
+		case sys.SIGSEGV, sys.SIGBUS, sys.SIGILL, sys.SIGFPE, sys.SIGTRAP:
+			info.Addr = si.Addr
+		}
+	}
+	if policy.Pass {
+		th.os.delayedSignal = sig
+	}
+	if halt {
+		// Suppress the signal for now, the thread stops as soon as it observes
+		// the SIGSTOP sent to it.
+		if err := th.resumeWithSig(0); err != nil {
+			return false, err
+		}
+		th.common.Signal = info
+		return false, nil
+	}
+	th.os.running = false
+	th.common.Signal = info
+	return true, nil
+}
@@ -0,0 +1,316 @@
+package native
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// Software watchpoints are used when the watched memory does not fit in the
+// debug registers left free by other watchpoints. The pages containing the watched memory are protected
+// with mprotect so that accessing them raises a SIGSEGV in the target.
+// When a thread faults on one of those pages it is stopped together with
+// all the other threads, then it executes the faulting instruction alone
+// with the original protection restored and finally the watched memory is
+// checked to decide whether the fault was a watchpoint hit.
+//
+// Every access to a protected page traps, including accesses to unrelated
+// data sharing the page with the watched memory, which makes the target a
+// lot slower. System calls that access a protected page fail with EFAULT
+// instead of faulting.
+
+var (
+	errSoftwareWatchNonStop = errors.New("not enough free hardware watchpoints, software watchpoints are not supported in non-stop mode")
+	errSoftwareWatchNotData = errors.New("not enough free hardware watchpoints, software watchpoints need writable memory")
+)
+
+// softwareWatchpoint is a watchpoint implemented by protecting the pages
+// that contain it.
+type softwareWatchpoint struct {
+	bp  *proc.Breakpoint
+	old []byte // contents of the watched memory the last time it was checked
+}
+
+// watchPage is a page protected because it contains software watchpoints.
+type watchPage struct {
+	orig int // protection of the page before the first watchpoint was set
+	cur  int // current protection of the page
+}
+
+func (w *softwareWatchpoint) pages() []uint64 {
+	pagesz := uint64(os.Getpagesize())
+	end := w.bp.Addr + uint64(len(w.old))
+	var r []uint64
+	for page := w.bp.Addr &^ (pagesz - 1); page < end; page += pagesz {
+		r = append(r, page)
+	}
+	return r
+}
+
+// contains returns true if addr is inside the watched memory.
+func (w *softwareWatchpoint) contains(addr uint64) bool {
+	return addr >= w.bp.Addr && addr < w.bp.Addr+uint64(len(w.old))
+}
+
+// writeSoftwareWatchpoint protects the pages containing the memory watched
+// by bp.
+func (dbp *nativeProcess) writeSoftwareWatchpoint(bp *proc.Breakpoint) error {
+	if dbp.group.nonStop {
+		return errSoftwareWatchNonStop
+	}
+	w := &softwareWatchpoint{bp: bp, old: make([]byte, bp.WatchSize())}
+	if _, err := dbp.memthread.ReadMemory(w.old, bp.Addr); err != nil {
+		return err
+	}
+	if dbp.swpages == nil {
+		dbp.swpages = make(map[uint64]*watchPage)
+	}
+	for _, page := range w.pages() {
+		if dbp.swpages[page] != nil {
+			continue
+		}
+		prot, err := pageProtection(dbp.pid, page)
+		if err != nil {
+			return err
+		}
+		if prot&sys.PROT_WRITE == 0 {
+			return errSoftwareWatchNotData
+		}
+		dbp.swpages[page] = &watchPage{orig: prot, cur: prot}
+	}
+	dbp.swwatch = append(dbp.swwatch, w)
+	if err := dbp.protectWatchPages(dbp.memthread); err != nil {
+		dbp.swwatch = dbp.swwatch[:len(dbp.swwatch)-1]
+		_ = dbp.protectWatchPages(dbp.memthread)
+		return err
+	}
+	return nil
+}
+
+// eraseSoftwareWatchpoint restores the protection of the pages that only
+// contained the memory watched by bp.
+func (dbp *nativeProcess) eraseSoftwareWatchpoint(bp *proc.Breakpoint) error {
+	for i, w := range dbp.swwatch {
+		if w.bp == bp {
+			dbp.swwatch = append(dbp.swwatch[:i], dbp.swwatch[i+1:]...)
+			break
+		}
+	}
+	return dbp.protectWatchPages(dbp.memthread)
+}
+
+// protectWatchPages changes the protection of every page in dbp.swpages
+// to match the software watchpoints that are set, pages that do not
+// contain watchpoints anymore get their original protection back.
+// The mprotect calls are executed by th, all threads of dbp must be stopped.
+func (dbp *nativeProcess) protectWatchPages(th *nativeThread) error {
+	want := make(map[uint64]int)
+	for _, w := range dbp.swwatch {
+		prot := sys.PROT_READ
+		if w.bp.WatchType.Read() {
+			prot = sys.PROT_NONE
+		}
+		for _, page := range w.pages() {
+			if cur, ok := want[page]; !ok || prot < cur {
+				want[page] = prot
+			}
+		}
+	}
+	for page, pg := range dbp.swpages {
+		prot, ok := want[page]
+		if !ok {
+			prot = pg.orig
+		}
+		if err := dbp.mprotect(th, page, pg, prot); err != nil {
+			return err
+		}
+		if !ok {
+			delete(dbp.swpages, page)
+		}
+	}
+	return nil
+}
+
+// unprotectWatchPages gives back their original protection to all pages
+// containing software watchpoints, without forgetting them.
+func (dbp *nativeProcess) unprotectWatchPages(th *nativeThread) error {
+	for page, pg := range dbp.swpages {
+		if err := dbp.mprotect(th, page, pg, pg.orig); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (dbp *nativeProcess) mprotect(th *nativeThread, page uint64, pg *watchPage, prot int) error {
+	if pg.cur == prot {
+		return nil
+	}
+	if _, err := dbp.injectSyscall(th, sys.SYS_MPROTECT, page, uint64(os.Getpagesize()), uint64(prot)); err != nil {
+		return fmt.Errorf("could not change protection of page %#x: %v", page, err)
+	}
+	pg.cur = prot
+	return nil
+}
+
+// isSoftwareWatchFault returns true if th was stopped by a SIGSEGV caused
+// by an access to a page protected for a software watchpoint. The fault is
+// recorded in th and handled by stepSoftwareWatch once all threads are
+// stopped.
+func (dbp *nativeProcess) isSoftwareWatchFault(th *nativeThread) bool {
+	if len(dbp.swpages) == 0 {
+		return false
+	}
+	var info siginfo
+	var err error
+	dbp.execPtraceFunc(func() { info, err = ptraceGetSiginfo(th.ID) })
+	if err != nil || info.Signo != int32(sys.SIGSEGV) || info.Code != _SEGV_ACCERR {
+		return false
+	}
+	pagesz := uint64(os.Getpagesize())
+	if dbp.swpages[info.Addr&^(pagesz-1)] == nil {
+		return false
+	}
+	th.os.swfault = true
+	th.os.swfaultAddr = info.Addr
+	return true
+}
+
+// stepSoftwareWatch makes th, which faulted on a page protected for a
+// software watchpoint, execute the faulting instruction and returns the
+// watchpoint that was hit, if any.
+// All threads of dbp must be stopped.
+func (dbp *nativeProcess) stepSoftwareWatch(th *nativeThread) (*proc.Breakpoint, error) {
+	th.os.swfault = false
+	if err := dbp.unprotectWatchPages(th); err != nil {
+		return nil, err
+	}
+	if err := th.singleStep(); err != nil {
+		return nil, err
+	}
+	if err := dbp.protectWatchPages(th); err != nil {
+		return nil, err
+	}
+	var hit *proc.Breakpoint
+	for _, w := range dbp.swwatch {
+		buf := make([]byte, len(w.old))
+		if _, err := th.ReadMemory(buf, w.bp.Addr); err != nil {
+			return nil, err
+		}
+		changed := !bytes.Equal(buf, w.old)
+		w.old = buf
+		if hit == nil && (w.contains(th.os.swfaultAddr) || (changed && w.bp.WatchType.Write())) {
+			hit = w.bp
+		}
+	}
+	if hit == nil && dbp.breakpoints.HasHWBreakpoints() {
+		// the instruction could have also triggered a hardware watchpoint,
+		// whose trap was consumed by the single step.
+		return th.findHardwareBreakpoint()
+	}
+	return hit, nil
+}
+
+// refreshSoftwareWatchpoints reads again the contents of the memory watched
+// by software watchpoints, so that changes made while the target was
+// stopped are not reported as hits.
+func (dbp *nativeProcess) refreshSoftwareWatchpoints() {
+	for _, w := range dbp.swwatch {
+		_, _ = dbp.memthread.ReadMemory(w.old, w.bp.Addr)
+	}
+}
+
+// injectSyscall makes th execute the system call nr with the given
+// arguments and returns its result. The registers and the memory of th are
+// restored afterwards, all threads of the process must be stopped.
+func (dbp *nativeProcess) injectSyscall(th *nativeThread, nr uint64, args ...uint64) (uint64, error) {
+	var saved, regs sys.PtraceRegs
+	var err error
+	dbp.execPtraceFunc(func() { err = sys.PtraceGetRegs(th.ID, &saved) })
+	if err != nil {
+		return 0, err
+	}
+	orig := make([]byte, len(syscallInstruction))
+	if _, err := th.ReadMemory(orig, saved.Rip); err != nil {
+		return 0, err
+	}
+	if _, err := th.WriteMemory(saved.Rip, syscallInstruction); err != nil {
+		return 0, err
+	}
+
+	regs = saved
+	regs.Rax = nr
+	regs.Orig_rax = ^uint64(0) // prevents the kernel from restarting an interrupted system call
+	argRegs := []*uint64{&regs.Rdi, &regs.Rsi, &regs.Rdx, &regs.R10, &regs.R8, &regs.R9}
+	for i := range args {
+		*argRegs[i] = args[i]
+	}
+	dbp.execPtraceFunc(func() { err = sys.PtraceSetRegs(th.ID, &regs) })
+	if err == nil {
+		err = th.singleStep()
+	}
+	if err == nil {
+		dbp.execPtraceFunc(func() { err = sys.PtraceGetRegs(th.ID, &regs) })
+	}
+
+	if _, werr := th.WriteMemory(saved.Rip, orig); werr != nil && err == nil {
+		err = werr
+	}
+	var rerr error
+	dbp.execPtraceFunc(func() { rerr = sys.PtraceSetRegs(th.ID, &saved) })
+	if rerr != nil && err == nil {
+		err = rerr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if ret := int64(regs.Rax); ret < 0 && ret > -4096 {
+		return 0, sys.Errno(-ret)
+	}
+	return regs.Rax, nil
+}
+
+// pageProtection returns the protection of the page at addr, read from
+// /proc/pid/maps.
+func pageProtection(pid int, addr uint64) (int, error) {
+	fh, err := os.Open(fmt.Sprintf("/proc/%d/maps", pid))
+	if err != nil {
+		return 0, err
+	}
+	defer fh.Close()
+	scan := bufio.NewScanner(fh)
+	for scan.Scan() {
+		fields := strings.Fields(scan.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		bounds := strings.SplitN(fields[0], "-", 2)
+		if len(bounds) != 2 {
+			continue
+		}
+		start, err1 := strconv.ParseUint(bounds[0], 16, 64)
+		end, err2 := strconv.ParseUint(bounds[1], 16, 64)
+		if err1 != nil || err2 != nil || addr < start || addr >= end {
+			continue
+		}
+		prot := sys.PROT_NONE
+		for i, p := range []int{sys.PROT_READ, sys.PROT_WRITE, sys.PROT_EXEC} {
+			if i < len(fields[1]) && fields[1][i] != '-' {
+				prot |= p
+			}
+		}
+		return prot, nil
+	}
+	if err := scan.Err(); err != nil {
+		return 0, err
+	}
+	return 0, fmt.Errorf("address %#x is not mapped", addr)
+}
@@ -48,6 +48,27 @@ func (t *nativeThread) Continue() error {
 	return t.resume()
 }
 
+// mustStepOverBreakpoint returns true if the thread must step over the
+// breakpoint it is stopped at before resuming. Data breakpoints trap after
+// the instruction accessing the watched memory was executed, stepping would
+// execute the next instruction with the other watchpoints unable to report
+// it, unless a software breakpoint is also set at the current PC.
+func (t *nativeThread) mustStepOverBreakpoint() bool {
+	bp := t.CurrentBreakpoint.Breakpoint
+	if bp == nil {
+		return false
+	}
+	if bp.WatchType == 0 {
+		return true
+	}
+	pc, err := t.PC()
+	if err != nil {
+		return true
+	}
+	_, ok := t.dbp.FindBreakpoint(pc, false)
+	return ok
+}
+
 // StepInstruction steps a single instruction.
 //
 // Executes exactly one instruction and then returns.
@@ -129,7 +150,10 @@ func (t *nativeThread) SetCurrentBreakpoint(adjustPC bool) error {
 
 	var bp *proc.Breakpoint
 
-	if t.dbp.Breakpoints().HasHWBreakpoints() {
+	if t.os.swhit != nil {
+		bp = t.os.swhit
+		t.os.swhit = nil
+	} else if t.dbp.Breakpoints().HasHWBreakpoints() {
 		var err error
 		bp, err = t.findHardwareBreakpoint()
 		if err != nil {
@@ -160,6 +184,9 @@ func (t *nativeThread) SetCurrentBreakpoint(adjustPC bool) error {
 	}
 
 	t.CurrentBreakpoint.Breakpoint = bp
+	if bp != nil && bp.SoftwareWatch {
+		t.CurrentBreakpoint.WatchAddr = t.os.swfaultAddr
+	}
 	return nil
 }
 
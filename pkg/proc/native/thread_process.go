@@ -17,6 +17,20 @@ type osSpecificDetails struct {
 	running             bool
 	setbp               bool
 	phantomBreakpointPC uint64
+
+	// swfault is set when the thread faulted on a page protected for a
+	// software watchpoint at swfaultAddr, see swwatch.go.
+	swfault     bool
+	swfaultAddr uint64
+	swhit       *proc.Breakpoint // software watchpoint hit by the last fault
+
+	// exitCaller is set when the thread stopped before exiting because it
+	// called exit_group, see exitstop.go.
+	exitCaller bool
+
+	// inSyscall is set between the syscall-enter-stop and the
+	// syscall-exit-stop of the thread, see syscallExitStop.
+	inSyscall bool
 }
 
 func (t *nativeThread) stop() (err error) {
@@ -36,6 +50,13 @@ func (t *nativeThread) resume() error {
 
 func (t *nativeThread) resumeWithSig(sig int) (err error) {
 	t.os.running = true
+	t.common.Signal = nil
+	t.common.Syscall = nil
+	t.common.Exit = nil
+	if t.dbp.group.tracingSyscalls() {
+		t.dbp.execPtraceFunc(func() { err = ptraceSyscall(t.ID, sig) })
+		return
+	}
 	t.dbp.execPtraceFunc(func() { err = ptraceCont(t.ID, sig) })
 	return
 }
@@ -66,7 +87,15 @@ func (t *nativeThread) singleStep() (err error) {
 				return nil
 			case sys.SIGSTOP:
 				// delayed SIGSTOP, ignore it
-			case sys.SIGILL, sys.SIGBUS, sys.SIGFPE, sys.SIGSEGV, sys.SIGSTKFLT:
+			case sys.SIGSEGV:
+				if t.dbp.isSoftwareWatchFault(t) {
+					// the instruction accessed a page protected for a software
+					// watchpoint, execute it with the page unprotected.
+					_, err := t.dbp.stepSoftwareWatch(t)
+					return err
+				}
+				sig = int(s)
+			case sys.SIGILL, sys.SIGBUS, sys.SIGFPE, sys.SIGSTKFLT:
 				// propagate signals that can have been caused by the current instruction
 				sig = int(s)
 			default:
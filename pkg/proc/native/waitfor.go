@@ -0,0 +1,100 @@
+package native
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hitzhangjie/dlv/pkg/proc"
+)
+
+// WaitFor scans /proc until a process described by waitFor is started and
+// returns its pid. Processes that were already running when WaitFor is
+// called are ignored. A new process that does not match yet is checked
+// again at every scan, because it could still call exec.
+func WaitFor(waitFor *proc.WaitFor) (int, error) {
+	match, err := waitFor.Matcher()
+	if err != nil {
+		return 0, err
+	}
+	interval := waitFor.Interval
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	var deadline time.Time
+	if waitFor.Duration > 0 {
+		deadline = time.Now().Add(waitFor.Duration)
+	}
+
+	pids, err := listProcesses()
+	if err != nil {
+		return 0, err
+	}
+	running := make(map[int]bool, len(pids))
+	for _, pid := range pids {
+		running[pid] = true
+	}
+
+	for {
+		pids, err := listProcesses()
+		if err != nil {
+			return 0, err
+		}
+		for _, pid := range pids {
+			if running[pid] {
+				continue
+			}
+			name, cmdline, err := processNameAndCmdline(pid)
+			if err != nil {
+				// the process exited
+				continue
+			}
+			if match(name, cmdline) {
+				return pid, nil
+			}
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return 0, fmt.Errorf("no process matching %q started in %v", waitFor.Name, waitFor.Duration)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// listProcesses returns the pids of the processes in /proc.
+func listProcesses() ([]int, error) {
+	d, err := os.Open("/proc")
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	pids := make([]int, 0, len(names))
+	for _, name := range names {
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// processNameAndCmdline returns the name of the process pid and its command
+// line, with the arguments separated by spaces.
+func processNameAndCmdline(pid int) (string, string, error) {
+	comm, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/comm", pid))
+	if err != nil {
+		return "", "", err
+	}
+	cmdline, err := ioutil.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return "", "", err
+	}
+	cmdline = bytes.TrimRight(cmdline, "\x00")
+	cmdline = bytes.ReplaceAll(cmdline, []byte{0}, []byte{' '})
+	return string(bytes.TrimSpace(comm)), string(cmdline), nil
+}
@@ -0,0 +1,132 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNonStopNotSupported is returned by SetNonStop when the backend can not
+// stop threads individually.
+var ErrNonStopNotSupported = errors.New("backend does not support non-stop mode")
+
+// errNoStop is returned by Continue when it is called by PollStop and no
+// thread stopped.
+var errNoStop = errors.New("no thread stopped")
+
+// ContinueMode describes which threads are resumed by ContinueOnce in
+// non-stop mode and whether it waits for one of them to stop.
+type ContinueMode struct {
+	// ThreadID, if not zero, is the only thread that is resumed, otherwise
+	// all stopped threads are resumed.
+	ThreadID int
+	// NoResume means that no thread is resumed, ContinueOnce only waits for
+	// a running thread to stop.
+	NoResume bool
+	// NoHang makes ContinueOnce return a nil thread instead of waiting when
+	// no thread has stopped.
+	NoHang bool
+}
+
+// NonStop returns true if the target is in non-stop mode.
+func (t *Target) NonStop() bool {
+	return t.nonStop
+}
+
+// SetNonStop enables or disables non-stop mode. In non-stop mode when a
+// thread stops, because it hit a breakpoint, only that thread is stopped
+// while the other threads keep running, Continue only resumes the threads
+// that are stopped. Disabling non-stop mode stops all threads.
+func (t *Target) SetNonStop(enabled bool) error {
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	ns, ok := t.proc.(NonStopper)
+	if !ok {
+		if !enabled {
+			return nil
+		}
+		return ErrNonStopNotSupported
+	}
+	if recorded, _ := t.Recorded(); enabled && recorded {
+		return errors.New("can not enable non-stop mode while recording")
+	}
+	if err := ns.SetNonStop(enabled); err != nil {
+		return err
+	}
+	// all processes of the group are resumed and stopped together
+	for _, tgt := range t.group.targets {
+		tgt.nonStop = enabled
+	}
+	t.group.clearCaches()
+	return nil
+}
+
+// ContinueThread resumes thread tid, the other stopped threads stay
+// stopped. It is only supported in non-stop mode.
+func (t *Target) ContinueThread(tid int) error {
+	if !t.nonStop {
+		return errors.New("continuing a single thread requires non-stop mode")
+	}
+	th, ok := t.FindThread(tid)
+	if !ok {
+		return fmt.Errorf("thread %d does not exist", tid)
+	}
+	if th.Common().Running {
+		return fmt.Errorf("thread %d is already running", tid)
+	}
+	t.contMode = ContinueMode{ThreadID: tid}
+	defer func() { t.contMode = ContinueMode{} }()
+	return t.Continue()
+}
+
+// PollStop checks, without blocking, whether one of the running threads
+// stopped while the target was not being continued and handles the stop
+// like Continue does. It returns true if the target is now stopped at a
+// new position. It is only meaningful in non-stop mode.
+func (t *Target) PollStop() (bool, error) {
+	if !t.nonStop {
+		return false, nil
+	}
+	t.contMode = ContinueMode{NoResume: true, NoHang: true}
+	defer func() { t.contMode = ContinueMode{} }()
+	err := t.Continue()
+	if err == errNoStop {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// HasRunningThreads returns true if some of the threads of the target are
+// running, which only happens in non-stop mode.
+func (t *Target) HasRunningThreads() bool {
+	if !t.nonStop {
+		return false
+	}
+	for _, th := range t.ThreadList() {
+		if th.Common().Running {
+			return true
+		}
+	}
+	return false
+}
+
+// StopRunning stops the threads that were left running in non-stop mode.
+func (t *Target) StopRunning() error {
+	if !t.HasRunningThreads() {
+		return nil
+	}
+	if err := t.proc.(NonStopper).StopThreads(); err != nil {
+		return err
+	}
+	t.group.clearCaches()
+	t.StopReason = StopManual
+	return nil
+}
+
+// setContinueMode tells the backend which threads the next call to
+// ContinueOnce resumes, see ContinueMode.
+func (t *Target) setContinueMode(mode ContinueMode) {
+	if ns, ok := t.proc.(NonStopper); ok && t.nonStop {
+		ns.SetContinueMode(mode)
+	}
+}
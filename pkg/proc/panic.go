@@ -0,0 +1,212 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"reflect"
+)
+
+// PanicCatchpoint describes which panics stop the target.
+type PanicCatchpoint struct {
+	// Recovered makes the target stop in runtime.gopanic, when the panic
+	// starts, so that panics that are later recovered stop it too. Otherwise
+	// only unrecovered panics stop the target, through the unrecovered-panic
+	// breakpoint.
+	Recovered bool
+
+	// Type, if not empty, is the dynamic type that the panic value must have
+	// for the target to stop, as printed by whatis (for example
+	// "*errors.errorString" or "string").
+	Type string
+
+	// Cond, if not empty, is a boolean expression that must be true for the
+	// target to stop. It is evaluated in the runtime.gopanic frame with the
+	// identifier panic bound to the panic value, for example
+	// `panic.(string) == "boom"`.
+	Cond string
+}
+
+// PanicInfo describes the panic a thread is stopped at.
+type PanicInfo struct {
+	Value *Variable  // the value passed to panic
+	Type  string     // dynamic type of Value, "nil" if Value is a nil interface
+	Frame Stackframe // the frame that called panic, runtime frames are skipped
+}
+
+// PanicCatchpoint returns the panics that stop the target, nil if it is
+// the default of stopping on every unrecovered panic.
+func (t *Target) PanicCatchpoint() *PanicCatchpoint {
+	if t.panicCatch == nil {
+		return nil
+	}
+	if t.panicCatch.Recovered && t.findBreakpointWithID(panicCatchID) == nil {
+		// the breakpoint was cleared by the user
+		t.panicCatch = nil
+		return nil
+	}
+	r := *t.panicCatch
+	return &r
+}
+
+// SetPanicCatchpoint changes the panics that stop the target, a nil pc
+// restores the default of stopping on every unrecovered panic.
+func (t *Target) SetPanicCatchpoint(pc *PanicCatchpoint) error {
+	if valid, err := t.Valid(); !valid {
+		return err
+	}
+	if bp := t.findBreakpointWithID(panicCatchID); bp != nil {
+		if err := t.ClearBreakpoint(bp.Addr); err != nil {
+			return err
+		}
+	}
+	unrecovered := t.findBreakpointWithID(unrecoveredPanicID)
+	if unrecovered == nil {
+		t.createUnrecoveredPanicBreakpoint()
+		unrecovered = t.findBreakpointWithID(unrecoveredPanicID)
+	}
+	if unrecovered != nil {
+		unrecovered.UserBreaklet().callback = nil
+	}
+	t.panicCatch = nil
+	if pc == nil {
+		return nil
+	}
+
+	var cond ast.Expr
+	if pc.Cond != "" {
+		var err error
+		cond, err = parser.ParseExpr(pc.Cond)
+		if err != nil {
+			return fmt.Errorf("could not parse panic condition: %v", err)
+		}
+	}
+
+	filter := func(th Thread) bool {
+		if pc.Type == "" && cond == nil {
+			return true
+		}
+		info, scope, err := t.threadPanic(th)
+		if err != nil || info == nil {
+			// better to stop than to miss a panic we could not read
+			return true
+		}
+		if pc.Type != "" && info.Type != pc.Type {
+			return false
+		}
+		if cond == nil {
+			return true
+		}
+		scope.boundVars = map[string]*Variable{"panic": info.Value}
+		active, err := scope.evalCondition(cond)
+		if err != nil {
+			// like breakpoint conditions, stop if the condition can not be
+			// evaluated
+			th.Breakpoint().CondError = err
+			return true
+		}
+		return active
+	}
+
+	if pc.Recovered {
+		pcs, err := FindFunctionLocation(t.Process, "runtime.gopanic", 0)
+		if err != nil {
+			return err
+		}
+		bp, err := t.SetBreakpointWithID(panicCatchID, pcs[0])
+		if err != nil {
+			return err
+		}
+		bp.Name = PanicCatch
+		bp.UserBreaklet().callback = filter
+	} else {
+		if unrecovered == nil {
+			return errors.New("could not find the function called by unrecovered panics")
+		}
+		unrecovered.UserBreaklet().callback = filter
+	}
+
+	r := *pc
+	t.panicCatch = &r
+	return nil
+}
+
+// findBreakpointWithID returns the physical breakpoint of the logical
+// breakpoint id, which must be set at a single address.
+func (t *Target) findBreakpointWithID(id int) *Breakpoint {
+	for _, bp := range t.Breakpoints().M {
+		if bp.LogicalID() == id {
+			return bp
+		}
+	}
+	return nil
+}
+
+// ThreadPanic returns the panic that thread is stopped at, if it is
+// stopped at the unrecovered-panic breakpoint or at the panic catchpoint,
+// nil otherwise.
+func (t *Target) ThreadPanic(thread Thread) (*PanicInfo, error) {
+	info, _, err := t.threadPanic(thread)
+	return info, err
+}
+
+// threadPanic is like ThreadPanic but also returns the scope of the
+// runtime.gopanic frame.
+func (t *Target) threadPanic(thread Thread) (*PanicInfo, *EvalScope, error) {
+	bp := thread.Breakpoint().Breakpoint
+	if bp == nil || (bp.Name != UnrecoveredPanic && bp.Name != PanicCatch) {
+		return nil, nil, nil
+	}
+	g, err := GetG(thread)
+	var frames []Stackframe
+	if err == nil && g != nil {
+		frames, err = g.Stacktrace(50, 0)
+	} else {
+		frames, err = ThreadStacktrace(thread, 50)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	i := 0
+	for i < len(frames) && (frames[i].Current.Fn == nil || frames[i].Current.Fn.Name != "runtime.gopanic") {
+		i++
+	}
+	if i >= len(frames) {
+		return nil, nil, errors.New("could not find runtime.gopanic on the stack")
+	}
+
+	// On entry to runtime.gopanic the value is its argument, later the
+	// argument could have been overwritten but it is saved in the _panic
+	// struct of the goroutine.
+	expr := "runtime.curg._panic.arg"
+	if i == 0 {
+		expr = "e"
+	}
+	scope := FrameToScope(t, thread.ProcessMemory(), g, frames[i:]...)
+	v, err := scope.EvalExpression(expr, loadFullValue)
+	if err != nil {
+		return nil, nil, err
+	}
+	v.Name = "panic"
+
+	r := &PanicInfo{Value: v, Type: panicValueType(v)}
+	for j := i + 1; j < len(frames); j++ {
+		if fn := frames[j].Current.Fn; fn == nil || fn.PackageName() != "runtime" || j == len(frames)-1 {
+			r.Frame = frames[j]
+			break
+		}
+	}
+	return r, scope, nil
+}
+
+// panicValueType returns the dynamic type of the interface v.
+func panicValueType(v *Variable) string {
+	if v.Kind != reflect.Interface || len(v.Children) == 0 {
+		return ""
+	}
+	if v.Children[0].Kind == reflect.Invalid || v.Children[0].DwarfType == nil {
+		return "nil"
+	}
+	return v.Children[0].DwarfType.String()
+}
@@ -1,6 +1,7 @@
 package proc
 
 import (
+	"debug/dwarf"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -150,3 +151,44 @@ func TestGenericFunctionParser(t *testing.T) {
 		}
 	}
 }
+
+func TestEBPFInlinedCallSites(t *testing.T) {
+	// Every inlined call to main.inlineThis should be found, with its
+	// argument available at the start of the call.
+	fixture := proctest.BuildFixture("testinline", proctest.EnableInlining|proctest.EnableOptimization)
+	bi := NewBinaryInfo(runtime.GOOS, runtime.GOARCH)
+	assertNoError(bi.LoadBinaryInfo(fixture.Path, 0), t, "LoadBinaryInfo")
+	fns, err := bi.FindFunction("main.inlineThis")
+	if err != nil || len(fns[0].InlinedCalls) == 0 {
+		t.Skip("no inlined calls to main.inlineThis found")
+	}
+	fn := fns[0]
+
+	callerFn := bi.PCToFunc(fn.InlinedCalls[0].LowPC)
+	if callerFn == nil || callerFn.Name != "main.main" {
+		t.Fatalf("wrong caller for inlined call %#v", callerFn)
+	}
+	tree, err := callerFn.cu.image.getDwarfTree(callerFn.offset)
+	assertNoError(err, t, "getDwarfTree")
+	sites := ebpfInlinedCallSites(tree, fn.offset)
+	if len(sites) != 2 {
+		t.Fatalf("expected 2 inlined calls, got %d", len(sites))
+	}
+	for _, site := range sites {
+		if site.pc < callerFn.Entry || site.pc >= callerFn.End {
+			t.Errorf("inlined call at %#x outside of %s", site.pc, callerFn.Name)
+		}
+		if _, err := ebpfCFAOffset(bi, site.pc); err != nil {
+			t.Errorf("ebpfCFAOffset(%#x): %v", site.pc, err)
+		}
+		found := false
+		for _, entry := range site.tree.Children {
+			if name, _ := entry.Val(dwarf.AttrName).(string); name == "a" && entry.Tag == dwarf.TagFormalParameter {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("argument a not found in inlined call at %#x", site.pc)
+		}
+	}
+}
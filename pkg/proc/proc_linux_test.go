@@ -1,11 +1,14 @@
 package proc_test
 
 import (
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/hitzhangjie/dlv/pkg/proc"
 	"github.com/hitzhangjie/dlv/pkg/proc/native"
 	proctest "github.com/hitzhangjie/dlv/pkg/proc/test"
 )
@@ -14,7 +17,7 @@ func TestLoadingExternalDebugInfo(t *testing.T) {
 	fixture := proctest.BuildFixture("locationsprog", 0)
 	defer os.Remove(fixture.Path)
 	stripAndCopyDebugInfo(fixture, t)
-	p, err := native.Launch(append([]string{fixture.Path}, ""), "", 0)
+	p, err := native.Launch(append([]string{fixture.Path}, ""), "", 0, native.LaunchOptions{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -37,3 +40,67 @@ func stripAndCopyDebugInfo(f proctest.Fixture, t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestLaunchRedirectsAndEnv(t *testing.T) {
+	fixture := proctest.BuildFixture("stdioenv", 0)
+	dir, err := ioutil.TempDir("", "stdioenv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stdin := filepath.Join(dir, "stdin")
+	if err := ioutil.WriteFile(stdin, []byte("hello\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := filepath.Join(dir, "stdout"), filepath.Join(dir, "stderr")
+
+	p, err := native.Launch([]string{fixture.Path}, "", 0, native.LaunchOptions{
+		Redirects: [3]string{stdin, stdout, stderr},
+		Env:       []string{"DLV_TEST_ENV=world"},
+		ClearEnv:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Detach(true)
+	if _, ok := p.Continue().(proc.ErrProcessExited); !ok {
+		t.Fatal("expected the process to exit")
+	}
+
+	out, _ := ioutil.ReadFile(stdout)
+	if string(out) != "hello\nworld 1\n" {
+		t.Errorf("wrong stdout %q", out)
+	}
+	out, _ = ioutil.ReadFile(stderr)
+	if string(out) != "to stderr\n" {
+		t.Errorf("wrong stderr %q", out)
+	}
+}
+
+func TestAttachWaitFor(t *testing.T) {
+	fixture := proctest.BuildFixture("loopprog", 0)
+	cmd := exec.Command(fixture.Path)
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		cmd.Start()
+	}()
+
+	pid, err := native.WaitFor(&proc.WaitFor{Name: filepath.Base(fixture.Path), Interval: time.Millisecond, Duration: 10 * time.Second})
+	assertNoError(err, t, "WaitFor")
+	if pid != cmd.Process.Pid {
+		t.Fatalf("wrong pid %d, expected %d", pid, cmd.Process.Pid)
+	}
+	p, err := native.Attach(pid)
+	assertNoError(err, t, "Attach")
+	p.Detach(true)
+	cmd.Wait()
+
+	_, err = native.WaitFor(&proc.WaitFor{Name: "loop.*[", Regexp: true})
+	if err == nil {
+		t.Fatal("expected an error for an invalid regular expression")
+	}
+	_, err = native.WaitFor(&proc.WaitFor{Name: "^nosuchprocess$", Regexp: true, Duration: 100 * time.Millisecond})
+	if err == nil {
+		t.Fatal("expected WaitFor to time out")
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 
@@ -35,7 +36,7 @@ import (
 )
 
 var normalLoadConfig = proc.LoadConfig{true, 1, 64, 64, -1, 0}
-var buildMode string
+var testBackend, buildMode string
 
 func init() {
 	runtime.GOMAXPROCS(4)
@@ -43,10 +44,12 @@ func init() {
 }
 
 func TestMain(m *testing.M) {
+	flag.StringVar(&testBackend, "backend", "", "selects backend")
 	flag.StringVar(&buildMode, "test-buildmode", "", "selects build mode")
 	var logConf string
 	flag.StringVar(&logConf, "log", "", "configures logging")
 	flag.Parse()
+	proctest.DefaultTestBackend(&testBackend)
 	if buildMode != "" && buildMode != "pie" {
 		fmt.Fprintf(os.Stderr, "unknown build mode %q", buildMode)
 		os.Exit(1)
@@ -91,7 +94,7 @@ func withTestProcessArgs(name string, t testing.TB, wd string, args []string, bu
 		buildFlags |= proctest.BuildModePIE
 	}
 	fixture := proctest.BuildFixture(name, buildFlags)
-	p, err := native.Launch(append([]string{fixture.Path}, args...), wd, 0)
+	p, err := native.Launch(append([]string{fixture.Path}, args...), wd, 0, native.LaunchOptions{})
 	if err != nil {
 		t.Fatal("Launch():", err)
 	}
@@ -3620,6 +3623,44 @@ func TestCallConcurrent(t *testing.T) {
 	})
 }
 
+func TestCallFunctionParkedGoroutine(t *testing.T) {
+	// Calls on a goroutine that isn't running on any thread are injected on
+	// a different thread and must still find their way back to the parked
+	// goroutine.
+	withTestProcess("fncallparked", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfo()")
+		var worker *proc.G
+		for _, g := range gs {
+			if loc := g.StartLoc(p); loc.Fn != nil && loc.Fn.Name == "main.worker" {
+				worker = g
+			}
+		}
+		if worker == nil || worker.Thread != nil {
+			t.Fatalf("could not find parked goroutine running main.worker")
+		}
+
+		for _, tc := range []struct {
+			expr, tgt string
+		}{
+			{"s.String()", `"state 1"`},
+			{"double(s.n)", "2"},
+			{"double(3)", "6"},
+		} {
+			assertNoError(p.SwitchGoroutine(worker), t, "SwitchGoroutine()")
+			assertNoError(proc.EvalExpressionWithCalls(p, p.SelectedGoroutine(), tc.expr, normalLoadConfig, true), t, fmt.Sprintf("EvalExpressionWithCalls(%q)", tc.expr))
+			retvals := p.CurrentThread().Common().ReturnValues(normalLoadConfig)
+			if len(retvals) != 1 {
+				t.Fatalf("call %q: wrong number of return values %d", tc.expr, len(retvals))
+			}
+			if s := api.ConvertVar(retvals[0]).SinglelineString(); s != tc.tgt {
+				t.Errorf("call %q: expected %s got %s", tc.expr, tc.tgt, s)
+			}
+		}
+	})
+}
+
 func TestPluginStepping(t *testing.T) {
 	pluginFixtures := proctest.WithPlugins(t, proctest.AllNonOptimized, "plugin1/", "plugin2/")
 
@@ -4235,6 +4276,342 @@ func TestWatchpointCounts(t *testing.T) {
 	})
 }
 
+func TestWatchpointsSoftware(t *testing.T) {
+	// Sets more watchpoints than there are debug registers, the last one is
+	// implemented by protecting its page and must be hit like the others.
+	withTestProcess("databpmany", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(p.Continue(), t, "Continue 0")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+
+		var bps []*proc.Breakpoint
+		for _, expr := range []string{"g1", "g2", "g3", "g4", "g5"} {
+			bp, err := p.SetWatchpoint(scope, expr, proc.WatchWrite, nil)
+			assertNoError(err, t, fmt.Sprintf("SetWatchpoint(%s)", expr))
+			bps = append(bps, bp)
+		}
+		for i, bp := range bps {
+			if bp.SoftwareWatch != (i == len(bps)-1) {
+				t.Fatalf("watchpoint %d: SoftwareWatch = %v", i, bp.SoftwareWatch)
+			}
+		}
+
+		for i, bp := range bps {
+			msg := fmt.Sprintf("Continue %d", i+1)
+			assertNoError(p.Continue(), t, msg)
+			assertLineNumber(p, t, 9+i, msg)
+			if curbp := p.CurrentThread().Breakpoint().Breakpoint; curbp == nil || curbp.LogicalID() != bp.LogicalID() {
+				t.Fatalf("%s: wrong watchpoint %v, expected %s", msg, curbp, bp.WatchExpr)
+			}
+		}
+
+		for _, bp := range bps {
+			assertNoError(p.ClearBreakpoint(bp.Addr), t, "ClearBreakpoint")
+		}
+		if err := p.Continue(); err != nil {
+			if _, exited := err.(proc.ErrProcessExited); !exited {
+				t.Fatalf("Continue: %v", err)
+			}
+		}
+	})
+}
+
+func TestWatchpointsRange(t *testing.T) {
+	// Watches a struct, which is split across two debug registers, and a
+	// slice, whose backing array needs a software watchpoint.
+	withTestProcess("databprange", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(p.Continue(), t, "Continue 0")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+
+		assertWatchHit := func(line int, hit string) {
+			t.Helper()
+			assertNoError(p.Continue(), t, "Continue")
+			assertLineNumber(p, t, line, "Continue")
+			bpstate := p.CurrentThread().Breakpoint()
+			if bpstate.Breakpoint == nil || bpstate.WatchExpr == "" {
+				t.Fatalf("not stopped at a watchpoint")
+			}
+			if bpstate.WatchHit != hit {
+				t.Fatalf("wrong watchpoint hit description %q, expected %q", bpstate.WatchHit, hit)
+			}
+		}
+
+		clearWatchpoint := func(bp *proc.Breakpoint) {
+			t.Helper()
+			for addr, other := range p.Breakpoints().M {
+				if other.LogicalID() == bp.LogicalID() {
+					assertNoError(p.ClearBreakpoint(addr), t, "ClearBreakpoint")
+				}
+			}
+		}
+
+		bp, err := p.SetWatchpoint(scope, "gfoo", proc.WatchWrite, nil)
+		assertNoError(err, t, "SetWatchpoint(gfoo)")
+		if bp.SoftwareWatch {
+			t.Fatal("watchpoint on gfoo should use debug registers")
+		}
+		assertWatchHit(16, "field Foo.a written")
+		assertWatchHit(17, "field Foo.bar written")
+		clearWatchpoint(bp)
+
+		bp, err = p.SetWatchpoint(scope, "gbuf", proc.WatchWrite, nil)
+		assertNoError(err, t, "SetWatchpoint(gbuf)")
+		if !bp.SoftwareWatch {
+			t.Fatal("watchpoint on gbuf should be a software watchpoint")
+		}
+		assertWatchHit(18, "element gbuf[5] written")
+		assertWatchHit(19, "element gbuf[40] written")
+		clearWatchpoint(bp)
+	})
+}
+
+func TestWatchpointsReadOnly(t *testing.T) {
+	// A read watchpoint is implemented with a read/write debug register, the
+	// writes on lines 12, 13 and 15 must not stop the program.
+	withTestProcess("databpread", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(p.Continue(), t, "Continue 0")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+
+		_, err = p.SetWatchpoint(scope, "gval", proc.WatchRead, nil)
+		assertNoError(err, t, "SetWatchpoint")
+
+		assertNoError(p.Continue(), t, "Continue 1")
+		assertLineNumber(p, t, 8, "Continue 1")
+		bpstate := p.CurrentThread().Breakpoint()
+		if bpstate.Breakpoint == nil || !bpstate.WatchType.Read() || bpstate.WatchType.Write() {
+			t.Fatalf("not stopped at the read watchpoint")
+		}
+
+		assertNoError(p.Continue(), t, "Continue 2")
+		assertLineNumber(p, t, 16, "Continue 2")
+	})
+}
+
+func TestStepSkip(t *testing.T) {
+	withTestProcess("stepskip", t, func(p *proc.Target, fixture proctest.Fixture) {
+		p.StepSkip = []proc.StepSkipRule{{Kind: proc.StepSkipFunction, Pattern: "main.skip*"}}
+
+		// helper is not skipped but returning from it to skipme must step out
+		// to main.main.
+		bp := setFileBreakpoint(p, t, fixture.Source, 10)
+		assertNoError(p.Continue(), t, "Continue")
+		assertLineNumber(p, t, 10, "Continue")
+		assertNoError(p.Step(), t, "Step out of skipped caller")
+		if fn := p.BinInfo().PCToFunc(currentPC(p, t)); fn == nil || fn.Name != "main.main" {
+			t.Fatalf("step stopped in %v, expected main.main", fn)
+		}
+		assertNoError(p.ClearBreakpoint(bp.Addr), t, "ClearBreakpoint")
+
+		bp = setFileBreakpoint(p, t, fixture.Source, 16)
+		assertNoError(p.Continue(), t, "Continue")
+		assertLineNumber(p, t, 16, "Continue")
+		assertNoError(p.Step(), t, "Step over skipped function")
+		assertLineNumber(p, t, 17, "Step over skipped function")
+		assertNoError(p.ClearBreakpoint(bp.Addr), t, "ClearBreakpoint")
+
+		p.StepSkip = []proc.StepSkipRule{{Kind: proc.StepSkipPackage, Pattern: "fmt"}, {Kind: proc.StepSkipFunction, Pattern: "main.helper"}}
+		setFileBreakpoint(p, t, fixture.Source, 18)
+		assertNoError(p.Continue(), t, "Continue")
+		assertLineNumber(p, t, 18, "Continue")
+		assertNoError(p.Step(), t, "Step over skipped function")
+		assertLineNumber(p, t, 19, "Step over skipped function")
+		assertNoError(p.Step(), t, "Step over skipped package")
+		assertLineNumber(p, t, 20, "Step over skipped package")
+	})
+}
+
+func TestSignalPolicyStop(t *testing.T) {
+	withTestProcess("sigusr1", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetSignalPolicy(int(syscall.SIGUSR1), proc.SignalPolicy{Stop: true, Pass: true}), t, "SetSignalPolicy")
+		if policy := p.SignalPolicy(int(syscall.SIGUSR1)); !policy.Print {
+			t.Fatalf("stopping on a signal should also print it: %#v", policy)
+		}
+
+		assertNoError(p.Continue(), t, "Continue")
+		if p.StopReason != proc.StopSignal {
+			t.Fatalf("wrong stop reason %v", p.StopReason)
+		}
+		sig := p.CurrentThread().Common().Signal
+		if sig == nil || sig.Signo != int(syscall.SIGUSR1) {
+			t.Fatalf("wrong signal %#v", sig)
+		}
+
+		// the signal is delivered when the program is resumed, it then exits
+		// normally.
+		err := p.Continue()
+		pe, ok := err.(proc.ErrProcessExited)
+		if !ok {
+			t.Fatalf("expected the process to exit, got %v", err)
+		}
+		if pe.Status != 0 {
+			t.Fatalf("wrong exit status %d", pe.Status)
+		}
+	})
+}
+
+func TestSyscallCatchpoints(t *testing.T) {
+	withTestProcess("catchsyscall", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetSyscallCatchpoints(false, []int{syscall.SYS_OPENAT}), t, "SetSyscallCatchpoints")
+
+		for _, exit := range []bool{false, true} {
+			assertNoError(p.Continue(), t, "Continue")
+			if p.StopReason != proc.StopSyscall {
+				t.Fatalf("wrong stop reason %v", p.StopReason)
+			}
+			sc := p.CurrentThread().Common().Syscall
+			if sc == nil || sc.Nr != syscall.SYS_OPENAT || sc.Exit != exit {
+				t.Fatalf("wrong syscall %#v (exit %v)", sc, exit)
+			}
+			if exit && sc.Ret < 0 {
+				t.Fatalf("openat failed: %d", sc.Ret)
+			}
+		}
+
+		assertNoError(p.SetSyscallCatchpoints(false, nil), t, "SetSyscallCatchpoints")
+		err := p.Continue()
+		if _, ok := err.(proc.ErrProcessExited); !ok {
+			t.Fatalf("expected the process to exit, got %v", err)
+		}
+	})
+}
+
+func TestSyscallCatchpointsENOSYS(t *testing.T) {
+	// The return of a system call that fails with ENOSYS must not be
+	// mistaken for its entry.
+	const nosys = 1000
+	withTestProcess("catchsyscall", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetSyscallCatchpoints(false, []int{nosys}), t, "SetSyscallCatchpoints")
+
+		for _, exit := range []bool{false, true} {
+			assertNoError(p.Continue(), t, "Continue")
+			sc := p.CurrentThread().Common().Syscall
+			if p.StopReason != proc.StopSyscall || sc == nil || sc.Nr != nosys || sc.Exit != exit {
+				t.Fatalf("wrong syscall stop %v %#v (exit %v)", p.StopReason, sc, exit)
+			}
+			if exit && sc.Ret != -int64(syscall.ENOSYS) {
+				t.Fatalf("wrong return value %d", sc.Ret)
+			}
+		}
+	})
+}
+
+func TestPanicCatchpointCond(t *testing.T) {
+	withTestProcess("panicrecover", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: true, Cond: `panic.(*errors.errorString).s == "an error"`}), t, "SetPanicCatchpoint")
+
+		// the condition fails to evaluate on the first panic, whose value
+		// is a string, stopping with a condition error
+		if err := p.Continue(); err == nil {
+			t.Fatal("condition error not reported")
+		}
+		bp := p.CurrentThread().Breakpoint()
+		if bp.Breakpoint == nil || bp.Name != proc.PanicCatch || bp.CondError == nil {
+			t.Fatalf("not stopped at the panic catchpoint with a condition error: %v %v", bp.Breakpoint, bp.CondError)
+		}
+
+		// the second panic does not satisfy the condition
+		cond := `panic.(*errors.errorString).s == "another error"`
+		assertNoError(p.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: true, Type: "*errors.errorString", Cond: cond}), t, "SetPanicCatchpoint")
+		if pc := p.PanicCatchpoint(); pc == nil || pc.Cond != cond {
+			t.Fatalf("wrong panic catchpoint %#v", pc)
+		}
+		err := p.Continue()
+		if _, ok := err.(proc.ErrProcessExited); !ok {
+			t.Fatalf("expected the process to exit, got %v", err)
+		}
+	})
+	withTestProcess("panicrecover", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: true, Type: "string", Cond: `panic.(string) == "a string"`}), t, "SetPanicCatchpoint")
+		assertNoError(p.Continue(), t, "Continue")
+		info, err := p.ThreadPanic(p.CurrentThread())
+		assertNoError(err, t, "ThreadPanic")
+		if info == nil || info.Frame.Current.Fn == nil || info.Frame.Current.Fn.Name != "main.main.func1" {
+			t.Fatalf("wrong panic %#v", info)
+		}
+		if err := p.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: true, Cond: "panic =="}); err == nil {
+			t.Fatal("invalid condition accepted")
+		}
+	})
+}
+
+func TestPanicCatchpointRecovered(t *testing.T) {
+	withTestProcess("panicrecover", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: true, Type: "*errors.errorString"}), t, "SetPanicCatchpoint")
+
+		// the first panic has a string value and is filtered out
+		assertNoError(p.Continue(), t, "Continue")
+		bp := p.CurrentThread().Breakpoint()
+		if bp.Breakpoint == nil || bp.Name != proc.PanicCatch {
+			t.Fatalf("not stopped at the panic catchpoint: %v", bp.Breakpoint)
+		}
+		info, err := p.ThreadPanic(p.CurrentThread())
+		assertNoError(err, t, "ThreadPanic")
+		if info.Type != "*errors.errorString" {
+			t.Fatalf("wrong panic type %q", info.Type)
+		}
+		if info.Frame.Current.Fn == nil || info.Frame.Current.Fn.Name != "main.main.func2" {
+			t.Fatalf("wrong panicking frame %v", info.Frame.Current.Fn)
+		}
+
+		assertNoError(p.SetPanicCatchpoint(nil), t, "SetPanicCatchpoint")
+		if pc := p.PanicCatchpoint(); pc != nil {
+			t.Fatalf("panic catchpoint not cleared: %#v", pc)
+		}
+		err = p.Continue()
+		if _, ok := err.(proc.ErrProcessExited); !ok {
+			t.Fatalf("expected the process to exit, got %v", err)
+		}
+	})
+}
+
+func TestStopOnExit(t *testing.T) {
+	withTestProcess("stoponexit", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetStopOnExit(true), t, "SetStopOnExit")
+
+		assertNoError(p.Continue(), t, "Continue")
+		if p.StopReason != proc.StopExiting {
+			t.Fatalf("wrong stop reason %v", p.StopReason)
+		}
+		exit := p.CurrentThread().Common().Exit
+		if exit == nil || exit.Status != 3 {
+			t.Fatalf("wrong exit %#v", exit)
+		}
+
+		// the memory of the process can still be read
+		if v, _ := constant.Int64Val(evalVariable(p, t, "main.status").Value); v != 3 {
+			t.Fatalf("wrong value of main.status %d", v)
+		}
+		frames, err := proc.ThreadStacktrace(p.CurrentThread(), 20)
+		assertNoError(err, t, "ThreadStacktrace")
+		found := false
+		for _, frame := range frames {
+			if frame.Current.Fn != nil && frame.Current.Fn.Name == "main.exitWithStatus" {
+				found = true
+			}
+		}
+		if !found {
+			t.Fatalf("main.exitWithStatus not found in the stack of the thread that called exit")
+		}
+
+		err = p.Continue()
+		pe, ok := err.(proc.ErrProcessExited)
+		if !ok {
+			t.Fatalf("expected the process to exit, got %v", err)
+		}
+		if pe.Status != 3 {
+			t.Fatalf("wrong exit status %d", pe.Status)
+		}
+	})
+}
+
 func TestManualStopWhileStopped(t *testing.T) {
 	// Checks that RequestManualStop sent to a stopped thread does not cause the target process to die.
 	withTestProcess("loopprog", t, func(p *proc.Target, fixture proctest.Fixture) {
@@ -4465,3 +4842,191 @@ func TestNilPtrDerefInBreakInstr(t *testing.T) {
 		}
 	})
 }
+
+func TestCheckpointWatchpoint(t *testing.T) {
+	// Hardware watchpoints must survive restoring a checkpoint.
+	withTestProcess("databpeasy", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.main")
+		assertNoError(p.Continue(), t, "Continue 0")
+		assertLineNumber(p, t, 13, "Continue 0")
+
+		id, err := p.Checkpoint("")
+		assertNoError(err, t, "Checkpoint()")
+
+		scope, err := proc.GoroutineScope(p, p.CurrentThread())
+		assertNoError(err, t, "GoroutineScope")
+		bp, err := p.SetWatchpoint(scope, "globalvar1", proc.WatchWrite, nil)
+		assertNoError(err, t, "SetWatchpoint")
+
+		// go back to the checkpoint, the watchpoint must still trigger
+		assertNoError(p.Restart(fmt.Sprintf("c%d", id)), t, "Restart()")
+		assertNoError(p.Continue(), t, "Continue 1")
+		assertLineNumber(p, t, 19, "Continue 1")
+
+		if curbp := p.CurrentThread().Breakpoint().Breakpoint; curbp == nil || (curbp.LogicalID() != bp.LogicalID()) {
+			t.Fatal("watchpoint not hit after Restart")
+		}
+	})
+}
+
+func TestCheckpointRewind(t *testing.T) {
+	withTestProcess("increment", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.Increment")
+
+		assertY := func(tgt int64) {
+			t.Helper()
+			y := evalVariable(p, t, "y")
+			if n, _ := constant.Int64Val(y.Value); n != tgt {
+				t.Fatalf("wrong value for y: %d, expected %d", n, tgt)
+			}
+		}
+
+		assertNoError(p.Continue(), t, "Continue()")
+		assertY(3)
+		_, err := p.Checkpoint("")
+		assertNoError(err, t, "Checkpoint()")
+		assertNoError(p.Continue(), t, "Continue()")
+		assertY(1)
+		assertNoError(p.Continue(), t, "Continue()")
+		assertY(0)
+
+		// go back to the previous breakpoint hit
+		assertNoError(p.Rewind(), t, "Rewind()")
+		assertY(1)
+		// go back to the checkpoint
+		assertNoError(p.Rewind(), t, "Rewind()")
+		assertY(3)
+		if err := p.Rewind(); err != proc.ErrNoCheckpoint {
+			t.Fatalf("expected ErrNoCheckpoint, got %v", err)
+		}
+	})
+}
+
+func TestRecordReverseStep(t *testing.T) {
+	withTestProcess("increment", t, func(p *proc.Target, fixture proctest.Fixture) {
+		setFunctionBreakpoint(p, t, "main.Increment")
+		assertNoError(p.Continue(), t, "Continue()")
+		assertLineNumber(p, t, 7, "Continue")
+
+		assertNoError(p.StartRecording(), t, "StartRecording()")
+		assertNoError(p.Next(), t, "Next()")
+		assertLineNumber(p, t, 10, "Next")
+		assertNoError(p.Next(), t, "Next()")
+		assertLineNumber(p, t, 11, "Next")
+		assertNoError(p.Step(), t, "Step()")
+		assertLineNumber(p, t, 7, "Step")
+		if y := evalVariable(p, t, "y"); constant.Compare(y.Value, token.NEQ, constant.MakeInt64(1)) {
+			t.Fatalf("wrong value for y: %v, expected 1", y.Value)
+		}
+		assertNoError(p.StopRecording(), t, "StopRecording()")
+
+		assertNoError(p.ChangeDirection(proc.Backward), t, "ChangeDirection(Backward)")
+		assertNoError(p.Step(), t, "reverse Step()")
+		assertLineNumber(p, t, 11, "reverse Step")
+		assertNoError(p.Next(), t, "reverse Next()")
+		assertLineNumber(p, t, 10, "reverse Next")
+		assertNoError(p.Next(), t, "reverse Next()")
+		assertLineNumber(p, t, 7, "reverse Next")
+		if y := evalVariable(p, t, "y"); constant.Compare(y.Value, token.NEQ, constant.MakeInt64(3)) {
+			t.Fatalf("wrong value for y: %v, expected 3", y.Value)
+		}
+
+		// replay what was recorded
+		assertNoError(p.ChangeDirection(proc.Forward), t, "ChangeDirection(Forward)")
+		assertNoError(p.Next(), t, "Next()")
+		assertLineNumber(p, t, 10, "replayed Next")
+	})
+}
+
+func TestFollowExec(t *testing.T) {
+	withTestProcess("followexec", t, func(p *proc.Target, fixture proctest.Fixture) {
+		grp := p.Group()
+		grp.NewTargetHook = func(parent, tgt *proc.Target) error {
+			setFunctionBreakpoint(tgt, t, "main.child")
+			return nil
+		}
+		assertNoError(grp.SetFollowMode(proc.FollowExec), t, "SetFollowMode()")
+		assertNoError(p.Continue(), t, "Continue()")
+
+		if len(grp.Targets()) != 2 {
+			t.Fatalf("wrong number of targets: %d", len(grp.Targets()))
+		}
+		child := grp.Selected
+		if child == p || child.Pid() == p.Pid() {
+			t.Fatalf("stopped in the wrong process %d", child.Pid())
+		}
+		loc, err := child.CurrentThread().Location()
+		assertNoError(err, t, "Location()")
+		if loc.Fn == nil || loc.Fn.Name != "main.child" {
+			t.Fatalf("wrong location %v", loc)
+		}
+
+		// the child exits, then its parent
+		err = child.Continue()
+		if _, exited := err.(proc.ErrProcessExited); !exited {
+			t.Fatalf("expected process exited error, got %v", err)
+		}
+		if ok, _ := child.Valid(); ok {
+			t.Fatalf("child process did not exit")
+		}
+	})
+}
+
+func TestNonStop(t *testing.T) {
+	withTestProcess("testthreads", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.SetNonStop(true), t, "SetNonStop()")
+		bp := setFunctionBreakpoint(p, t, "main.anotherthread")
+
+		assertStoppedAtBp := func(msg string) {
+			t.Helper()
+			curth := p.CurrentThread()
+			if curbp := curth.Breakpoint().Breakpoint; curbp == nil || curbp.Addr != bp.Addr {
+				t.Fatalf("%s: thread %d not stopped at breakpoint", msg, curth.ThreadID())
+			}
+			if curth.Common().Running {
+				t.Fatalf("%s: thread %d is running", msg, curth.ThreadID())
+			}
+		}
+
+		assertNoError(p.Continue(), t, "Continue()")
+		assertStoppedAtBp("Continue")
+		if !p.HasRunningThreads() {
+			t.Fatal("all threads were stopped")
+		}
+
+		assertNoError(p.ContinueThread(p.CurrentThread().ThreadID()), t, "ContinueThread()")
+		assertStoppedAtBp("ContinueThread")
+
+		assertNoError(p.StopRunning(), t, "StopRunning()")
+		if p.HasRunningThreads() {
+			t.Fatal("threads still running after StopRunning")
+		}
+	})
+}
+
+func TestFollowCallsDepth(t *testing.T) {
+	// Increment(3) calls Increment(1) which calls Increment(0), only the
+	// first two calls are within two calls of main.main.
+	withTestProcess("increment", t, func(p *proc.Target, fixture proctest.Fixture) {
+		bp := setFunctionBreakpoint(p, t, "main.Increment")
+		bp.RootFuncName = "main.main"
+		bp.TraceFollowCalls = 2
+
+		for _, tc := range []struct{ y, depth int64 }{{3, 1}, {1, 2}} {
+			assertNoError(p.Continue(), t, "Continue()")
+			y := evalVariable(p, t, "y")
+			if n, _ := constant.Int64Val(y.Value); n != tc.y {
+				t.Fatalf("wrong value for y: %d, expected %d", n, tc.y)
+			}
+			depth, ok := proc.FollowCallsDepth(p.CurrentThread(), bp)
+			if !ok || int64(depth) != tc.depth {
+				t.Fatalf("wrong depth for y=%d: %d %v, expected %d", tc.y, depth, ok, tc.depth)
+			}
+		}
+
+		err := p.Continue()
+		if _, exited := err.(proc.ErrProcessExited); !exited {
+			t.Fatalf("expected process to exit, got %v", err)
+		}
+	})
+}
@@ -1,7 +1,14 @@
 package proc
 
 import (
+	"go/parser"
+	"go/token"
+	"reflect"
 	"testing"
+
+	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
+	"github.com/hitzhangjie/dlv/pkg/dwarf/op"
+	"github.com/hitzhangjie/dlv/pkg/proc/internal/ebpf"
 )
 
 func TestAlignAddr(t *testing.T) {
@@ -96,3 +103,151 @@ func TestConvertInt(t *testing.T) {
 		}
 	}
 }
+
+func TestStepSkipRuleMatch(t *testing.T) {
+	for _, tc := range []struct {
+		kind    StepSkipKind
+		pattern string
+		fn      string
+		tgt     bool
+	}{
+		{StepSkipPackage, "github.com/org/repo", "github.com/org/repo.F", true},
+		{StepSkipPackage, "github.com/org/*", "github.com/org/repo/sub.F", false},
+		{StepSkipPackage, "github.com/org/**", "github.com/org/repo/sub.F", true},
+		{StepSkipPackage, "github.com/org/**", "github.com/org.F", true},
+		{StepSkipPackage, "github.com/**/sub", "github.com/org/repo/sub.F", true},
+		{StepSkipPackage, "github.com/**/sub", "github.com/org/repo/other.F", false},
+		{StepSkipPackage, "**", "main.main", true},
+		{StepSkipFunction, "**/*.String", "github.com/org/repo.(*T).String", true},
+		{StepSkipFunction, "main.skip*", "main.skipme", true},
+	} {
+		rule := StepSkipRule{Kind: tc.kind, Pattern: tc.pattern}
+		if out := rule.match(nil, &Function{Name: tc.fn}); out != tc.tgt {
+			t.Errorf("%s %s matching %s: got %v expected %v", tc.kind, tc.pattern, tc.fn, out, tc.tgt)
+		}
+	}
+}
+
+func ebpfTestArgs() []ebpf.UProbeArgMap {
+	common := func(name string, size int64, kind reflect.Kind) godwarf.CommonType {
+		return godwarf.CommonType{Name: name, ByteSize: size, ReflectKind: kind}
+	}
+	intType := &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: common("int", 8, reflect.Int)}}
+	int32Type := &godwarf.IntType{BasicType: godwarf.BasicType{CommonType: common("int32", 4, reflect.Int32)}}
+	stringType := &godwarf.StringType{StructType: godwarf.StructType{CommonType: common("string", 16, reflect.String)}}
+	header := &godwarf.StructType{CommonType: common("main.Header", 16, reflect.Struct), Field: []*godwarf.StructField{
+		{Name: "Host", Type: stringType, ByteOffset: 0},
+	}}
+	req := &godwarf.StructType{CommonType: common("main.Req", 32, reflect.Struct), Field: []*godwarf.StructField{
+		{Name: "ID", Type: intType, ByteOffset: 0},
+		{Name: "Name", Type: stringType, ByteOffset: 8},
+		{Name: "Header", Type: &godwarf.PtrType{CommonType: common("*main.Header", 8, reflect.Ptr), Type: header}, ByteOffset: 24},
+	}}
+	packed := &godwarf.StructType{CommonType: common("main.Packed", 16, reflect.Struct), Field: []*godwarf.StructField{
+		{Name: "A", Type: int32Type, ByteOffset: 0},
+		{Name: "B", Type: int32Type, ByteOffset: 4},
+		{Name: "C", Type: intType, ByteOffset: 8},
+	}}
+
+	return []ebpf.UProbeArgMap{
+		{Name: "req", Offset: 16, Size: 8, Kind: reflect.Ptr, Type: &godwarf.PtrType{CommonType: common("*main.Req", 8, reflect.Ptr), Type: req}},
+		{Name: "hdr", Size: 16, Kind: reflect.Struct, Type: header, InReg: true, Pieces: []int{0, 3}, DwarfPieces: []op.Piece{
+			{Size: 8, Kind: op.RegPiece, Val: 0},
+			{Size: 8, Kind: op.RegPiece, Val: 3},
+		}},
+		{Name: "p", Size: 16, Kind: reflect.Struct, Type: packed, InReg: true, Pieces: []int{0, 3, 1}, DwarfPieces: []op.Piece{
+			{Size: 4, Kind: op.RegPiece, Val: 0},
+			{Size: 4, Kind: op.RegPiece, Val: 3},
+			{Size: 8, Kind: op.RegPiece, Val: 1},
+		}},
+	}
+}
+
+func TestEBPFCapture(t *testing.T) {
+	args := ebpfTestArgs()
+	for _, tc := range []struct {
+		expr   string
+		arg    int
+		derefs []int64
+		offset int64
+		kind   reflect.Kind
+	}{
+		{"req.Name", 0, []int64{0}, 8, reflect.String},
+		{"req.Header.Host", 0, []int64{0, 24}, 0, reflect.String},
+		{"hdr.Host", 1, nil, 0, reflect.String},
+		{"p.C", 2, nil, 16, reflect.Int},
+	} {
+		capture, err := ebpfCapture(args, tc.expr, 8)
+		if err != nil {
+			t.Errorf("%s: %v", tc.expr, err)
+			continue
+		}
+		if capture.Arg != tc.arg || !reflect.DeepEqual(capture.Derefs, tc.derefs) || capture.Offset != tc.offset || capture.Kind != tc.kind {
+			t.Errorf("%s: got arg %d derefs %v offset %d kind %v", tc.expr, capture.Arg, capture.Derefs, capture.Offset, capture.Kind)
+		}
+	}
+
+	if capture, err := ebpfCapture(args, "other.Name", 8); capture != nil || err != nil {
+		t.Errorf("other.Name: expected no capture, got %v %v", capture, err)
+	}
+	for _, expr := range []string{"req.Missing", "req.ID.X", "req.Header[0]"} {
+		if _, err := ebpfCapture(args, expr, 8); err == nil {
+			t.Errorf("%s: expected an error", expr)
+		}
+	}
+}
+
+func TestEBPFFilters(t *testing.T) {
+	args := ebpfTestArgs()
+	args = append(args, ebpf.UProbeArgMap{Name: "ok", Offset: 24, Size: 1, Kind: reflect.Bool, Type: &godwarf.BoolType{BasicType: godwarf.BasicType{CommonType: godwarf.CommonType{Name: "bool", ByteSize: 1, ReflectKind: reflect.Bool}}}})
+
+	type filter struct {
+		arg    int
+		derefs []int64
+		offset int64
+		size   int64
+		signed bool
+		op     token.Token
+		value  int64
+	}
+	for _, tc := range []struct {
+		cond    string
+		filters []filter
+	}{
+		{"p.C > 100", []filter{{2, nil, 16, 8, true, token.GTR, 100}}},
+		{"100 > p.A", []filter{{2, nil, 0, 4, true, token.LSS, 100}}},
+		{"len(req.Name) == 0", []filter{{0, []int64{0}, 16, 8, true, token.EQL, 0}}},
+		{"req.ID >= -1 && (ok == true)", []filter{{0, []int64{0}, 0, 8, true, token.GEQ, -1}, {3, nil, 0, 1, false, token.EQL, 1}}},
+		{"req != nil", []filter{{0, nil, 0, 8, false, token.NEQ, 0}}},
+	} {
+		cond, err := parser.ParseExpr(tc.cond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		filters, err := ebpfFilters(args, cond, 8)
+		if err != nil {
+			t.Errorf("%s: %v", tc.cond, err)
+			continue
+		}
+		if len(filters) != len(tc.filters) {
+			t.Errorf("%s: expected %d filters got %d", tc.cond, len(tc.filters), len(filters))
+			continue
+		}
+		for i, f := range filters {
+			tgt := tc.filters[i]
+			if f.Capture.Arg != tgt.arg || !reflect.DeepEqual(f.Capture.Derefs, tgt.derefs) || f.Capture.Offset != tgt.offset || f.Capture.Size != tgt.size || f.Signed != tgt.signed || f.Op != tgt.op || f.Value != tgt.value {
+				t.Errorf("%s: filter %d mismatch, got %#v", tc.cond, i, f)
+			}
+		}
+	}
+
+	for _, cond := range []string{"p.C > p.A", "p.C || ok", "len(p.C) == 0", "ok > true", "req.Name == 1", "other > 0", "cap(req.Name) == 0"} {
+		expr, err := parser.ParseExpr(cond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ebpfFilters(args, expr, 8); err == nil {
+			t.Errorf("%s: expected an error", cond)
+		}
+	}
+}
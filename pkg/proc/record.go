@@ -0,0 +1,36 @@
+package proc
+
+import "errors"
+
+// ErrNotRecordable is returned by StartRecording when the backend can not
+// record the execution of the target.
+var ErrNotRecordable = errors.New("backend does not support recording")
+
+// StartRecording starts recording the execution of the current thread.
+// While recording the current thread is executed one instruction at a time
+// and the other threads are stopped, the recorded instructions can then be
+// executed backwards by changing the direction of execution to Backward.
+func (t *Target) StartRecording() error {
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	if t.nonStop {
+		return errors.New("can not record in non-stop mode")
+	}
+	rec, ok := t.proc.(Recorder)
+	if !ok {
+		return ErrNotRecordable
+	}
+	return rec.StartRecording(t.CurrentThread().ThreadID())
+}
+
+// StopRecording stops recording, what was recorded so far can still be
+// executed backwards and replayed until the target is resumed without
+// recording.
+func (t *Target) StopRecording() error {
+	rec, ok := t.proc.(Recorder)
+	if !ok {
+		return ErrNotRecordable
+	}
+	return rec.StopRecording()
+}
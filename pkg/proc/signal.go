@@ -0,0 +1,73 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+)
+
+// ErrSignalPolicyNotSupported is returned by SetSignalPolicy when the
+// backend can not intercept the signals received by the target.
+var ErrSignalPolicyNotSupported = errors.New("backend does not support signal policies")
+
+// SignalPolicy describes how a signal received by the target is handled,
+// similarly to gdb's handle command.
+type SignalPolicy struct {
+	Stop  bool // the target stops when it receives the signal
+	Print bool // a message is printed when the target receives the signal
+	Pass  bool // the signal is delivered to the target
+}
+
+// DefaultSignalPolicy is the policy of the signals whose policy was not
+// changed: they are delivered to the target silently.
+var DefaultSignalPolicy = SignalPolicy{Pass: true}
+
+// SignalInfo describes the signal that stopped a thread.
+type SignalInfo struct {
+	Signo int    // signal number
+	Code  int    // si_code of the signal
+	Addr  uint64 // faulting address, only for SIGSEGV, SIGBUS, SIGILL, SIGFPE and SIGTRAP
+}
+
+// SignalPolicy returns the policy of signal sig.
+func (t *Target) SignalPolicy(sig int) SignalPolicy {
+	if policy, ok := t.group.signals[sig]; ok {
+		return policy
+	}
+	return DefaultSignalPolicy
+}
+
+// SignalPolicies returns the policies of all signals whose policy was
+// changed.
+func (t *Target) SignalPolicies() map[int]SignalPolicy {
+	r := make(map[int]SignalPolicy, len(t.group.signals))
+	for sig, policy := range t.group.signals {
+		r[sig] = policy
+	}
+	return r
+}
+
+// SetSignalPolicy changes how signal sig is handled for all the targets of
+// the group. Like in gdb a signal that stops the target is always printed.
+func (t *Target) SetSignalPolicy(sig int, policy SignalPolicy) error {
+	switch syscall.Signal(sig) {
+	case syscall.SIGTRAP, syscall.SIGSTOP, syscall.SIGKILL:
+		return fmt.Errorf("the policy of signal %d can not be changed, it is used by the debugger", sig)
+	}
+	if sig <= 0 || sig > 64 {
+		return fmt.Errorf("invalid signal %d", sig)
+	}
+	sh, ok := t.proc.(SignalHandler)
+	if !ok {
+		return ErrSignalPolicyNotSupported
+	}
+	if policy.Stop {
+		policy.Print = true
+	}
+	sh.SetSignalPolicy(sig, policy)
+	if t.group.signals == nil {
+		t.group.signals = make(map[int]SignalPolicy)
+	}
+	t.group.signals[sig] = policy
+	return nil
+}
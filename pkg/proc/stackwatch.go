@@ -137,7 +137,7 @@ func (t *Target) clearStackWatchBreakpoints(watchpoint *Breakpoint) error {
 // user is notified of the watchpoint going out of scope.
 func watchpointOutOfScope(t *Target, watchpoint *Breakpoint) {
 	t.Breakpoints().WatchOutOfScope = append(t.Breakpoints().WatchOutOfScope, watchpoint)
-	err := t.ClearBreakpoint(watchpoint.Addr)
+	err := t.clearWatchpoint(watchpoint)
 	if err != nil {
 		log.Error("could not clear out-of-scope watchpoint: %v", err)
 	}
@@ -151,6 +151,19 @@ func adjustStackWatchpoint(t *Target, th Thread, watchpoint *Breakpoint) {
 	if g == nil {
 		return
 	}
+	if watchpoint.watch == nil {
+		adjustStackWatchChunk(t, g, watchpoint)
+		return
+	}
+	for _, chunk := range watchpoint.watch.chunks {
+		adjustStackWatchChunk(t, g, chunk)
+	}
+	watchpoint.watch.addr = watchpoint.watch.chunks[0].Addr
+}
+
+// adjustStackWatchChunk moves one of the physical breakpoints of a stack
+// watchpoint to the new stack of g.
+func adjustStackWatchChunk(t *Target, g *G, watchpoint *Breakpoint) {
 	err := t.proc.EraseBreakpoint(watchpoint)
 	if err != nil {
 		log.Error("could not adjust watchpoint at %#x: %v", watchpoint.Addr, err)
@@ -0,0 +1,140 @@
+package proc
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// StepSkipKind is the part of a function a StepSkipRule is matched against.
+type StepSkipKind uint8
+
+const (
+	StepSkipFunction StepSkipKind = iota // the fully qualified name of the function
+	StepSkipPackage                      // the import path of the package of the function
+	StepSkipFile                         // the source file containing the function
+)
+
+// ParseStepSkipKind returns the StepSkipKind called s, one of "func",
+// "package" or "file".
+func ParseStepSkipKind(s string) (StepSkipKind, error) {
+	switch s {
+	case "func":
+		return StepSkipFunction, nil
+	case "package":
+		return StepSkipPackage, nil
+	case "file":
+		return StepSkipFile, nil
+	}
+	return 0, fmt.Errorf("unknown step skip rule kind %q, must be one of func, package or file", s)
+}
+
+func (kind StepSkipKind) String() string {
+	switch kind {
+	case StepSkipFunction:
+		return "func"
+	case StepSkipPackage:
+		return "package"
+	case StepSkipFile:
+		return "file"
+	}
+	return "unknown"
+}
+
+// StepSkipRule describes functions that Step never stops in.
+// Pattern is a glob, with the syntax of path.Match, matched against the
+// part of the function described by Kind, a "**" path element matches any
+// number of path elements, for example "github.com/org/**" matches every
+// package below github.com/org. File patterns that do not contain a path
+// separator are matched against the base name of the file.
+type StepSkipRule struct {
+	Kind    StepSkipKind
+	Pattern string
+}
+
+// match returns true if fn is matched by rule.
+func (rule StepSkipRule) match(bi *BinaryInfo, fn *Function) bool {
+	var name string
+	switch rule.Kind {
+	case StepSkipFunction:
+		name = fn.Name
+	case StepSkipPackage:
+		name = fn.PackageName()
+	case StepSkipFile:
+		name, _, _ = bi.PCToLine(fn.Entry)
+		if !containsSeparator(rule.Pattern) {
+			name = filepath.Base(name)
+		}
+	}
+	return globMatch(strings.Split(filepath.ToSlash(rule.Pattern), "/"), strings.Split(filepath.ToSlash(name), "/"))
+}
+
+// globMatch returns true if the path elements of name are matched by the
+// elements of pattern, a "**" element matches zero or more elements.
+func globMatch(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := len(name); i >= 0; i-- {
+				if globMatch(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func containsSeparator(pattern string) bool {
+	for _, ch := range pattern {
+		if ch == '/' || ch == filepath.Separator {
+			return true
+		}
+	}
+	return false
+}
+
+// skipStepInto returns true if fn is matched by one of the rules in
+// t.StepSkip.
+func (t *Target) skipStepInto(fn *Function) bool {
+	if fn == nil {
+		return false
+	}
+	for _, rule := range t.StepSkip {
+		if rule.match(t.BinInfo(), fn) {
+			return true
+		}
+	}
+	return false
+}
+
+// stepOutOfSkipped continues stepping out of the current function while it
+// is matched by t.StepSkip, so that a step that returned from a function to
+// a skipped caller stops in the first caller that is not skipped.
+func (t *Target) stepOutOfSkipped() error {
+	if len(t.StepSkip) == 0 || t.GetDirection() == Backward {
+		return nil
+	}
+	for t.StopReason == StopNextFinished && !t.Breakpoints().HasSteppingBreakpoints() {
+		topframe, retframe, err := topframe(t.SelectedGoroutine(), t.CurrentThread())
+		if err != nil || !t.skipStepInto(topframe.Current.Fn) {
+			return nil
+		}
+		if retframe.Current.Fn == nil {
+			// nothing to step out to
+			return nil
+		}
+		if err := t.StepOut(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
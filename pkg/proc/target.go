@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"go/constant"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -32,8 +33,10 @@ const (
 // Target represents the process being debugged.
 type Target struct {
 	Process
+	RecordingManipulation
 
-	proc ProcessInternal
+	proc   ProcessInternal
+	recman RecordingManipulationInternal
 
 	pid int
 
@@ -49,6 +52,13 @@ type Target struct {
 	// will keep the stepping breakpoints instead of clearing them.
 	KeepSteppingBreakpoints KeepSteppingBreakpoints
 
+	// StepSkip lists the functions that Step does not stop in, it steps
+	// through them instead.
+	StepSkip []StepSkipRule
+
+	// panicCatch are the panics that stop the target, see SetPanicCatchpoint.
+	panicCatch *PanicCatchpoint
+
 	// currentThread is the thread that will be used by next/step/stepout and to evaluate variables if no goroutine is selected.
 	currentThread Thread
 
@@ -77,6 +87,20 @@ type Target struct {
 	// can be given a unique address.
 	fakeMemoryRegistry    []*compositeMemory
 	fakeMemoryRegistryMap map[string]*compositeMemory
+
+	// history records checkpoints and breakpoint hits, see Rewind.
+	history stopHistory
+
+	// group is the group of targets this target belongs to.
+	group *TargetGroup
+
+	// nonStop is true if only the thread that stops is halted, see SetNonStop.
+	nonStop  bool
+	contMode ContinueMode
+
+	// ebpfInlinedCalls maps the start of the inlined calls traced with eBPF
+	// to the function being called.
+	ebpfInlinedCalls map[uint64]*Function
 }
 
 type KeepSteppingBreakpoints uint8
@@ -125,6 +149,12 @@ func (sr StopReason) String() string {
 		return "call returned"
 	case StopWatchpoint:
 		return "watchpoint"
+	case StopSignal:
+		return "signal"
+	case StopSyscall:
+		return "syscall"
+	case StopExiting:
+		return "exiting"
 	default:
 		return ""
 	}
@@ -141,6 +171,9 @@ const (
 	StopNextFinished                   // The next/step/stepout command terminated
 	StopCallReturned                   // An injected call completed
 	StopWatchpoint                     // The target process hit one or more watchpoints
+	StopSignal                         // The target process received a signal whose policy is to stop
+	StopSyscall                        // The target process entered or returned from a caught system call
+	StopExiting                        // The target process is about to exit, see SetStopOnExit
 )
 
 // NewTargetConfig contains the configuration for a new Target object,
@@ -179,6 +212,15 @@ func NewTarget(p ProcessInternal, pid int, currentThread Thread, cfg NewTargetCo
 		pid:           pid,
 	}
 
+	if recman, ok := p.(RecordingManipulationInternal); ok {
+		t.recman = recman
+	} else {
+		t.recman = &dummyRecordingManipulation{}
+	}
+	t.RecordingManipulation = t.recman
+	t.history.init()
+	t.group = newGroup(t)
+
 	g, _ := GetG(currentThread)
 	t.selectedGoroutine = g
 
@@ -251,9 +293,18 @@ func (t *Target) ClearCaches() {
 }
 
 // Restart will start the process over from the location specified by the "from" locspec.
-// This is only useful for recorded targets.
+// This is only useful for recorded targets and for backends supporting checkpoints.
 // Restarting of a normal process happens at a higher level (debugger.Restart).
 func (t *Target) Restart(from string) error {
+	currentThread, err := t.recman.Restart(from)
+	if err != nil {
+		return err
+	}
+	t.currentThread = currentThread
+	if p, ok := t.proc.(interface{ Pid() int }); ok {
+		// restoring a checkpoint replaces the process
+		t.pid = p.Pid()
+	}
 	t.ClearCaches()
 	t.selectedGoroutine, _ = GetG(t.CurrentThread())
 	if from != "" {
@@ -388,28 +439,50 @@ func (t *Target) CurrentThread() Thread {
 type UProbeTraceResult struct {
 	FnAddr       int
 	GoroutineID  int
+	Inlined      bool      // True if FnAddr is the start of an inlined call.
+	InlinedFn    *Function // Function called by the inlined call, if Inlined is set.
 	InputParams  []*Variable
 	ReturnParams []*Variable
+	Captures     []*Variable
 }
 
 func (t *Target) GetBufferedTracepoints() []*UProbeTraceResult {
 	var results []*UProbeTraceResult
 	tracepoints := t.proc.GetBufferedTracepoints()
 	convertInputParamToVariable := func(ip *ebpf.RawUProbeParam) *Variable {
-		v := &Variable{}
-		v.RealType = ip.RealType
-		v.Len = ip.Len
-		v.Base = ip.Base
-		v.Addr = ip.Addr
-		v.Kind = ip.Kind
+		if ip.Err != nil {
+			return &Variable{Name: ip.Name, RealType: ip.RealType, Kind: ip.Kind, Unreadable: ip.Err}
+		}
 
 		cachedMem := CreateLoadedCachedMemory(ip.Data)
 		compMem, _ := CreateCompositeMemory(cachedMem, t.BinInfo().Arch, op.DwarfRegisters{}, ip.Pieces)
-		v.mem = compMem
+
+		var v *Variable
+		if ip.RealType != nil {
+			v = newVariable(ip.Name, ip.Addr, ip.RealType, t.BinInfo(), compMem)
+		} else {
+			v = &Variable{Name: ip.Name, Addr: ip.Addr, Kind: ip.Kind, mem: compMem}
+		}
+
+		// Only the data directly referenced by strings and slices was read
+		// by the eBPF program, everything else lives in the memory of the
+		// target process and must not be followed.
+		cfg := loadFullValue
+		cfg.FollowPointers = false
+		switch ip.Kind {
+		case reflect.String:
+			v.Len = ip.Len
+			v.Base = ip.Base
+			cfg.MaxStringLen = int(ip.Loaded)
+		case reflect.Slice:
+			v.Len = ip.Len
+			v.Base = ip.Base
+			cfg.MaxArrayValues = int(ip.Loaded)
+		}
 
 		// Load the value here so that we don't have to export
 		// loadValue outside of proc.
-		v.loadValue(loadFullValue)
+		v.loadValue(cfg)
 
 		return v
 	}
@@ -417,6 +490,10 @@ func (t *Target) GetBufferedTracepoints() []*UProbeTraceResult {
 		r := &UProbeTraceResult{}
 		r.FnAddr = tp.FnAddr
 		r.GoroutineID = tp.GoroutineID
+		if tp.Inlined {
+			r.Inlined = true
+			r.InlinedFn = t.ebpfInlinedCalls[uint64(tp.FnAddr)]
+		}
 		for _, ip := range tp.InputParams {
 			v := convertInputParamToVariable(ip)
 			r.InputParams = append(r.InputParams, v)
@@ -425,11 +502,23 @@ func (t *Target) GetBufferedTracepoints() []*UProbeTraceResult {
 			v := convertInputParamToVariable(ip)
 			r.ReturnParams = append(r.ReturnParams, v)
 		}
+		for _, ip := range tp.Captures {
+			v := convertInputParamToVariable(ip)
+			r.Captures = append(r.Captures, v)
+		}
 		results = append(results, r)
 	}
 	return results
 }
 
+// GetLatencyHistograms returns the latency histograms collected by eBPF
+// tracepoints set with latency measurement enabled, indexed by function
+// entry point. The i-th element of each histogram counts the calls that
+// took between 2^i and 2^(i+1)-1 nanoseconds.
+func (t *Target) GetLatencyHistograms() map[uint64][]uint64 {
+	return t.proc.GetLatencyHistograms()
+}
+
 // SetNextBreakpointID sets the breakpoint ID of the next breakpoint
 func (t *Target) SetNextBreakpointID(id int) {
 	t.Breakpoints().breakpointIDCounter = id
@@ -66,6 +66,7 @@ func (t *Target) Continue() error {
 			}
 		}
 	}()
+	mode := t.contMode
 	for {
 		if t.CheckAndClearManualStopRequest() {
 			t.StopReason = StopManual
@@ -74,11 +75,33 @@ func (t *Target) Continue() error {
 			}
 			return nil
 		}
-		t.ClearCaches()
+		t.group.clearCaches()
+		t.setContinueMode(mode)
 		trapthread, stopReason, contOnceErr := t.proc.ContinueOnce()
+		if trapthread == nil && contOnceErr == nil {
+			// only happens in non-stop mode with ContinueMode.NoHang set
+			return errNoStop
+		}
+		if trapthread != nil {
+			if tgt := t.group.targetForThread(trapthread.ThreadID()); tgt != nil && tgt != t {
+				// All targets of the group are resumed together, the stop happened
+				// in a different process and from now on it's that target that
+				// handles it.
+				trapthread, _ = tgt.FindThread(trapthread.ThreadID())
+				t = tgt
+				t.group.Selected = t
+			}
+		}
 		t.StopReason = stopReason
+		t.history.event++
 
 		threads := t.ThreadList()
+		if t.nonStop && trapthread != nil {
+			// The other threads are either running or were already stopped
+			// before, if the stop is not reported only this thread is resumed.
+			threads = []Thread{trapthread}
+			mode = ContinueMode{ThreadID: trapthread.ThreadID(), NoHang: mode.NoHang}
+		}
 		for _, thread := range threads {
 			if thread.Breakpoint().Breakpoint != nil {
 				thread.Breakpoint().Breakpoint.checkCondition(t, thread, thread.Breakpoint())
@@ -167,6 +190,15 @@ func (t *Target) Continue() error {
 				if err := conditionErrors(threads); err != nil {
 					return err
 				}
+				if t.GetDirection() == Backward {
+					// we are right after a CALL instruction, step back into the
+					// function that was called.
+					if err := t.ClearSteppingBreakpoints(); err != nil {
+						return err
+					}
+					t.StopReason = StopNextFinished
+					return t.StepInstruction()
+				}
 				text, err := disassembleCurrentInstruction(t, curthread, 0)
 				if err != nil {
 					return err
@@ -207,7 +239,11 @@ func (t *Target) Continue() error {
 			t.StopReason = StopBreakpoint
 			if curbp.Breakpoint.WatchType != 0 {
 				t.StopReason = StopWatchpoint
+				if curbp.watch != nil {
+					curbp.WatchHit = curbp.watch.describeHit(t.Memory(), curbp)
+				}
 			}
+			t.history.hits = append(t.history.hits, breakpointHit{event: t.history.event, addr: curbp.Addr})
 			return conditionErrors(threads)
 		default:
 			// not a manual stop, not on runtime.Breakpoint, not on a breakpoint, just repeat
@@ -317,7 +353,10 @@ func (t *Target) Step() (err error) {
 		return err
 	}
 
-	return t.Continue()
+	if err = t.Continue(); err != nil {
+		return err
+	}
+	return t.stepOutOfSkipped()
 }
 
 // sameGoroutineCondition returns an expression that evaluates to true when
@@ -369,6 +408,15 @@ func (t *Target) StepOut() error {
 
 	sameGCond := sameGoroutineCondition(selg)
 
+	if t.GetDirection() == Backward {
+		if err := stepOutReverse(t, topframe, retframe, sameGCond); err != nil {
+			return err
+		}
+
+		success = true
+		return t.Continue()
+	}
+
 	deferpc, err := setDeferBreakpoint(t, nil, topframe, sameGCond, false)
 	if err != nil {
 		return err
@@ -463,6 +511,7 @@ func (t *Target) StepInstruction() (err error) {
 // when removing instructions belonging to inlined calls we also remove all
 // instructions belonging to the current inlined call.
 func next(dbp *Target, stepInto, inlinedStepOut bool) error {
+	backward := dbp.GetDirection() == Backward
 	selg := dbp.SelectedGoroutine()
 	curthread := dbp.CurrentThread()
 	topframe, retframe, err := topframe(selg, curthread)
@@ -498,6 +547,24 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 
 	sameGCond := sameGoroutineCondition(selg)
 
+	var firstPCAfterPrologue uint64
+
+	if backward {
+		firstPCAfterPrologue, err = FirstPCAfterPrologue(dbp, topframe.Current.Fn, false)
+		if err != nil {
+			return err
+		}
+		if firstPCAfterPrologue == topframe.Current.PC {
+			// We don't want to step into the prologue so we just execute a reverse step out instead
+			if err := stepOutReverse(dbp, topframe, retframe, sameGCond); err != nil {
+				return err
+			}
+
+			success = true
+			return nil
+		}
+	}
+
 	text, err := disassemble(dbp.Memory(), regs, dbp.Breakpoints(), dbp.BinInfo(), topframe.Current.Fn.Entry, topframe.Current.Fn.End, false)
 	if err != nil && stepInto {
 		return err
@@ -508,16 +575,18 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 		sameFrameCond = astutil.And(sameGCond, frameoffCondition(&topframe))
 	}
 
-	if stepInto {
+	if stepInto && !backward {
 		err := setStepIntoBreakpoints(dbp, topframe.Current.Fn, text, topframe, sameGCond)
 		if err != nil {
 			return err
 		}
 	}
 
-	_, err = setDeferBreakpoint(dbp, text, topframe, sameGCond, stepInto)
-	if err != nil {
-		return err
+	if !backward {
+		_, err = setDeferBreakpoint(dbp, text, topframe, sameGCond, stepInto)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Add breakpoints on all the lines in the current function
@@ -526,6 +595,20 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 		return err
 	}
 
+	if backward {
+		// Ensure that pcs contains firstPCAfterPrologue when reverse stepping.
+		found := false
+		for _, pc := range pcs {
+			if pc == firstPCAfterPrologue {
+				found = true
+				break
+			}
+		}
+		if !found {
+			pcs = append(pcs, firstPCAfterPrologue)
+		}
+	}
+
 	if !stepInto {
 		// Removing any PC range belonging to an inlined call
 		frame := topframe
@@ -562,6 +645,13 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 		}
 	}
 
+	if stepInto && backward {
+		err := setStepIntoBreakpointsReverse(dbp, text, topframe, sameGCond)
+		if err != nil {
+			return err
+		}
+	}
+
 	if !topframe.Inlined {
 		topframe, retframe := skipAutogeneratedWrappersOut(selg, curthread, &topframe, &retframe)
 		retFrameCond := astutil.And(sameGCond, frameoffCondition(retframe))
@@ -570,10 +660,16 @@ func next(dbp *Target, stepInto, inlinedStepOut bool) error {
 		// For inlined functions there is no need to do this, the set of PCs
 		// returned by the AllPCsBetween call above already cover all instructions
 		// of the containing function.
-		bp, _ := dbp.SetBreakpoint(retframe.Current.PC, NextBreakpoint, retFrameCond)
+		// When going backwards the breakpoint goes on the CALL instruction
+		// instead.
+		retpc := retframe.Current.PC
+		if backward && retframe.Current.Fn != nil {
+			retpc, _ = findCallInstrForRet(dbp, dbp.Memory(), retpc, retframe.Current.Fn)
+		}
+		bp, _ := dbp.SetBreakpoint(retpc, NextBreakpoint, retFrameCond)
 		// Return address could be wrong, if we are unable to set a breakpoint
 		// there it's ok.
-		if bp != nil {
+		if bp != nil && !backward {
 			configureReturnBreakpoint(dbp.BinInfo(), bp, topframe, retFrameCond)
 		}
 	}
@@ -613,7 +709,7 @@ func setStepIntoBreakpointsReverse(dbp *Target, text []AsmInstruction, topframe
 			continue
 		}
 
-		if instr.DestLoc.Fn.privateRuntime() {
+		if instr.DestLoc.Fn.privateRuntime() || dbp.skipStepInto(instr.DestLoc.Fn) {
 			continue
 		}
 
@@ -697,9 +793,10 @@ func setStepIntoBreakpoint(dbp *Target, curfn *Function, text []AsmInstruction,
 		return nil
 	}
 
-	//TODO(aarzilli): if we want to let users hide functions
-	// or entire packages from being stepped into with 'step'
-	// those extra checks should be done here.
+	// Skip functions hidden by the user, the call is stepped over.
+	if dbp.skipStepInto(fn) {
+		return nil
+	}
 
 	pc := instr.DestLoc.PC
 
@@ -851,7 +948,7 @@ func setDeferBreakpoint(p *Target, text []AsmInstruction, topframe Stackframe, s
 	var deferpc uint64
 	if topframe.TopmostDefer != nil && topframe.TopmostDefer.DwrapPC != 0 {
 		_, _, deferfn := topframe.TopmostDefer.DeferredFunc(p)
-		if deferfn != nil {
+		if deferfn != nil && !(stepInto && p.skipStepInto(deferfn)) {
 			var err error
 			deferpc, err = FirstPCAfterPrologue(p, deferfn, false)
 			if err != nil {
@@ -880,6 +977,66 @@ func setDeferBreakpoint(p *Target, text []AsmInstruction, topframe Stackframe, s
 	return deferpc, nil
 }
 
+// stepOutReverse sets a breakpoint on the CALL instruction that created the current frame, this is either:
+//   - the CALL instruction immediately preceding the return address of the
+//     current frame
+//   - the return address of the current frame if the current frame was
+//     created by a runtime.deferreturn run
+//   - the return address of the runtime.gopanic frame if the current frame
+//     was created by a panic
+//
+// This function is used to implement reversed StepOut
+func stepOutReverse(p *Target, topframe, retframe Stackframe, sameGCond ast.Expr) error {
+	curthread := p.CurrentThread()
+	selg := p.SelectedGoroutine()
+
+	if selg != nil && selg.Thread != nil {
+		curthread = selg.Thread
+	}
+
+	callerText, err := disassemble(p.Memory(), nil, p.Breakpoints(), p.BinInfo(), retframe.Current.Fn.Entry, retframe.Current.Fn.End, false)
+	if err != nil {
+		return err
+	}
+	deferReturns := FindDeferReturnCalls(callerText)
+
+	var frames []Stackframe
+	if selg == nil {
+		frames, err = ThreadStacktrace(curthread, 3)
+	} else {
+		frames, err = selg.Stacktrace(3, 0)
+	}
+	if err != nil {
+		return err
+	}
+
+	var callpc uint64
+
+	if ok, pc := isDeferReturnCall(frames, deferReturns); ok {
+		callpc = pc
+	} else if ok, panicFrame := isPanicCall(frames); ok {
+		if len(frames) < panicFrame+2 || frames[panicFrame+1].Current.Fn == nil {
+			if panicFrame < len(frames) {
+				return &ErrNoSourceForPC{frames[panicFrame].Current.PC}
+			}
+			return &ErrNoSourceForPC{frames[0].Current.PC}
+		}
+		callpc, err = findCallInstrForRet(p, p.Memory(), frames[panicFrame].Ret, frames[panicFrame+1].Current.Fn)
+		if err != nil {
+			return err
+		}
+	} else {
+		callpc, err = findCallInstrForRet(p, p.Memory(), topframe.Ret, retframe.Current.Fn)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = allowDuplicateBreakpoint(p.SetBreakpoint(callpc, NextBreakpoint, sameGCond))
+
+	return err
+}
+
 // findCallInstrForRet returns the PC address of the CALL instruction
 // immediately preceding the instruction at ret.
 func findCallInstrForRet(p Process, mem MemoryReadWriter, ret uint64, fn *Function) (uint64, error) {
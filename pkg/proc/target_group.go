@@ -0,0 +1,160 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FollowMode describes which of the processes created by the target are
+// debugged along with it.
+type FollowMode uint8
+
+const (
+	// FollowFork makes children created with fork become new targets.
+	FollowFork FollowMode = 1 << iota
+	// FollowExec replaces a process that calls exec, and any child process
+	// it spawns, with a new target for the program being executed.
+	FollowExec
+)
+
+// ErrFollowNotSupported is returned by SetFollowMode when the backend can not
+// follow child processes.
+var ErrFollowNotSupported = errors.New("backend does not support following child processes")
+
+// TargetGroup is the set of targets debugged together: the process that was
+// launched or attached to and the processes it created that are being
+// followed.
+//
+// All targets in a group are resumed and stopped together, when one of
+// them stops the group switches to it.
+type TargetGroup struct {
+	targets []*Target
+
+	// Selected is the target that commands operate on.
+	Selected *Target
+
+	// NewTargetHook, if set, is called every time a process created by
+	// another target of the group is added to it, before the new target is
+	// resumed. Parent is the target of the process that created it, or the
+	// target the process replaced after calling exec, it can be nil.
+	NewTargetHook func(parent, t *Target) error
+
+	follow FollowMode
+
+	// signals are the signal policies changed by SetSignalPolicy.
+	signals map[int]SignalPolicy
+
+	// syscalls are the system calls that stop the targets.
+	syscalls syscallCatchpoints
+
+	// stopOnExit is true if the targets stop before exiting.
+	stopOnExit bool
+}
+
+func newGroup(t *Target) *TargetGroup {
+	return &TargetGroup{targets: []*Target{t}, Selected: t}
+}
+
+// Group returns the group of targets t belongs to.
+func (t *Target) Group() *TargetGroup {
+	return t.group
+}
+
+// Targets returns the list of targets in the group, including the ones that
+// have exited.
+func (grp *TargetGroup) Targets() []*Target {
+	return grp.targets
+}
+
+// FindTarget returns the target with the specified pid, if more than one
+// target has the same pid (because the process called exec) the valid one
+// is returned.
+func (grp *TargetGroup) FindTarget(pid int) *Target {
+	var r *Target
+	for _, t := range grp.targets {
+		if t.Pid() != pid {
+			continue
+		}
+		r = t
+		if ok, _ := t.Valid(); ok {
+			break
+		}
+	}
+	return r
+}
+
+// SwitchTarget makes the target with the specified pid the selected target.
+func (grp *TargetGroup) SwitchTarget(pid int) error {
+	t := grp.FindTarget(pid)
+	if t == nil {
+		return fmt.Errorf("target %d does not exist", pid)
+	}
+	if _, err := t.Valid(); err != nil {
+		return err
+	}
+	grp.Selected = t
+	return nil
+}
+
+// AddTarget adds t to the group. The parentPid argument is the pid of the
+// process that created t's process, or of the process t replaced after an
+// exec.
+func (grp *TargetGroup) AddTarget(t *Target, parentPid int) error {
+	parent := grp.FindTarget(parentPid)
+	t.group = grp
+	t.nonStop = grp.targets[0].nonStop
+	grp.targets = append(grp.targets, t)
+	if grp.NewTargetHook != nil {
+		return grp.NewTargetHook(parent, t)
+	}
+	return nil
+}
+
+// FollowMode returns the current follow mode of the group.
+func (grp *TargetGroup) FollowMode() FollowMode {
+	return grp.follow
+}
+
+// SetFollowMode changes which child processes are followed.
+func (grp *TargetGroup) SetFollowMode(mode FollowMode) error {
+	for _, t := range grp.targets {
+		if ok, _ := t.Valid(); !ok {
+			continue
+		}
+		f, ok := t.proc.(Follower)
+		if !ok {
+			if mode != 0 {
+				return ErrFollowNotSupported
+			}
+			continue
+		}
+		if err := f.SetFollowMode(mode); err != nil {
+			return err
+		}
+	}
+	grp.follow = mode
+	return nil
+}
+
+// targetForThread returns the valid target that owns thread tid.
+func (grp *TargetGroup) targetForThread(tid int) *Target {
+	for _, t := range grp.targets {
+		if ok, _ := t.Valid(); !ok {
+			continue
+		}
+		if _, ok := t.FindThread(tid); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// clearCaches clears the caches of every target in the group, all of them
+// are resumed together.
+func (grp *TargetGroup) clearCaches() {
+	for _, t := range grp.targets {
+		if ok, _ := t.Valid(); ok {
+			t.ClearCaches()
+		}
+	}
+}
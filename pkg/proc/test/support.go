@@ -287,3 +287,15 @@ func RegabiSupported() bool {
 		return runtime.GOARCH == "amd64"
 	}
 }
+
+// DefaultTestBackend changes the value of testBackend to be the default
+// test backend, if testBackend isn't already set.
+func DefaultTestBackend(testBackend *string) {
+	if *testBackend != "" {
+		return
+	}
+	*testBackend = os.Getenv("PROCTEST")
+	if *testBackend == "" {
+		*testBackend = "native"
+	}
+}
@@ -53,8 +53,19 @@ type Location struct {
 // implementations of the Thread interface.
 type CommonThread struct {
 	CallReturn   bool // returnValues are the return values of a call injection
+	Running      bool // thread is executing, only happens in non-stop mode
 	returnValues []*Variable
 	g            *G // cached g for this thread
+
+	// Signal is the signal that stopped the thread, if its policy is to stop,
+	// see SignalPolicy.
+	Signal *SignalInfo
+	// Syscall is the system call the thread stopped at, if it stopped because
+	// of a syscall catchpoint.
+	Syscall *SyscallInfo
+	// Exit describes the exit of the process, if the thread stopped because
+	// the process is about to exit, see SetStopOnExit.
+	Exit *ExitInfo
 }
 
 // ReturnValues reads the return values from the function executing on
@@ -184,22 +184,29 @@ func resolveParametricType(tgt *Target, bi *BinaryInfo, mem MemoryReadWriter, t
 	if dictAddr == 0 {
 		return ptyp.TypedefType.Type, errors.New("parametric type without a dictionary")
 	}
-	rtypeAddr, err := readUintRaw(mem, dictAddr+uint64(ptyp.DictIndex*int64(bi.Arch.PtrSize())), int64(bi.Arch.PtrSize()))
+	typ, err := dictionaryType(bi, mem, dictAddr, ptyp.DictIndex)
 	if err != nil {
 		return ptyp.TypedefType.Type, err
 	}
+
+	return typ, nil
+}
+
+// dictionaryType returns the type described by the idx-th entry of the
+// dictionary at dictAddr.
+func dictionaryType(bi *BinaryInfo, mem MemoryReadWriter, dictAddr uint64, idx int64) (godwarf.Type, error) {
+	rtypeAddr, err := readUintRaw(mem, dictAddr+uint64(idx*int64(bi.Arch.PtrSize())), int64(bi.Arch.PtrSize()))
+	if err != nil {
+		return nil, err
+	}
 	runtimeType, err := bi.findType("runtime._type")
 	if err != nil {
-		return ptyp.TypedefType.Type, err
+		return nil, err
 	}
 	_type := newVariable("", rtypeAddr, runtimeType, bi, mem)
 
 	typ, _, err := runtimeTypeToDIE(_type, 0)
-	if err != nil {
-		return ptyp.TypedefType.Type, err
-	}
-
-	return typ, nil
+	return typ, err
 }
 
 type nameOfRuntimeTypeEntry struct {
@@ -81,6 +81,9 @@ const (
 	VariableCPtr
 	// VariableCPURegister means this variable is a CPU register.
 	VariableCPURegister
+	// VariableTypeParameter means this variable is a type parameter of a
+	// generic function, its type is the type argument of the instantiation.
+	VariableTypeParameter
 )
 
 // Variable represents a variable. It contains the address, name,
@@ -116,6 +119,9 @@ type Variable struct {
 
 	// closureAddr is the closure address for function variables (0 for non-closures)
 	closureAddr uint64
+	// dictAddr is the dictionary address for function variables that are
+	// instantiations of generic functions
+	dictAddr uint64
 
 	// number of elements to skip when loading a map
 	mapSkip int
@@ -873,6 +879,15 @@ func (v *Variable) parseG() (*G, error) {
 			unreadable = true
 			return 0
 		}
+		if vv.Kind == reflect.Struct {
+			// Since Go 1.20 some fields (atomicstatus) are wrapped in the
+			// runtime/internal/atomic types.
+			vv = vv.fieldVariable("value")
+			if vv == nil {
+				unreadable = true
+				return 0
+			}
+		}
 		n, _ := constant.Int64Val(vv.Value)
 		return n
 	}
@@ -0,0 +1,55 @@
+package proc
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// WaitFor describes a process that the debugger waits for, before
+// attaching to it, because its pid is not known in advance.
+type WaitFor struct {
+	// Name identifies the process. Unless Regexp is set it must be equal
+	// to the name of the process (as in /proc/<pid>/comm), to the path or
+	// the base name of the executable (the first argument of the command
+	// line), or be a prefix of the command line.
+	Name string
+	// Regexp makes Name a regular expression, matched against the name and
+	// the command line of the process.
+	Regexp bool
+	// Interval is the time between two scans of the process list.
+	Interval time.Duration
+	// Duration is the maximum time to wait for, zero waits forever.
+	Duration time.Duration
+}
+
+// Valid returns true if wf describes a process to wait for.
+func (wf *WaitFor) Valid() bool {
+	return wf != nil && wf.Name != ""
+}
+
+// Matcher returns a function that reports whether a process with the given
+// name and command line (with the arguments separated by spaces) is the
+// process described by wf.
+func (wf *WaitFor) Matcher() (func(name, cmdline string) bool, error) {
+	if wf.Regexp {
+		re, err := regexp.Compile(wf.Name)
+		if err != nil {
+			return nil, err
+		}
+		return func(name, cmdline string) bool {
+			return re.MatchString(name) || re.MatchString(cmdline)
+		}, nil
+	}
+	return func(name, cmdline string) bool {
+		if name == wf.Name || strings.HasPrefix(cmdline, wf.Name) {
+			return true
+		}
+		argv0 := cmdline
+		if i := strings.Index(cmdline, " "); i >= 0 {
+			argv0 = cmdline[:i]
+		}
+		return argv0 != "" && filepath.Base(argv0) == wf.Name
+	}, nil
+}
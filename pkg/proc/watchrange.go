@@ -0,0 +1,184 @@
+package proc
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/hitzhangjie/dlv/pkg/dwarf/godwarf"
+)
+
+// This file implements watchpoints on values that do not fit in a single
+// debug register, like strings, slices, arrays and structs.
+//
+// The watched memory is split in aligned chunks of 1, 2, 4 or 8 bytes and
+// each chunk is set as a separate physical watchpoint, all of them sharing
+// the logical ID of the first one. If there are not enough free debug
+// registers for all the chunks the whole range is watched by a single
+// software watchpoint instead, see Breakpoint.SoftwareWatch.
+//
+// When a watchpoint is hit the part of the watched value that was accessed
+// is described in the BreakpointState of the thread, for example "field
+// Foo.bar written".
+
+// watchedMemory is the memory watched by a logical watchpoint.
+type watchedMemory struct {
+	expr     string
+	addr     uint64
+	size     int64
+	kind     reflect.Kind
+	typ      godwarf.Type // type of the watched value, for arrays and slices the type of their elements
+	software bool         // watched by a single software watchpoint
+	chunks   []*Breakpoint
+
+	// old is the content of the watched memory the last time it was checked,
+	// used to determine which part of it was written.
+	old []byte
+}
+
+// watchChunk is a part of the watched memory that fits in a debug register.
+type watchChunk struct {
+	addr uint64
+	size int64
+}
+
+// newWatchedMemory returns the memory that must be watched for xv, the
+// backing memory for strings and slices.
+func newWatchedMemory(t *Target, expr string, xv *Variable) (*watchedMemory, error) {
+	wm := &watchedMemory{expr: expr, addr: xv.Addr, size: xv.DwarfType.Size(), kind: xv.Kind, typ: xv.DwarfType}
+	switch xv.Kind {
+	case reflect.String:
+		wm.addr, wm.size = xv.Base, xv.Len
+	case reflect.Slice:
+		wm.addr, wm.size, wm.typ = xv.Base, xv.Len*xv.stride, xv.fieldType
+	case reflect.Array:
+		wm.typ = xv.fieldType
+	}
+	if wm.addr == 0 || wm.size <= 0 {
+		return nil, fmt.Errorf("can not watch %q: no memory to watch", expr)
+	}
+	wm.old = make([]byte, wm.size)
+	if _, err := t.Memory().ReadMemory(wm.old, wm.addr); err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+// split splits the watched memory into chunks that can be watched by a
+// debug register each.
+func (wm *watchedMemory) split(maxsz int64) []watchChunk {
+	var r []watchChunk
+	addr, size := wm.addr, wm.size
+	for size > 0 {
+		sz := maxsz
+		for sz > 1 && (addr%uint64(sz) != 0 || sz > size) {
+			sz /= 2
+		}
+		r = append(r, watchChunk{addr, sz})
+		addr += uint64(sz)
+		size -= sz
+	}
+	return r
+}
+
+// describeHit returns a description of the part of the watched memory
+// accessed when bpstate, one of the chunks of wm, was hit.
+func (wm *watchedMemory) describeHit(mem MemoryReadWriter, bpstate *BreakpointState) string {
+	off := int64(bpstate.Addr - wm.addr)
+	if bpstate.WatchAddr != 0 {
+		off = int64(bpstate.WatchAddr - wm.addr)
+	}
+	written := false
+	cur := make([]byte, len(wm.old))
+	if _, err := mem.ReadMemory(cur, wm.addr); err == nil {
+		for i := range cur {
+			if cur[i] != wm.old[i] {
+				off = int64(i)
+				written = true
+				break
+			}
+		}
+		wm.old = cur
+	}
+	if off < 0 || off >= wm.size {
+		off = 0
+	}
+
+	wtype := bpstate.WatchType
+	access := "accessed"
+	switch {
+	case written || !wtype.Read():
+		access = "written"
+	case !wtype.Write():
+		access = "read"
+	}
+	return wm.describe(off) + " " + access
+}
+
+// describe returns a description of the part of the watched value at
+// offset off.
+func (wm *watchedMemory) describe(off int64) string {
+	switch wm.kind {
+	case reflect.String:
+		return fmt.Sprintf("byte %s[%d]", wm.expr, off)
+	case reflect.Slice, reflect.Array:
+		if sz := wm.typ.Size(); sz > 0 {
+			return fmt.Sprintf("element %s[%d]%s", wm.expr, off/sz, fieldPath(wm.typ, off%sz))
+		}
+	case reflect.Struct:
+		if path := fieldPath(wm.typ, off); path != "" {
+			return "field " + shortTypeName(wm.typ) + path
+		}
+	}
+	return wm.expr
+}
+
+// fieldPath returns the selector of the part of a value of type typ at
+// offset off, for example ".bar" or ".arr[3].x".
+func fieldPath(typ godwarf.Type, off int64) string {
+	switch t := resolveTypedef(typ).(type) {
+	case *godwarf.StructType:
+		for _, f := range t.Field {
+			if off >= f.ByteOffset && off < f.ByteOffset+f.Type.Size() {
+				return "." + f.Name + fieldPath(f.Type, off-f.ByteOffset)
+			}
+		}
+	case *godwarf.ArrayType:
+		if sz := t.Type.Size(); sz > 0 {
+			return fmt.Sprintf("[%d]", off/sz) + fieldPath(t.Type, off%sz)
+		}
+	}
+	return ""
+}
+
+// shortTypeName returns the name of typ without its package path.
+func shortTypeName(typ godwarf.Type) string {
+	name := typ.Common().Name
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	return name
+}
+
+// clearWatchpoint clears all the chunks of watchpoint.
+func (t *Target) clearWatchpoint(watchpoint *Breakpoint) error {
+	if watchpoint.watch == nil {
+		return t.ClearBreakpoint(watchpoint.Addr)
+	}
+	var errs []string
+	for _, bp := range watchpoint.watch.chunks {
+		if err := t.ClearBreakpoint(bp.Addr); err != nil {
+			if _, notfound := err.(NoBreakpointError); !notfound {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -127,3 +127,67 @@ func resolveCallArgX86(inst *x86asm.Inst, instAddr uint64, currentGoroutine bool
 	}
 	return &Location{PC: pc, File: file, Line: line, Fn: fn}
 }
+
+// x86MaxInstLen is the maximum length of an x86 instruction.
+const x86MaxInstLen = 15
+
+// x86MemArgAddr returns the address of the memory operand of inst, which
+// ends at pc, computed with the values of the registers in regs.
+func x86MemArgAddr(inst *x86asm.Inst, pc uint64, regs *op.DwarfRegisters, bi *BinaryInfo) (uint64, bool) {
+	reg := func(r x86asm.Reg) (uint64, bool) {
+		switch r {
+		case 0:
+			return 0, true
+		case x86asm.RIP:
+			return pc, true
+		}
+		n, err := bi.Arch.getAsmRegister(regs, int(r))
+		return n, err == nil
+	}
+	for _, arg := range inst.Args {
+		mem, ismem := arg.(x86asm.Mem)
+		if !ismem {
+			continue
+		}
+		if mem.Segment != 0 {
+			return 0, false
+		}
+		base, ok1 := reg(mem.Base)
+		index, ok2 := reg(mem.Index)
+		if !ok1 || !ok2 {
+			return 0, false
+		}
+		return uint64(int64(base) + int64(index*uint64(mem.Scale)) + mem.Disp), true
+	}
+	return 0, false
+}
+
+// x86StoreOps are the instructions that write their first operand without
+// reading it.
+var x86StoreOps = map[x86asm.Op]bool{
+	x86asm.MOV: true, x86asm.MOVBE: true, x86asm.MOVD: true, x86asm.MOVQ: true,
+	x86asm.MOVAPD: true, x86asm.MOVAPS: true, x86asm.MOVUPD: true, x86asm.MOVUPS: true,
+	x86asm.MOVDQA: true, x86asm.MOVDQU: true, x86asm.MOVSD_XMM: true, x86asm.MOVSS: true,
+	x86asm.MOVHPD: true, x86asm.MOVHPS: true, x86asm.MOVLPD: true, x86asm.MOVLPS: true,
+	x86asm.MOVNTI: true, x86asm.MOVNTDQ: true, x86asm.MOVNTPD: true, x86asm.MOVNTPS: true,
+	x86asm.MOVNTQ: true, x86asm.MOVNTSD: true, x86asm.MOVNTSS: true,
+	x86asm.POP:  true,
+	x86asm.SETA: true, x86asm.SETAE: true, x86asm.SETB: true, x86asm.SETBE: true,
+	x86asm.SETE: true, x86asm.SETG: true, x86asm.SETGE: true, x86asm.SETL: true,
+	x86asm.SETLE: true, x86asm.SETNE: true, x86asm.SETNO: true, x86asm.SETNP: true,
+	x86asm.SETNS: true, x86asm.SETO: true, x86asm.SETP: true, x86asm.SETS: true,
+}
+
+// x86OnlyWritesMemory returns true if inst writes to memory without reading
+// it.
+func x86OnlyWritesMemory(inst *x86asm.Inst) bool {
+	switch inst.Op {
+	case x86asm.STOSB, x86asm.STOSW, x86asm.STOSD, x86asm.STOSQ:
+		return true
+	}
+	if !x86StoreOps[inst.Op] {
+		return false
+	}
+	_, ismem := inst.Args[0].(x86asm.Mem)
+	return ismem
+}
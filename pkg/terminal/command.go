@@ -28,6 +28,7 @@ import (
 	"github.com/hitzhangjie/dlv/pkg/config"
 	"github.com/hitzhangjie/dlv/pkg/locspec"
 	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
 	"github.com/hitzhangjie/dlv/pkg/terminal/colorize"
 	"github.com/hitzhangjie/dlv/service"
 	"github.com/hitzhangjie/dlv/service/api"
@@ -42,6 +43,7 @@ const (
 	noPrefix = cmdPrefix(0)
 	onPrefix = cmdPrefix(1 << iota)
 	deferredPrefix
+	revPrefix
 )
 
 type callContext struct {
@@ -120,16 +122,27 @@ func DebugCommands(client service.Client) *Commands {
 		{aliases: []string{"help", "h"}, cmdFn: c.help, helpMsg: helpCmdHelpMsg},
 		{aliases: []string{"break", "b"}, group: breakCmds, cmdFn: breakpoint, helpMsg: breakCmdHelpMsg},
 		{aliases: []string{"trace", "t"}, group: breakCmds, cmdFn: tracepoint, allowedPrefixes: onPrefix, helpMsg: traceCmdHelpMsg},
+		{aliases: []string{"watch"}, group: breakCmds, cmdFn: watchpoint, helpMsg: watchCmdHelpMsg},
+		{aliases: []string{"catch"}, group: breakCmds, cmdFn: catch, helpMsg: catchCmdHelpMsg},
 		{aliases: []string{"restart", "r"}, group: runCmds, cmdFn: restart, helpMsg: restartCmdHelpMsg},
 		{aliases: []string{"rebuild"}, group: runCmds, cmdFn: c.rebuild, helpMsg: rebuildCmdHelpMsg},
 		{aliases: []string{"continue", "c"}, group: runCmds, cmdFn: c.cont, helpMsg: continueCmdHelpMsg},
-		{aliases: []string{"step", "s"}, group: runCmds, cmdFn: c.step, helpMsg: stepCmdHelpMsg},
-		{aliases: []string{"step-instruction", "si"}, group: runCmds, cmdFn: c.stepInstruction, helpMsg: stepInstCmdHelpMsg},
-		{aliases: []string{"next", "n"}, group: runCmds, cmdFn: c.next, helpMsg: nextCmdHelpMsg},
-		{aliases: []string{"stepout", "so"}, group: runCmds, cmdFn: c.stepout, helpMsg: stepOutCmdHelpMsg},
+		{aliases: []string{"rewind", "rw"}, group: runCmds, cmdFn: c.rewind, helpMsg: rewindCmdHelpMsg},
+		{aliases: []string{"checkpoint", "check"}, group: runCmds, cmdFn: checkpoint, helpMsg: checkpointCmdHelpMsg},
+		{aliases: []string{"checkpoints"}, group: runCmds, cmdFn: checkpoints, helpMsg: checkpointsCmdHelpMsg},
+		{aliases: []string{"clear-checkpoint", "clearcheck"}, group: runCmds, cmdFn: clearCheckpoint, helpMsg: clearcheckCmdHelpMsg},
+		{aliases: []string{"record"}, group: runCmds, cmdFn: record, helpMsg: recordCmdHelpMsg},
+		{aliases: []string{"handle"}, group: runCmds, cmdFn: handle, helpMsg: handleCmdHelpMsg},
+		{aliases: []string{"rev"}, group: runCmds, cmdFn: c.revCmd, helpMsg: revCmdHelpMsg},
+		{aliases: []string{"step", "s"}, group: runCmds, cmdFn: c.step, allowedPrefixes: revPrefix, helpMsg: stepCmdHelpMsg},
+		{aliases: []string{"step-instruction", "si"}, group: runCmds, cmdFn: c.stepInstruction, allowedPrefixes: revPrefix, helpMsg: stepInstCmdHelpMsg},
+		{aliases: []string{"next", "n"}, group: runCmds, cmdFn: c.next, allowedPrefixes: revPrefix, helpMsg: nextCmdHelpMsg},
+		{aliases: []string{"stepout", "so"}, group: runCmds, cmdFn: c.stepout, allowedPrefixes: revPrefix, helpMsg: stepOutCmdHelpMsg},
 		{aliases: []string{"call"}, group: runCmds, cmdFn: c.call, helpMsg: callCmdHelpMsg},
 		{aliases: []string{"threads"}, group: goroutineCmds, cmdFn: threads, helpMsg: threadsCmdHelpMsg},
 		{aliases: []string{"thread", "tr"}, group: goroutineCmds, cmdFn: thread, helpMsg: threadCmdHelpMsg},
+		{aliases: []string{"targets"}, group: goroutineCmds, cmdFn: targets, helpMsg: targetsCmdHelpMsg},
+		{aliases: []string{"target"}, group: goroutineCmds, cmdFn: target, helpMsg: targetCmdHelpMsg},
 		{aliases: []string{"clear"}, group: breakCmds, cmdFn: clear, helpMsg: clearCmdHelpMsg},
 		{aliases: []string{"clearall"}, group: breakCmds, cmdFn: clearAll, helpMsg: clearallCmdHelpMsg},
 		{aliases: []string{"toggle"}, group: breakCmds, cmdFn: toggle, helpMsg: toggleCmdHelpMsg},
@@ -323,7 +336,9 @@ func threads(t *Term, ctx callContext, args string) error {
 		if state.CurrentThread != nil && state.CurrentThread.ID == th.ID {
 			prefix = "* "
 		}
-		if th.Function != nil {
+		if th.Running {
+			log.Info("%sThread %d (running)", prefix, th.ID)
+		} else if th.Function != nil {
 			log.Info("%sThread %d at %#v %s:%d %s",
 				prefix, th.ID, th.PC, t.formatPath(th.File),
 				th.Line, th.Function.Name())
@@ -363,6 +378,42 @@ func thread(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+func targets(t *Term, ctx callContext, args string) error {
+	tgts, err := t.client.ListTargets()
+	if err != nil {
+		return err
+	}
+	for _, tgt := range tgts {
+		prefix := "  "
+		if tgt.Selected {
+			prefix = "* "
+		}
+		exited := ""
+		if tgt.Exited {
+			exited = " (exited)"
+		}
+		log.Info("%sProcess %d %s%s", prefix, tgt.Pid, tgt.Path, exited)
+	}
+	return nil
+}
+
+func target(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("you must specify a process id")
+	}
+	pid, err := strconv.Atoi(args)
+	if err != nil {
+		return err
+	}
+	state, err := t.client.SwitchTarget(pid)
+	if err != nil {
+		return err
+	}
+	log.Info("Switched to process %d", pid)
+	printcontext(t, state)
+	return nil
+}
+
 type byGoroutineID []*api.Goroutine
 
 func (a byGoroutineID) Len() int { return len(a) }
@@ -616,6 +667,9 @@ func (t *Term) formatGoroutine(g *api.Goroutine, fgl api.FormatGoroutineLoc) str
 
 	buf := new(strings.Builder)
 	fmt.Fprintf(buf, "%d - %s: %s", g.ID, locname, t.formatLocation(loc))
+	if g.Running {
+		fmt.Fprintf(buf, " (running)")
+	}
 	if g.ThreadID != 0 {
 		fmt.Fprintf(buf, " (thread %d)", g.ThreadID)
 	}
@@ -707,7 +761,18 @@ func writeGoroutineLabels(w io.Writer, g *api.Goroutine, prefix string) {
 }
 
 func restart(t *Term, ctx callContext, args string) error {
-	discarded, err := t.client.Restart(false)
+	var discarded []api.DiscardedBreakpoint
+	var err error
+	if strings.TrimSpace(args) == "" {
+		discarded, err = t.client.Restart(false)
+	} else {
+		var in service.RestartIn
+		in, err = parseRestartArgs(args)
+		if err != nil {
+			return err
+		}
+		discarded, err = t.client.RestartWith(in)
+	}
 	if err != nil {
 		return err
 	}
@@ -719,6 +784,78 @@ func restart(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+// parseRestartArgs parses the arguments of the restart command:
+//
+//	[-noargs] [-env KEY=VALUE] [-unsetenv KEY] [-wd dir] [--] [newargv...] [redirects...]
+func parseRestartArgs(args string) (service.RestartIn, error) {
+	var in service.RestartIn
+	argv := config.SplitQuotedFields(args, '\'')
+	for len(argv) > 0 {
+		switch argv[0] {
+		case "-noargs":
+			in.ResetArgs = true
+			argv = argv[1:]
+			if len(argv) > 0 {
+				return in, errors.New("too many arguments to restart")
+			}
+			return in, nil
+		case "-env", "-unsetenv", "-wd":
+			if len(argv) < 2 {
+				return in, fmt.Errorf("%s requires an argument", argv[0])
+			}
+			switch argv[0] {
+			case "-env":
+				if i := strings.Index(argv[1], "="); i <= 0 {
+					return in, fmt.Errorf("environment variable %q must be in the KEY=VALUE form", argv[1])
+				}
+				in.Env = append(in.Env, argv[1])
+			case "-unsetenv":
+				if strings.Contains(argv[1], "=") {
+					return in, fmt.Errorf("invalid environment variable name %q", argv[1])
+				}
+				in.Env = append(in.Env, argv[1])
+			case "-wd":
+				in.WorkingDir = argv[1]
+			}
+			argv = argv[2:]
+			continue
+		case "--":
+			argv = argv[1:]
+		}
+		break
+	}
+	if len(argv) == 0 {
+		return in, nil
+	}
+
+	in.ResetArgs = true
+	for len(argv) > 0 {
+		last := argv[len(argv)-1]
+		var i int
+		var path string
+		switch {
+		case strings.HasPrefix(last, "<"):
+			i, path = 0, last[1:]
+		case strings.HasPrefix(last, ">"):
+			i, path = 1, last[1:]
+		case strings.HasPrefix(last, "2>"):
+			i, path = 2, last[2:]
+		default:
+			in.NewArgs = argv
+			return in, nil
+		}
+		if path == "" {
+			return in, fmt.Errorf("redirect %q has no destination", last)
+		}
+		if in.NewRedirects[i] != "" {
+			return in, fmt.Errorf("redirect %q specified more than once", last)
+		}
+		in.NewRedirects[i] = path
+		argv = argv[:len(argv)-1]
+	}
+	return in, nil
+}
+
 // parseOptionalCount parses an optional count argument.
 // If there are not arguments, a value of 1 is returned as the default.
 func parseOptionalCount(arg string) (int64, error) {
@@ -738,7 +875,21 @@ func printcontextNoState(t *Term) {
 
 func (c *Commands) rebuild(t *Term, ctx callContext, args string) error {
 	defer t.printDisplays()
-	discarded, err := t.client.Restart(true)
+	var discarded []api.DiscardedBreakpoint
+	var err error
+	args = strings.TrimSpace(args)
+	switch {
+	case args == "":
+		discarded, err = t.client.Restart(true)
+	case strings.HasPrefix(args, "--build-flags"):
+		flags := strings.TrimSpace(strings.TrimPrefix(args[len("--build-flags"):], "="))
+		if len(flags) >= 2 && flags[0] == '"' && flags[len(flags)-1] == '"' {
+			flags = flags[1 : len(flags)-1]
+		}
+		discarded, err = t.client.RebuildWithFlags(flags)
+	default:
+		return fmt.Errorf("wrong argument %q, usage: rebuild [--build-flags <flags>]", args)
+	}
 	if len(discarded) > 0 {
 		log.Warn("not all breakpoints could be restored.")
 	}
@@ -748,6 +899,10 @@ func (c *Commands) rebuild(t *Term, ctx callContext, args string) error {
 // args != "", continue <locspec>
 // args == "", continue
 func (c *Commands) cont(t *Term, ctx callContext, args string) error {
+	if strings.HasPrefix(args, "-thread") {
+		return c.contThread(t, args)
+	}
+
 	// args != "", add breakpoint at <locspec> first
 	if args != "" {
 		tmp, err := setBreakpoint(t, ctx, false, args)
@@ -782,6 +937,134 @@ func (c *Commands) cont(t *Term, ctx callContext, args string) error {
 	return nil
 }
 
+// contThread resumes a single thread, in non-stop mode.
+func (c *Commands) contThread(t *Term, args string) error {
+	v := strings.Fields(args)
+	if len(v) != 2 || v[0] != "-thread" {
+		return errors.New("usage: continue -thread <id>")
+	}
+	tid, err := strconv.Atoi(v[1])
+	if err != nil {
+		return err
+	}
+	defer t.printDisplays()
+
+	c.frame = 0
+	state, err := t.client.ContinueThread(tid)
+	if err != nil {
+		printcontextNoState(t)
+		return err
+	}
+	printcontext(t, state)
+	if state.CurrentThread != nil {
+		printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	}
+	return nil
+}
+
+func (c *Commands) rewind(t *Term, ctx callContext, args string) error {
+	defer t.printDisplays()
+
+	c.frame = 0
+	stateChan := t.client.Rewind()
+	var state *api.DebuggerState
+	for state = range stateChan {
+		if state.Err != nil {
+			printcontextNoState(t)
+			return state.Err
+		}
+		printcontext(t, state)
+	}
+	printfile(t, state.CurrentThread.File, state.CurrentThread.Line, true)
+	return nil
+}
+
+func (c *Commands) revCmd(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return errors.New("not enough arguments")
+	}
+
+	ctx.Prefix = revPrefix
+	return c.CallWithContext(args, t, ctx)
+}
+
+func record(t *Term, ctx callContext, args string) error {
+	switch args {
+	case "":
+		if t.client.Recorded() {
+			log.Info("Recording is available.")
+		} else {
+			log.Info("Nothing was recorded.")
+		}
+		return nil
+	case "on":
+		if err := t.client.Record(true); err != nil {
+			return err
+		}
+		log.Info("Recording started.")
+		return nil
+	case "off":
+		if err := t.client.Record(false); err != nil {
+			return err
+		}
+		log.Info("Recording stopped.")
+		return nil
+	default:
+		return errors.New("wrong argument to record, must be on or off")
+	}
+}
+
+func checkpoint(t *Term, ctx callContext, args string) error {
+	if args == "" {
+		state, err := t.client.GetState()
+		if err != nil {
+			return err
+		}
+		var loc api.Location = api.Location{PC: state.CurrentThread.PC, File: state.CurrentThread.File, Line: state.CurrentThread.Line, Function: state.CurrentThread.Function}
+		if state.SelectedGoroutine != nil {
+			loc = state.SelectedGoroutine.CurrentLoc
+		}
+		args = fmt.Sprintf("%s() %s:%d (%#x)", loc.Function.Name(), loc.File, loc.Line, loc.PC)
+	}
+
+	cpid, err := t.client.Checkpoint(args)
+	if err != nil {
+		return err
+	}
+
+	log.Info("Checkpoint c%d created.", cpid)
+	return nil
+}
+
+func checkpoints(t *Term, ctx callContext, args string) error {
+	cps, err := t.client.ListCheckpoints()
+	if err != nil {
+		return err
+	}
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 4, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tWhen\tNote")
+	for _, cp := range cps {
+		fmt.Fprintf(w, "c%d\t%s\t%s\n", cp.ID, cp.When, cp.Where)
+	}
+	w.Flush()
+	return nil
+}
+
+func clearCheckpoint(t *Term, ctx callContext, args string) error {
+	if len(args) == 0 {
+		return errors.New("not enough arguments to clear-checkpoint")
+	}
+	if args[0] != 'c' {
+		return errors.New("clear-checkpoint argument must be a checkpoint ID")
+	}
+	id, err := strconv.Atoi(args[1:])
+	if err != nil {
+		return errors.New("clear-checkpoint argument must be a checkpoint ID")
+	}
+	return t.client.ClearCheckpoint(id)
+}
+
 func continueUntilCompleteNext(t *Term, state *api.DebuggerState, op string, shouldPrintFile bool) error {
 	defer t.printDisplays()
 	if !state.NextInProgress {
@@ -868,6 +1151,9 @@ func (c *Commands) step(t *Term, ctx callContext, args string) error {
 	// this step operation maybe interrupted by some breakpoints.
 	c.frame = 0
 	stepfn := t.client.Step
+	if ctx.Prefix == revPrefix {
+		stepfn = t.client.ReverseStep
+	}
 	state, err := exitedToError(stepfn())
 	if err != nil {
 		printcontextNoState(t)
@@ -893,6 +1179,9 @@ func (c *Commands) stepInstruction(t *Term, ctx callContext, args string) error
 
 	// tell dbg server to step next instruction
 	fn := t.client.StepInstruction
+	if ctx.Prefix == revPrefix {
+		fn = t.client.ReverseStepInstruction
+	}
 	state, err := exitedToError(fn())
 	if err != nil {
 		printcontextNoState(t)
@@ -914,6 +1203,9 @@ func (c *Commands) next(t *Term, ctx callContext, args string) error {
 
 	// tell dbg server to run to next source
 	nextfn := t.client.Next
+	if ctx.Prefix == revPrefix {
+		nextfn = t.client.ReverseNext
+	}
 
 	// next [count]
 	var count int64
@@ -954,6 +1246,9 @@ func (c *Commands) stepout(t *Term, ctx callContext, args string) error {
 	}
 
 	stepoutfn := t.client.StepOut
+	if ctx.Prefix == revPrefix {
+		stepoutfn = t.client.ReverseStepOut
+	}
 	state, err := exitedToError(stepoutfn())
 	if err != nil {
 		printcontextNoState(t)
@@ -1284,6 +1579,9 @@ func watchpoint(t *Term, ctx callContext, args string) error {
 		return err
 	}
 	log.Info("%s set at %s", formatBreakpointName(bp, true), t.formatBreakpointLocation(bp))
+	if bp.SoftwareWatch {
+		log.Warn("not enough free hardware watchpoints, %s is implemented with page protection and the program will run much slower", formatBreakpointName(bp, false))
+	}
 	return nil
 }
 
@@ -1905,6 +2203,281 @@ func printStack(t *Term, out io.Writer, stack []api.Stackframe, indent string, o
 	api.PrintStack(t.formatPath, out, stack, indent, offsets, func(api.Stackframe) bool { return true })
 }
 
+func catch(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	if len(v) == 0 {
+		return printCatchpoints(t)
+	}
+	switch v[0] {
+	case "syscall":
+		if err := catchSyscall(t, v[1:]); err != nil {
+			return err
+		}
+		return printCatchpoints(t)
+	case "panic":
+		// the condition is the rest of the line, it can contain spaces
+		opts, cond := strings.TrimSpace(args), ""
+		if i := strings.Index(opts, "-cond"); i >= 0 {
+			opts, cond = opts[:i], strings.TrimSpace(opts[i+len("-cond"):])
+			if cond == "" {
+				return errors.New("-cond requires an expression")
+			}
+		}
+		if err := catchPanic(t, strings.Fields(opts)[1:], cond); err != nil {
+			return err
+		}
+		return printCatchpoints(t)
+	default:
+		return fmt.Errorf("wrong argument %q to catch, must be syscall or panic", v[0])
+	}
+}
+
+// catchPanic changes the panics that stop the program according to args:
+// -recovered also stops on panics that are later recovered, -type T only
+// stops on panics whose value has dynamic type T and -clear restores the
+// default. If cond is not empty only panics for which it is true stop the
+// program.
+func catchPanic(t *Term, args []string, cond string) error {
+	pc := &api.PanicCatchpoint{Cond: cond}
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-clear":
+			if len(args) != 1 || cond != "" {
+				return errors.New("-clear can not be used with other options")
+			}
+			return t.client.SetPanicCatchpoint(nil)
+		case "-recovered":
+			pc.Recovered = true
+		case "-type":
+			if i+1 >= len(args) {
+				return errors.New("-type requires a type name")
+			}
+			i++
+			pc.Type = args[i]
+		default:
+			return fmt.Errorf("wrong argument %q to catch panic", args[i])
+		}
+	}
+	if !pc.Recovered && pc.Type == "" && pc.Cond == "" {
+		return t.client.SetPanicCatchpoint(nil)
+	}
+	return t.client.SetPanicCatchpoint(pc)
+}
+
+// catchSyscall adds the system calls in args to the caught ones, all of
+// them if args is empty, or removes them if args starts with -clear.
+func catchSyscall(t *Term, args []string) error {
+	all, syscalls, err := t.client.ListSyscallCatchpoints()
+	if err != nil {
+		return err
+	}
+	if len(args) > 0 && args[0] == "-clear" {
+		if len(args) == 1 {
+			return t.client.SetSyscallCatchpoints(false, nil)
+		}
+		if all {
+			return errors.New("all system calls are caught, use 'catch syscall -clear' to clear them")
+		}
+		clear := make(map[string]bool, len(args)-1)
+		for _, arg := range args[1:] {
+			clear[canonicalSyscallName(arg)] = true
+		}
+		keep := syscalls[:0]
+		for _, name := range syscalls {
+			if !clear[canonicalSyscallName(name)] {
+				keep = append(keep, name)
+			}
+		}
+		return t.client.SetSyscallCatchpoints(false, keep)
+	}
+	if len(args) == 0 || all {
+		return t.client.SetSyscallCatchpoints(true, nil)
+	}
+	return t.client.SetSyscallCatchpoints(false, append(syscalls, args...))
+}
+
+// canonicalSyscallName returns the name of the system call s, specified by
+// name or number.
+func canonicalSyscallName(s string) string {
+	if nr, err := strconv.Atoi(s); err == nil {
+		return linutil.SyscallName(nr)
+	}
+	if nr, ok := linutil.SyscallNumber(s); ok {
+		return linutil.SyscallName(nr)
+	}
+	return s
+}
+
+func printCatchpoints(t *Term) error {
+	all, syscalls, err := t.client.ListSyscallCatchpoints()
+	if err != nil {
+		return err
+	}
+	pc, err := t.client.GetPanicCatchpoint()
+	if err != nil {
+		return err
+	}
+	switch {
+	case all:
+		log.Info("Catching all system calls")
+	case len(syscalls) > 0:
+		log.Info("Catching system calls: %s", strings.Join(syscalls, " "))
+	default:
+		log.Info("Not catching system calls")
+	}
+	if pc == nil {
+		log.Info("Catching unrecovered panics")
+		return nil
+	}
+	what := "unrecovered panics"
+	if pc.Recovered {
+		what = "panics"
+	}
+	if pc.Type != "" {
+		what += " with values of type " + pc.Type
+	}
+	if pc.Cond != "" {
+		what += " where " + pc.Cond
+	}
+	if pc.Recovered {
+		what += ", including recovered ones"
+	}
+	log.Info("Catching %s", what)
+	return nil
+}
+
+// printPanic prints the panic the current thread stopped at.
+func printPanic(t *Term, p *api.Panic) {
+	log.Info("panic(%s) with value %s", p.Type, p.Value.MultilineString("\t", ""))
+	if fn := p.Location.Function; fn != nil {
+		log.Info("\tcalled by %s() %s:%d", fn.Name(), t.formatPath(p.Location.File), p.Location.Line)
+	}
+}
+
+// printSyscall prints the system call the current thread stopped at and
+// the stack of the goroutine that issued it.
+func printSyscall(t *Term, state *api.DebuggerState) {
+	sc := state.Syscall
+	call := fmt.Sprintf("%s(%s)", sc.Name, strings.Join(sc.Args, ", "))
+	who := fmt.Sprintf("Thread %d", state.CurrentThread.ID)
+	if state.CurrentThread.GoroutineID != 0 {
+		who = fmt.Sprintf("Goroutine %d (thread %d)", state.CurrentThread.GoroutineID, state.CurrentThread.ID)
+	}
+	switch {
+	case !sc.Exit:
+		log.Info("%s entering syscall %s", who, call)
+	case sc.Errno != "":
+		log.Info("%s returned from syscall %s = %d %s", who, call, sc.Ret, sc.Errno)
+	default:
+		log.Info("%s returned from syscall %s = %d", who, call, sc.Ret)
+	}
+	stack, err := t.client.Stacktrace(-1, 20, 0, nil)
+	if err != nil {
+		log.Warn("could not read stack: %v", err)
+		return
+	}
+	printStack(t, os.Stdout, stack, "\t", false)
+}
+
+func handle(t *Term, ctx callContext, args string) error {
+	v := strings.Fields(args)
+	policies, err := t.client.ListSignalPolicies()
+	if err != nil {
+		return err
+	}
+	if len(v) == 0 {
+		printSignalPolicies(policies)
+		return nil
+	}
+
+	var policy *api.SignalPolicy
+	for i := range policies {
+		if signalMatches(&policies[i], v[0]) {
+			policy = &policies[i]
+			break
+		}
+	}
+	if policy == nil {
+		return fmt.Errorf("unknown signal %q", v[0])
+	}
+	if len(v) == 1 {
+		printSignalPolicies([]api.SignalPolicy{*policy})
+		return nil
+	}
+
+	for _, kw := range v[1:] {
+		switch kw {
+		case "stop":
+			policy.Stop, policy.Print = true, true
+		case "nostop":
+			policy.Stop = false
+		case "print":
+			policy.Print = true
+		case "noprint":
+			policy.Print, policy.Stop = false, false
+		case "pass", "noignore":
+			policy.Pass = true
+		case "nopass", "ignore":
+			policy.Pass = false
+		default:
+			return fmt.Errorf("wrong argument %q to handle, must be one of stop, nostop, print, noprint, pass, nopass", kw)
+		}
+	}
+	newPolicy, err := t.client.SetSignalPolicy(*policy)
+	if err != nil {
+		return err
+	}
+	t.saveSignalPolicy(newPolicy)
+	printSignalPolicies([]api.SignalPolicy{newPolicy})
+	return nil
+}
+
+// signalMatches returns true if arg is the number or the name, with or
+// without the SIG prefix, of the signal of policy.
+func signalMatches(policy *api.SignalPolicy, arg string) bool {
+	if n, err := strconv.Atoi(arg); err == nil {
+		return n == policy.Signal
+	}
+	arg = strings.ToUpper(arg)
+	return arg == policy.Name || "SIG"+arg == policy.Name
+}
+
+func printSignalPolicies(policies []api.SignalPolicy) {
+	yesno := func(b bool) string {
+		if b {
+			return "Yes"
+		}
+		return "No"
+	}
+	log.Info("%-10s %-5s %-5s %-5s", "Signal", "Stop", "Print", "Pass")
+	for _, policy := range policies {
+		log.Info("%-10s %-5s %-5s %-5s", policy.Name, yesno(policy.Stop), yesno(policy.Print), yesno(policy.Pass))
+	}
+}
+
+// saveSignalPolicy records policy in the configuration, so that it is
+// saved by 'config -save'.
+func (t *Term) saveSignalPolicy(policy api.SignalPolicy) {
+	p := config.SignalPolicy{Signal: policy.Name, Stop: policy.Stop, Print: policy.Print, Pass: policy.Pass}
+	for i := range t.conf.Signals {
+		if t.conf.Signals[i].Signal == policy.Name {
+			t.conf.Signals[i] = p
+			return
+		}
+	}
+	t.conf.Signals = append(t.conf.Signals, p)
+}
+
+// applySignalPolicies sets the signal policies of the configuration.
+func (t *Term) applySignalPolicies() {
+	for _, p := range t.conf.Signals {
+		_, err := t.client.SetSignalPolicy(api.SignalPolicy{Name: p.Signal, Stop: p.Stop, Print: p.Print, Pass: p.Pass})
+		if err != nil {
+			log.Warn("could not set the policy of signal %s: %v", p.Signal, err)
+		}
+	}
+}
+
 func printcontext(t *Term, state *api.DebuggerState) {
 	for i := range state.Threads {
 		if (state.CurrentThread != nil) && (state.Threads[i].ID == state.CurrentThread.ID) {
@@ -1920,6 +2493,30 @@ func printcontext(t *Term, state *api.DebuggerState) {
 		return
 	}
 
+	if sig := state.Signal; sig != nil {
+		if sig.Addr != 0 {
+			log.Info("Thread %d received signal %s (code %d, addr %#x)", state.CurrentThread.ID, sig.Name, sig.Code, sig.Addr)
+		} else {
+			log.Info("Thread %d received signal %s (code %d)", state.CurrentThread.ID, sig.Name, sig.Code)
+		}
+	}
+
+	if state.Syscall != nil {
+		printSyscall(t, state)
+	}
+
+	if state.Panic != nil {
+		printPanic(t, state.Panic)
+	}
+
+	if state.Exiting {
+		if state.ExitStatus < 0 {
+			log.Info("Process %d is about to be killed by signal %d, continue to let it exit", state.Pid, -state.ExitStatus)
+		} else {
+			log.Info("Process %d is about to exit with status %d, continue to let it exit", state.Pid, state.ExitStatus)
+		}
+	}
+
 	var th *api.Thread
 	if state.SelectedGoroutine == nil {
 		th = state.CurrentThread
@@ -2003,6 +2600,9 @@ func printcontextThread(t *Term, th *api.Thread) {
 	bpname := ""
 	if th.Breakpoint.WatchExpr != "" {
 		bpname = fmt.Sprintf("watchpoint on [%s] ", th.Breakpoint.WatchExpr)
+		if th.WatchHit != "" {
+			bpname += fmt.Sprintf("(%s) ", th.WatchHit)
+		}
 	} else if th.Breakpoint.Name != "" {
 		bpname = fmt.Sprintf("[%s] ", th.Breakpoint.Name)
 	}
@@ -2095,6 +2695,10 @@ func printBreakpointInfo(t *Term, th *api.Thread, tracepointOnNewline bool) {
 }
 
 func printTracepoint(t *Term, th *api.Thread, bpname string, fn *api.Function, args string, hasReturnValue bool) {
+	if th.Breakpoint.TraceFollowCalls > 0 {
+		printFollowCallsTracepoint(t, th, bpname, fn, args)
+		return
+	}
 	if th.Breakpoint.Tracepoint {
 		log.Error("> goroutine(%d): %s%s(%s)", th.GoroutineID, bpname, fn.Name(), args)
 		if !hasReturnValue {
@@ -2117,6 +2721,30 @@ func printTracepoint(t *Term, th *api.Thread, bpname string, fn *api.Function, a
 	}
 }
 
+// printFollowCallsTracepoint prints a tracepoint created by
+// 'trace --follow-calls', indented by its depth below the root function so
+// that the output forms a call tree.
+func printFollowCallsTracepoint(t *Term, th *api.Thread, bpname string, fn *api.Function, args string) {
+	var indent string
+	if th.BreakpointInfo != nil {
+		indent = strings.Repeat("  ", th.BreakpointInfo.TraceDepth)
+	}
+	if th.Breakpoint.Tracepoint {
+		log.Error("> goroutine(%d):%s %s%s(%s)", th.GoroutineID, indent, bpname, fn.Name(), args)
+	}
+	if th.Breakpoint.TraceReturn {
+		retVals := make([]string, 0, len(th.ReturnValues))
+		for _, v := range th.ReturnValues {
+			retVals = append(retVals, v.SinglelineString())
+		}
+		log.Error(">> goroutine(%d):%s %s%s => (%s)", th.GoroutineID, indent, bpname, fn.Name(), strings.Join(retVals, ","))
+	}
+	if th.BreakpointInfo != nil && th.BreakpointInfo.Stacktrace != nil {
+		log.Error("\tStack:")
+		printStack(t, os.Stderr, th.BreakpointInfo.Stacktrace, "\t\t", false)
+	}
+}
+
 func printfile(t *Term, filename string, line int, showArrow bool) error {
 	if filename == "" {
 		return nil
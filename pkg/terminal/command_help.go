@@ -35,14 +35,52 @@ The memory location is specified with the same expression language used by 'prin
 
 	watch v
 
-will watch the address of variable 'v'.
+will watch the address of variable 'v'. Strings and slices watch their
+backing memory, arrays and structs are watched as a whole; when the
+watchpoint is hit the part of the value that was accessed is reported, for
+example "field Foo.bar written".
 
 Note that writes that do not change the value of the watched memory address might not be reported.
 
+The CPU can not stop on reads only, 'watch -r' is emulated by stopping on
+every access and resuming the program when the instruction that accessed the
+memory only wrote to it.
+
+Up to four debug registers of the CPU are used to watch memory, each covers up
+to 8 aligned bytes. Watchpoints that do not fit in the free debug registers
+are implemented by protecting the memory pages that contain the watched
+variable: every access to those pages stops the program, which makes it run
+much slower, and system calls accessing them fail. Watchpoints on stack
+variables always need a debug register.
+
 See also: "help print".`
 
-	restartCmdHelpMsg = "Restart process."
-	rebuildCmdHelpMsg = "Rebuild the target executable and restarts it. It does not work if the executable was not built by delve."
+	restartCmdHelpMsg = `Restart process.
+
+	restart [-env KEY=VALUE] [-unsetenv KEY] [-wd <dir>] [--] [newargv...] [redirects...]
+	restart [-env KEY=VALUE] [-unsetenv KEY] [-wd <dir>] -noargs
+
+If newargv is omitted the process is restarted with the same argument vector.
+If -noargs is specified instead, the argument vector is cleared. Use -- before newargv if its first argument starts with a dash.
+
+A list of file redirections can be specified after the new argument list to override the redirections defined using the '--redirect' command line option. A syntax similar to Unix shells is used:
+
+	<input.txt	redirects the standard input of the target process from input.txt
+	>output.txt	redirects the standard output of the target process to output.txt
+	2>error.txt	redirects the standard error of the target process to error.txt
+
+-env sets an environment variable of the target process and -unsetenv removes one, both can be repeated. -wd changes the working directory of the target process.
+
+The new arguments, redirections, environment and working directory are also used by the following restarts, breakpoints are kept.`
+	rebuildCmdHelpMsg = `Rebuild the target executable and restarts it. It does not work if the executable was not built by delve.
+
+	rebuild [--build-flags <flags>]
+
+With --build-flags the flags passed to the go command are replaced, for this and the following rebuilds, by the ones specified, for example:
+
+	rebuild --build-flags "-tags=integration -race"
+
+Values of -gcflags are merged with the flags disabling optimizations.`
 
 	continueCmdHelpMsg = `Run until breakpoint or program termination.
 
@@ -54,6 +92,12 @@ For example:
 
 	continue main.main
 	continue encoding/json.Marshal
+
+In non-stop mode (--non-stop) only the threads that hit a breakpoint are
+stopped and continue resumes all of them, a single thread can be resumed
+with:
+
+	continue -thread <id>
 `
 	stepCmdHelpMsg = "Single step through program."
 
@@ -75,13 +119,26 @@ Current limitations:
 - only pointers to stack-allocated objects can be passed as argument.
 - only some automatic type conversions are supported.
 - functions can only be called on running goroutines that are not
-  executing the runtime.
+  executing the runtime, or on parked goroutines.
 - the current goroutine needs to have at least 256 bytes of free space on
   the stack.
 - functions can only be called when the goroutine is stopped at a safe
   point.
 - calling a function will resume execution of all goroutines.
 - only supported on linux's native backend.
+
+When the selected goroutine is parked (e.g. blocked on a channel) the call
+is executed by a goroutine that is running user code, the expression is
+evaluated in the topmost frame of the parked goroutine that isn't executing
+the runtime. The parked goroutine can be woken up while the call executes.
+
+Composite literals (e.g. main.T{A: 1}, []int{1, 2} or
+map[string]int{"a": 1}) and new(T) can be used in the call expression,
+their values are allocated in the target by calling runtime.mallocgc.
+
+Generic functions are called by specifying their type arguments explicitly
+(e.g. sum[int](1, 2)), methods of instantiated generic types can be called
+normally.
 `
 	threadsCmdHelpMsg = "Print out info for every traced thread."
 
@@ -89,6 +146,19 @@ Current limitations:
 
 	thread <id>`
 
+	targetsCmdHelpMsg = `Print out info for every process being debugged.
+
+Child processes are debugged along with the target when dlv is started
+with --follow-fork or --follow-exec. The process commands operate on is
+marked with '*'.`
+
+	targetCmdHelpMsg = `Switch to the specified process.
+
+	target <pid>
+
+Breakpoints created after switching are only set in the selected process,
+processes created by a followed process inherit its breakpoints.`
+
 	clearCmdHelpMsg = `Deletes breakpoint.
 
 	clear <breakpoint name or id>`
@@ -358,6 +428,10 @@ The '% n' form means we should stop at the breakpoint when the hitcount is a mul
 	config substitute-path <from> <to> : Adds a path substitution rule.
 	config substitute-path <from>      : Removes a paths substitution rule.
 
+	config step-skip <kind> <pattern>  : Adds a step skip rule, kind is one of func, package or file.
+	config step-skip <pattern>         : Removes a step skip rule.
+	                                     Patterns are globs, a ** path element matches any number of path elements.
+
 	config alias <command> <alias>     : Defines <alias> an alias to <command>
 	config alias <alias>               : Removes an alias <alias>`
 
@@ -403,13 +477,24 @@ If display is called without arguments it will print the value of all expression
 
 The core dump is always written in ELF, even on systems (windows, macOS) where this is not customary. For environments other than linux/amd64 threads and registers are dumped in a format that only Delve can read back.`
 
-	rewindCmdHelpMsg = "Run backwards until breakpoint or program termination."
+	rewindCmdHelpMsg = `Go back to the last breakpoint hit or to the most recent checkpoint.
+
+Restores the most recent checkpoint created before the current position and
+then runs forward up to the last breakpoint hit between that checkpoint and
+the current position. If no breakpoint was hit in between the program is left
+stopped at the checkpoint. See also 'checkpoint'.`
 
 	checkpointCmdHelpMsg = `Creates a checkpoint at the current position.
 
 	checkpoint [note]
 
-The "note" is arbitrary text that can be used to identify the checkpoint, if it is not specified it defaults to the current filename:line position.`
+The "note" is arbitrary text that can be used to identify the checkpoint, if it is not specified it defaults to the current filename:line position.
+
+Checkpoints are created by forking the target process, the copy is kept
+stopped until it is restored by 'rewind' or deleted by 'clear-checkpoint'.
+Only the current thread is preserved in the copy: goroutines that were
+running on other threads will not make progress after a checkpoint has been
+restored.`
 
 	checkpointsCmdHelpMsg = "Print out info for existing checkpoints."
 
@@ -417,6 +502,91 @@ The "note" is arbitrary text that can be used to identify the checkpoint, if it
 
 	clear-checkpoint <id>`
 
+	recordCmdHelpMsg = `Starts or stops recording the execution of the current thread.
+
+	record [on|off]
+
+While recording is on the current thread is executed one instruction at a
+time, saving registers and the memory it writes, and the other threads are
+kept stopped. The recorded instructions can then be executed backwards with
+'rev' and 'rewind' and replayed forwards again. Resuming the program without
+recording discards what was recorded.
+
+Without arguments reports whether something was recorded.`
+
+	catchCmdHelpMsg = `Stops the program when an event happens.
+
+	catch syscall [name|number ...]
+	catch syscall -clear [name|number ...]
+	catch panic [-type T] [-recovered] [-cond expr]
+	catch panic -clear
+
+Without arguments prints the active catchpoints.
+
+catch syscall stops the program when it enters and when it returns from the
+listed system calls, or from every system call if none is listed. When the
+program stops the arguments, the return value of the system call and the
+stack of the goroutine that issued it are printed. Arguments that are
+strings, like paths, are printed as strings, the others in hexadecimal.
+System calls can be specified by name, for example openat, or by number.
+The -clear option stops catching the listed system calls, or all of them if
+none is listed.
+
+Catching system calls makes the program slower, even the system calls that
+are not caught stop it briefly.
+
+By default the program stops on unrecovered panics, when it is about to
+die. With catch panic -recovered it stops in runtime.gopanic as soon as a
+panic starts, so that panics that are later recovered, for example by a
+middleware, stop it too. With -type it only stops on panics whose value has
+dynamic type T, as printed by whatis, for example:
+
+	catch panic -recovered -type *errors.errorString
+
+With -cond, which must be the last option, it only stops on panics for
+which the boolean expression expr is true. The expression is evaluated in
+the runtime.gopanic frame and the identifier panic refers to the panic
+value. The program also stops if the expression can not be evaluated, use
+-type to only evaluate it on panics with values of a given type, for
+example:
+
+	catch panic -recovered -type string -cond panic.(string) == "boom"
+
+When -recovered is used the filters only apply to the panics stopped in
+runtime.gopanic, unrecovered panics of every type still stop the program.
+The value of the panic, its type and the function that panicked are printed
+when the program stops on a panic. The -clear option restores the default.`
+
+	handleCmdHelpMsg = `Changes how signals received by the program are handled.
+
+	handle [signal [keywords...]]
+
+Signals can be specified by name, with or without the SIG prefix, or by
+number. Real-time signals are named like kill -l does, SIGRTMIN+n or
+SIGRTMAX-n. Keywords are:
+
+	stop	the program stops when it receives the signal, implies print
+	nostop	the program does not stop when it receives the signal
+	print	a message is printed when the program receives the signal
+	noprint	no message is printed, implies nostop
+	pass	the signal is delivered to the program
+	nopass	the signal is discarded
+
+Without keywords prints how the signal is handled, without arguments prints
+how all signals are handled. By default signals are passed to the program
+without stopping or printing anything. The policies changed with handle are
+saved by 'config -save', they can also be listed in the 'signals' section of
+the configuration file.
+
+For example:
+
+	handle SIGUSR1 nostop print
+	handle SIGSEGV stop nopass`
+
 	revCmdHelpMsg = `Reverses the execution of the target program for the command specified.
-Currently, only the rev step-instruction command is supported.`
+
+	rev <command>
+
+Supported commands are next, step, stepout and step-instruction. The target
+must have been recorded first, see 'record'.`
 )
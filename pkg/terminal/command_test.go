@@ -646,6 +646,40 @@ func TestRestart(t *testing.T) {
 	})
 }
 
+func TestParseRestartArgs(t *testing.T) {
+	tests := []struct {
+		args string
+		in   service.RestartIn
+		err  bool
+	}{
+		{"hello world", service.RestartIn{ResetArgs: true, NewArgs: []string{"hello", "world"}}, false},
+		{"-noargs", service.RestartIn{ResetArgs: true}, false},
+		{"-env A=1 -unsetenv B -wd /tmp", service.RestartIn{Env: []string{"A=1", "B"}, WorkingDir: "/tmp"}, false},
+		{"-env A=1 -- -v 'a b' <in.txt 2>err.txt", service.RestartIn{Env: []string{"A=1"}, ResetArgs: true, NewArgs: []string{"-v", "a b"}, NewRedirects: [3]string{"in.txt", "", "err.txt"}}, false},
+		{"-env A", service.RestartIn{}, true},
+		{"-unsetenv A=1", service.RestartIn{}, true},
+		{"-wd", service.RestartIn{}, true},
+		{"-noargs hello", service.RestartIn{}, true},
+		{"hello >a >b", service.RestartIn{}, true},
+	}
+	for _, tc := range tests {
+		in, err := parseRestartArgs(tc.args)
+		if tc.err {
+			if err == nil {
+				t.Errorf("%q: expected error", tc.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.args, err)
+			continue
+		}
+		if !reflect.DeepEqual(in, tc.in) {
+			t.Errorf("%q: got %#v, want %#v", tc.args, in, tc.in)
+		}
+	}
+}
+
 func findCmdName(c *Commands, cmdstr string, prefix cmdPrefix) string {
 	for _, v := range c.cmds {
 		if v.match(cmdstr) {
@@ -1080,3 +1114,18 @@ func TestBreakpointEditing(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalSyscallName(t *testing.T) {
+	for _, tc := range []struct{ in, tgt string }{
+		{"openat", "openat"},
+		{"257", "openat"},
+		{"231", "exit_group"},
+		{"1000", "syscall_1000"},
+		{"syscall_1000", "syscall_1000"},
+		{"nosuchsyscall", "nosuchsyscall"},
+	} {
+		if out := canonicalSyscallName(tc.in); out != tc.tgt {
+			t.Errorf("canonicalSyscallName(%q) = %q, expected %q", tc.in, out, tc.tgt)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package terminal
 import (
 	"fmt"
 	"os"
+	"path"
 	"reflect"
 	"text/tabwriter"
 
@@ -25,6 +26,7 @@ func configureCmd(t *Term, ctx callContext, args string) error {
 		if t.client != nil { // only happens in tests
 			lcfg := t.loadConfig()
 			t.client.SetReturnValuesLoadConfig(&lcfg)
+			t.client.SetStepSkip(t.stepSkipRules())
 		}
 		return nil
 	}
@@ -59,6 +61,10 @@ func configureSet(t *Term, args string) error {
 		return configureSetSubstitutePath(t, rest)
 	}
 
+	if field.Kind() == reflect.Slice && field.Type().Elem().Name() == "StepSkipRule" {
+		return configureSetStepSkip(t, rest)
+	}
+
 	return config.ConfigureSetSimple(rest, cfgname, field)
 }
 
@@ -89,6 +95,41 @@ func configureSetSubstitutePath(t *Term, rest string) error {
 	return nil
 }
 
+// add or delete step skip rule
+func configureSetStepSkip(t *Term, rest string) error {
+	argv := config.SplitQuotedFields(rest, '"')
+	switch len(argv) {
+	case 1: // delete step skip rule
+		for i := range t.conf.StepSkip {
+			if t.conf.StepSkip[i].Pattern == argv[0] {
+				copy(t.conf.StepSkip[i:], t.conf.StepSkip[i+1:])
+				t.conf.StepSkip = t.conf.StepSkip[:len(t.conf.StepSkip)-1]
+				return nil
+			}
+		}
+		return fmt.Errorf("could not find step skip rule for %q", argv[0])
+	case 2: // add step skip rule
+		switch argv[0] {
+		case "func", "package", "file":
+		default:
+			return fmt.Errorf("unknown step skip rule kind %q, must be one of func, package or file", argv[0])
+		}
+		if _, err := path.Match(argv[1], ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", argv[1], err)
+		}
+		for i := range t.conf.StepSkip {
+			if t.conf.StepSkip[i].Pattern == argv[1] {
+				t.conf.StepSkip[i].Kind = argv[0]
+				return nil
+			}
+		}
+		t.conf.StepSkip = append(t.conf.StepSkip, config.StepSkipRule{Kind: argv[0], Pattern: argv[1]})
+	default:
+		return fmt.Errorf("wrong number of arguments to \"config step-skip\"")
+	}
+	return nil
+}
+
 func configureSetAlias(t *Term, rest string) error {
 	argv := config.SplitQuotedFields(rest, '"')
 	switch len(argv) {
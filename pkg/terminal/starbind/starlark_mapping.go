@@ -265,11 +265,43 @@ func (env *Env) starlarkPredeclare() starlark.StringDict {
 				return starlark.None, decorateError(thread, err)
 			}
 		}
+		if len(args) > 1 && args[1] != starlark.None {
+			err := unmarshalStarlarkValue(args[1], &rpcArgs.LoadArgs, "LoadArgs")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 2 && args[2] != starlark.None {
+			err := unmarshalStarlarkValue(args[2], &rpcArgs.Captures, "Captures")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 3 && args[3] != starlark.None {
+			err := unmarshalStarlarkValue(args[3], &rpcArgs.Cond, "Cond")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 4 && args[4] != starlark.None {
+			err := unmarshalStarlarkValue(args[4], &rpcArgs.Latency, "Latency")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
 		for _, kv := range kwargs {
 			var err error
 			switch kv[0].(starlark.String) {
 			case "FunctionName":
 				err = unmarshalStarlarkValue(kv[1], &rpcArgs.FunctionName, "FunctionName")
+			case "LoadArgs":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.LoadArgs, "LoadArgs")
+			case "Captures":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.Captures, "Captures")
+			case "Cond":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.Cond, "Cond")
+			case "Latency":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.Latency, "Latency")
 			default:
 				err = fmt.Errorf("unknown argument %q", kv[0])
 			}
@@ -714,6 +746,18 @@ func (env *Env) starlarkPredeclare() starlark.StringDict {
 		}
 		return env.interfaceToStarlarkValue(rpcRet), nil
 	})
+	r["get_latency_histograms"] = starlark.NewBuiltin("get_latency_histograms", func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		if err := isCancelled(thread); err != nil {
+			return starlark.None, decorateError(thread, err)
+		}
+		var rpcArgs service.GetLatencyHistogramsIn
+		var rpcRet service.GetLatencyHistogramsOut
+		err := env.ctx.Client().CallAPI("GetLatencyHistograms", &rpcArgs, &rpcRet)
+		if err != nil {
+			return starlark.None, err
+		}
+		return env.interfaceToStarlarkValue(rpcRet), nil
+	})
 	r["get_thread"] = starlark.NewBuiltin("get_thread", func(thread *starlark.Thread, _ *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
 		if err := isCancelled(thread); err != nil {
 			return starlark.None, decorateError(thread, err)
@@ -1193,11 +1237,59 @@ func (env *Env) starlarkPredeclare() starlark.StringDict {
 				return starlark.None, decorateError(thread, err)
 			}
 		}
+		if len(args) > 1 && args[1] != starlark.None {
+			err := unmarshalStarlarkValue(args[1], &rpcArgs.BuildFlags, "BuildFlags")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 2 && args[2] != starlark.None {
+			err := unmarshalStarlarkValue(args[2], &rpcArgs.ResetArgs, "ResetArgs")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 3 && args[3] != starlark.None {
+			err := unmarshalStarlarkValue(args[3], &rpcArgs.NewArgs, "NewArgs")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 4 && args[4] != starlark.None {
+			err := unmarshalStarlarkValue(args[4], &rpcArgs.NewRedirects, "NewRedirects")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 5 && args[5] != starlark.None {
+			err := unmarshalStarlarkValue(args[5], &rpcArgs.Env, "Env")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
+		if len(args) > 6 && args[6] != starlark.None {
+			err := unmarshalStarlarkValue(args[6], &rpcArgs.WorkingDir, "WorkingDir")
+			if err != nil {
+				return starlark.None, decorateError(thread, err)
+			}
+		}
 		for _, kv := range kwargs {
 			var err error
 			switch kv[0].(starlark.String) {
 			case "Rebuild":
 				err = unmarshalStarlarkValue(kv[1], &rpcArgs.Rebuild, "Rebuild")
+			case "BuildFlags":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.BuildFlags, "BuildFlags")
+			case "ResetArgs":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.ResetArgs, "ResetArgs")
+			case "NewArgs":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.NewArgs, "NewArgs")
+			case "NewRedirects":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.NewRedirects, "NewRedirects")
+			case "Env":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.Env, "Env")
+			case "WorkingDir":
+				err = unmarshalStarlarkValue(kv[1], &rpcArgs.WorkingDir, "WorkingDir")
 			default:
 				err = fmt.Errorf("unknown argument %q", kv[0])
 			}
@@ -79,6 +79,7 @@ func New(client service.Client, conf *config.Config) *Term {
 	if client != nil {
 		cfg := t.loadConfig()
 		client.SetReturnValuesLoadConfig(&cfg)
+		client.SetStepSkip(t.stepSkipRules())
 	}
 
 	t.starlarkEnv = starbind.New(starlarkContext{t})
@@ -140,6 +141,43 @@ func (t *Term) sigintGuard(ch <-chan os.Signal, multiClient bool) {
 	}
 }
 
+// printEvents prints the stops of the threads that were left running, in
+// non-stop mode, while no command was executing, and the automatic rebuilds
+// of the target in watch mode.
+func (t *Term) printEvents() {
+	for {
+		state, err := t.client.WaitEvent()
+		if err != nil || state == nil {
+			return
+		}
+		if state.Rebuild != nil {
+			t.printRebuild(state)
+			continue
+		}
+		if state.Exited {
+			log.Info("\nProcess %d has exited with status %d", state.Pid, state.ExitStatus)
+			continue
+		}
+		if state.CurrentThread != nil {
+			log.Info("\nThread %d stopped", state.CurrentThread.ID)
+		}
+		printcontext(t, state)
+	}
+}
+
+// printRebuild prints the result of an automatic rebuild of the target.
+func (t *Term) printRebuild(state *api.DebuggerState) {
+	r := state.Rebuild
+	if r.Err != "" {
+		log.Error("\nCould not rebuild after changes to %s: %s", strings.Join(r.Files, ", "), r.Err)
+		return
+	}
+	log.Info("\nRebuilt after changes to %s, process restarted with PID: %d", strings.Join(r.Files, ", "), state.Pid)
+	for i := range r.DiscardedBreakpoints {
+		log.Info("Discarded %s at %s: %v", formatBreakpointName(r.DiscardedBreakpoints[i].Breakpoint, false), t.formatBreakpointLocation(r.DiscardedBreakpoints[i].Breakpoint), r.DiscardedBreakpoints[i].Reason)
+	}
+}
+
 // Run begins running dlv in the terminal.
 func (t *Term) Run() (int, error) {
 	defer t.Close()
@@ -181,13 +219,17 @@ func (t *Term) Run() (int, error) {
 		return
 	})
 
+	t.applySignalPolicies()
+
 	log.Info("Type 'help' for list of commands.")
 
 	var lastCmd string
 
 	// Ensure that the target process is neither running nor recording by
 	// making a blocking call.
-	_, _ = t.client.GetState()
+	if state, err := t.client.GetState(); err == nil && (state.NonStop || state.Watching) {
+		go t.printEvents()
+	}
 
 	for {
 		cmdstr, err := t.promptForInput()
@@ -245,6 +287,19 @@ func (t *Term) substitutePath(path string) string {
 	return locspec.SubstitutePath(path, t.substitutePathRules())
 }
 
+// stepSkipRules returns the step skip rules of the configuration as pairs
+// of kind and pattern, see api.DebuggerCommand.StepSkip.
+func (t *Term) stepSkipRules() [][2]string {
+	if t.conf == nil || len(t.conf.StepSkip) == 0 {
+		return nil
+	}
+	r := make([][2]string, 0, len(t.conf.StepSkip))
+	for _, rule := range t.conf.StepSkip {
+		r = append(r, [2]string{rule.Kind, rule.Pattern})
+	}
+	return r
+}
+
 func (t *Term) substitutePathRules() [][2]string {
 	if t.substitutePathRulesCache != nil {
 		return t.substitutePathRulesCache
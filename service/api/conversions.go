@@ -22,23 +22,26 @@ import (
 // ConvertBreakpoint converts from a proc.Breakpoint to an api.Breakpoint.
 func ConvertBreakpoint(bp *proc.Breakpoint) *Breakpoint {
 	b := &Breakpoint{
-		Name:         bp.Name,
-		ID:           bp.LogicalID(),
-		FunctionName: bp.Function,
-		File:         bp.File,
-		Line:         bp.Line,
-		Addr:         bp.Addr,
-		Tracepoint:   bp.Tracepoint,
-		TraceReturn:  bp.TraceReturn,
-		Stacktrace:   bp.Stacktrace,
-		Goroutine:    bp.Goroutine,
-		Variables:    bp.Variables,
-		LoadArgs:     LoadConfigFromProc(bp.LoadArgs),
-		LoadLocals:   LoadConfigFromProc(bp.LoadLocals),
-		WatchExpr:    bp.WatchExpr,
-		WatchType:    WatchType(bp.WatchType),
-		Addrs:        []uint64{bp.Addr},
-		UserData:     bp.UserData,
+		Name:             bp.Name,
+		ID:               bp.LogicalID(),
+		FunctionName:     bp.Function,
+		File:             bp.File,
+		Line:             bp.Line,
+		Addr:             bp.Addr,
+		Tracepoint:       bp.Tracepoint,
+		TraceReturn:      bp.TraceReturn,
+		RootFuncName:     bp.RootFuncName,
+		TraceFollowCalls: bp.TraceFollowCalls,
+		Stacktrace:       bp.Stacktrace,
+		Goroutine:        bp.Goroutine,
+		Variables:        bp.Variables,
+		LoadArgs:         LoadConfigFromProc(bp.LoadArgs),
+		LoadLocals:       LoadConfigFromProc(bp.LoadLocals),
+		WatchExpr:        bp.WatchExpr,
+		WatchType:        WatchType(bp.WatchType),
+		SoftwareWatch:    bp.SoftwareWatch,
+		Addrs:            []uint64{bp.Addr},
+		UserData:         bp.UserData,
 	}
 
 	breaklet := bp.UserBreaklet()
@@ -92,6 +95,11 @@ func ConvertThread(thread proc.Thread) *Thread {
 		gid      int
 	)
 
+	if thread.Common().Running {
+		// the registers of a running thread can not be read
+		return &Thread{ID: thread.ThreadID(), Running: true}
+	}
+
 	loc, err := thread.Location()
 	if err == nil {
 		pc = loc.PC
@@ -101,8 +109,10 @@ func ConvertThread(thread proc.Thread) *Thread {
 	}
 
 	var bp *Breakpoint
+	var watchHit string
 	if b := thread.Breakpoint(); b.Active {
 		bp = ConvertBreakpoint(b.Breakpoint)
+		watchHit = b.WatchHit
 	}
 
 	if g, _ := proc.GetG(thread); g != nil {
@@ -117,6 +127,7 @@ func ConvertThread(thread proc.Thread) *Thread {
 		Function:    function,
 		GoroutineID: gid,
 		Breakpoint:  bp,
+		WatchHit:    watchHit,
 	}
 }
 
@@ -308,6 +319,8 @@ func ConvertGoroutine(tgt *proc.Target, g *proc.G) *Goroutine {
 		WaitReason:     g.WaitReason,
 		Labels:         g.Labels(),
 		Status:         g.Status,
+		// the thread of a goroutine is only known if it is stopped
+		Running: tgt.NonStop() && g.Status == proc.Grunning && th == nil,
 	}
 }
 
@@ -45,6 +45,11 @@ func (v *Variable) writeTo(buf io.Writer, top, newlines, includeType bool, inden
 		return
 	}
 
+	if v.Flags&VariableTypeParameter != 0 {
+		fmt.Fprint(buf, v.Type)
+		return
+	}
+
 	if !top && v.Addr == 0 && v.Value == "" {
 		if includeType && v.Type != "void" {
 			fmt.Fprintf(buf, "%s nil", v.Type)
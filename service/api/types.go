@@ -42,10 +42,99 @@ type DebuggerState struct {
 	ExitStatus int  `json:"exitStatus"`
 	// When contains a description of the current position in a recording
 	When string
+	// NonStop is true if only the threads that hit a breakpoint are stopped
+	// while the other threads keep running, see Thread.Running.
+	NonStop bool `json:"nonStop,omitempty"`
+	// Signal is the signal that stopped the current thread, if its policy
+	// is to stop, see SignalPolicy.
+	Signal *Signal `json:"signal,omitempty"`
+	// Syscall is the system call the current thread stopped at, if it
+	// stopped because of a syscall catchpoint.
+	Syscall *Syscall `json:"syscall,omitempty"`
+	// Panic is the panic the current thread stopped at, if it stopped at
+	// the unrecovered-panic breakpoint or at the panic catchpoint.
+	Panic *Panic `json:"panic,omitempty"`
+	// Exiting indicates that the process is about to exit with ExitStatus,
+	// it is stopped before its memory is released, see StopOnExit in the
+	// debugger configuration. The current thread is the one that called exit.
+	Exiting bool `json:"exiting,omitempty"`
+	// Watching is true if the target is rebuilt and restarted when its
+	// source files change, see Watch in the debugger configuration.
+	Watching bool `json:"watching,omitempty"`
+	// Rebuild is set in the states returned by WaitEvent after the target
+	// was rebuilt and restarted because its source files changed.
+	Rebuild *Rebuild `json:"rebuild,omitempty"`
 	// Filled by RPCClient.Continue, indicates an error
 	Err error `json:"-"`
 }
 
+// Signal describes a signal received by the target.
+type Signal struct {
+	Number int    `json:"number"`
+	Name   string `json:"name"`
+	// Code is the si_code of the signal.
+	Code int `json:"code"`
+	// Addr is the faulting address, only set for SIGSEGV, SIGBUS, SIGILL,
+	// SIGFPE and SIGTRAP.
+	Addr uint64 `json:"addr,omitempty"`
+}
+
+// Syscall describes a system call the target stopped at.
+type Syscall struct {
+	Nr   int    `json:"nr"`
+	Name string `json:"name"`
+	// Exit is true if the thread is returning from the system call,
+	// otherwise it is entering it.
+	Exit bool `json:"exit"`
+	// Args are the arguments of the system call, the ones that are strings,
+	// like paths, are quoted, the others are in hexadecimal.
+	Args []string `json:"args"`
+	// Ret is the value returned by the system call, if Exit is true.
+	Ret int64 `json:"ret"`
+	// Errno is the name of the error returned by the system call, if it
+	// failed.
+	Errno string `json:"errno,omitempty"`
+}
+
+// Panic describes a panic the target stopped at.
+type Panic struct {
+	// Value is the value passed to panic.
+	Value Variable `json:"value"`
+	// Type is the dynamic type of Value.
+	Type string `json:"type"`
+	// Location is where panic was called, runtime frames are skipped.
+	Location Location `json:"location"`
+}
+
+// PanicCatchpoint describes which panics stop the target.
+type PanicCatchpoint struct {
+	// Recovered is true if the target stops when a panic starts, including
+	// panics that are later recovered, otherwise only unrecovered panics
+	// stop it.
+	Recovered bool `json:"recovered"`
+	// Type, if not empty, is the dynamic type the panic value must have for
+	// the target to stop.
+	Type string `json:"type,omitempty"`
+	// Cond, if not empty, is a boolean expression that must be true for the
+	// target to stop, the identifier panic refers to the panic value.
+	Cond string `json:"cond,omitempty"`
+}
+
+// SignalPolicy describes how a signal received by the target is handled.
+type SignalPolicy struct {
+	// Signal is the number of the signal, when setting a policy it can be
+	// left to zero and the signal specified by Name instead.
+	Signal int    `json:"signal"`
+	Name   string `json:"name"`
+	// Stop is true if the target stops when it receives the signal.
+	Stop bool `json:"stop"`
+	// Print is true if a message is printed when the target receives the
+	// signal.
+	Print bool `json:"print"`
+	// Pass is true if the signal is delivered to the target.
+	Pass bool `json:"pass"`
+}
+
 // TracepointResult result of tracepoint
 type TracepointResult struct {
 	// Addr is the address of this tracepoint.
@@ -59,9 +148,24 @@ type TracepointResult struct {
 	FunctionName string `json:"functionName,omitempty"`
 
 	GoroutineID int `json:"goroutineID"`
+	// Inlined is true if the tracepoint was hit at the start of an inlined
+	// call, in which case there are no return parameters.
+	Inlined bool `json:"inlined,omitempty"`
 
 	InputParams  []Variable `json:"inputParams,omitempty"`
 	ReturnParams []Variable `json:"returnParams,omitempty"`
+	// Captures are the values reached from the parameters by following
+	// pointers, as requested when the tracepoint was created.
+	Captures []Variable `json:"captures,omitempty"`
+}
+
+// LatencyHistogram is the distribution of the durations of the calls to
+// a function traced with an eBPF latency tracepoint.
+type LatencyHistogram struct {
+	FunctionName string `json:"functionName"`
+	// Counts[i] is the number of calls that took between 2^i and
+	// 2^(i+1)-1 nanoseconds.
+	Counts []uint64 `json:"counts"`
 }
 
 // Breakpoint addresses a set of locations at which process execution may be suspended.
@@ -98,6 +202,14 @@ type Breakpoint struct {
 	// TraceReturn flag signifying this is a breakpoint set at a return
 	// statement in a traced function.
 	TraceReturn bool `json:"traceReturn"`
+	// RootFuncName is a regular expression matching the functions whose
+	// calls are followed by this tracepoint, see TraceFollowCalls.
+	RootFuncName string `json:"rootFuncName,omitempty"`
+	// TraceFollowCalls, if greater than zero, makes the debugger set
+	// tracepoints on the functions called by the traced function, down to
+	// TraceFollowCalls calls below a function matching RootFuncName. Only
+	// the calls made below such a function are reported.
+	TraceFollowCalls int `json:"traceFollowCalls,omitempty"`
 	// retrieve goroutine information
 	Goroutine bool `json:"goroutine"`
 	// number of stack frames to retrieve
@@ -112,6 +224,9 @@ type Breakpoint struct {
 	// WatchExpr is the expression used to create this watchpoint
 	WatchExpr string
 	WatchType WatchType
+	// SoftwareWatch is true if the watchpoint is implemented without a debug
+	// register, which slows down the target considerably.
+	SoftwareWatch bool `json:"softwareWatch,omitempty"`
 
 	VerboseDescr []string `json:"VerboseDescr,omitempty"`
 
@@ -176,6 +291,11 @@ type Thread struct {
 	ReturnValues []Variable
 	// CallReturn is true if ReturnValues are the return values of an injected call.
 	CallReturn bool
+	// Running is true if the thread is executing, in non-stop mode.
+	Running bool `json:"running,omitempty"`
+	// WatchHit describes the part of the watched value that was accessed,
+	// if the thread is stopped at a watchpoint.
+	WatchHit string `json:"watchHit,omitempty"`
 }
 
 // Location holds program location information.
@@ -284,6 +404,10 @@ const (
 
 	// VariableCPURegister means this variable is a CPU register.
 	VariableCPURegister
+
+	// VariableTypeParameter means this variable is a type parameter of a
+	// generic function, its type is the type argument of the instantiation.
+	VariableTypeParameter
 )
 
 // Variable describes a variable.
@@ -369,6 +493,9 @@ type Goroutine struct {
 	Unreadable string `json:"unreadable"`
 	// Goroutine's pprof labels
 	Labels map[string]string `json:"labels,omitempty"`
+	// Running is true if the goroutine is executing on a thread that is
+	// running, in non-stop mode.
+	Running bool `json:"running,omitempty"`
 }
 
 const (
@@ -381,7 +508,7 @@ type DebuggerCommand struct {
 	// Name is the command to run.
 	Name string `json:"name"`
 	// ThreadID is used to specify which thread to use with the SwitchThread
-	// command.
+	// command, in non-stop mode it is the only thread resumed by Continue.
 	ThreadID int `json:"threadID,omitempty"`
 	// GoroutineID is used to specify which thread to use with the SwitchGoroutine
 	// and Call commands.
@@ -406,6 +533,11 @@ type DebuggerCommand struct {
 	// violate the rules about stack objects you can disable this safety check
 	// by setting UnsafeCall to true.
 	UnsafeCall bool `json:"unsafeCall,omitempty"`
+
+	// StepSkip is the list of functions that the Step and ReverseStep
+	// commands do not stop in, each rule is a pair of kind ("func",
+	// "package" or "file") and glob pattern.
+	StepSkip [][2]string `json:"stepSkip,omitempty"`
 }
 
 // BreakpointInfo contains informations about the current breakpoint
@@ -415,6 +547,10 @@ type BreakpointInfo struct {
 	Variables  []Variable   `json:"variables,omitempty"`
 	Arguments  []Variable   `json:"arguments,omitempty"`
 	Locals     []Variable   `json:"locals,omitempty"`
+	// TraceDepth is the depth of the call, relative to the closest call to
+	// a function matching Breakpoint.RootFuncName, for tracepoints that
+	// follow calls.
+	TraceDepth int `json:"traceDepth,omitempty"`
 }
 
 // EvalScope is the scope a command should be evaluated in.
@@ -428,6 +564,8 @@ type EvalScope struct {
 const (
 	// Continue resumes process execution.
 	Continue = "continue"
+	// Rewind restores the most recent checkpoint and resumes execution up to the last breakpoint hit before the current position.
+	Rewind = "rewind"
 	// DirecitonCongruentContinue resumes process execution, if a next, step or stepout operation is in progress it will resume execution.
 	DirectionCongruentContinue = "directionCongruentContinue"
 	// Step continues to next source line, entering function calls.
@@ -438,6 +576,14 @@ const (
 	StepInstruction = "stepInstruction"
 	// Next continues to the next source line, not entering function calls.
 	Next = "next"
+	// ReverseStep reverses execution to the previous source line, entering function calls.
+	ReverseStep = "reverseStep"
+	// ReverseStepOut reverses execution to the CALL instruction that created the current frame.
+	ReverseStepOut = "reverseStepOut"
+	// ReverseStepInstruction reverses execution of exactly 1 cpu instruction.
+	ReverseStepInstruction = "reverseStepInstruction"
+	// ReverseNext reverses execution to the previous source line, not entering function calls.
+	ReverseNext = "reverseNext"
 	// SwitchThread switches the debugger's current thread context.
 	SwitchThread = "switchThread"
 	// SwitchGoroutine switches the debugger's current thread context to the thread running the specified goroutine
@@ -525,6 +671,17 @@ type DiscardedBreakpoint struct {
 	Reason     string
 }
 
+// Rebuild describes an automatic rebuild and restart of the target.
+type Rebuild struct {
+	// Files are the source files whose changes triggered the rebuild.
+	Files []string
+	// Err is the error that made the rebuild or the restart fail, if any.
+	Err string
+	// DiscardedBreakpoints are the breakpoints that could not be set again
+	// in the new executable.
+	DiscardedBreakpoints []DiscardedBreakpoint
+}
+
 // Checkpoint is a point in the program that
 // can be returned to in certain execution modes.
 type Checkpoint struct {
@@ -533,6 +690,18 @@ type Checkpoint struct {
 	Where string
 }
 
+// Target is a process being debugged, either the process that was launched
+// or attached to or one of the child processes being followed.
+type Target struct {
+	Pid int
+	// Path is the path of the executable of the process.
+	Path string
+	// Exited is true if the process has exited or was detached from.
+	Exited bool
+	// Selected is true for the target commands operate on.
+	Selected bool
+}
+
 // Image represents a loaded shared object (go plugin or shared library)
 type Image struct {
 	Path    string
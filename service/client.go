@@ -24,6 +24,12 @@ type Client interface {
 
 	// Restarts program. Set true if you want to rebuild the process we are debugging.
 	Restart(rebuild bool) ([]api.DiscardedBreakpoint, error)
+	// RestartWith restarts the process changing its arguments, redirects,
+	// environment or working directory, see RestartIn.
+	RestartWith(in RestartIn) ([]api.DiscardedBreakpoint, error)
+	// RebuildWithFlags rebuilds and restarts the process, passing buildFlags
+	// to the go command from now on.
+	RebuildWithFlags(buildFlags string) ([]api.DiscardedBreakpoint, error)
 
 	// GetState returns the current debugger state.
 	GetState() (*api.DebuggerState, error)
@@ -34,23 +40,38 @@ type Client interface {
 	Continue() <-chan *api.DebuggerState
 	// DirectionCongruentContinue resumes process execution, if a next, step or stepout operation is in progress it will resume execution.
 	DirectionCongruentContinue() <-chan *api.DebuggerState
+	// Rewind restores the most recent checkpoint and runs up to the last breakpoint hit before the current position.
+	Rewind() <-chan *api.DebuggerState
 	// Next continues to the next source line, not entering function calls.
 	Next() (*api.DebuggerState, error)
+	// ReverseNext continues backward to the previous line of source code, not entering function calls.
+	ReverseNext() (*api.DebuggerState, error)
 	// Step continues to the next source line, entering function calls.
 	Step() (*api.DebuggerState, error)
+	// ReverseStep continues backward to the previous line of source code, entering function calls.
+	ReverseStep() (*api.DebuggerState, error)
 	// StepOut continues to the return address of the current function.
 	StepOut() (*api.DebuggerState, error)
+	// ReverseStepOut continues backward to the caller of the current function.
+	ReverseStepOut() (*api.DebuggerState, error)
 	// Call resumes process execution while making a function call.
 	Call(goroutineID int, expr string, unsafe bool) (*api.DebuggerState, error)
 
 	// SingleStep will step a single cpu instruction.
 	StepInstruction() (*api.DebuggerState, error)
+	// ReverseStepInstruction will reverse step a single cpu instruction.
+	ReverseStepInstruction() (*api.DebuggerState, error)
 	// SwitchThread switches the current thread context.
 	SwitchThread(threadID int) (*api.DebuggerState, error)
 	// SwitchGoroutine switches the current goroutine (and the current thread as well)
 	SwitchGoroutine(goroutineID int) (*api.DebuggerState, error)
 	// Halt suspends the process.
 	Halt() (*api.DebuggerState, error)
+	// ContinueThread resumes only the specified thread, in non-stop mode.
+	ContinueThread(threadID int) (*api.DebuggerState, error)
+	// WaitEvent waits for a thread that was left running, in non-stop mode,
+	// to stop.
+	WaitEvent() (*api.DebuggerState, error)
 
 	// GetBreakpoint gets a breakpoint by ID.
 	GetBreakpoint(id int) (*api.Breakpoint, error)
@@ -139,6 +160,9 @@ type Client interface {
 
 	// SetReturnValuesLoadConfig sets the load configuration for return values.
 	SetReturnValuesLoadConfig(*api.LoadConfig)
+	// SetStepSkip sets the functions that step does not stop in, see
+	// api.DebuggerCommand.StepSkip.
+	SetStepSkip(rules [][2]string)
 
 	// FunctionReturnLocations return locations when function `fnName` returns
 	FunctionReturnLocations(fnName string) ([]uint64, error)
@@ -154,6 +178,39 @@ type Client interface {
 	// This function will return an error if it reads less than `length` bytes.
 	ExamineMemory(address uint64, length int) ([]byte, bool, error)
 
+	// Checkpoint sets a checkpoint at the current position.
+	Checkpoint(where string) (checkpointID int, err error)
+	// ListCheckpoints gets all checkpoints.
+	ListCheckpoints() ([]api.Checkpoint, error)
+	// ClearCheckpoint removes a checkpoint
+	ClearCheckpoint(id int) error
+
+	// ListTargets returns the processes being debugged.
+	ListTargets() ([]api.Target, error)
+	// SwitchTarget makes the process with the specified pid the target of subsequent commands.
+	SwitchTarget(pid int) (*api.DebuggerState, error)
+
+	// Recorded returns true if the target has something recorded that can be executed backwards.
+	Recorded() bool
+	// Record starts or stops recording the execution of the current thread.
+	Record(on bool) error
+
+	// ListSignalPolicies returns how each signal received by the target is handled.
+	ListSignalPolicies() ([]api.SignalPolicy, error)
+	// SetSignalPolicy changes how a signal received by the target is handled.
+	SetSignalPolicy(policy api.SignalPolicy) (api.SignalPolicy, error)
+
+	// ListSyscallCatchpoints returns the system calls that stop the target.
+	ListSyscallCatchpoints() (all bool, syscalls []string, err error)
+	// SetSyscallCatchpoints changes the system calls that stop the target.
+	SetSyscallCatchpoints(all bool, syscalls []string) error
+	// GetPanicCatchpoint returns the panics that stop the target, nil if
+	// only unrecovered panics do.
+	GetPanicCatchpoint() (*api.PanicCatchpoint, error)
+	// SetPanicCatchpoint changes the panics that stop the target, nil
+	// restores the default.
+	SetPanicCatchpoint(pc *api.PanicCatchpoint) error
+
 	// CoreDumpStart starts creating a core dump to the specified file
 	CoreDumpStart(dest string) (api.DumpState, error)
 	// CoreDumpWait waits for the core dump to finish, or for the specified amount of milliseconds
@@ -176,6 +233,7 @@ var _ Client = &RPCClient{}
 type RPCClient struct {
 	client        *rpc.Client
 	retValLoadCfg *api.LoadConfig
+	stepSkip      [][2]string
 }
 
 // NewClient creates a new RPCClient.
@@ -212,7 +270,19 @@ func (c *RPCClient) Detach(kill bool) error {
 
 func (c *RPCClient) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
 	out := new(RestartOut)
-	err := c.call("Restart", RestartIn{rebuild}, out)
+	err := c.call("Restart", RestartIn{Rebuild: rebuild}, out)
+	return out.DiscardedBreakpoints, err
+}
+
+func (c *RPCClient) RestartWith(in RestartIn) ([]api.DiscardedBreakpoint, error) {
+	out := new(RestartOut)
+	err := c.call("Restart", in, out)
+	return out.DiscardedBreakpoints, err
+}
+
+func (c *RPCClient) RebuildWithFlags(buildFlags string) ([]api.DiscardedBreakpoint, error) {
+	out := new(RestartOut)
+	err := c.call("Restart", RestartIn{Rebuild: true, BuildFlags: &buildFlags}, out)
 	return out.DiscardedBreakpoints, err
 }
 
@@ -236,6 +306,10 @@ func (c *RPCClient) DirectionCongruentContinue() <-chan *api.DebuggerState {
 	return c.continueDir(api.DirectionCongruentContinue)
 }
 
+func (c *RPCClient) Rewind() <-chan *api.DebuggerState {
+	return c.continueDir(api.Rewind)
+}
+
 func (c *RPCClient) continueDir(cmd string) <-chan *api.DebuggerState {
 	ch := make(chan *api.DebuggerState)
 	go func() {
@@ -275,15 +349,39 @@ func (c *RPCClient) continueDir(cmd string) <-chan *api.DebuggerState {
 	return ch
 }
 
+func (c *RPCClient) ContinueThread(threadID int) (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.Continue, ThreadID: threadID, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) WaitEvent() (*api.DebuggerState, error) {
+	var out WaitEventOut
+	err := c.call("WaitEvent", WaitEventIn{}, &out)
+	return out.State, err
+}
+
 func (c *RPCClient) Next() (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.Next, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
 	return &out.State, err
 }
 
+func (c *RPCClient) ReverseNext() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseNext, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Step() (*api.DebuggerState, error) {
 	var out CommandOut
-	err := c.call("Command", api.DebuggerCommand{Name: api.Step, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	err := c.call("Command", api.DebuggerCommand{Name: api.Step, ReturnInfoLoadConfig: c.retValLoadCfg, StepSkip: c.stepSkip}, &out)
+	return &out.State, err
+}
+
+func (c *RPCClient) ReverseStep() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStep, ReturnInfoLoadConfig: c.retValLoadCfg, StepSkip: c.stepSkip}, &out)
 	return &out.State, err
 }
 
@@ -293,6 +391,12 @@ func (c *RPCClient) StepOut() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) ReverseStepOut() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStepOut, ReturnInfoLoadConfig: c.retValLoadCfg}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) Call(goroutineID int, expr string, unsafe bool) (*api.DebuggerState, error) {
 	var out CommandOut
 	err := c.call("Command", api.DebuggerCommand{Name: api.Call, ReturnInfoLoadConfig: c.retValLoadCfg, Expr: expr, UnsafeCall: unsafe, GoroutineID: goroutineID}, &out)
@@ -305,6 +409,12 @@ func (c *RPCClient) StepInstruction() (*api.DebuggerState, error) {
 	return &out.State, err
 }
 
+func (c *RPCClient) ReverseStepInstruction() (*api.DebuggerState, error) {
+	var out CommandOut
+	err := c.call("Command", api.DebuggerCommand{Name: api.ReverseStepInstruction}, &out)
+	return &out.State, err
+}
+
 func (c *RPCClient) SwitchThread(threadID int) (*api.DebuggerState, error) {
 	var out CommandOut
 	cmd := api.DebuggerCommand{
@@ -337,6 +447,12 @@ func (c *RPCClient) GetBufferedTracepoints() ([]api.TracepointResult, error) {
 	return out.TracepointResults, err
 }
 
+func (c *RPCClient) GetLatencyHistograms() ([]api.LatencyHistogram, error) {
+	var out GetLatencyHistogramsOut
+	err := c.call("GetLatencyHistograms", GetLatencyHistogramsIn{}, &out)
+	return out.Histograms, err
+}
+
 func (c *RPCClient) GetBreakpoint(id int) (*api.Breakpoint, error) {
 	var out GetBreakpointOut
 	err := c.call("GetBreakpoint", GetBreakpointIn{id, ""}, &out)
@@ -359,9 +475,9 @@ func (c *RPCClient) CreateBreakpoint(breakPoint *api.Breakpoint) (*api.Breakpoin
 	return &out.Breakpoint, err
 }
 
-func (c *RPCClient) CreateEBPFTracepoint(fnName string) error {
+func (c *RPCClient) CreateEBPFTracepoint(fnName string, loadArgs *api.LoadConfig, captures []string, cond string, latency bool) error {
 	var out CreateEBPFTracepointOut
-	return c.call("CreateEBPFTracepoint", CreateEBPFTracepointIn{FunctionName: fnName}, &out)
+	return c.call("CreateEBPFTracepoint", CreateEBPFTracepointIn{FunctionName: fnName, LoadArgs: loadArgs, Captures: captures, Cond: cond, Latency: latency}, &out)
 }
 
 func (c *RPCClient) CreateWatchpoint(scope api.EvalScope, expr string, wtype api.WatchType) (*api.Breakpoint, error) {
@@ -535,10 +651,89 @@ func (c *RPCClient) DisassemblePC(scope api.EvalScope, pc uint64, flavour api.As
 	return out.Disassemble, err
 }
 
+func (c *RPCClient) Checkpoint(where string) (int, error) {
+	var out CheckpointOut
+	err := c.call("Checkpoint", CheckpointIn{where}, &out)
+	return out.ID, err
+}
+
+func (c *RPCClient) ListCheckpoints() ([]api.Checkpoint, error) {
+	var out ListCheckpointsOut
+	err := c.call("ListCheckpoints", ListCheckpointsIn{}, &out)
+	return out.Checkpoints, err
+}
+
+func (c *RPCClient) ClearCheckpoint(id int) error {
+	var out ClearCheckpointOut
+	err := c.call("ClearCheckpoint", ClearCheckpointIn{id}, &out)
+	return err
+}
+
+func (c *RPCClient) ListTargets() ([]api.Target, error) {
+	var out ListTargetsOut
+	err := c.call("ListTargets", ListTargetsIn{}, &out)
+	return out.Targets, err
+}
+
+func (c *RPCClient) SwitchTarget(pid int) (*api.DebuggerState, error) {
+	var out SwitchTargetOut
+	err := c.call("SwitchTarget", SwitchTargetIn{pid}, &out)
+	return out.State, err
+}
+
+func (c *RPCClient) Recorded() bool {
+	out := new(RecordedOut)
+	c.call("Recorded", RecordedIn{}, out)
+	return out.Recorded
+}
+
+func (c *RPCClient) Record(on bool) error {
+	var out RecordOut
+	return c.call("Record", RecordIn{on}, &out)
+}
+
+func (c *RPCClient) ListSignalPolicies() ([]api.SignalPolicy, error) {
+	var out ListSignalPoliciesOut
+	err := c.call("ListSignalPolicies", ListSignalPoliciesIn{}, &out)
+	return out.Policies, err
+}
+
+func (c *RPCClient) SetSignalPolicy(policy api.SignalPolicy) (api.SignalPolicy, error) {
+	var out SetSignalPolicyOut
+	err := c.call("SetSignalPolicy", SetSignalPolicyIn{policy}, &out)
+	return out.Policy, err
+}
+
+func (c *RPCClient) ListSyscallCatchpoints() (bool, []string, error) {
+	var out ListSyscallCatchpointsOut
+	err := c.call("ListSyscallCatchpoints", ListSyscallCatchpointsIn{}, &out)
+	return out.All, out.Syscalls, err
+}
+
+func (c *RPCClient) SetSyscallCatchpoints(all bool, syscalls []string) error {
+	var out SetSyscallCatchpointsOut
+	return c.call("SetSyscallCatchpoints", SetSyscallCatchpointsIn{all, syscalls}, &out)
+}
+
+func (c *RPCClient) GetPanicCatchpoint() (*api.PanicCatchpoint, error) {
+	var out GetPanicCatchpointOut
+	err := c.call("GetPanicCatchpoint", GetPanicCatchpointIn{}, &out)
+	return out.Catchpoint, err
+}
+
+func (c *RPCClient) SetPanicCatchpoint(pc *api.PanicCatchpoint) error {
+	var out SetPanicCatchpointOut
+	return c.call("SetPanicCatchpoint", SetPanicCatchpointIn{pc}, &out)
+}
+
 func (c *RPCClient) SetReturnValuesLoadConfig(cfg *api.LoadConfig) {
 	c.retValLoadCfg = cfg
 }
 
+func (c *RPCClient) SetStepSkip(rules [][2]string) {
+	c.stepSkip = rules
+}
+
 func (c *RPCClient) FunctionReturnLocations(fnName string) ([]uint64, error) {
 	var out FunctionReturnLocationsOut
 	err := c.call("FunctionReturnLocations", FunctionReturnLocationsIn{fnName}, &out)
@@ -1,5 +1,28 @@
 package debugger
 
+import "github.com/hitzhangjie/dlv/pkg/proc"
+
+// RestartOptions are changes to the target applied by Restart, they
+// persist for the following restarts.
+type RestartOptions struct {
+	// BuildFlags, if not nil, replace the flags passed to the go command
+	// when the target is rebuilt.
+	BuildFlags *string
+
+	// ResetArgs makes NewArgs and NewRedirects replace the arguments and
+	// the redirects of the target, argv[0] can not be changed.
+	ResetArgs    bool
+	NewArgs      []string
+	NewRedirects [3]string
+
+	// Env are changes to the environment of the target, KEY=VALUE sets a
+	// variable, KEY removes it.
+	Env []string
+
+	// WorkingDir, if not empty, is the new working directory of the target.
+	WorkingDir string
+}
+
 // Config provides the configuration to start a Debugger.
 //
 // Only one of ProcessArgs or AttachPid should be specified. If ProcessArgs is
@@ -14,6 +37,10 @@ type Config struct {
 	// attach.
 	AttachPid int
 
+	// AttachWaitFor, if valid, makes the debugger wait for a process to be
+	// started and attach to it, AttachPid is set to its PID.
+	AttachWaitFor *proc.WaitFor
+
 	// CoreFile specifies the path to the core dump to open.
 	CoreFile string
 
@@ -28,9 +55,50 @@ type Config struct {
 	// Packages contains the packages that we are debugging.
 	Packages []string
 
+	// BuildFlags are the flags passed to the go command when the packages
+	// are built, see gobuild.GoBuild.
+	BuildFlags string
+
+	// Watch makes the debugger rebuild and restart the target when the Go
+	// source files of the main module change, only for the executables it
+	// built.
+	Watch bool
+
 	// ExecuteKind contains the kind of the executed program.
 	ExecuteKind ExecuteKind
 
 	// DisableASLR disables ASLR
 	DisableASLR bool
+
+	// FollowFork makes child processes created by the target with fork
+	// become new targets.
+	FollowFork bool
+
+	// FollowExec makes a target that calls exec, and the programs it spawns,
+	// become new targets for the program being executed.
+	FollowExec bool
+
+	// NonStop makes a breakpoint hit only stop the thread that hit it, the
+	// other threads keep running.
+	NonStop bool
+
+	// StopOnExit makes the target stop just before it exits, while its
+	// memory can still be inspected.
+	StopOnExit bool
+
+	// TTY is the terminal used as controlling terminal and standard file
+	// descriptors by the target, instead of the one of the debugger.
+	TTY string
+
+	// Redirects are the files stdin, stdout and stderr of the target are
+	// redirected to, empty for the ones that are not redirected.
+	Redirects [3]string
+
+	// Env are the environment variables, in the KEY=VALUE form, added to
+	// the environment of the target.
+	Env []string
+
+	// ClearEnv makes the target not inherit the environment of the
+	// debugger, it only gets the variables in Env.
+	ClearEnv bool
 }
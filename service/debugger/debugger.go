@@ -5,10 +5,12 @@ import (
 	"debug/elf"
 	"errors"
 	"fmt"
+	"go/ast"
 	"go/parser"
 	"go/token"
 	"io/ioutil"
 	"os"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
@@ -24,6 +26,7 @@ import (
 	"github.com/hitzhangjie/dlv/pkg/log"
 	"github.com/hitzhangjie/dlv/pkg/proc"
 	"github.com/hitzhangjie/dlv/pkg/proc/core"
+	"github.com/hitzhangjie/dlv/pkg/proc/linutil"
 	"github.com/hitzhangjie/dlv/pkg/proc/native"
 	"github.com/hitzhangjie/dlv/service/api"
 
@@ -44,6 +47,18 @@ type Debugger struct {
 
 	targetMutex sync.Mutex
 	target      *proc.Target
+	// group contains target and the processes it created that are being
+	// followed, target is always the selected target of the group.
+	group *proc.TargetGroup
+
+	// polling is true while pollNonStop is waiting for the threads left
+	// running in non-stop mode, the stops it sees are sent to events.
+	polling bool
+	events  chan *api.DebuggerState
+
+	// watcher rebuilds and restarts the target when its source files
+	// change, see Config.Watch.
+	watcher *sourceWatcher
 
 	running      bool
 	runningMutex sync.Mutex
@@ -53,6 +68,19 @@ type Debugger struct {
 	// Debugger keeps a map of disabled breakpoints so lower layers like proc
 	// doesn't need to deal with them.
 	disabledBreakpoints map[int]*api.Breakpoint
+
+	// followedCalls is the set of functions whose callees have been
+	// instrumented by tracepoints that follow calls, see followCalls.
+	followedCalls map[string]bool
+
+	// signalPolicies are the policies changed with SetSignalPolicy, they are
+	// applied again to the new target group by setupGroup when restarting.
+	signalPolicies map[int]proc.SignalPolicy
+
+	// catchAllSyscalls and catchSyscalls are the syscall catchpoints set
+	// with SetSyscallCatchpoints, applied again by setupGroup.
+	catchAllSyscalls bool
+	catchSyscalls    []int
 }
 
 // New creates a new Debugger, processArgs will be passed to the new process.
@@ -61,6 +89,16 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		config:              config,
 		processArgs:         processArgs,
 		disabledBreakpoints: make(map[int]*api.Breakpoint),
+		events:              make(chan *api.DebuggerState, maxPendingEvents),
+	}
+
+	if d.config.AttachWaitFor.Valid() {
+		log.Info("waiting for process %q", d.config.AttachWaitFor.Name)
+		pid, err := native.WaitFor(d.config.AttachWaitFor)
+		if err != nil {
+			return nil, err
+		}
+		d.config.AttachPid = pid
 	}
 
 	// Create the process by either attaching/launching or open coredump.
@@ -100,9 +138,82 @@ func New(config *Config, processArgs []string) (*Debugger, error) {
 		}
 	}
 
+	d.group = d.target.Group()
+	if d.config.CoreFile == "" {
+		if err := d.setupGroup(); err != nil {
+			d.detach(true)
+			return nil, err
+		}
+	}
+
+	if d.config.Watch {
+		if err := d.watchSources(); err != nil {
+			d.detach(true)
+			return nil, err
+		}
+	}
+
 	return d, nil
 }
 
+// setupGroup sets the follow mode of the target group and makes the
+// breakpoints of a target be inherited by the processes it creates.
+func (d *Debugger) setupGroup() error {
+	d.group.NewTargetHook = d.newTarget
+	var mode proc.FollowMode
+	if d.config.FollowFork {
+		mode |= proc.FollowFork
+	}
+	if d.config.FollowExec {
+		mode |= proc.FollowExec
+	}
+	if mode != 0 {
+		if err := d.group.SetFollowMode(mode); err != nil {
+			return err
+		}
+	}
+	if d.config.StopOnExit {
+		if err := d.target.SetStopOnExit(true); err != nil {
+			return err
+		}
+	}
+	for sig, policy := range d.signalPolicies {
+		if err := d.target.SetSignalPolicy(sig, policy); err != nil {
+			return err
+		}
+	}
+	if d.catchAllSyscalls || len(d.catchSyscalls) > 0 {
+		if err := d.target.SetSyscallCatchpoints(d.catchAllSyscalls, d.catchSyscalls); err != nil {
+			return err
+		}
+	}
+	if d.config.NonStop {
+		return d.target.SetNonStop(true)
+	}
+	return nil
+}
+
+// newTarget is called when a followed process is added to the target
+// group, the user breakpoints of its parent are set on it.
+func (d *Debugger) newTarget(parent, t *proc.Target) error {
+	log.Info("new target process %d (%s)", t.Pid(), executablePath(t))
+	if parent == nil {
+		return nil
+	}
+	var bps []*proc.Breakpoint
+	for _, bp := range parent.Breakpoints().M {
+		if bp.IsUser() {
+			bps = append(bps, bp)
+		}
+	}
+	sameBinary := executablePath(parent) == executablePath(t)
+	discarded, err := d.recreateBreakpoints(t, api.ConvertBreakpoints(bps), !sameBinary)
+	for _, dbp := range discarded {
+		log.Warn("breakpoint %d not set in process %d: %s", dbp.Breakpoint.ID, t.Pid(), dbp.Reason)
+	}
+	return err
+}
+
 // canRestart returns true if the target was started with Launch and can be restarted
 func (d *Debugger) canRestart() bool {
 	switch {
@@ -145,7 +256,68 @@ func (d *Debugger) Launch(processArgs []string, wd string) (*proc.Target, error)
 		launchFlags |= proc.LaunchDisableASLR
 	}
 
-	return native.Launch(processArgs, wd, launchFlags)
+	return native.Launch(processArgs, wd, launchFlags, native.LaunchOptions{
+		TTY:       d.config.TTY,
+		Redirects: d.config.Redirects,
+		Env:       d.config.Env,
+		ClearEnv:  d.config.ClearEnv,
+	})
+}
+
+// applyRestartOptions applies opts to the arguments and the configuration
+// of the target.
+func (d *Debugger) applyRestartOptions(opts RestartOptions) error {
+	for _, kv := range opts.Env {
+		if kv == "" || kv[0] == '=' {
+			return fmt.Errorf("invalid environment variable %q", kv)
+		}
+	}
+	if opts.WorkingDir != "" {
+		if fi, err := os.Stat(opts.WorkingDir); err != nil || !fi.IsDir() {
+			return fmt.Errorf("invalid working directory %q", opts.WorkingDir)
+		}
+		d.config.WorkingDir = opts.WorkingDir
+	}
+	if opts.BuildFlags != nil {
+		d.config.BuildFlags = *opts.BuildFlags
+	}
+	if opts.ResetArgs {
+		d.processArgs = append([]string{d.processArgs[0]}, opts.NewArgs...)
+		d.config.Redirects = opts.NewRedirects
+	}
+	for _, kv := range opts.Env {
+		d.setEnv(kv)
+	}
+	return nil
+}
+
+// setEnv changes the environment of the target, kv is either KEY=VALUE to
+// set a variable or KEY to remove it.
+func (d *Debugger) setEnv(kv string) {
+	key := kv
+	if i := strings.Index(kv, "="); i >= 0 {
+		key = kv[:i]
+	}
+	removeKey := func(env []string) []string {
+		r := env[:0]
+		for _, v := range env {
+			if v != key && !strings.HasPrefix(v, key+"=") {
+				r = append(r, v)
+			}
+		}
+		return r
+	}
+	d.config.Env = removeKey(d.config.Env)
+	if key != kv {
+		d.config.Env = append(d.config.Env, kv)
+		return
+	}
+	if !d.config.ClearEnv {
+		// a variable inherited from the debugger can only be removed by
+		// passing the whole environment to the target
+		d.config.Env = append(removeKey(os.Environ()), d.config.Env...)
+		d.config.ClearEnv = true
+	}
 }
 
 // Attach will attach to the process specified by 'pid'.
@@ -153,6 +325,12 @@ func (d *Debugger) Attach(pid int) (*proc.Target, error) {
 	return native.Attach(pid)
 }
 
+// AttachPid returns the PID of the process the debugger attached to, zero
+// if it was not attached to an existing process.
+func (d *Debugger) AttachPid() int {
+	return d.config.AttachPid
+}
+
 // ProcessPid returns the PID of the process
 // the debugger is debugging.
 func (d *Debugger) ProcessPid() int {
@@ -175,7 +353,10 @@ func (d *Debugger) LastModified() time.Time {
 func (d *Debugger) FunctionReturnLocations(fnName string) ([]uint64, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
+	return d.functionReturnLocations(fnName)
+}
 
+func (d *Debugger) functionReturnLocations(fnName string) ([]uint64, error) {
 	var (
 		p = d.target
 		g = p.SelectedGoroutine()
@@ -215,35 +396,113 @@ func (d *Debugger) FunctionReturnLocations(fnName string) ([]uint64, error) {
 // detaching.
 func (d *Debugger) Detach(kill bool) error {
 	log.Debug("detaching")
+	if d.watcher != nil {
+		d.watcher.close()
+	}
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
+	d.selectValidTarget()
 	if ok, _ := d.target.Valid(); !ok {
 		return nil
 	}
 	return d.detach(kill)
 }
 
+// selectValidTarget switches to another target of the group if the
+// selected target has exited.
+func (d *Debugger) selectValidTarget() {
+	if d.group == nil {
+		return
+	}
+	if ok, _ := d.target.Valid(); ok {
+		return
+	}
+	for _, t := range d.group.Targets() {
+		if ok, _ := t.Valid(); ok {
+			d.group.Selected = t
+			d.target = t
+			return
+		}
+	}
+}
+
+// ListTargets returns the targets being debugged: the process that was
+// launched or attached to and the child processes being followed.
+func (d *Debugger) ListTargets() []api.Target {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	targets := []*proc.Target{d.target}
+	if d.group != nil {
+		targets = d.group.Targets()
+	}
+	r := make([]api.Target, 0, len(targets))
+	for _, t := range targets {
+		ok, _ := t.Valid()
+		r = append(r, api.Target{
+			Pid:      t.Pid(),
+			Path:     executablePath(t),
+			Exited:   !ok,
+			Selected: t == d.target,
+		})
+	}
+	return r
+}
+
+// SwitchTarget makes the process with the specified pid the target of
+// subsequent commands.
+func (d *Debugger) SwitchTarget(pid int) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	if d.group == nil {
+		if d.target.Pid() == pid {
+			return nil
+		}
+		return fmt.Errorf("target %d does not exist", pid)
+	}
+	if err := d.group.SwitchTarget(pid); err != nil {
+		return err
+	}
+	d.target = d.group.Selected
+	return nil
+}
+
 func (d *Debugger) detach(kill bool) error {
 	if d.config.AttachPid == 0 {
 		// tracee process is started by debugger, not an existing process,
 		// so we should kill it when detaching.
 		kill = true
 	}
-	return d.target.Detach(kill)
+	if d.group == nil {
+		return d.target.Detach(kill)
+	}
+	// followed processes are detached first, the process that was launched
+	// or attached to is always the first target of the group.
+	targets := d.group.Targets()
+	for i := len(targets) - 1; i > 0; i-- {
+		if ok, _ := targets[i].Valid(); !ok {
+			continue
+		}
+		if err := targets[i].Detach(kill); err != nil {
+			return err
+		}
+	}
+	return targets[0].Detach(kill)
 }
 
 // Restart will restart the target process, first killing and then exec'ing it again.
 //
-// If the target process is a recording it will restart it from the given
-// position. If pos starts with 'c' it's a checkpoint ID, otherwise it's an
-// event number. If resetArgs is true, newArgs will replace the process args.
-func (d *Debugger) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
+// The changes in opts are applied to the configuration of the target
+// before it is restarted, and persist for the following restarts.
+func (d *Debugger) Restart(rebuild bool, opts RestartOptions) ([]api.DiscardedBreakpoint, error) {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
 	if !d.canRestart() {
 		return nil, ErrCanNotRestart
 	}
+	if err := d.applyRestartOptions(opts); err != nil {
+		return nil, err
+	}
 
 	if valid, _ := d.target.Valid(); valid {
 		// Ensure the process is in a PTRACE_STOP.
@@ -262,12 +521,12 @@ func (d *Debugger) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
 	if rebuild {
 		switch d.config.ExecuteKind {
 		case ExecutingGeneratedFile:
-			err = gobuild.GoBuild(d.processArgs[0], d.config.Packages)
+			err = gobuild.GoBuild(d.processArgs[0], d.config.Packages, d.config.BuildFlags)
 			if err != nil {
 				return nil, fmt.Errorf("could not rebuild process: %s", err)
 			}
 		case ExecutingGeneratedTest:
-			err = gobuild.GoTestBuild(d.processArgs[0], d.config.Packages)
+			err = gobuild.GoTestBuild(d.processArgs[0], d.config.Packages, d.config.BuildFlags)
 			if err != nil {
 				return nil, fmt.Errorf("could not rebuild process: %s", err)
 			}
@@ -282,17 +541,60 @@ func (d *Debugger) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
 		return nil, fmt.Errorf("could not launch process: %s", err)
 	}
 
-	discarded := []api.DiscardedBreakpoint{}
 	breakpoints := api.ConvertBreakpoints(d.breakpoints())
+	panicCatch := d.target.PanicCatchpoint()
 	d.target = p
+	d.group = p.Group()
+	if err := d.setupGroup(); err != nil {
+		return nil, err
+	}
+	// The callees of followed functions are instrumented again as the new
+	// process enters them, they could have changed if it was rebuilt.
+	d.followedCalls = nil
+	discarded, err := d.recreateBreakpoints(p, breakpoints, rebuild)
+	if err != nil {
+		return nil, err
+	}
+	// The panic catchpoint has a reserved ID and is skipped by
+	// recreateBreakpoints.
+	if panicCatch != nil {
+		if err := p.SetPanicCatchpoint(panicCatch); err != nil {
+			return nil, err
+		}
+	}
 	maxID := 0
+	for _, bp := range breakpoints {
+		if bp.ID > maxID {
+			maxID = bp.ID
+		}
+	}
+	for _, bp := range d.disabledBreakpoints {
+		if bp.ID > maxID {
+			maxID = bp.ID
+		}
+	}
+	d.target.SetNextBreakpointID(maxID)
+	return discarded, nil
+}
+
+// executablePath returns the path of the executable of t.
+func executablePath(t *proc.Target) string {
+	if images := t.BinInfo().Images; len(images) > 0 {
+		return images[0].Path
+	}
+	return ""
+}
+
+// recreateBreakpoints sets breakpoints on p, a new process. File:line
+// breakpoints are looked up again, address breakpoints are set at the same
+// address unless noAddrs is set. The breakpoints that could not be set are
+// returned.
+func (d *Debugger) recreateBreakpoints(p *proc.Target, breakpoints []*api.Breakpoint, noAddrs bool) ([]api.DiscardedBreakpoint, error) {
+	discarded := []api.DiscardedBreakpoint{}
 	for _, oldBp := range breakpoints {
 		if oldBp.ID < 0 {
 			continue
 		}
-		if oldBp.ID > maxID {
-			maxID = oldBp.ID
-		}
 		if oldBp.WatchExpr != "" {
 			discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: "can not recreate watchpoints on restart"})
 		} else if len(oldBp.File) > 0 {
@@ -301,10 +603,10 @@ func (d *Debugger) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
 				discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: err.Error()})
 				continue
 			}
-			createLogicalBreakpoint(d, addrs, oldBp, oldBp.ID)
+			createLogicalBreakpoint(d, p, addrs, oldBp, oldBp.ID)
 		} else {
 			// Avoid setting a breakpoint based on address when rebuilding
-			if rebuild {
+			if noAddrs {
 				discarded = append(discarded, api.DiscardedBreakpoint{Breakpoint: oldBp, Reason: "can not recreate address breakpoints on restart"})
 				continue
 			}
@@ -317,12 +619,6 @@ func (d *Debugger) Restart(rebuild bool) ([]api.DiscardedBreakpoint, error) {
 			}
 		}
 	}
-	for _, bp := range d.disabledBreakpoints {
-		if bp.ID > maxID {
-			maxID = bp.ID
-		}
-	}
-	d.target.SetNextBreakpointID(maxID)
 	return discarded, nil
 }
 
@@ -382,6 +678,38 @@ func (d *Debugger) state(retLoadCfg *proc.LoadConfig) (*api.DebuggerState, error
 	}
 
 	state.NextInProgress = d.target.Breakpoints().HasSteppingBreakpoints()
+	state.NonStop = d.target.NonStop()
+	state.Watching = d.watcher != nil
+
+	if d.target.StopReason == proc.StopSyscall {
+		if sc := d.target.CurrentThread().Common().Syscall; sc != nil {
+			state.Syscall = convertSyscall(d.target.Memory(), sc)
+		}
+	}
+
+	if d.target.StopReason == proc.StopSignal {
+		if sig := d.target.CurrentThread().Common().Signal; sig != nil {
+			state.Signal = &api.Signal{Number: sig.Signo, Name: signalName(sig.Signo), Code: sig.Code, Addr: sig.Addr}
+		}
+	}
+
+	if d.target.StopReason == proc.StopExiting {
+		if exit := d.target.CurrentThread().Common().Exit; exit != nil {
+			state.Exiting = true
+			state.Pid = d.target.Pid()
+			state.ExitStatus = exit.Status
+		}
+	}
+
+	if state.CurrentThread != nil && state.CurrentThread.Breakpoint != nil {
+		if p, err := d.target.ThreadPanic(d.target.CurrentThread()); err == nil && p != nil {
+			state.Panic = &api.Panic{Value: *api.ConvertVar(p.Value), Type: p.Type, Location: api.ConvertLocation(p.Frame.Call)}
+		}
+	}
+
+	if recorded, _ := d.target.Recorded(); recorded {
+		state.When, _ = d.target.When()
+	}
 
 	state.WatchOutOfScope = make([]*api.Breakpoint, 0, len(d.target.Breakpoints().WatchOutOfScope))
 	for _, bp := range d.target.Breakpoints().WatchOutOfScope {
@@ -432,6 +760,11 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 			return nil, errors.New("breakpoint name already exists")
 		}
 	}
+	if requestedBp.TraceFollowCalls > 0 {
+		if _, err := regexp.Compile(requestedBp.RootFuncName); err != nil {
+			return nil, fmt.Errorf("invalid root function: %v", err)
+		}
+	}
 
 	switch {
 	case requestedBp.TraceReturn:
@@ -451,7 +784,7 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 		return nil, err
 	}
 
-	createdBp, err := createLogicalBreakpoint(d, addrs, requestedBp, 0)
+	createdBp, err := createLogicalBreakpoint(d, d.target, addrs, requestedBp, 0)
 	if err != nil {
 		return nil, err
 	}
@@ -461,9 +794,7 @@ func (d *Debugger) CreateBreakpoint(requestedBp *api.Breakpoint) (*api.Breakpoin
 
 // createLogicalBreakpoint creates one physical breakpoint for each address
 // in addrs and associates all of them with the same logical breakpoint.
-func createLogicalBreakpoint(d *Debugger, addrs []uint64, requestedBp *api.Breakpoint, id int) (*api.Breakpoint, error) {
-	p := d.target
-
+func createLogicalBreakpoint(d *Debugger, p *proc.Target, addrs []uint64, requestedBp *api.Breakpoint, id int) (*api.Breakpoint, error) {
 	if dbp, ok := d.disabledBreakpoints[requestedBp.ID]; ok {
 		return dbp, proc.BreakpointExistsError{File: dbp.File, Line: dbp.Line, Addr: dbp.Addr}
 	}
@@ -512,11 +843,22 @@ func isBreakpointExistsErr(err error) bool {
 	return r
 }
 
-func (d *Debugger) CreateEBPFTracepoint(fnName string) error {
+// CreateEBPFTracepoint sets an eBPF based tracepoint on fnName, see
+// proc.Target.SetEBPFTracepoint for the meaning of cfg, captures, cond
+// and latency.
+func (d *Debugger) CreateEBPFTracepoint(fnName string, cfg *proc.LoadConfig, captures []string, cond string, latency bool) error {
 	d.targetMutex.Lock()
 	defer d.targetMutex.Unlock()
 
-	return d.target.SetEBPFTracepoint(fnName)
+	var condExpr ast.Expr
+	if cond != "" {
+		var err error
+		condExpr, err = parser.ParseExpr(cond)
+		if err != nil {
+			return err
+		}
+	}
+	return d.target.SetEBPFTracepoint(fnName, cfg, captures, condExpr, latency)
 }
 
 // AmendBreakpoint will update the breakpoint with the matching ID.
@@ -570,6 +912,8 @@ func copyBreakpointInfo(bp *proc.Breakpoint, requested *api.Breakpoint) (err err
 	bp.Name = requested.Name
 	bp.Tracepoint = requested.Tracepoint
 	bp.TraceReturn = requested.TraceReturn
+	bp.RootFuncName = requested.RootFuncName
+	bp.TraceFollowCalls = requested.TraceFollowCalls
 	bp.Goroutine = requested.Goroutine
 	bp.Stacktrace = requested.Stacktrace
 	bp.Variables = requested.Variables
@@ -813,10 +1157,15 @@ func (d *Debugger) CreateWatchpoint(goid, frame, deferredCall int, expr string,
 	if err != nil {
 		return nil, err
 	}
+	// watchpoints on large values are made of multiple physical breakpoints
+	bps := d.findBreakpoint(bp.LogicalID())
 	if d.findBreakpointByName(expr) == nil {
-		bp.Name = expr
+		for _, bp := range bps {
+			bp.Name = expr
+		}
 	}
-	return api.ConvertBreakpoint(bp), nil
+	sort.Sort(breakpointsByLogicalID(bps))
+	return api.ConvertBreakpoints(bps)[0], nil
 }
 
 // Threads returns the threads of the target process.
@@ -868,11 +1217,33 @@ func (d *Debugger) IsRunning() bool {
 	return d.running
 }
 
+// setStepSkip sets the functions that step does not stop in, rules are
+// pairs of kind and pattern, see api.DebuggerCommand.StepSkip.
+func (d *Debugger) setStepSkip(rules [][2]string) error {
+	stepSkip := make([]proc.StepSkipRule, 0, len(rules))
+	for _, rule := range rules {
+		kind, err := proc.ParseStepSkipKind(rule[0])
+		if err != nil {
+			return err
+		}
+		if _, err := path.Match(rule[1], ""); err != nil {
+			return fmt.Errorf("invalid step skip pattern %q: %v", rule[1], err)
+		}
+		stepSkip = append(stepSkip, proc.StepSkipRule{Kind: kind, Pattern: rule[1]})
+	}
+	d.target.StepSkip = stepSkip
+	return nil
+}
+
 // Command handles commands which control the debugger lifecycle
 func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struct{}) (*api.DebuggerState, error) {
 	var err error
 
-	if command.Name == api.Halt {
+	// In non-stop mode, when no command is executing, the threads that are
+	// still running are stopped directly.
+	haltRunning := command.Name == api.Halt && d.target.NonStop() && !d.IsRunning()
+
+	if command.Name == api.Halt && !haltRunning {
 		// RequestManualStop does not invoke any ptrace syscalls, so it's safe to
 		// access the process directly.
 		log.Debug("halting")
@@ -893,6 +1264,15 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 	d.setRunning(true)
 	defer d.setRunning(false)
 
+	d.selectValidTarget()
+	defer func() {
+		if d.group != nil {
+			// the stop could happen in a different target
+			d.target = d.group.Selected
+		}
+		d.startPollNonStop()
+	}()
+
 	if command.Name != api.SwitchGoroutine && command.Name != api.SwitchThread && command.Name != api.Halt {
 		d.target.ResumeNotify(resumeNotify)
 	} else if resumeNotify != nil {
@@ -901,11 +1281,22 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 
 	switch command.Name {
 	case api.Continue:
-		log.Debug("continuing")
-		err = d.target.Continue()
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		if command.ThreadID != 0 {
+			log.Debug("continuing thread %d", command.ThreadID)
+			err = d.target.ContinueThread(command.ThreadID)
+		} else {
+			log.Debug("continuing")
+			err = d.target.Continue()
+		}
 	case api.DirectionCongruentContinue:
 		log.Debug("continuing (direction congruent)")
 		err = d.target.Continue()
+	case api.Rewind:
+		log.Debug("rewinding")
+		err = d.target.Rewind()
 	case api.Call:
 		log.Debug("function call %s", command.Expr)
 		if command.ReturnInfoLoadConfig == nil {
@@ -920,16 +1311,66 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		}
 		retLoadCfg := *api.LoadConfigToProc(command.ReturnInfoLoadConfig)
 		err = proc.EvalExpressionWithCalls(d.target, g, command.Expr, retLoadCfg, !command.UnsafeCall)
+		if err == nil && g != nil && g.Thread == nil && d.target.StopReason == proc.StopCallReturned {
+			// the call was injected on a different goroutine, select the parked
+			// goroutine it was made for again.
+			if pg, _ := proc.FindGoroutine(d.target, g.ID); pg != nil {
+				err = d.target.SwitchGoroutine(pg)
+			}
+		}
 	case api.Next:
 		log.Debug("nexting")
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		err = d.target.Next()
+	case api.ReverseNext:
+		log.Debug("reverse nexting")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
 		err = d.target.Next()
 	case api.Step:
 		log.Debug("stepping")
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		if err := d.setStepSkip(command.StepSkip); err != nil {
+			return nil, err
+		}
+		err = d.target.Step()
+	case api.ReverseStep:
+		log.Debug("reverse stepping")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
+		if err := d.setStepSkip(command.StepSkip); err != nil {
+			return nil, err
+		}
 		err = d.target.Step()
 	case api.StepInstruction:
+		log.Debug("single stepping")
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepInstruction()
+	case api.ReverseStepInstruction:
+		log.Debug("reverse single stepping")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
 		err = d.target.StepInstruction()
 	case api.StepOut:
 		log.Debug("step out")
+		if err := d.target.ChangeDirection(proc.Forward); err != nil {
+			return nil, err
+		}
+		err = d.target.StepOut()
+	case api.ReverseStepOut:
+		log.Debug("reverse step out")
+		if err := d.target.ChangeDirection(proc.Backward); err != nil {
+			return nil, err
+		}
 		err = d.target.StepOut()
 	case api.SwitchThread:
 		log.Debug("switching to thread %d", command.ThreadID)
@@ -945,6 +1386,10 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 		withBreakpointInfo = false
 	case api.Halt:
 		// RequestManualStop already called
+		if haltRunning {
+			log.Debug("halting running threads")
+			err = d.target.StopRunning()
+		}
 		withBreakpointInfo = false
 	}
 
@@ -966,6 +1411,9 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 	if withBreakpointInfo {
 		err = d.collectBreakpointInformation(state)
 	}
+	if err == nil {
+		err = d.followCalls(state)
+	}
 	for _, th := range state.Threads {
 		if th.Breakpoint != nil && th.Breakpoint.TraceReturn {
 			for _, v := range th.BreakpointInfo.Arguments {
@@ -978,6 +1426,89 @@ func (d *Debugger) Command(command *api.DebuggerCommand, resumeNotify chan struc
 	return state, err
 }
 
+// maxPendingEvents is the number of stops, seen while no command is
+// executing in non-stop mode, kept until a client asks for them.
+const maxPendingEvents = 64
+
+// nonStopPollInterval is how often the threads left running in non-stop
+// mode are checked.
+const nonStopPollInterval = 50 * time.Millisecond
+
+// startPollNonStop starts pollNonStop if some threads were left running.
+// Must be called with targetMutex held.
+func (d *Debugger) startPollNonStop() {
+	if d.polling {
+		return
+	}
+	if ok, _ := d.target.Valid(); !ok || !d.target.HasRunningThreads() {
+		return
+	}
+	d.polling = true
+	go d.pollNonStop()
+}
+
+// pollNonStop waits for the threads left running in non-stop mode to stop,
+// every stop is sent to the events channel, see WaitEvent.
+func (d *Debugger) pollNonStop() {
+	ticker := time.NewTicker(nonStopPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		d.targetMutex.Lock()
+		if ok, _ := d.target.Valid(); !ok || !d.target.HasRunningThreads() {
+			d.polling = false
+			d.targetMutex.Unlock()
+			return
+		}
+		var state *api.DebuggerState
+		stopped, err := d.target.PollStop()
+		if d.group != nil {
+			d.target = d.group.Selected
+		}
+		switch {
+		case err != nil:
+			if pe, ok := err.(proc.ErrProcessExited); ok {
+				state = &api.DebuggerState{Pid: pe.Pid, Exited: true, ExitStatus: pe.Status}
+			} else {
+				log.Warn("non-stop: %v", err)
+			}
+		case stopped:
+			state, err = d.state(nil)
+			if err == nil {
+				err = d.collectBreakpointInformation(state)
+			}
+			if err != nil {
+				log.Warn("non-stop: %v", err)
+			}
+		}
+		d.targetMutex.Unlock()
+		if state != nil {
+			d.sendEvent(state)
+		}
+	}
+}
+
+// sendEvent queues state to be returned by WaitEvent, if too many events
+// are pending the oldest one is dropped.
+func (d *Debugger) sendEvent(state *api.DebuggerState) {
+	for {
+		select {
+		case d.events <- state:
+			return
+		default:
+		}
+		select {
+		case <-d.events:
+		default:
+		}
+	}
+}
+
+// WaitEvent waits for a thread to stop, in non-stop mode, while no command
+// was executing and returns the state of the debugger at that point.
+func (d *Debugger) WaitEvent() *api.DebuggerState {
+	return <-d.events
+}
+
 func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error {
 	if state == nil {
 		return nil
@@ -1016,6 +1547,12 @@ func (d *Debugger) collectBreakpointInformation(state *api.DebuggerState) error
 			return fmt.Errorf("could not find thread %d", state.Threads[i].ID)
 		}
 
+		if bp.TraceFollowCalls > 0 {
+			if pbp := thread.Breakpoint().Breakpoint; pbp != nil {
+				bpi.TraceDepth, _ = proc.FollowCallsDepth(thread, pbp)
+			}
+		}
+
 		if len(bp.Variables) == 0 && bp.LoadArgs == nil && bp.LoadLocals == nil {
 			// don't try to create goroutine scope if there is nothing to load
 			continue
@@ -1646,6 +2183,248 @@ func (d *Debugger) FindThreadReturnValues(id int, cfg proc.LoadConfig) ([]*proc.
 	return thread.Common().ReturnValues(cfg), nil
 }
 
+// Checkpoint will set a checkpoint specified by the locspec.
+func (d *Debugger) Checkpoint(where string) (int, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.Checkpoint(where)
+}
+
+// Checkpoints will return a list of checkpoints.
+func (d *Debugger) Checkpoints() ([]proc.Checkpoint, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.Checkpoints()
+}
+
+// ClearCheckpoint will clear the checkpoint of the given ID.
+func (d *Debugger) ClearCheckpoint(id int) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.ClearCheckpoint(id)
+}
+
+// Record starts or stops recording the execution of the current thread.
+func (d *Debugger) Record(on bool) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	if on {
+		return d.target.StartRecording()
+	}
+	return d.target.StopRecording()
+}
+
+// Recorded returns true if the target has something recorded that can be
+// executed backwards.
+func (d *Debugger) Recorded() (recorded bool, tracedir string) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	return d.target.Recorded()
+}
+
+// SignalPolicies returns how each signal received by the target is handled.
+func (d *Debugger) SignalPolicies() []api.SignalPolicy {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	var r []api.SignalPolicy
+	for sig := 1; sig <= sigrtmax; sig++ {
+		name := signalName(sig)
+		if name == "" {
+			continue
+		}
+		policy := d.target.SignalPolicy(sig)
+		r = append(r, api.SignalPolicy{Signal: sig, Name: name, Stop: policy.Stop, Print: policy.Print, Pass: policy.Pass})
+	}
+	return r
+}
+
+// SetSignalPolicy changes how a signal received by the target is handled,
+// if the signal number of policy is zero the signal is looked up by name.
+// Returns the new policy.
+func (d *Debugger) SetSignalPolicy(policy api.SignalPolicy) (api.SignalPolicy, error) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	sig := policy.Signal
+	if sig == 0 {
+		var err error
+		sig, err = parseSignal(policy.Name)
+		if err != nil {
+			return api.SignalPolicy{}, err
+		}
+	}
+	if err := d.target.SetSignalPolicy(sig, proc.SignalPolicy{Stop: policy.Stop, Print: policy.Print, Pass: policy.Pass}); err != nil {
+		return api.SignalPolicy{}, err
+	}
+	p := d.target.SignalPolicy(sig)
+	if d.signalPolicies == nil {
+		d.signalPolicies = make(map[int]proc.SignalPolicy)
+	}
+	d.signalPolicies[sig] = p
+	return api.SignalPolicy{Signal: sig, Name: signalName(sig), Stop: p.Stop, Print: p.Print, Pass: p.Pass}, nil
+}
+
+// Real-time signals, numbered like kill -l does: the first two are used by
+// the C library and have no name.
+const (
+	sigrtmin = 34
+	sigrtmax = 64
+)
+
+// signalName returns the name of signal sig, real-time signals are named
+// relative to SIGRTMIN or SIGRTMAX.
+func signalName(sig int) string {
+	switch {
+	case sig == sigrtmin:
+		return "SIGRTMIN"
+	case sig == sigrtmax:
+		return "SIGRTMAX"
+	case sig > sigrtmin && sig <= (sigrtmin+sigrtmax)/2:
+		return fmt.Sprintf("SIGRTMIN+%d", sig-sigrtmin)
+	case sig > sigrtmin && sig < sigrtmax:
+		return fmt.Sprintf("SIGRTMAX-%d", sigrtmax-sig)
+	case sig >= 32 && sig < sigrtmin:
+		return fmt.Sprintf("SIG%d", sig)
+	}
+	return sys.SignalName(syscall.Signal(sig))
+}
+
+// parseSignal returns the number of the signal called name, with or
+// without the SIG prefix, or the number in name.
+func parseSignal(name string) (int, error) {
+	if n, err := strconv.Atoi(name); err == nil {
+		return n, nil
+	}
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+	if sig := sys.SignalNum(name); sig != 0 {
+		return int(sig), nil
+	}
+	for _, prefix := range []string{"SIGRTMIN+", "SIGRTMAX-"} {
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if n, err := strconv.Atoi(name[len(prefix):]); err == nil {
+			sig := sigrtmin + n
+			if prefix == "SIGRTMAX-" {
+				sig = sigrtmax - n
+			}
+			if sig >= sigrtmin && sig <= sigrtmax {
+				return sig, nil
+			}
+		}
+	}
+	for sig := 32; sig <= sigrtmax; sig++ {
+		if signalName(sig) == name {
+			return sig, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signal %q", name)
+}
+
+// PanicCatchpoint returns the panics that stop the target, nil if only
+// unrecovered panics do.
+func (d *Debugger) PanicCatchpoint() *api.PanicCatchpoint {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	pc := d.target.PanicCatchpoint()
+	if pc == nil {
+		return nil
+	}
+	return &api.PanicCatchpoint{Recovered: pc.Recovered, Type: pc.Type, Cond: pc.Cond}
+}
+
+// SetPanicCatchpoint changes the panics that stop the target, nil restores
+// the default of stopping on every unrecovered panic.
+func (d *Debugger) SetPanicCatchpoint(pc *api.PanicCatchpoint) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	if pc == nil {
+		return d.target.SetPanicCatchpoint(nil)
+	}
+	return d.target.SetPanicCatchpoint(&proc.PanicCatchpoint{Recovered: pc.Recovered, Type: pc.Type, Cond: pc.Cond})
+}
+
+// SyscallCatchpoints returns the names of the system calls that stop the
+// target, all is true if every system call does.
+func (d *Debugger) SyscallCatchpoints() (all bool, names []string) {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	all, nrs := d.target.SyscallCatchpoints()
+	for _, nr := range nrs {
+		names = append(names, linutil.SyscallName(nr))
+	}
+	return all, names
+}
+
+// SetSyscallCatchpoints makes the target stop when entering and returning
+// from the system calls in syscalls, specified by name or number, or from
+// every system call if all is true.
+func (d *Debugger) SetSyscallCatchpoints(all bool, syscalls []string) error {
+	d.targetMutex.Lock()
+	defer d.targetMutex.Unlock()
+	nrs := make([]int, 0, len(syscalls))
+	for _, s := range syscalls {
+		nr, err := strconv.Atoi(s)
+		if err != nil {
+			var ok bool
+			nr, ok = linutil.SyscallNumber(s)
+			if !ok {
+				return fmt.Errorf("unknown system call %q", s)
+			}
+		}
+		nrs = append(nrs, nr)
+	}
+	if err := d.target.SetSyscallCatchpoints(all, nrs); err != nil {
+		return err
+	}
+	d.catchAllSyscalls, d.catchSyscalls = all, nrs
+	return nil
+}
+
+// convertSyscall converts sc to its API representation, reading the
+// arguments that are strings from mem.
+func convertSyscall(mem proc.MemoryReadWriter, sc *proc.SyscallInfo) *api.Syscall {
+	r := &api.Syscall{Nr: sc.Nr, Name: linutil.SyscallName(sc.Nr), Exit: sc.Exit, Ret: sc.Ret}
+	for _, arg := range sc.Args {
+		r.Args = append(r.Args, fmt.Sprintf("%#x", arg))
+	}
+	for _, i := range linutil.SyscallStringArgs(sc.Nr) {
+		if s, err := readCString(mem, sc.Args[i]); err == nil {
+			r.Args[i] = strconv.Quote(s)
+		}
+	}
+	if sc.Exit && sc.Ret < 0 && sc.Ret > -4096 {
+		r.Errno = sys.ErrnoName(syscall.Errno(-sc.Ret))
+	}
+	return r
+}
+
+// readCString reads a NUL terminated string at addr, at most 4096 bytes
+// long.
+func readCString(mem proc.MemoryReadWriter, addr uint64) (string, error) {
+	const maxlen = 4096
+	if addr == 0 {
+		return "", errors.New("nil pointer")
+	}
+	var r []byte
+	buf := make([]byte, 64)
+	for len(r) < maxlen {
+		// do not read across a page boundary, the next page might not be mapped
+		n := 64 - int(addr%64)
+		if _, err := mem.ReadMemory(buf[:n], addr); err != nil {
+			return "", err
+		}
+		if i := bytes.IndexByte(buf[:n], 0); i >= 0 {
+			return string(append(r, buf[:i]...)), nil
+		}
+		r = append(r, buf[:n]...)
+		addr += uint64(n)
+	}
+	return string(r), nil
+}
+
 // ListDynamicLibraries returns a list of loaded dynamic libraries.
 func (d *Debugger) ListDynamicLibraries() []*proc.Image {
 	d.targetMutex.Lock()
@@ -1801,8 +2580,12 @@ func (d *Debugger) GetBufferedTracepoints() []api.TracepointResult {
 	results := make([]api.TracepointResult, len(traces))
 	for i, trace := range traces {
 		f, l, fn := d.target.BinInfo().PCToLine(uint64(trace.FnAddr))
+		if trace.InlinedFn != nil {
+			fn = trace.InlinedFn
+		}
 
 		results[i].FunctionName = fn.Name
+		results[i].Inlined = trace.Inlined
 		results[i].Line = l
 		results[i].File = f
 		results[i].GoroutineID = trace.GoroutineID
@@ -1813,7 +2596,29 @@ func (d *Debugger) GetBufferedTracepoints() []api.TracepointResult {
 		for _, p := range trace.ReturnParams {
 			results[i].ReturnParams = append(results[i].ReturnParams, *api.ConvertVar(p))
 		}
+		for _, p := range trace.Captures {
+			results[i].Captures = append(results[i].Captures, *api.ConvertVar(p))
+		}
+	}
+	return results
+}
+
+// GetLatencyHistograms returns the latency histograms of the functions
+// traced with an eBPF latency tracepoint, sorted by function name.
+func (d *Debugger) GetLatencyHistograms() []api.LatencyHistogram {
+	hists := d.target.GetLatencyHistograms()
+	if len(hists) == 0 {
+		return nil
+	}
+	results := make([]api.LatencyHistogram, 0, len(hists))
+	for entry, counts := range hists {
+		fn := d.target.BinInfo().PCToFunc(entry)
+		if fn == nil {
+			continue
+		}
+		results = append(results, api.LatencyHistogram{FunctionName: fn.Name, Counts: counts})
 	}
+	sort.Slice(results, func(i, j int) bool { return results[i].FunctionName < results[j].FunctionName })
 	return results
 }
 
@@ -1,9 +1,13 @@
 package debugger
 
 import (
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/hitzhangjie/dlv/pkg/gobuild"
 	proctest "github.com/hitzhangjie/dlv/pkg/proc/test"
@@ -16,7 +20,7 @@ func TestDebugger_LaunchNoMain(t *testing.T) {
 
 	exepath := filepath.Join(nomaindir, "debug")
 	defer os.Remove(exepath)
-	if err := gobuild.GoBuild(exepath, []string{nomaindir}); err != nil {
+	if err := gobuild.GoBuild(exepath, []string{nomaindir}, ""); err != nil {
 		t.Fatalf("go build error %v", err)
 	}
 
@@ -29,3 +33,206 @@ func TestDebugger_LaunchNoMain(t *testing.T) {
 		t.Fatalf("expected error \"%v\" got \"%v\"", api.ErrNotExecutable, err)
 	}
 }
+
+func TestDebugger_Watch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dlvwatch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	src := "package main\n\nimport \"time\"\n\nfunc main() {\n\ttime.Sleep(time.Hour)\n}\n"
+	if err := ioutil.WriteFile("go.mod", []byte("module example.com/watch\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile("main.go", []byte(src), 0600); err != nil {
+		t.Fatal(err)
+	}
+	exepath := filepath.Join(dir, "__debug_bin")
+	if err := gobuild.GoBuild(exepath, nil, ""); err != nil {
+		t.Fatalf("go build error %v", err)
+	}
+
+	d, err := New(&Config{WorkingDir: dir, ExecuteKind: ExecutingGeneratedFile, Watch: true}, []string{exepath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Detach(true)
+	pid := d.ProcessPid()
+
+	if err := ioutil.WriteFile("main.go", []byte(src+"\nvar x = 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan *api.DebuggerState, 1)
+	go func() { events <- d.WaitEvent() }()
+	select {
+	case state := <-events:
+		if state.Rebuild == nil || state.Rebuild.Err != "" {
+			t.Fatalf("rebuild failed: %#v", state.Rebuild)
+		}
+		if len(state.Rebuild.Files) != 1 || filepath.Base(state.Rebuild.Files[0]) != "main.go" {
+			t.Fatalf("wrong changed files %v", state.Rebuild.Files)
+		}
+		if state.Pid == pid || state.Pid != d.ProcessPid() {
+			t.Fatalf("process not restarted, pid %d (was %d)", state.Pid, pid)
+		}
+	case <-time.After(time.Minute):
+		t.Fatal("timed out waiting for the rebuild")
+	}
+}
+
+func TestDebugger_RestartOptions(t *testing.T) {
+	os.Setenv("DLV_TEST_INHERITED", "1")
+	defer os.Unsetenv("DLV_TEST_INHERITED")
+	d := &Debugger{config: &Config{Env: []string{"A=1"}}, processArgs: []string{"exe", "old"}}
+
+	err := d.applyRestartOptions(RestartOptions{ResetArgs: true, NewArgs: []string{"new"}, NewRedirects: [3]string{"", "out.txt", ""}, Env: []string{"A=2", "B=3"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(d.processArgs, []string{"exe", "new"}) || d.config.Redirects[1] != "out.txt" {
+		t.Fatalf("wrong args %v or redirects %v", d.processArgs, d.config.Redirects)
+	}
+	if !reflect.DeepEqual(d.config.Env, []string{"A=2", "B=3"}) || d.config.ClearEnv {
+		t.Fatalf("wrong environment %v (clear %v)", d.config.Env, d.config.ClearEnv)
+	}
+
+	// removing an inherited variable passes the whole environment
+	if err := d.applyRestartOptions(RestartOptions{Env: []string{"DLV_TEST_INHERITED", "A"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !d.config.ClearEnv || len(d.config.Env) == 0 {
+		t.Fatalf("environment not cleared")
+	}
+	for _, kv := range d.config.Env {
+		if strings.HasPrefix(kv, "DLV_TEST_INHERITED=") || strings.HasPrefix(kv, "A=") {
+			t.Fatalf("variable %q not removed", kv)
+		}
+	}
+	if d.config.Env[len(d.config.Env)-1] != "B=3" {
+		t.Fatalf("variable B lost: %v", d.config.Env)
+	}
+	if !reflect.DeepEqual(d.processArgs, []string{"exe", "new"}) {
+		t.Fatalf("args changed: %v", d.processArgs)
+	}
+
+	if err := d.applyRestartOptions(RestartOptions{WorkingDir: "/nonexistent/dir"}); err == nil {
+		t.Fatal("expected error for a missing working directory")
+	}
+}
+
+func TestDebugger_FollowCalls(t *testing.T) {
+	fixture := filepath.Join(proctest.FindFixturesDir(), "increment.go")
+	exepath := filepath.Join(t.TempDir(), "increment")
+	if err := gobuild.GoBuild(exepath, []string{fixture}, ""); err != nil {
+		t.Fatalf("go build error %v", err)
+	}
+
+	d, err := New(&Config{WorkingDir: ".", ExecuteKind: ExecutingExistingFile}, []string{exepath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Detach(true)
+
+	hasTracepoint := func(fnName string) bool {
+		for _, bp := range d.Breakpoints(false) {
+			if bp.FunctionName == fnName && bp.Tracepoint {
+				return true
+			}
+		}
+		return false
+	}
+
+	_, err = d.CreateBreakpoint(&api.Breakpoint{FunctionName: "main.main", Tracepoint: true, RootFuncName: "main.main", TraceFollowCalls: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hasTracepoint("main.Increment") {
+		t.Fatal("callee instrumented before main.main was entered")
+	}
+	state, err := d.Command(&api.DebuggerCommand{Name: api.Continue}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.CurrentThread == nil || state.CurrentThread.Function == nil || state.CurrentThread.Function.Name() != "main.main" {
+		t.Fatalf("not stopped in main.main: %#v", state.CurrentThread)
+	}
+	if !hasTracepoint("main.Increment") {
+		t.Fatal("callee main.Increment not instrumented")
+	}
+	if !d.followedCalls["main.main"] {
+		t.Fatal("main.main not recorded as followed")
+	}
+
+	if _, err := d.Restart(false, RestartOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.followedCalls) != 0 {
+		t.Fatalf("followed calls not reset on restart: %v", d.followedCalls)
+	}
+}
+
+func TestDebugger_SignalNames(t *testing.T) {
+	for sig, name := range map[int]string{10: "SIGUSR1", 32: "SIG32", 34: "SIGRTMIN", 35: "SIGRTMIN+1", 49: "SIGRTMIN+15", 50: "SIGRTMAX-14", 63: "SIGRTMAX-1", 64: "SIGRTMAX"} {
+		if out := signalName(sig); out != name {
+			t.Errorf("signalName(%d) = %q, expected %q", sig, out, name)
+		}
+		if out, err := parseSignal(name); err != nil || out != sig {
+			t.Errorf("parseSignal(%q) = %d, %v, expected %d", name, out, err, sig)
+		}
+	}
+	if sig, err := parseSignal("rtmin+3"); err != nil || sig != 37 {
+		t.Errorf("parseSignal(rtmin+3) = %d, %v", sig, err)
+	}
+	if _, err := parseSignal("SIGRTMAX+1"); err == nil {
+		t.Errorf("parseSignal(SIGRTMAX+1): expected an error")
+	}
+}
+
+func TestDebugger_RestartCatchpoints(t *testing.T) {
+	fixture := filepath.Join(proctest.FindFixturesDir(), "increment.go")
+	exepath := filepath.Join(t.TempDir(), "increment")
+	if err := gobuild.GoBuild(exepath, []string{fixture}, ""); err != nil {
+		t.Fatalf("go build error %v", err)
+	}
+
+	d, err := New(&Config{WorkingDir: ".", ExecuteKind: ExecutingExistingFile}, []string{exepath})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Detach(true)
+
+	if _, err := d.SetSignalPolicy(api.SignalPolicy{Name: "SIGUSR1", Stop: true, Pass: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetSyscallCatchpoints(false, []string{"openat", "1000"}); err != nil {
+		t.Fatal(err)
+	}
+	panicCatch := &api.PanicCatchpoint{Recovered: true, Type: "string", Cond: `panic.(string) == "boom"`}
+	if err := d.SetPanicCatchpoint(panicCatch); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.Restart(false, RestartOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if pc := d.PanicCatchpoint(); !reflect.DeepEqual(pc, panicCatch) {
+		t.Fatalf("panic catchpoint lost on restart: %#v", pc)
+	}
+	if all, names := d.SyscallCatchpoints(); all || !reflect.DeepEqual(names, []string{"openat", "syscall_1000"}) {
+		t.Fatalf("syscall catchpoints lost on restart: %v %v", all, names)
+	}
+	for _, policy := range d.SignalPolicies() {
+		if policy.Name == "SIGUSR1" {
+			if !policy.Stop || !policy.Print || !policy.Pass {
+				t.Fatalf("policy of SIGUSR1 lost on restart: %#v", policy)
+			}
+			return
+		}
+	}
+	t.Fatal("SIGUSR1 not listed")
+}
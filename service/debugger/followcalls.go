@@ -0,0 +1,141 @@
+package debugger
+
+import (
+	"strings"
+
+	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/pkg/proc"
+	"github.com/hitzhangjie/dlv/service/api"
+)
+
+// followCalls sets tracepoints on the functions called by the functions
+// whose tracepoints, created with TraceFollowCalls, were just hit. Since this
+// happens when a traced function is entered its callees are instrumented
+// before they can be called. Only functions less than TraceFollowCalls calls
+// below a root function have their callees instrumented.
+// Must be called with targetMutex held.
+func (d *Debugger) followCalls(state *api.DebuggerState) error {
+	if state == nil {
+		return nil
+	}
+	for _, th := range state.Threads {
+		bp := th.Breakpoint
+		if bp == nil || bp.TraceFollowCalls <= 0 || !bp.Tracepoint || th.BreakpointInfo == nil {
+			continue
+		}
+		if th.BreakpointInfo.TraceDepth >= bp.TraceFollowCalls {
+			continue
+		}
+		// The topmost frame, rather than th.Function, is the function being
+		// entered when the tracepoint is at the start of an inlined call.
+		thread, found := d.target.FindThread(th.ID)
+		if !found {
+			continue
+		}
+		frames, err := proc.ThreadStacktrace(thread, 0)
+		if err != nil || len(frames) == 0 || frames[0].Call.Fn == nil {
+			continue
+		}
+		if err := d.instrumentCallees(frames[0].Call.Fn.Name, bp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instrumentCallees sets a tracepoint, with the same settings as bp, on the
+// entry point and the return instructions of every function directly
+// called by fnName.
+func (d *Debugger) instrumentCallees(fnName string, bp *api.Breakpoint) error {
+	if d.followedCalls[fnName] {
+		return nil
+	}
+	if d.followedCalls == nil {
+		d.followedCalls = make(map[string]bool)
+	}
+	d.followedCalls[fnName] = true
+
+	callees, err := d.callees(fnName)
+	if err != nil {
+		return err
+	}
+	for _, callee := range callees {
+		addrs, err := proc.FindFunctionLocation(d.target, callee, 0)
+		if err != nil {
+			log.Warn("could not follow call to %s: %v", callee, err)
+			continue
+		}
+		_, err = createLogicalBreakpoint(d, d.target, addrs, &api.Breakpoint{
+			Tracepoint:       true,
+			Stacktrace:       bp.Stacktrace,
+			LoadArgs:         bp.LoadArgs,
+			RootFuncName:     bp.RootFuncName,
+			TraceFollowCalls: bp.TraceFollowCalls,
+		}, 0)
+		if err != nil && !isBreakpointExistsErr(err) {
+			return err
+		}
+
+		retAddrs, err := d.functionReturnLocations(callee)
+		if err != nil {
+			return err
+		}
+		for _, addr := range retAddrs {
+			_, err = createLogicalBreakpoint(d, d.target, []uint64{addr}, &api.Breakpoint{
+				TraceReturn:      true,
+				Stacktrace:       bp.Stacktrace,
+				LoadArgs:         bp.LoadArgs,
+				RootFuncName:     bp.RootFuncName,
+				TraceFollowCalls: bp.TraceFollowCalls,
+			}, 0)
+			if err != nil && !isBreakpointExistsErr(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// callees returns the functions directly called by fnName. Calls through
+// function values and interfaces can not be resolved statically and are
+// not followed, neither are calls into the runtime: setting tracepoints on
+// the functions used by the runtime itself, like the memory allocator,
+// would make the target unusably slow.
+func (d *Debugger) callees(fnName string) ([]string, error) {
+	fns, err := d.target.BinInfo().FindFunction(fnName)
+	if err != nil {
+		return nil, err
+	}
+	var regs proc.Registers
+	if th := d.target.CurrentThread(); th != nil {
+		regs, _ = th.Registers()
+	}
+
+	seen := make(map[string]bool)
+	var r []string
+	for _, fn := range fns {
+		if fn.Entry == 0 {
+			continue
+		}
+		text, err := proc.Disassemble(d.target.Memory(), regs, d.target.Breakpoints(), d.target.BinInfo(), fn.Entry, fn.End)
+		if err != nil {
+			return nil, err
+		}
+		for _, instr := range text {
+			if !instr.IsCall() || instr.DestLoc == nil || instr.DestLoc.Fn == nil {
+				continue
+			}
+			name := instr.DestLoc.Fn.Name
+			if seen[name] || isRuntimeFunction(name) {
+				continue
+			}
+			seen[name] = true
+			r = append(r, name)
+		}
+	}
+	return r, nil
+}
+
+func isRuntimeFunction(name string) bool {
+	return strings.HasPrefix(name, "runtime.") || strings.HasPrefix(name, "internal/runtime/") || strings.HasPrefix(name, "runtime/internal/")
+}
@@ -0,0 +1,184 @@
+package debugger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"unsafe"
+
+	sys "golang.org/x/sys/unix"
+
+	"github.com/hitzhangjie/dlv/pkg/log"
+	"github.com/hitzhangjie/dlv/service/api"
+)
+
+// watchQuietPeriod is how long the source files must stay unchanged before
+// the target is rebuilt, editors often write a file more than once when
+// saving it.
+const watchQuietPeriod = 200 * time.Millisecond
+
+const watchMask = sys.IN_CLOSE_WRITE | sys.IN_MOVED_TO | sys.IN_MOVED_FROM | sys.IN_CREATE | sys.IN_DELETE
+
+// sourceWatcher watches, with inotify, the directories of the main module
+// for changes to Go source files.
+type sourceWatcher struct {
+	file *os.File
+	dirs map[int32]string // watch descriptor to directory
+}
+
+// watchSources starts rebuilding and restarting the target every time the
+// Go source files of the main module change. The result of every rebuild
+// is sent to the events channel, see WaitEvent.
+func (d *Debugger) watchSources() error {
+	switch d.config.ExecuteKind {
+	case ExecutingGeneratedFile, ExecutingGeneratedTest:
+	default:
+		return errors.New("watching the source files only works for executables built by delve")
+	}
+	root, err := mainModuleDir()
+	if err != nil {
+		return err
+	}
+	fd, err := sys.InotifyInit1(sys.IN_CLOEXEC | sys.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	w := &sourceWatcher{file: os.NewFile(uintptr(fd), "inotify"), dirs: make(map[int32]string)}
+	if err := w.addTree(root); err != nil {
+		w.close()
+		return err
+	}
+	log.Info("watching %s for changes", root)
+	d.watcher = w
+
+	changes := make(chan string)
+	go w.read(changes)
+	go d.rebuildOnChanges(changes)
+	return nil
+}
+
+// mainModuleDir returns the root directory of the main module, or the
+// current directory when not building in module mode.
+func mainModuleDir() (string, error) {
+	out, err := exec.Command("go", "env", "GOMOD").Output()
+	if err != nil {
+		return "", err
+	}
+	gomod := strings.TrimSpace(string(out))
+	if gomod == "" || gomod == os.DevNull {
+		return os.Getwd()
+	}
+	return filepath.Dir(gomod), nil
+}
+
+// addTree watches dir and its subdirectories, except hidden directories,
+// testdata, vendor and the directories of other modules.
+func (w *sourceWatcher) addTree(dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != dir {
+			name := info.Name()
+			if strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata" || name == "vendor" {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, "go.mod")); err == nil {
+				return filepath.SkipDir
+			}
+		}
+		wd, err := sys.InotifyAddWatch(int(w.file.Fd()), path, watchMask)
+		if err != nil {
+			return err
+		}
+		w.dirs[int32(wd)] = path
+		return nil
+	})
+}
+
+// read sends the paths of the Go source files that changed to changes,
+// until the watcher is closed.
+func (w *sourceWatcher) read(changes chan<- string) {
+	defer close(changes)
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		for off := 0; off+sys.SizeofInotifyEvent <= n; {
+			ev := (*sys.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			name := buf[off+sys.SizeofInotifyEvent : off+sys.SizeofInotifyEvent+int(ev.Len)]
+			off += sys.SizeofInotifyEvent + int(ev.Len)
+			dir, ok := w.dirs[ev.Wd]
+			if !ok {
+				continue
+			}
+			path := filepath.Join(dir, string(bytes.TrimRight(name, "\x00")))
+			if ev.Mask&sys.IN_ISDIR != 0 {
+				if ev.Mask&(sys.IN_CREATE|sys.IN_MOVED_TO) != 0 {
+					w.addTree(path)
+				}
+				continue
+			}
+			if strings.HasSuffix(path, ".go") {
+				changes <- path
+			}
+		}
+	}
+}
+
+func (w *sourceWatcher) close() {
+	w.file.Close()
+}
+
+// rebuildOnChanges rebuilds and restarts the target once the files sent to
+// changes stop changing for watchQuietPeriod.
+func (d *Debugger) rebuildOnChanges(changes <-chan string) {
+	files := make(map[string]bool)
+	timer := time.NewTimer(watchQuietPeriod)
+	timer.Stop()
+	for {
+		select {
+		case path, ok := <-changes:
+			if !ok {
+				timer.Stop()
+				return
+			}
+			files[path] = true
+			timer.Reset(watchQuietPeriod)
+		case <-timer.C:
+			changed := make([]string, 0, len(files))
+			for path := range files {
+				changed = append(changed, path)
+			}
+			sort.Strings(changed)
+			files = make(map[string]bool)
+			d.sendEvent(d.rebuild(changed))
+		}
+	}
+}
+
+// rebuild stops the target, if it is running, then rebuilds and restarts
+// it, files are the source files that changed.
+func (d *Debugger) rebuild(files []string) *api.DebuggerState {
+	log.Info("%s changed, rebuilding", strings.Join(files, ", "))
+	if d.IsRunning() {
+		if _, err := d.Command(&api.DebuggerCommand{Name: api.Halt}, nil); err != nil {
+			log.Warn("could not stop the target: %v", err)
+		}
+	}
+	r := &api.Rebuild{Files: files}
+	discarded, err := d.Restart(true, RestartOptions{})
+	if err != nil {
+		r.Err = err.Error()
+		return &api.DebuggerState{Rebuild: r}
+	}
+	r.DiscardedBreakpoints = discarded
+	return &api.DebuggerState{Pid: d.ProcessPid(), Watching: true, Rebuild: r}
+}
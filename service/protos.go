@@ -38,6 +38,22 @@ type DetachOut struct {
 type RestartIn struct {
 	// When Rebuild is set the process will be build again
 	Rebuild bool
+	// BuildFlags, if not nil, replace the flags passed to the go command
+	// when the process is rebuilt, for this and the following rebuilds.
+	BuildFlags *string
+	// ResetArgs tells whether NewArgs and NewRedirects should take effect.
+	ResetArgs bool
+	// NewArgs are arguments to launch a new process. They replace only the
+	// argv[1] and later. Argv[0] cannot be changed.
+	NewArgs []string
+	// NewRedirects are the files stdin, stdout and stderr are redirected
+	// to, empty for the ones that are not redirected.
+	NewRedirects [3]string
+	// Env are changes to the environment of the process, KEY=VALUE sets a
+	// variable, KEY removes it.
+	Env []string
+	// WorkingDir, if not empty, is the new working directory of the process.
+	WorkingDir string
 }
 
 type RestartOut struct {
@@ -70,6 +86,15 @@ type GetBufferedTracepointsOut struct {
 	TracepointResults []api.TracepointResult
 }
 
+// rpc GetLatencyHistograms
+
+type GetLatencyHistogramsIn struct {
+}
+
+type GetLatencyHistogramsOut struct {
+	Histograms []api.LatencyHistogram
+}
+
 // rpc GetBreakpoint
 
 type GetBreakpointIn struct {
@@ -132,6 +157,18 @@ type CreateBreakpointOut struct {
 
 type CreateEBPFTracepointIn struct {
 	FunctionName string
+	// LoadArgs limits how much of the strings and slices passed to the
+	// function is read, MaxStringLen and MaxArrayValues are used.
+	LoadArgs *api.LoadConfig
+	// Captures lists expressions, such as "req.Header.Host", that are read
+	// by following pointers from the arguments of the function.
+	Captures []string
+	// Cond is a condition, such as "req.ID > 100", evaluated in the kernel
+	// at function entry. Calls for which it is false are not reported.
+	Cond string
+	// Latency, if set, measures the duration of the calls to the function
+	// instead of reporting them, see GetLatencyHistograms.
+	Latency bool
 }
 
 type CreateEBPFTracepointOut struct {
@@ -362,6 +399,77 @@ type RecordedOut struct {
 	TraceDirectory string
 }
 
+// rpc Record
+
+type RecordIn struct {
+	On bool
+}
+
+type RecordOut struct {
+}
+
+// rpc ListSignalPolicies
+
+type ListSignalPoliciesIn struct {
+}
+
+type ListSignalPoliciesOut struct {
+	Policies []api.SignalPolicy
+}
+
+// rpc SetSignalPolicy
+
+type SetSignalPolicyIn struct {
+	Policy api.SignalPolicy
+}
+
+type SetSignalPolicyOut struct {
+	Policy api.SignalPolicy
+}
+
+// rpc ListSyscallCatchpoints
+
+type ListSyscallCatchpointsIn struct {
+}
+
+type ListSyscallCatchpointsOut struct {
+	All      bool
+	Syscalls []string
+}
+
+// rpc SetSyscallCatchpoints
+
+type SetSyscallCatchpointsIn struct {
+	// All catches every system call.
+	All bool
+	// Syscalls are the names or numbers of the system calls to catch.
+	Syscalls []string
+}
+
+type SetSyscallCatchpointsOut struct {
+}
+
+// rpc GetPanicCatchpoint
+
+type GetPanicCatchpointIn struct {
+}
+
+type GetPanicCatchpointOut struct {
+	// Catchpoint is nil if only unrecovered panics of any type stop the
+	// target, which is the default.
+	Catchpoint *api.PanicCatchpoint
+}
+
+// rpc SetPanicCatchpoint
+
+type SetPanicCatchpointIn struct {
+	// Catchpoint, if nil, restores the default.
+	Catchpoint *api.PanicCatchpoint
+}
+
+type SetPanicCatchpointOut struct {
+}
+
 // rpc Checkpoint
 
 type CheckpointIn struct {
@@ -390,6 +498,34 @@ type ClearCheckpointIn struct {
 type ClearCheckpointOut struct {
 }
 
+// rpc ListTargets
+
+type ListTargetsIn struct {
+}
+
+type ListTargetsOut struct {
+	Targets []api.Target
+}
+
+// rpc SwitchTarget
+
+type SwitchTargetIn struct {
+	Pid int
+}
+
+type SwitchTargetOut struct {
+	State *api.DebuggerState
+}
+
+// rpc WaitEvent
+
+type WaitEventIn struct {
+}
+
+type WaitEventOut struct {
+	State *api.DebuggerState
+}
+
 // rpc IsMulticlient
 
 type IsMulticlientIn struct {
@@ -108,7 +108,7 @@ func (s *server) Stop() error {
 		s.debugger.Command(&api.DebuggerCommand{Name: api.Halt}, nil)
 	}
 	// if tracee is launched by tracer, kill it
-	kill := s.config.DebuggerConfig.AttachPid == 0
+	kill := s.debugger.AttachPid() == 0
 	return s.debugger.Detach(kill)
 }
 
@@ -46,13 +46,20 @@ func (s *RPCServer) Detach(arg DetachIn, out *DetachOut) error {
 // Restart restarts program.
 func (s *RPCServer) Restart(arg RestartIn, cb RPCCallback) {
 	close(cb.SetupDoneChan())
-	if s.config.DebuggerConfig.AttachPid != 0 {
+	if s.debugger.AttachPid() != 0 {
 		cb.Return(nil, errors.New("cannot restart process Delve did not create"))
 		return
 	}
 	var out RestartOut
 	var err error
-	out.DiscardedBreakpoints, err = s.debugger.Restart(arg.Rebuild)
+	out.DiscardedBreakpoints, err = s.debugger.Restart(arg.Rebuild, debugger.RestartOptions{
+		BuildFlags:   arg.BuildFlags,
+		ResetArgs:    arg.ResetArgs,
+		NewArgs:      arg.NewArgs,
+		NewRedirects: arg.NewRedirects,
+		Env:          arg.Env,
+		WorkingDir:   arg.WorkingDir,
+	})
 	cb.Return(out, err)
 }
 
@@ -155,9 +162,16 @@ func (s *RPCServer) CreateBreakpoint(arg CreateBreakpointIn, out *CreateBreakpoi
 	return nil
 }
 
+// GetLatencyHistograms returns the latency histograms collected by the
+// eBPF latency tracepoints.
+func (s *RPCServer) GetLatencyHistograms(arg GetLatencyHistogramsIn, out *GetLatencyHistogramsOut) error {
+	out.Histograms = s.debugger.GetLatencyHistograms()
+	return nil
+}
+
 // CreateEBPFTracepoint create ebpf tracepoint
 func (s *RPCServer) CreateEBPFTracepoint(arg CreateEBPFTracepointIn, out *CreateEBPFTracepointOut) error {
-	return s.debugger.CreateEBPFTracepoint(arg.FunctionName)
+	return s.debugger.CreateEBPFTracepoint(arg.FunctionName, api.LoadConfigToProc(arg.LoadArgs), arg.Captures, arg.Cond, arg.Latency)
 }
 
 // ClearBreakpoint deletes a breakpoint by Name (if Name is not an
@@ -410,7 +424,7 @@ func (s *RPCServer) ListGoroutines(arg ListGoroutinesIn, out *ListGoroutinesOut)
 
 // AttachedToExistingProcess returns whether we attached to a running process or not
 func (c *RPCServer) AttachedToExistingProcess(arg AttachedToExistingProcessIn, out *AttachedToExistingProcessOut) error {
-	if c.config.DebuggerConfig.AttachPid != 0 {
+	if c.debugger.AttachPid() != 0 {
 		out.Answer = true
 	}
 	return nil
@@ -457,6 +471,108 @@ func (c *RPCServer) Disassemble(arg DisassembleIn, out *DisassembleOut) error {
 	return nil
 }
 
+// Recorded returns true if the target has something recorded that can be
+// executed backwards.
+func (s *RPCServer) Recorded(arg RecordedIn, out *RecordedOut) error {
+	out.Recorded, out.TraceDirectory = s.debugger.Recorded()
+	return nil
+}
+
+// Record starts or stops recording the execution of the current thread.
+func (s *RPCServer) Record(arg RecordIn, out *RecordOut) error {
+	return s.debugger.Record(arg.On)
+}
+
+// ListSignalPolicies returns how each signal received by the target is
+// handled.
+func (s *RPCServer) ListSignalPolicies(arg ListSignalPoliciesIn, out *ListSignalPoliciesOut) error {
+	out.Policies = s.debugger.SignalPolicies()
+	return nil
+}
+
+// SetSignalPolicy changes how a signal received by the target is handled,
+// the signal can be specified by number or by name.
+func (s *RPCServer) SetSignalPolicy(arg SetSignalPolicyIn, out *SetSignalPolicyOut) error {
+	var err error
+	out.Policy, err = s.debugger.SetSignalPolicy(arg.Policy)
+	return err
+}
+
+// ListSyscallCatchpoints returns the system calls that stop the target.
+func (s *RPCServer) ListSyscallCatchpoints(arg ListSyscallCatchpointsIn, out *ListSyscallCatchpointsOut) error {
+	out.All, out.Syscalls = s.debugger.SyscallCatchpoints()
+	return nil
+}
+
+// SetSyscallCatchpoints changes the system calls that stop the target,
+// the target stops when entering and when returning from them.
+func (s *RPCServer) SetSyscallCatchpoints(arg SetSyscallCatchpointsIn, out *SetSyscallCatchpointsOut) error {
+	return s.debugger.SetSyscallCatchpoints(arg.All, arg.Syscalls)
+}
+
+// GetPanicCatchpoint returns the panics that stop the target.
+func (s *RPCServer) GetPanicCatchpoint(arg GetPanicCatchpointIn, out *GetPanicCatchpointOut) error {
+	out.Catchpoint = s.debugger.PanicCatchpoint()
+	return nil
+}
+
+// SetPanicCatchpoint changes the panics that stop the target.
+func (s *RPCServer) SetPanicCatchpoint(arg SetPanicCatchpointIn, out *SetPanicCatchpointOut) error {
+	return s.debugger.SetPanicCatchpoint(arg.Catchpoint)
+}
+
+// Checkpoint sets a checkpoint at the current position.
+func (s *RPCServer) Checkpoint(arg CheckpointIn, out *CheckpointOut) error {
+	var err error
+	out.ID, err = s.debugger.Checkpoint(arg.Where)
+	return err
+}
+
+// ListCheckpoints lists checkpoints.
+func (s *RPCServer) ListCheckpoints(arg ListCheckpointsIn, out *ListCheckpointsOut) error {
+	cps, err := s.debugger.Checkpoints()
+	if err != nil {
+		return err
+	}
+	out.Checkpoints = make([]api.Checkpoint, len(cps))
+	for i := range cps {
+		out.Checkpoints[i] = api.Checkpoint(cps[i])
+	}
+	return nil
+}
+
+// ClearCheckpoint removes a checkpoint.
+func (s *RPCServer) ClearCheckpoint(arg ClearCheckpointIn, out *ClearCheckpointOut) error {
+	return s.debugger.ClearCheckpoint(arg.ID)
+}
+
+// ListTargets lists the processes being debugged.
+func (s *RPCServer) ListTargets(arg ListTargetsIn, out *ListTargetsOut) error {
+	out.Targets = s.debugger.ListTargets()
+	return nil
+}
+
+// SwitchTarget makes the process with the specified pid the target of
+// subsequent commands.
+func (s *RPCServer) SwitchTarget(arg SwitchTargetIn, out *SwitchTargetOut) error {
+	if err := s.debugger.SwitchTarget(arg.Pid); err != nil {
+		return err
+	}
+	st, err := s.debugger.State(false)
+	if err != nil {
+		return err
+	}
+	out.State = st
+	return nil
+}
+
+// WaitEvent waits for a thread, left running in non-stop mode, to stop and
+// returns the state of the debugger at that point.
+func (s *RPCServer) WaitEvent(arg WaitEventIn, cb RPCCallback) {
+	close(cb.SetupDoneChan())
+	cb.Return(WaitEventOut{State: s.debugger.WaitEvent()}, nil)
+}
+
 func (s *RPCServer) IsMulticlient(arg IsMulticlientIn, out *IsMulticlientOut) error {
 	*out = IsMulticlientOut{
 		IsMulticlient: s.config.AcceptMulti,
@@ -108,7 +108,7 @@ func withTestProcessArgs(name string, t *testing.T, wd string, args []string, bu
 	var err error
 	switch testBackend {
 	case "native":
-		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, 0)
+		p, err = native.Launch(append([]string{fixture.Path}, args...), wd, 0, native.LaunchOptions{})
 	default:
 		t.Fatalf("unknown backend %q", testBackend)
 	}
@@ -795,6 +795,20 @@ func TestEvalExpression(t *testing.T) {
 		{"ni8 << 8", false, "0", "0", "int8", nil},
 		{"ni8 >> 1", false, "-3", "-3", "int8", nil},
 		{"bytearray[0] * bytearray[0]", false, "144", "144", "uint8", nil},
+
+		// composite literals and new
+		{"main.astruct{A: 1, B: 2}", false, "main.astruct {A: 1, B: 2}", "main.astruct {A: 1, B: 2}", "main.astruct", nil},
+		{"astruct{3, 4}", false, "main.astruct {A: 3, B: 4}", "main.astruct {A: 3, B: 4}", "main.astruct", nil},
+		{"main.astruct{B: 5}.B", false, "5", "5", "int", nil},
+		{"[]int{1, 2, 3}", false, "[]int len: 3, cap: 3, [1,2,3]", "[]int len: 3, cap: 3, [...]", "[]int", nil},
+		{"[...]int{2: 1}", false, "[3]int [0,0,1]", "[3]int [...]", "[3]int", nil},
+		{"[]main.astruct{{1, 2}, {B: 3}}", false, "[]main.astruct len: 2, cap: 2, [{A: 1, B: 2},{A: 0, B: 3}]", "[]main.astruct len: 2, cap: 2, [...]", "[]main.astruct", nil},
+		{`[]string{"a", "b"}`, false, `[]string len: 2, cap: 2, ["a","b"]`, "[]string len: 2, cap: 2, [...]", "[]string", nil},
+		{"*new(int)", false, "0", "0", "int", nil},
+		{"*new(main.astruct)", false, "main.astruct {A: 0, B: 0}", "main.astruct {A: 0, B: 0}", "main.astruct", nil},
+		{"main.astruct{C: 1}", false, "", "", "", errors.New("unknown field C in struct literal of type main.astruct")},
+		{"main.astruct{1}", false, "", "", "", errors.New("wrong number of values in struct literal of type main.astruct")},
+		{`map[string]main.astruct{"a": {}}`, false, "", "", "", errors.New("map literals can not be evaluated because function calls are not allowed without using 'call'")},
 	}
 
 	ver, _ := goversion.Parse(runtime.Version())
@@ -1135,6 +1149,18 @@ func TestCallFunction(t *testing.T) {
 		{`"de"+"mo"`, []string{`::"demo"`}, nil},
 	}
 
+	var testcasesCompositeLit = []testCaseCallFunction{
+		// composite literals and new allocated in the target
+		{`stringsJoin([]string{"x", "y"}, comma)`, []string{`:string:"x,y"`}, nil},
+		{`getAStruct(3).VRcvr(len([]int{1, 2}))`, []string{`:string:"2 + 3 = 5"`}, nil},
+		{`pa2 = &astruct{X: 9}; pa2`, []string{`pa2:*main.astruct:*main.astruct {X: 9}`}, nil},
+		{`pa2 = new(astruct); pa2`, []string{`pa2:*main.astruct:*main.astruct {X: 0}`}, nil},
+		// the garbage collector is enabled again once the literal is built
+		// and once the arguments of a call are in its argument frame
+		{`pa2 = &astruct{X: 9}; runtime.memstats.enablegc`, []string{`:bool:true`}, nil},
+		{`stringsJoin([]string{"x", "y"}, comma); runtime.memstats.enablegc`, []string{`:string:"x,y"`, `:bool:true`}, nil},
+	}
+
 	var testcases112 = []testCaseCallFunction{
 		// string allocation requires trusted argument order, which we don't have in Go 1.11
 		{`stringsJoin(stringslice, ",")`, []string{`:string:"one,two,three"`}, nil},
@@ -1218,11 +1244,69 @@ func TestCallFunction(t *testing.T) {
 			}
 		}
 
+		if goversion.VersionAfterOrEqual(runtime.Version(), 1, 17) {
+			for _, tc := range testcasesCompositeLit {
+				testCallFunction(t, p, tc)
+			}
+		}
+
 		// LEAVE THIS AS THE LAST ITEM, IT BREAKS THE TARGET PROCESS!!!
 		testCallFunction(t, p, testCaseCallFunction{"-unsafe escapeArg(&a2)", nil, nil})
 	})
 }
 
+func TestCallFunctionParkedGoroutine(t *testing.T) {
+	testcases := []testCaseCallFunction{
+		{"s.String()", []string{`:string:"state 1"`}, nil},
+		{"double(s.n)", []string{":int:2"}, nil},
+		{"s.n = double(3); s.n", []string{"s.n:int:6"}, nil},
+	}
+
+	withTestProcess("fncallparked", t, func(p *proc.Target, fixture proctest.Fixture) {
+		assertNoError(p.Continue(), t, "Continue()")
+		gs, _, err := proc.GoroutinesInfo(p, 0, 0)
+		assertNoError(err, t, "GoroutinesInfo()")
+		var worker *proc.G
+		for _, g := range gs {
+			if loc := g.StartLoc(p); loc.Fn != nil && loc.Fn.Name == "main.worker" {
+				worker = g
+			}
+		}
+		if worker == nil || worker.Thread != nil {
+			t.Fatalf("could not find parked goroutine running main.worker")
+		}
+		for _, tc := range testcases {
+			// the call is injected on a different goroutine, which becomes the
+			// selected goroutine when the call returns.
+			assertNoError(p.SwitchGoroutine(worker), t, "SwitchGoroutine()")
+			testCallFunction(t, p, tc)
+		}
+	})
+}
+
+func TestCallFunctionGenerics(t *testing.T) {
+	if !goversion.VersionAfterOrEqual(runtime.Version(), 1, 18) {
+		t.Skip("generics not supported")
+	}
+
+	testcases := []testCaseCallFunction{
+		{"sum[int](1, 2)", []string{":int:3"}, nil},
+		{"sum[float64](1.5, 2)", []string{":float64:3.5"}, nil},
+		{"s.Has(\"a\")", []string{":bool:true"}, nil},
+		{"s.Add(\"b\"); len(s.m)", []string{"len(s.m)::2"}, nil},
+		{"sum[int8](1, 2)", nil, errors.New("could not find function main.sum[int8]")},
+	}
+
+	withTestProcess("testvariables_generic", t, func(p *proc.Target, fixture proctest.Fixture) {
+		for i := 0; i < 4; i++ {
+			assertNoError(p.Continue(), t, fmt.Sprintf("Continue() returned an error (%d)", i))
+		}
+		for _, tc := range testcases {
+			testCallFunction(t, p, tc)
+		}
+	})
+}
+
 func testCallFunctionSetBreakpoint(t *testing.T, p *proc.Target, fixture proctest.Fixture) {
 	buf, err := ioutil.ReadFile(fixture.Source)
 	assertNoError(err, t, "ReadFile")
@@ -1462,6 +1546,9 @@ func TestEvalExpressionGenerics(t *testing.T) {
 			{"arg1", true, "3", "", "int", nil},
 			{"arg2", true, "2.1", "", "float32", nil},
 			{"m", true, "map[float32]int [2.1: 3, ]", "", "map[float32]int", nil},
+			{"T", true, "int", "", "int", nil},
+			{"K", true, "float32", "", "float32", nil},
+			{"T(2)", false, "2", "", "int", nil},
 		},
 
 		// testfn[*astruct, astruct]
@@ -1469,6 +1556,21 @@ func TestEvalExpressionGenerics(t *testing.T) {
 			{"arg1", true, "*main.astruct {x: 0, y: 1}", "", "*main.astruct", nil},
 			{"arg2", true, "main.astruct {x: 2, y: 3}", "", "main.astruct", nil},
 			{"m", true, "map[main.astruct]*main.astruct [{x: 2, y: 3}: *{x: 0, y: 1}, ]", "", "map[main.astruct]*main.astruct", nil},
+			{"T", true, "*main.astruct", "", "*main.astruct", nil},
+			{"K", true, "main.astruct", "", "main.astruct", nil},
+		},
+
+		// (*Set[string]).Len
+		{
+			{"T", true, "string", "", "string", nil},
+			{"len(s.m)", false, "1", "", "", nil},
+			{"T(\"b\")", false, "\"b\"", "", "string", nil},
+		},
+
+		// main
+		{
+			{"len((*Set[string])(uintptr(s)).m)", false, "1", "", "", nil},
+			{"T", false, "", "", "", errors.New("could not find symbol value for T")},
 		},
 	}
 